@@ -0,0 +1,275 @@
+package chainstream
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+var (
+	// ErrCursorPruned is returned when the pruning point isn't in the selected parent chain
+	// of the block of the cursor, or the block was deleted by the pruning, so that the
+	// acceptance data of the chain blocks the stream would go over may no longer exist
+	ErrCursorPruned = errors.New("the cursor was pruned")
+
+	// ErrUnknownCursor is returned when the block of the cursor is invalid, or its blue
+	// score doesn't match the one of the cursor
+	ErrUnknownCursor = errors.New("the cursor doesn't match any known block")
+
+	// ErrUnexpectedAcknowledgement is returned when an update that doesn't start at the
+	// current cursor is acknowledged
+	ErrUnexpectedAcknowledgement = errors.New("the update doesn't start at the current cursor")
+)
+
+// ChainStream streams the changes of the virtual selected parent chain relative to a cursor.
+// Every update takes the cursor from the last chain block the consumer processed to the new
+// virtual selected parent chain, first going down over the blocks that left the chain and then
+// up over the ones that joined it.
+//
+// Updates are delivered exactly once as long as the consumer persists the cursor of every update
+// along with its effects, acknowledges it, and passes the last persisted cursor to New when it
+// restarts. Until an update is acknowledged, Next keeps returning updates from the same cursor.
+type ChainStream interface {
+	Cursor() *Cursor
+	Next(maxAddedBlocks int) (*ChainUpdate, error)
+	Acknowledge(update *ChainUpdate) error
+	HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription, maxAddedBlocks int,
+		handler func(update *ChainUpdate) error) error
+}
+
+// ChainBlock is a block that either joined or left the virtual selected parent chain
+type ChainBlock struct {
+	Hash           *externalapi.DomainHash
+	BlueScore      uint64
+	AcceptanceData externalapi.AcceptanceData
+}
+
+// ChainUpdate takes a cursor to a newer one
+type ChainUpdate struct {
+	From *Cursor
+	To   *Cursor
+
+	// Removed are the chain blocks that left the chain, ordered from the cursor downwards
+	Removed []*ChainBlock
+
+	// Added are the chain blocks that joined the chain, ordered from the lowest upwards
+	Added []*ChainBlock
+}
+
+// IsEmpty returns whether the update doesn't change the cursor
+func (update *ChainUpdate) IsEmpty() bool {
+	return len(update.Removed) == 0 && len(update.Added) == 0
+}
+
+type chainStream struct {
+	lock sync.Mutex
+
+	consensus externalapi.Consensus
+	cursor    *Cursor
+}
+
+// New instantiates a ChainStream that starts after the given cursor, or at the pruning point if
+// cursor is nil
+func New(consensus externalapi.Consensus, cursor *Cursor) (ChainStream, error) {
+	if cursor == nil {
+		pruningPoint, err := consensus.PruningPoint()
+		if err != nil {
+			return nil, err
+		}
+		pruningPointInfo, err := consensus.GetBlockInfo(pruningPoint)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &Cursor{BlockHash: pruningPoint, BlueScore: pruningPointInfo.BlueScore}
+	}
+	return &chainStream{
+		consensus: consensus,
+		cursor:    cursor.Clone(),
+	}, nil
+}
+
+// Cursor returns the cursor of the last acknowledged update
+func (cs *chainStream) Cursor() *Cursor {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	return cs.cursor.Clone()
+}
+
+// Next returns the changes of the virtual selected parent chain since the cursor, with up to
+// maxAddedBlocks added chain blocks. The blocks that left the chain are always returned in full,
+// since the update must unwind all of them before the cursor can move up the new chain.
+func (cs *chainStream) Next(maxAddedBlocks int) (*ChainUpdate, error) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if maxAddedBlocks < 1 {
+		return nil, errors.Errorf("maxAddedBlocks must be positive, got %d", maxAddedBlocks)
+	}
+
+	err := cs.validateCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	chainPath, err := cs.consensus.GetVirtualSelectedParentChainFromBlock(cs.cursor.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	addedHashes := chainPath.Added
+	if len(addedHashes) > maxAddedBlocks {
+		addedHashes = addedHashes[:maxAddedBlocks]
+	}
+
+	removed, err := cs.chainBlocks(chainPath.Removed)
+	if err != nil {
+		return nil, err
+	}
+	added, err := cs.chainBlocks(addedHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &ChainUpdate{
+		From:    cs.cursor.Clone(),
+		To:      cs.cursor.Clone(),
+		Removed: removed,
+		Added:   added,
+	}
+	switch {
+	case len(added) > 0:
+		lastAdded := added[len(added)-1]
+		update.To = &Cursor{BlockHash: lastAdded.Hash, BlueScore: lastAdded.BlueScore}
+	case len(removed) > 0:
+		// The chain was only unwound, so the cursor moves to the common ancestor
+		lowestRemovedInfo, err := cs.consensus.GetBlockInfo(removed[len(removed)-1].Hash)
+		if err != nil {
+			return nil, err
+		}
+		commonAncestorInfo, err := cs.consensus.GetBlockInfo(lowestRemovedInfo.SelectedParent)
+		if err != nil {
+			return nil, err
+		}
+		update.To = &Cursor{BlockHash: lowestRemovedInfo.SelectedParent, BlueScore: commonAncestorInfo.BlueScore}
+	}
+	return update, nil
+}
+
+// validateCursor makes sure the chain blocks the stream goes over from the cursor can still be
+// streamed. The stream unwinds the cursor down to the virtual selected parent chain, which goes
+// through the pruning point, so the pruning point must be in the selected parent chain of the
+// cursor. Otherwise the cursor is in the past or the anticone of the pruning point, or unwinding
+// it goes below the pruning point. Blue scores can't tell that, since a side chain block may have
+// a higher blue score than the pruning point while being in its anticone.
+func (cs *chainStream) validateCursor() error {
+	cursorInfo, err := cs.consensus.GetBlockInfo(cs.cursor.BlockHash)
+	if err != nil {
+		return err
+	}
+	// The block of a cursor of this stream only disappears once the pruning deletes it
+	if !cursorInfo.Exists {
+		return errors.Wrapf(ErrCursorPruned, "block %s of the cursor doesn't exist", cs.cursor.BlockHash)
+	}
+	if !cursorInfo.HasHeader() {
+		return errors.Wrapf(ErrUnknownCursor, "block %s of the cursor is invalid", cs.cursor.BlockHash)
+	}
+	if cursorInfo.BlueScore != cs.cursor.BlueScore {
+		return errors.Wrapf(ErrUnknownCursor, "block %s of the cursor has blue score %d but the cursor has %d",
+			cs.cursor.BlockHash, cursorInfo.BlueScore, cs.cursor.BlueScore)
+	}
+
+	pruningPoint, err := cs.consensus.PruningPoint()
+	if err != nil {
+		return err
+	}
+	isPruningPointInCursorChain, err := cs.consensus.IsInSelectedParentChainOf(pruningPoint, cs.cursor.BlockHash)
+	if err != nil {
+		return err
+	}
+	if !isPruningPointInCursorChain {
+		return errors.Wrapf(ErrCursorPruned, "the pruning point %s isn't in the selected parent chain of "+
+			"block %s of the cursor", pruningPoint, cs.cursor.BlockHash)
+	}
+	return nil
+}
+
+func (cs *chainStream) chainBlocks(blockHashes []*externalapi.DomainHash) ([]*ChainBlock, error) {
+	acceptanceData, err := cs.consensus.GetBlocksAcceptanceData(blockHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	chainBlocks := make([]*ChainBlock, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		blockInfo, err := cs.consensus.GetBlockInfo(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		chainBlocks[i] = &ChainBlock{
+			Hash:           blockHash,
+			BlueScore:      blockInfo.BlueScore,
+			AcceptanceData: acceptanceData[i],
+		}
+	}
+	return chainBlocks, nil
+}
+
+// Acknowledge moves the cursor to the end of the given update, which must start at the current cursor
+func (cs *chainStream) Acknowledge(update *ChainUpdate) error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if !update.From.Equal(cs.cursor) {
+		return errors.Wrapf(ErrUnexpectedAcknowledgement, "the update starts at %s while the cursor is at %s",
+			update.From.BlockHash, cs.cursor.BlockHash)
+	}
+	cs.cursor = update.To.Clone()
+	return nil
+}
+
+// HandleConsensusEvents passes the updates of the stream to the given handler whenever the
+// virtual changes, until the subscription is canceled. Every update is acknowledged once the
+// handler returns without an error, and an error from the handler stops the streaming.
+// The events are only used as a signal, so events dropped by the subscription are harmless.
+func (cs *chainStream) HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription,
+	maxAddedBlocks int, handler func(update *ChainUpdate) error) error {
+
+	// Updates that accumulated before the subscription are handled first
+	err := cs.handleUpdates(maxAddedBlocks, handler)
+	if err != nil {
+		return err
+	}
+	for event := range subscription.Events() {
+		if _, ok := event.Event.(*externalapi.VirtualChangeSet); !ok {
+			continue
+		}
+		err := cs.handleUpdates(maxAddedBlocks, handler)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *chainStream) handleUpdates(maxAddedBlocks int, handler func(update *ChainUpdate) error) error {
+	for {
+		update, err := cs.Next(maxAddedBlocks)
+		if err != nil {
+			return err
+		}
+		if update.IsEmpty() {
+			return nil
+		}
+		err = handler(update)
+		if err != nil {
+			return err
+		}
+		err = cs.Acknowledge(update)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Streamed %d removed and %d added chain blocks up to %s",
+			len(update.Removed), len(update.Added), update.To.BlockHash)
+	}
+}
@@ -0,0 +1,222 @@
+package chainstream_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/chainstream"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+func addChain(t *testing.T, tc testapi.TestConsensus, tip *externalapi.DomainHash,
+	length int, coinbaseData *externalapi.DomainCoinbaseData) []*externalapi.DomainHash {
+
+	chain := make([]*externalapi.DomainHash, length)
+	for i := range chain {
+		blockHash, _, err := tc.AddBlock([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		chain[i] = blockHash
+		tip = blockHash
+	}
+	return chain
+}
+
+func checkChainBlocks(t *testing.T, name string, chainBlocks []*chainstream.ChainBlock, expected []*externalapi.DomainHash) {
+	if len(chainBlocks) != len(expected) {
+		t.Fatalf("TestChainStream: expected %d %s chain blocks but got %d", len(expected), name, len(chainBlocks))
+	}
+	for i, chainBlock := range chainBlocks {
+		if !chainBlock.Hash.Equal(expected[i]) {
+			t.Fatalf("TestChainStream: %s chain block %d: want: %s, got: %s", name, i, expected[i], chainBlock.Hash)
+		}
+		if len(chainBlock.AcceptanceData) == 0 {
+			t.Fatalf("TestChainStream: %s chain block %s has no acceptance data", name, chainBlock.Hash)
+		}
+	}
+}
+
+func TestChainStream(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestChainStream")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	mainChain := addChain(t, tc, consensusConfig.GenesisHash, 5, nil)
+
+	// A stream without a cursor starts at the pruning point, which is the genesis here
+	stream, err := chainstream.New(tc, nil)
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	if !stream.Cursor().BlockHash.Equal(consensusConfig.GenesisHash) {
+		t.Fatalf("TestChainStream: the stream doesn't start at the genesis")
+	}
+
+	// Until it's acknowledged, the same update is returned
+	update, err := stream.Next(3)
+	if err != nil {
+		t.Fatalf("Next: %+v", err)
+	}
+	repeatedUpdate, err := stream.Next(3)
+	if err != nil {
+		t.Fatalf("Next: %+v", err)
+	}
+	if !repeatedUpdate.To.Equal(update.To) {
+		t.Fatalf("TestChainStream: an unacknowledged update wasn't repeated")
+	}
+	checkChainBlocks(t, "added", update.Added, mainChain[:3])
+	checkChainBlocks(t, "removed", update.Removed, nil)
+	err = stream.Acknowledge(update)
+	if err != nil {
+		t.Fatalf("Acknowledge: %+v", err)
+	}
+	err = stream.Acknowledge(update)
+	if !errors.Is(err, chainstream.ErrUnexpectedAcknowledgement) {
+		t.Fatalf("TestChainStream: expected ErrUnexpectedAcknowledgement but got: %+v", err)
+	}
+
+	update, err = stream.Next(10)
+	if err != nil {
+		t.Fatalf("Next: %+v", err)
+	}
+	checkChainBlocks(t, "added", update.Added, mainChain[3:])
+	err = stream.Acknowledge(update)
+	if err != nil {
+		t.Fatalf("Acknowledge: %+v", err)
+	}
+	persistedCursor := stream.Cursor().Serialize()
+
+	// A longer side chain that forks from mainChain[1] reorgs the chain. Its blocks pay a different
+	// script so that they differ from the main chain blocks with the same parents.
+	sideChainCoinbaseData := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	sideChain := addChain(t, tc, mainChain[1], 7, sideChainCoinbaseData)
+
+	// A stream that resumes from the persisted cursor first unwinds the removed blocks from the
+	// cursor downwards, and then goes up the side chain
+	cursor, err := chainstream.DeserializeCursor(persistedCursor)
+	if err != nil {
+		t.Fatalf("DeserializeCursor: %+v", err)
+	}
+	resumedStream, err := chainstream.New(tc, cursor)
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	update, err = resumedStream.Next(100)
+	if err != nil {
+		t.Fatalf("Next: %+v", err)
+	}
+	checkChainBlocks(t, "removed", update.Removed, []*externalapi.DomainHash{mainChain[4], mainChain[3], mainChain[2]})
+	checkChainBlocks(t, "added", update.Added, sideChain)
+	if !update.To.BlockHash.Equal(sideChain[len(sideChain)-1]) {
+		t.Fatalf("TestChainStream: the update doesn't end at the side chain tip")
+	}
+	err = resumedStream.Acknowledge(update)
+	if err != nil {
+		t.Fatalf("Acknowledge: %+v", err)
+	}
+	update, err = resumedStream.Next(100)
+	if err != nil {
+		t.Fatalf("Next: %+v", err)
+	}
+	if !update.IsEmpty() {
+		t.Fatalf("TestChainStream: expected an empty update at the virtual selected parent")
+	}
+
+	// A cursor whose blue score doesn't match its block is rejected
+	mismatchedCursor := &chainstream.Cursor{BlockHash: mainChain[2], BlueScore: cursor.BlueScore}
+	mismatchedStream, err := chainstream.New(tc, mismatchedCursor)
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	_, err = mismatchedStream.Next(100)
+	if !errors.Is(err, chainstream.ErrUnknownCursor) {
+		t.Fatalf("TestChainStream: expected ErrUnknownCursor but got: %+v", err)
+	}
+}
+
+func TestChainStreamPrunedCursor(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+	consensusConfig.DisableDifficultyAdjustment = true
+	// This is done to reduce the finality interval to 5 blocks and the pruning depth to 12 blocks
+	consensusConfig.FinalityDuration = 5 * consensusConfig.TargetTimePerBlock
+	consensusConfig.K = 0
+	consensusConfig.DifficultyAdjustmentWindowSize = 10
+
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestChainStreamPrunedCursor")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	mainChain := addChain(t, tc, consensusConfig.GenesisHash, 10, nil)
+	sideChainCoinbaseData := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	sideChain := addChain(t, tc, mainChain[2], 3, sideChainCoinbaseData)
+	mainChain = append(mainChain, addChain(t, tc, mainChain[len(mainChain)-1], 70, nil)...)
+
+	pruningPoint, err := tc.PruningPoint()
+	if err != nil {
+		t.Fatalf("PruningPoint: %+v", err)
+	}
+	if pruningPoint.Equal(consensusConfig.GenesisHash) {
+		t.Fatalf("TestChainStreamPrunedCursor: the pruning point didn't move")
+	}
+
+	cursorOf := func(blockHash *externalapi.DomainHash) *chainstream.Cursor {
+		blockInfo, err := tc.GetBlockInfo(blockHash)
+		if err != nil {
+			t.Fatalf("GetBlockInfo: %+v", err)
+		}
+		return &chainstream.Cursor{BlockHash: blockHash, BlueScore: blockInfo.BlueScore}
+	}
+
+	tests := []struct {
+		name   string
+		cursor *chainstream.Cursor
+	}{
+		{name: "main chain block below the pruning point", cursor: cursorOf(mainChain[5])},
+		{name: "side chain block", cursor: cursorOf(sideChain[len(sideChain)-1])},
+		{
+			// The blue score of a deleted block is unknown, so it doesn't matter
+			name: "deleted block",
+			cursor: &chainstream.Cursor{
+				BlockHash: externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+				BlueScore: 1000,
+			},
+		},
+	}
+	for _, test := range tests {
+		stream, err := chainstream.New(tc, test.cursor)
+		if err != nil {
+			t.Fatalf("New: %+v", err)
+		}
+		_, err = stream.Next(100)
+		if !errors.Is(err, chainstream.ErrCursorPruned) {
+			t.Fatalf("TestChainStreamPrunedCursor: %s: expected ErrCursorPruned but got: %+v", test.name, err)
+		}
+	}
+
+	stream, err := chainstream.New(tc, cursorOf(pruningPoint))
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	_, err = stream.Next(100)
+	if err != nil {
+		t.Fatalf("TestChainStreamPrunedCursor: expected a cursor at the pruning point to be streamed from: %+v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package chainstream
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+const serializedCursorLength = externalapi.DomainHashSize + 8
+
+// Cursor is the last virtual selected parent chain block a consumer of a ChainStream
+// has processed. The blue score is kept along with the hash, so that a cursor that was
+// pruned can be told apart from one that never existed.
+type Cursor struct {
+	BlockHash *externalapi.DomainHash
+	BlueScore uint64
+}
+
+// Equal returns whether cursor equals to other
+func (cursor *Cursor) Equal(other *Cursor) bool {
+	if cursor == nil || other == nil {
+		return cursor == other
+	}
+	return cursor.BlockHash.Equal(other.BlockHash) && cursor.BlueScore == other.BlueScore
+}
+
+// Clone returns a clone of Cursor
+func (cursor *Cursor) Clone() *Cursor {
+	return &Cursor{
+		BlockHash: cursor.BlockHash,
+		BlueScore: cursor.BlueScore,
+	}
+}
+
+// Serialize serializes the cursor so that it can be persisted by the consumer
+func (cursor *Cursor) Serialize() []byte {
+	serialized := make([]byte, serializedCursorLength)
+	copy(serialized, cursor.BlockHash.ByteSlice())
+	binary.LittleEndian.PutUint64(serialized[externalapi.DomainHashSize:], cursor.BlueScore)
+	return serialized
+}
+
+// DeserializeCursor deserializes a cursor that was serialized with Cursor.Serialize
+func DeserializeCursor(serialized []byte) (*Cursor, error) {
+	if len(serialized) != serializedCursorLength {
+		return nil, errors.Errorf("a serialized cursor is %d bytes but got %d bytes",
+			serializedCursorLength, len(serialized))
+	}
+	blockHash, err := externalapi.NewDomainHashFromByteSlice(serialized[:externalapi.DomainHashSize])
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{
+		BlockHash: blockHash,
+		BlueScore: binary.LittleEndian.Uint64(serialized[externalapi.DomainHashSize:]),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+
+	infrastructuredatabase "github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
+	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database/ldb"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+	"github.com/zilong-dai/karlsen-miner/prefixmanager/prefix"
+)
+
+const defaultCacheSizeMiB = 256
+
+// databaseFlags are the flags shared by every command that opens a consensus database
+type databaseFlags struct {
+	network      string
+	dbPath       string
+	prefix       uint
+	cacheSizeMiB int
+}
+
+func (df *databaseFlags) register(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&df.network, "network", "mainnet", "The network of the database: mainnet, testnet, simnet or devnet")
+	flagSet.StringVar(&df.dbPath, "dbpath", "", "The path of the consensus database directory")
+	flagSet.UintVar(&df.prefix, "prefix", 0, "The database prefix the consensus is stored under")
+	flagSet.IntVar(&df.cacheSizeMiB, "cache", defaultCacheSizeMiB, "The LevelDB cache size in MiB")
+}
+
+func (df *databaseFlags) params() (*dagconfig.Params, error) {
+	return networkParams(df.network)
+}
+
+func (df *databaseFlags) consensusConfig() (*consensus.Config, error) {
+	params, err := df.params()
+	if err != nil {
+		return nil, err
+	}
+	return &consensus.Config{Params: *params}, nil
+}
+
+func (df *databaseFlags) databasePrefix() (*prefix.Prefix, error) {
+	if df.prefix > 0xff {
+		return nil, errors.Errorf("invalid prefix %d", df.prefix)
+	}
+	return prefix.Deserialize([]byte{byte(df.prefix)})
+}
+
+func (df *databaseFlags) openDatabase() (infrastructuredatabase.Database, error) {
+	if df.dbPath == "" {
+		return nil, errors.New("--dbpath is required")
+	}
+	return ldb.NewLevelDB(df.dbPath, df.cacheSizeMiB)
+}
+
+func networkParams(network string) (*dagconfig.Params, error) {
+	switch network {
+	case "mainnet":
+		return &dagconfig.MainnetParams, nil
+	case "testnet":
+		return &dagconfig.TestnetParams, nil
+	case "simnet":
+		return &dagconfig.SimnetParams, nil
+	case "devnet":
+		return &dagconfig.DevnetParams, nil
+	}
+	return nil, errors.Errorf("unknown network %q", network)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/difficultysimulator"
+)
+
+func difficultySimulate(args []string) error {
+	flagSet := flag.NewFlagSet("difficulty-simulate", flag.ExitOnError)
+	network := flagSet.String("network", "simnet", "The network whose consensus parameters are simulated: mainnet, testnet, simnet or devnet")
+	duration := flagSet.Duration("duration", time.Hour, "The simulated time")
+	windowSize := flagSet.Int("daawindow", 0, "Override the DAA window size, or 0 for the network's")
+	steps := flagSet.String("hashrate", "0:1", "Comma separated hashrate steps as <seconds>:<multiple of the genesis hashrate>")
+	churningMiners := flagSet.Int("churnminers", 0, "The number of miners that keep going online and offline")
+	churnMinerShare := flagSet.Float64("churnminershare", 0.01, "The hashrate of every churning miner, as a multiple of the genesis hashrate")
+	churnPeriod := flagSet.Duration("churnperiod", 10*time.Minute, "The mean online and offline period of the churning miners")
+	attackerShare := flagSet.Float64("attacker", 0, "The hashrate of the timestamp-manipulating attacker, as a multiple of the genesis hashrate")
+	attackerStrategy := flagSet.String("attackerstrategy", "future", "The timestamps of the attacker: future or past")
+	propagationDelay := flagSet.Duration("delay", 0, "The block propagation delay")
+	seed := flagSet.Int64("seed", 0, "The seed of the simulation")
+	blocksPath := flagSet.String("blocks", "", "Also write every mined block as CSV to this file")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	params, err := networkParams(*network)
+	if err != nil {
+		return err
+	}
+	consensusConfig := &consensus.Config{Params: *params}
+	if *windowSize != 0 {
+		consensusConfig.DifficultyAdjustmentWindowSize = *windowSize
+	}
+	genesisHashesPerSecond := difficultysimulator.GenesisHashesPerSecond(params)
+
+	stepHashrate, err := parseHashrateSteps(*steps, genesisHashesPerSecond)
+	if err != nil {
+		return err
+	}
+	honest := difficultysimulator.SumHashrate{stepHashrate}
+	if *churningMiners > 0 {
+		honest = append(honest, difficultysimulator.NewChurningMiners(rand.New(rand.NewSource(*seed)), *churningMiners,
+			*churnMinerShare*genesisHashesPerSecond, *duration, *churnPeriod, *churnPeriod))
+	}
+
+	config := &difficultysimulator.Config{
+		ConsensusConfig:  consensusConfig,
+		Duration:         *duration,
+		PropagationDelay: *propagationDelay,
+		Honest:           honest,
+		Seed:             *seed,
+	}
+	if *attackerShare > 0 {
+		config.Attacker = &difficultysimulator.Attacker{
+			Hashrate: difficultysimulator.ConstantHashrate(*attackerShare * genesisHashesPerSecond),
+		}
+		switch *attackerStrategy {
+		case "future":
+			config.Attacker.Strategy = difficultysimulator.TimestampStrategyFuture
+		case "past":
+			config.Attacker.Strategy = difficultysimulator.TimestampStrategyPast
+		default:
+			return errors.Errorf("unknown attacker strategy %q", *attackerStrategy)
+		}
+	}
+
+	result, err := difficultysimulator.Run(config)
+	if err != nil {
+		return err
+	}
+
+	if *blocksPath != "" {
+		blocksFile, err := os.Create(*blocksPath)
+		if err != nil {
+			return err
+		}
+		defer blocksFile.Close()
+		err = result.WriteBlocksCSV(blocksFile)
+		if err != nil {
+			return err
+		}
+	}
+	return result.WriteSamplesCSV(os.Stdout)
+}
+
+// parseHashrateSteps parses steps such as "0:1,600:4" into a StepHashrate
+func parseHashrateSteps(steps string, genesisHashesPerSecond float64) (difficultysimulator.StepHashrate, error) {
+	var hashrateSteps []difficultysimulator.HashrateStep
+	for _, step := range strings.Split(steps, ",") {
+		parts := strings.Split(step, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid hashrate step %q", step)
+		}
+		seconds, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid hashrate step %q", step)
+		}
+		multiple, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid hashrate step %q", step)
+		}
+		hashrateSteps = append(hashrateSteps, difficultysimulator.HashrateStep{
+			At:              time.Duration(seconds * float64(time.Second)),
+			HashesPerSecond: multiple * genesisHashesPerSecond,
+		})
+	}
+	return difficultysimulator.NewStepHashrate(hashrateSteps...), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/emission"
+)
+
+func emissionSchedule(args []string) error {
+	flagSet := flag.NewFlagSet("emission", flag.ExitOnError)
+	network := flagSet.String("network", "mainnet", "The network whose emission is calculated: mainnet, testnet, simnet or devnet")
+	format := flagSet.String("format", "csv", "The output format of the schedule: csv or json")
+	daaScore := flagSet.Int64("daascore", -1, "Print the block subsidy and the supply at this DAA score instead of the schedule")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	params, err := networkParams(*network)
+	if err != nil {
+		return err
+	}
+	schedule := emission.NewSchedule(params)
+	err = schedule.CrossCheck()
+	if err != nil {
+		return err
+	}
+
+	if *daaScore >= 0 {
+		fmt.Printf("Block subsidy at DAA score %d: %d sompi\n", *daaScore, schedule.BlockSubsidy(uint64(*daaScore)))
+		fmt.Printf("Supply at DAA score %d: %d sompi\n", *daaScore, schedule.Supply(uint64(*daaScore)))
+		fmt.Printf("Total supply: %d sompi\n", schedule.TotalSupply())
+		return nil
+	}
+
+	switch *format {
+	case "csv":
+		return emission.WriteCSV(os.Stdout, schedule.Periods())
+	case "json":
+		return emission.WriteJSON(os.Stdout, schedule.Periods())
+	}
+	return errors.Errorf("unknown format %q", *format)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+)
+
+func fsck(args []string) error {
+	flagSet := flag.NewFlagSet("fsck", flag.ExitOnError)
+	dbFlags := &databaseFlags{}
+	dbFlags.register(flagSet)
+	shouldRepair := flagSet.Bool("repair", false, "Repair the issues that can be repaired")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config, err := dbFlags.consensusConfig()
+	if err != nil {
+		return err
+	}
+	dbPrefix, err := dbFlags.databasePrefix()
+	if err != nil {
+		return err
+	}
+	db, err := dbFlags.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	consensusInstance, shouldMigrate, err := consensus.NewFactory().NewConsensus(config, db, dbPrefix, nil)
+	if err != nil {
+		return err
+	}
+	if shouldMigrate {
+		return errors.New("the database requires a migration and cannot be checked")
+	}
+
+	report, err := consensusInstance.CheckIntegrity(*shouldRepair)
+	if err != nil {
+		return err
+	}
+
+	repairedCount := 0
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+		if issue.IsRepaired {
+			repairedCount++
+		}
+	}
+	fmt.Printf("Checked %d chain blocks: %d issues found, %d repaired\n",
+		report.CheckedChainBlocks, len(report.Issues), repairedCount)
+
+	if !report.IsConsistent() {
+		return errors.Errorf("%d issues were not repaired", len(report.Issues)-repairedCount)
+	}
+	return nil
+}
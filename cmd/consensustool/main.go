@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"difficulty-simulate": {
+		description: "Simulate the difficulty adjustment under a hashrate timeline",
+		run:         difficultySimulate,
+	},
+	"emission": {
+		description: "Print the emission schedule and the supply of a network",
+		run:         emissionSchedule,
+	},
+	"fsck": {
+		description: "Check the consistency of a consensus database and optionally repair it",
+		run:         fsck,
+	},
+	"proof-export": {
+		description: "Export the pruning point proof of a database to a file",
+		run:         proofExport,
+	},
+	"proof-verify": {
+		description: "Validate a pruning point proof file against a fresh consensus",
+		run:         proofVerify,
+	},
+	"reachability-compact": {
+		description: "Rebuild the reachability intervals of a consensus database from scratch",
+		run:         reachabilityCompact,
+	},
+	"snapshot-export": {
+		description: "Export a consensus snapshot from a database",
+		run:         snapshotExport,
+	},
+	"snapshot-import": {
+		description: "Import a consensus snapshot into an empty database prefix",
+		run:         snapshotImport,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", os.Args[1])
+		printUsage()
+		os.Exit(1)
+	}
+
+	err := cmd.run(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %+v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].description)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/memorydb"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/pruningpointproof"
+	"github.com/zilong-dai/karlsen-miner/prefixmanager/prefix"
+)
+
+func proofExport(args []string) error {
+	flagSet := flag.NewFlagSet("proof-export", flag.ExitOnError)
+	dbFlags := &databaseFlags{}
+	dbFlags.register(flagSet)
+	outputPath := flagSet.String("output", "", "The path of the proof file to write")
+	format := flagSet.String("format", "binary", "The encoding of the proof file: binary or json")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *outputPath == "" {
+		return errors.New("--output is required")
+	}
+	if *format != "binary" && *format != "json" {
+		return errors.Errorf("unknown format %q", *format)
+	}
+
+	config, err := dbFlags.consensusConfig()
+	if err != nil {
+		return err
+	}
+	dbPrefix, err := dbFlags.databasePrefix()
+	if err != nil {
+		return err
+	}
+	db, err := dbFlags.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	consensusInstance, shouldMigrate, err := consensus.NewFactory().NewConsensus(config, db, dbPrefix, nil)
+	if err != nil {
+		return err
+	}
+	if shouldMigrate {
+		return errors.New("the database requires a migration and cannot be exported")
+	}
+
+	proof, err := consensusInstance.BuildPruningPointProof()
+	if err != nil {
+		return err
+	}
+	if len(proof.Headers) == 0 {
+		return errors.New("the pruning point is the genesis, so there's no proof to export")
+	}
+
+	file, err := os.Create(*outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if *format == "json" {
+		err = pruningpointproof.WriteJSON(file, proof)
+	} else {
+		var serialized []byte
+		serialized, err = pruningpointproof.Serialize(proof)
+		if err == nil {
+			_, err = file.Write(serialized)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Pruning point proof with %d levels written to %s\n", len(proof.Headers), *outputPath)
+	return nil
+}
+
+func proofVerify(args []string) error {
+	flagSet := flag.NewFlagSet("proof-verify", flag.ExitOnError)
+	network := flagSet.String("network", "mainnet", "The network the proof belongs to: mainnet, testnet, simnet or devnet")
+	inputPath := flagSet.String("input", "", "The path of the proof file to read, in either encoding")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *inputPath == "" {
+		return errors.New("--input is required")
+	}
+
+	params, err := networkParams(*network)
+	if err != nil {
+		return err
+	}
+	proof, err := readProofFile(*inputPath)
+	if err != nil {
+		return err
+	}
+
+	// The proof is validated against a fresh consensus, so it only has to be better than the genesis
+	dbPrefix, err := prefix.Deserialize([]byte{0})
+	if err != nil {
+		return err
+	}
+	db := memorydb.New()
+	defer db.Close()
+	consensusInstance, _, err := consensus.NewFactory().NewConsensus(&consensus.Config{Params: *params}, db, dbPrefix, nil)
+	if err != nil {
+		return err
+	}
+
+	err = consensusInstance.ValidatePruningPointProof(proof)
+	if err != nil {
+		return errors.Wrap(err, "the proof is invalid")
+	}
+	levelChains, err := consensusInstance.GetPruningPointProofLevelChains(proof)
+	if err != nil {
+		return err
+	}
+
+	for _, levelChain := range levelChains {
+		printLevelChain(levelChain)
+	}
+	if len(levelChains) == 0 || levelChains[0].SelectedTip() == nil {
+		return errors.New("the proof has no level 0 chain to take the pruning point from")
+	}
+	fmt.Printf("The proof is valid. Pruning point: %s\n", levelChains[0].SelectedTip().Hash)
+	return nil
+}
+
+// readProofFile reads a proof file, telling the binary encoding apart from JSON by its magic
+func readProofFile(path string) (*externalapi.PruningPointProof, error) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(fileBytes); len(trimmed) > 0 && trimmed[0] == '{' {
+		return pruningpointproof.ReadJSON(bytes.NewReader(fileBytes))
+	}
+	return pruningpointproof.Deserialize(fileBytes)
+}
+
+func printLevelChain(levelChain *externalapi.PruningPointProofLevelChain) {
+	fmt.Printf("Level %d: %d headers, %d chain blocks\n", levelChain.Level, levelChain.HeaderCount, len(levelChain.Chain))
+	for _, chainBlock := range levelChain.Chain {
+		fmt.Printf("  %s blue score: %d, blue work: %s\n", chainBlock.Hash, chainBlock.BlueScore, chainBlock.BlueWork.Text(16))
+	}
+}
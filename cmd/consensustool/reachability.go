@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+)
+
+func reachabilityCompact(args []string) error {
+	flagSet := flag.NewFlagSet("reachability-compact", flag.ExitOnError)
+	dbFlags := &databaseFlags{}
+	dbFlags.register(flagSet)
+	reindexSlack := flagSet.Uint64("reindexslack", 0, "The reachability reindex slack, or 0 for the default")
+	reindexWindow := flagSet.Uint64("reindexwindow", 0, "The reachability reindex window, or 0 for the default")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	config, err := dbFlags.consensusConfig()
+	if err != nil {
+		return err
+	}
+	config.ReachabilityReindexSlack = *reindexSlack
+	config.ReachabilityReindexWindow = *reindexWindow
+	dbPrefix, err := dbFlags.databasePrefix()
+	if err != nil {
+		return err
+	}
+	db, err := dbFlags.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	consensusInstance, shouldMigrate, err := consensus.NewFactory().NewConsensus(config, db, dbPrefix, nil)
+	if err != nil {
+		return err
+	}
+	if shouldMigrate {
+		return errors.New("the database requires a migration and cannot be compacted")
+	}
+
+	result, err := consensusInstance.CompactReachabilityIntervals()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt the intervals of %d blocks: removed %d tree children and %d future covering set "+
+		"entries of blocks without reachability data\n",
+		result.Blocks, result.RemovedTreeChildren, result.RemovedFutureCoveringSetEntries)
+
+	statistics, err := consensusInstance.GetReachabilityReindexStatistics()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reindex root updates: %d, concentrated blocks: %d\n",
+		statistics.ReindexRootUpdates, statistics.ConcentratedBlocks)
+	return nil
+}
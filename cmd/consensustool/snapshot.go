@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+)
+
+func snapshotExport(args []string) error {
+	flagSet := flag.NewFlagSet("snapshot-export", flag.ExitOnError)
+	dbFlags := &databaseFlags{}
+	dbFlags.register(flagSet)
+	outputPath := flagSet.String("output", "", "The path of the snapshot file to write")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *outputPath == "" {
+		return errors.New("--output is required")
+	}
+
+	config, err := dbFlags.consensusConfig()
+	if err != nil {
+		return err
+	}
+	dbPrefix, err := dbFlags.databasePrefix()
+	if err != nil {
+		return err
+	}
+	db, err := dbFlags.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	consensusInstance, shouldMigrate, err := consensus.NewFactory().NewConsensus(config, db, dbPrefix, nil)
+	if err != nil {
+		return err
+	}
+	if shouldMigrate {
+		return errors.New("the database requires a migration and cannot be exported")
+	}
+
+	file, err := os.Create(*outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bufferedWriter := bufio.NewWriter(file)
+	err = consensusInstance.ExportSnapshot(bufferedWriter)
+	if err != nil {
+		return err
+	}
+	err = bufferedWriter.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot written to %s\n", *outputPath)
+	return nil
+}
+
+func snapshotImport(args []string) error {
+	flagSet := flag.NewFlagSet("snapshot-import", flag.ExitOnError)
+	dbFlags := &databaseFlags{}
+	dbFlags.register(flagSet)
+	inputPath := flagSet.String("input", "", "The path of the snapshot file to read")
+	err := flagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *inputPath == "" {
+		return errors.New("--input is required")
+	}
+
+	config, err := dbFlags.consensusConfig()
+	if err != nil {
+		return err
+	}
+	dbPrefix, err := dbFlags.databasePrefix()
+	if err != nil {
+		return err
+	}
+	db, err := dbFlags.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	file, err := os.Open(*inputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	consensusInstance, err := consensus.NewFactory().NewConsensusFromSnapshot(config, db, dbPrefix, file, nil)
+	if err != nil {
+		return err
+	}
+
+	pruningPoint, err := consensusInstance.PruningPoint()
+	if err != nil {
+		return err
+	}
+	virtualInfo, err := consensusInstance.GetVirtualInfo()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot imported. Pruning point: %s, virtual DAA score: %d\n", pruningPoint, virtualInfo.DAAScore)
+	return nil
+}
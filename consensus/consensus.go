@@ -13,35 +13,48 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/merkle"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/transactionhelper"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/txscript"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
 )
 
 type consensus struct {
 	lock            *sync.Mutex
 	databaseContext model.DBManager
+	prefixBucket    model.DBBucket
 
 	genesisBlock *externalapi.DomainBlock
 	genesisHash  *externalapi.DomainHash
+	dagParams    *dagconfig.Params
 
 	expectedDAAWindowDurationInMilliseconds int64
-
-	blockProcessor        model.BlockProcessor
-	blockBuilder          model.BlockBuilder
-	consensusStateManager model.ConsensusStateManager
-	transactionValidator  model.TransactionValidator
-	syncManager           model.SyncManager
-	pastMedianTimeManager model.PastMedianTimeManager
-	blockValidator        model.BlockValidator
-	coinbaseManager       model.CoinbaseManager
-	dagTopologyManagers   []model.DAGTopologyManager
-	dagTraversalManager   model.DAGTraversalManager
-	difficultyManager     model.DifficultyManager
-	ghostdagManagers      []model.GHOSTDAGManager
-	headerTipsManager     model.HeadersSelectedTipManager
-	mergeDepthManager     model.MergeDepthManager
-	pruningManager        model.PruningManager
-	reachabilityManager   model.ReachabilityManager
-	finalityManager       model.FinalityManager
-	pruningProofManager   model.PruningProofManager
+	maxBlockMass                            uint64
+
+	blockProcessor         model.BlockProcessor
+	blockBuilder           model.BlockBuilder
+	blockTemplateManager   model.BlockTemplateManager
+	transactionSelector    model.TransactionSelector
+	consensusStateManager  model.ConsensusStateManager
+	transactionValidator   model.TransactionValidator
+	syncManager            model.SyncManager
+	pastMedianTimeManager  model.PastMedianTimeManager
+	blockValidator         model.BlockValidator
+	coinbaseManager        model.CoinbaseManager
+	dagTopologyManagers    []model.DAGTopologyManager
+	dagTraversalManager    model.DAGTraversalManager
+	difficultyManager      model.DifficultyManager
+	ghostdagManagers       []model.GHOSTDAGManager
+	headerTipsManager      model.HeadersSelectedTipManager
+	mergeDepthManager      model.MergeDepthManager
+	pruningManager         model.PruningManager
+	reachabilityManager    model.ReachabilityManager
+	finalityManager        model.FinalityManager
+	pruningProofManager    model.PruningProofManager
+	integrityChecker       model.IntegrityChecker
+	consensusEventsManager model.ConsensusEventsManager
 
 	acceptanceDataStore                 model.AcceptanceDataStore
 	blockStore                          model.BlockStore
@@ -70,9 +83,17 @@ type consensus struct {
 // where UpdatePruningPointByVirtual skips a pruning point.
 const virtualResolveChunk = 100
 
+// unlock releases the consensus lock, and then delivers the events published while it was held.
+// Subscribers may block the delivery or call back into consensus, so events are never delivered
+// while the lock is held.
+func (s *consensus) unlock() {
+	s.lock.Unlock()
+	s.consensusEventsManager.DeliverPublishedEvents()
+}
+
 func (s *consensus) ValidateAndInsertBlockWithTrustedData(block *externalapi.BlockWithTrustedData, validateUTXO bool) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	_, _, err := s.blockProcessor.ValidateAndInsertBlockWithTrustedData(block, validateUTXO)
 	if err != nil {
@@ -84,7 +105,7 @@ func (s *consensus) ValidateAndInsertBlockWithTrustedData(block *externalapi.Blo
 // Init initializes consensus
 func (s *consensus) Init(skipAddingGenesis bool) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	onEnd := logger.LogAndMeasureExecutionTime(log, "Init")
 	defer onEnd()
@@ -173,29 +194,130 @@ func (s *consensus) BuildBlock(coinbaseData *externalapi.DomainCoinbaseData,
 
 // BuildBlockTemplate builds a block over the current state, with the transactions
 // selected by the given transactionSelector plus metadata information related to
-// coinbase rewards and node sync status
+// coinbase rewards and node sync status. The template is given a new job ID, and is
+// cached for the following calls to RefreshBlockTemplate.
 func (s *consensus) BuildBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData,
 	transactions []*externalapi.DomainTransaction) (*externalapi.DomainBlockTemplate, error) {
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	block, hasRedReward, err := s.blockBuilder.BuildBlock(coinbaseData, transactions)
+	blockTemplate, err := s.blockTemplateManager.BuildBlockTemplate(coinbaseData, transactions)
+	if err != nil {
+		return nil, err
+	}
+	return s.populateBlockTemplateSyncStatus(blockTemplate)
+}
+
+// maxBlockTemplateFromCandidatesAttempts is the number of times BuildBlockTemplateFromCandidates selects
+// transactions before giving up, so that the consensus lock isn't held for long by invalid candidates
+const maxBlockTemplateFromCandidatesAttempts = 5
+
+// BuildBlockTemplateFromCandidates selects the transactions of a new block out of the given candidates
+// according to the given options, and builds a block template with them like BuildBlockTemplate does.
+// Candidates that turn out to be invalid over the current virtual are left out.
+func (s *consensus) BuildBlockTemplateFromCandidates(coinbaseData *externalapi.DomainCoinbaseData,
+	candidates []*externalapi.DomainTransaction, options *externalapi.TransactionSelectionOptions) (
+	*externalapi.DomainBlockTemplate, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if options.MaxMass == 0 {
+		maxMass, err := s.transactionsMaxMass(coinbaseData)
+		if err != nil {
+			return nil, err
+		}
+		options = &externalapi.TransactionSelectionOptions{Mode: options.Mode, MaxMass: maxMass}
+	}
+
+	// Every attempt leaves out the candidates the previous one found invalid, and the selection may replace
+	// them with candidates that are invalid as well, so the number of attempts is bounded
+	for attempt := 1; ; attempt++ {
+		selection, err := s.transactionSelector.SelectTransactions(candidates, options)
+		if err != nil {
+			return nil, err
+		}
+
+		blockTemplate, err := s.blockTemplateManager.BuildBlockTemplate(coinbaseData, selection.Transactions)
+		invalidTransactionsErr := ruleerrors.ErrInvalidTransactionsInNewBlock{}
+		if !errors.As(err, &invalidTransactionsErr) {
+			if err != nil {
+				return nil, err
+			}
+			return s.populateBlockTemplateSyncStatus(blockTemplate)
+		}
+		if attempt == maxBlockTemplateFromCandidatesAttempts {
+			return nil, errors.Wrapf(err, "failed building a block template out of the candidates after %d attempts",
+				attempt)
+		}
+
+		validCandidates := invalidTransactionsErr.WithoutInvalidTransactions(candidates)
+		if len(validCandidates) == len(candidates) {
+			return nil, errors.Wrap(err, "the invalid transactions of the block template aren't any of the candidates")
+		}
+		log.Debugf("Removed %d invalid candidates while building a block template", len(candidates)-len(validCandidates))
+		candidates = validCandidates
+	}
+}
+
+// transactionsMaxMass returns the mass left for the transactions of a new block
+// once the coinbase transaction with the given coinbase data is included
+func (s *consensus) transactionsMaxMass(coinbaseData *externalapi.DomainCoinbaseData) (uint64, error) {
+	stagingArea := model.NewStagingArea()
+	coinbaseTransaction, _, err := s.coinbaseManager.ExpectedCoinbaseTransaction(
+		stagingArea, model.VirtualBlockHash, coinbaseData)
+	if err != nil {
+		return 0, err
+	}
+	s.transactionValidator.PopulateMass(coinbaseTransaction)
+	if coinbaseTransaction.Mass >= s.maxBlockMass {
+		return 0, errors.Errorf("the coinbase transaction mass %d leaves no room for transactions "+
+			"under the max block mass of %d", coinbaseTransaction.Mass, s.maxBlockMass)
+	}
+	return s.maxBlockMass - coinbaseTransaction.Mass, nil
+}
+
+// RefreshBlockTemplate returns the last built block template with an updated timestamp and the
+// given coinbase data, without rebuilding the rest of the block. If coinbaseData is nil, the coinbase
+// data of the last template is kept. The job ID is kept as long as the virtual doesn't change.
+func (s *consensus) RefreshBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData) (
+	*externalapi.DomainBlockTemplate, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.refreshBlockTemplateNoLock(coinbaseData)
+}
+
+func (s *consensus) refreshBlockTemplateNoLock(coinbaseData *externalapi.DomainCoinbaseData) (
+	*externalapi.DomainBlockTemplate, error) {
+
+	blockTemplate, err := s.blockTemplateManager.RefreshBlockTemplate(coinbaseData)
 	if err != nil {
 		return nil, err
 	}
+	return s.populateBlockTemplateSyncStatus(blockTemplate)
+}
+
+func (s *consensus) populateBlockTemplateSyncStatus(blockTemplate *externalapi.DomainBlockTemplate) (
+	*externalapi.DomainBlockTemplate, error) {
 
 	isNearlySynced, err := s.isNearlySyncedNoLock()
 	if err != nil {
 		return nil, err
 	}
+	blockTemplate.IsNearlySynced = isNearlySynced
+	return blockTemplate, nil
+}
 
-	return &externalapi.DomainBlockTemplate{
-		Block:                block,
-		CoinbaseData:         coinbaseData,
-		CoinbaseHasRedReward: hasRedReward,
-		IsNearlySynced:       isNearlySynced,
-	}, nil
+// IsBlockTemplateJobStale returns whether the virtual changed since the
+// block template with the given job ID was built
+func (s *consensus) IsBlockTemplateJobStale(jobID uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.blockTemplateManager.IsJobStale(jobID)
 }
 
 // ValidateAndInsertBlock validates the given block and, if valid, applies it
@@ -208,7 +330,7 @@ func (s *consensus) ValidateAndInsertBlock(block *externalapi.DomainBlock, updat
 			for {
 				_, isCompletelyResolved, err := s.resolveVirtualChunkNoLock(virtualResolveChunk)
 				if err != nil {
-					s.lock.Unlock()
+					s.unlock()
 					return err
 				}
 				if isCompletelyResolved {
@@ -216,20 +338,20 @@ func (s *consensus) ValidateAndInsertBlock(block *externalapi.DomainBlock, updat
 					// Otherwise, we might actually enter it in `s.virtualNotUpdated == true` state
 					_, err = s.validateAndInsertBlockNoLock(block, updateVirtual)
 					// Finally, unlock for the last iteration and return
-					s.lock.Unlock()
+					s.unlock()
 					if err != nil {
 						return err
 					}
 					return nil
 				}
 				// Unlock to allow other threads to enter consensus
-				s.lock.Unlock()
+				s.unlock()
 				// Lock for the next iteration
 				s.lock.Lock()
 			}
 		}
 		_, err := s.validateAndInsertBlockNoLock(block, updateVirtual)
-		s.lock.Unlock()
+		s.unlock()
 		if err != nil {
 			return err
 		}
@@ -241,7 +363,7 @@ func (s *consensus) ValidateAndInsertBlock(block *externalapi.DomainBlock, updat
 
 func (s *consensus) validateAndInsertBlockWithLock(block *externalapi.DomainBlock, updateVirtual bool) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	_, err := s.validateAndInsertBlockNoLock(block, updateVirtual)
 	if err != nil {
@@ -275,25 +397,34 @@ func (s *consensus) validateAndInsertBlockNoLock(block *externalapi.DomainBlock,
 }
 
 func (s *consensus) sendBlockAddedEvent(block *externalapi.DomainBlock, blockStatus externalapi.BlockStatus) error {
-	if s.consensusEventsChan != nil {
-		if blockStatus == externalapi.StatusHeaderOnly || blockStatus == externalapi.StatusInvalid {
-			return nil
-		}
+	if blockStatus == externalapi.StatusHeaderOnly || blockStatus == externalapi.StatusInvalid {
+		return nil
+	}
 
+	event := &externalapi.BlockAdded{Block: block}
+	if s.consensusEventsChan != nil {
 		if len(s.consensusEventsChan) == cap(s.consensusEventsChan) {
 			return errors.Errorf("consensusEventsChan is full")
 		}
-		s.consensusEventsChan <- &externalapi.BlockAdded{Block: block}
+		s.consensusEventsChan <- event
 	}
+	s.consensusEventsManager.Publish(event)
 	return nil
 }
 
 func (s *consensus) sendVirtualChangedEvent(virtualChangeSet *externalapi.VirtualChangeSet, wasVirtualUpdated bool) error {
-	if !wasVirtualUpdated || s.consensusEventsChan == nil || virtualChangeSet == nil {
+	if !wasVirtualUpdated || virtualChangeSet == nil {
+		return nil
+	}
+
+	s.sendNewBlockTemplateEvent()
+
+	hasSubscribers := s.consensusEventsManager.HasSubscribers(externalapi.ConsensusEventTypeVirtualChangeSet)
+	if s.consensusEventsChan == nil && !hasSubscribers {
 		return nil
 	}
 
-	if len(s.consensusEventsChan) == cap(s.consensusEventsChan) {
+	if s.consensusEventsChan != nil && len(s.consensusEventsChan) == cap(s.consensusEventsChan) {
 		return errors.Errorf("consensusEventsChan is full")
 	}
 
@@ -317,10 +448,113 @@ func (s *consensus) sendVirtualChangedEvent(virtualChangeSet *externalapi.Virtua
 	virtualChangeSet.VirtualSelectedParentBlueScore = virtualSelectedParentGHOSTDAGData.BlueScore()
 	virtualChangeSet.VirtualDAAScore = virtualDAAScore
 
-	s.consensusEventsChan <- virtualChangeSet
+	if s.consensusEventsChan != nil {
+		s.consensusEventsChan <- virtualChangeSet
+	}
+	if hasSubscribers {
+		s.consensusEventsManager.Publish(virtualChangeSet)
+	}
 	return nil
 }
 
+// sendNewBlockTemplateEvent invalidates the cached block template, and rebuilds
+// it over the new virtual if anyone subscribed to the new templates. The block that
+// changed the virtual is already committed at this point, so a failure to rebuild
+// the template is only logged.
+func (s *consensus) sendNewBlockTemplateEvent() {
+	s.blockTemplateManager.InvalidateBlockTemplate()
+
+	if !s.blockTemplateManager.HasBlockTemplate() ||
+		!s.consensusEventsManager.HasSubscribers(externalapi.ConsensusEventTypeNewBlockTemplate) {
+		return
+	}
+
+	blockTemplate, err := s.refreshBlockTemplateNoLock(nil)
+	if err != nil {
+		log.Warnf("Failed to rebuild the block template over the new virtual: %s", err)
+		return
+	}
+	s.consensusEventsManager.Publish(&externalapi.NewBlockTemplate{Template: blockTemplate})
+}
+
+// SubscribeEvents subscribes to the events raised by consensus. Unlike the channel given to NewConsensus,
+// every subscription has its own buffer and backpressure policy, and may filter the events by type.
+func (s *consensus) SubscribeEvents(options *externalapi.ConsensusEventSubscriptionOptions) (
+	externalapi.ConsensusEventSubscription, error) {
+
+	return s.consensusEventsManager.Subscribe(options)
+}
+
+// FinalityConflicts returns the finality conflicts that weren't resolved by the operator yet
+func (s *consensus) FinalityConflicts() ([]*externalapi.FinalityConflict, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	return s.consensusStateManager.FinalityConflicts(stagingArea)
+}
+
+// ResolveFinalityConflict resolves the unresolved finality conflict the given block is a violating block of.
+// If followViolatingChain is true, the finality of the current selected chain is given up, and the virtual is
+// resolved again so that the violating chain may become the selected chain. Otherwise, the current selected
+// chain is kept and the conflict is dismissed.
+func (s *consensus) ResolveFinalityConflict(violatingBlockHash *externalapi.DomainHash, followViolatingChain bool) error {
+	err := s.resolveFinalityConflictWithLock(violatingBlockHash, followViolatingChain)
+	if err != nil {
+		return err
+	}
+	if !followViolatingChain {
+		return nil
+	}
+	return s.ResolveVirtual(nil)
+}
+
+func (s *consensus) resolveFinalityConflictWithLock(violatingBlockHash *externalapi.DomainHash, followViolatingChain bool) error {
+	s.lock.Lock()
+	defer s.unlock()
+
+	stagingArea := model.NewStagingArea()
+	err := s.validateBlockHashExists(stagingArea, violatingBlockHash)
+	if err != nil {
+		return err
+	}
+	conflict, err := s.consensusStateManager.ResolveFinalityConflict(stagingArea, violatingBlockHash, followViolatingChain)
+	if err != nil {
+		return err
+	}
+	err = staging.CommitAllChanges(s.databaseContext, stagingArea)
+	if err != nil {
+		return err
+	}
+
+	s.consensusEventsManager.Publish(&externalapi.FinalityConflictResolved{
+		Conflict:              conflict,
+		FollowsViolatingChain: followViolatingChain,
+	})
+	return nil
+}
+
+// DiagnoseBlockParents explains which of the given candidate parents make a block built on top of them violate
+// the merge set size limit or the bounded merge depth rule, and how the virtual parent selection trims them.
+// Nothing is committed, so it can be used to find out why a block was rejected or its parents were trimmed.
+func (s *consensus) DiagnoseBlockParents(parents []*externalapi.DomainHash) (*externalapi.BlockParentsDiagnostics, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(parents) == 0 {
+		return nil, errors.New("at least one parent is required")
+	}
+
+	stagingArea := model.NewStagingArea()
+	for _, parent := range parents {
+		err := s.validateBlockHashExists(stagingArea, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.consensusStateManager.DiagnoseBlockParents(stagingArea, parents)
+}
+
 // ValidateTransactionAndPopulateWithConsensusData validates the given transaction
 // and populates it with any missing consensus data
 func (s *consensus) ValidateTransactionAndPopulateWithConsensusData(transaction *externalapi.DomainTransaction) error {
@@ -509,6 +743,97 @@ func (s *consensus) GetBlocksAcceptanceData(blockHashes []*externalapi.DomainHas
 	return blocksAcceptanceData, nil
 }
 
+// GetTransactionInclusionProof returns a proof that the hash of the given transaction is included
+// in the hash merkle root of the given block
+func (s *consensus) GetTransactionInclusionProof(blockHash *externalapi.DomainHash,
+	transactionID *externalapi.DomainTransactionID) (*externalapi.MerkleProof, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	err := s.validateBlockHashExists(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	hasBlock, err := s.blockStore.HasBlock(s.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if !hasBlock {
+		return nil, errors.Errorf("block %s is header-only", blockHash)
+	}
+	block, err := s.blockStore.Block(s.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, transaction := range block.Transactions {
+		if consensushashing.TransactionID(transaction).Equal(transactionID) {
+			return merkle.CalculateHashMerkleProof(block.Transactions, i)
+		}
+	}
+	return nil, errors.Errorf("transaction %s is not included in block %s", transactionID, blockHash)
+}
+
+// GetTransactionAcceptanceProof returns a proof that the ID of the given transaction is included
+// in the accepted ID merkle root of the given block. Only blocks that were in the virtual selected
+// parent chain have acceptance data.
+func (s *consensus) GetTransactionAcceptanceProof(blockHash *externalapi.DomainHash,
+	transactionID *externalapi.DomainTransactionID) (*externalapi.MerkleProof, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	err := s.validateBlockHashExists(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptanceData, err := s.acceptanceDataStore.Get(s.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, errors.Errorf("block %s has no acceptance data", blockHash)
+		}
+		return nil, err
+	}
+
+	acceptedTransactions := merkle.AcceptedTransactions(acceptanceData)
+	for i, transaction := range acceptedTransactions {
+		if consensushashing.TransactionID(transaction).Equal(transactionID) {
+			return merkle.CalculateIDMerkleProof(acceptedTransactions, i)
+		}
+	}
+	return nil, errors.Errorf("transaction %s is not accepted by block %s", transactionID, blockHash)
+}
+
+func (s *consensus) GetCoinbaseRewardBreakdown(blockHash *externalapi.DomainHash) (*externalapi.CoinbaseRewardBreakdown, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+
+	err := s.validateBlockHashExists(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown, err := s.coinbaseManager.CoinbaseRewardBreakdown(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, output := range breakdown.Outputs {
+		_, address, err := txscript.ExtractScriptPubKeyAddress(output.ScriptPublicKey, s.dagParams)
+		if err == nil && address != nil {
+			output.Address = address.String()
+		}
+	}
+	return breakdown, nil
+}
+
 func (s *consensus) GetHashesBetween(lowHash, highHash *externalapi.DomainHash, maxBlocks uint64) (
 	hashes []*externalapi.DomainHash, actualHighHash *externalapi.DomainHash, err error) {
 
@@ -614,6 +939,74 @@ func (s *consensus) GetVirtualUTXOs(expectedVirtualParents []*externalapi.Domain
 	return virtualUTXOs, nil
 }
 
+// GetVirtualUTXODiffFromPruningPoint returns the UTXO diff that takes the UTXO set of the pruning point
+// to the virtual UTXO set, along with the virtual parents the diff leads to. It's built from the
+// acceptance data of the virtual selected parent chain above the pruning point and of the virtual itself.
+func (s *consensus) GetVirtualUTXODiffFromPruningPoint(expectedPruningPointHash *externalapi.DomainHash) (
+	utxoDiff externalapi.UTXODiff, virtualParents []*externalapi.DomainHash, err error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+
+	pruningPointHash, err := s.pruningStore.PruningPoint(s.databaseContext, stagingArea)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !expectedPruningPointHash.Equal(pruningPointHash) {
+		return nil, nil, errors.Wrapf(ruleerrors.ErrWrongPruningPointHash, "expected pruning point %s but got %s",
+			expectedPruningPointHash,
+			pruningPointHash)
+	}
+
+	virtualParents, err = s.dagTopologyManagers[0].Parents(stagingArea, model.VirtualBlockHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	chainPath, err := s.consensusStateManager.GetVirtualSelectedParentChainFromBlock(stagingArea, pruningPointHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mutableUTXODiff := utxo.NewMutableUTXODiff()
+	acceptingBlocks := append(chainPath.Added, model.VirtualBlockHash)
+	for _, acceptingBlock := range acceptingBlocks {
+		acceptanceData, err := s.acceptanceDataStore.Get(s.databaseContext, stagingArea, acceptingBlock)
+		if err != nil {
+			return nil, nil, err
+		}
+		daaScore, err := s.daaBlocksStore.DAAScore(s.databaseContext, stagingArea, acceptingBlock)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, blockAcceptanceData := range acceptanceData {
+			for _, transactionAcceptanceData := range blockAcceptanceData.TransactionAcceptanceData {
+				if !transactionAcceptanceData.IsAccepted {
+					continue
+				}
+				// The stored transactions don't carry the UTXO entries they spent, so they're
+				// taken from the acceptance data
+				transaction := transactionAcceptanceData.Transaction.Clone()
+				if len(transaction.Inputs) != len(transactionAcceptanceData.TransactionInputUTXOEntries) {
+					return nil, nil, errors.Errorf("transaction %s in the acceptance data of %s has %d inputs "+
+						"but %d UTXO entries", consensushashing.TransactionID(transaction), acceptingBlock,
+						len(transaction.Inputs), len(transactionAcceptanceData.TransactionInputUTXOEntries))
+				}
+				for i, input := range transaction.Inputs {
+					input.UTXOEntry = transactionAcceptanceData.TransactionInputUTXOEntries[i]
+				}
+				err = mutableUTXODiff.AddTransaction(transaction, daaScore)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	return mutableUTXODiff.ToImmutable(), virtualParents, nil
+}
+
 func (s *consensus) PruningPoint() (*externalapi.DomainHash, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -668,9 +1061,16 @@ func (s *consensus) AppendImportedPruningPointUTXOs(outpointAndUTXOEntryPairs []
 
 func (s *consensus) ValidateAndInsertImportedPruningPoint(newPruningPoint *externalapi.DomainHash) error {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
-	return s.blockProcessor.ValidateAndInsertImportedPruningPoint(newPruningPoint)
+	err := s.blockProcessor.ValidateAndInsertImportedPruningPoint(newPruningPoint)
+	if err != nil {
+		return err
+	}
+
+	// The virtual is replaced by the one of the imported pruning point
+	s.blockTemplateManager.InvalidateBlockTemplate()
+	return nil
 }
 
 func (s *consensus) GetVirtualSelectedParent() (*externalapi.DomainHash, error) {
@@ -920,6 +1320,57 @@ func (s *consensus) EstimateNetworkHashesPerSecond(startHash *externalapi.Domain
 	return s.difficultyManager.EstimateNetworkHashesPerSecond(startHash, windowSize)
 }
 
+// EstimateNetworkHashesPerSecondSeries estimates the hashrate over sliding windows along the selected
+// chain of startHash, along with the share of the blue work done by the miner selected in the options
+func (s *consensus) EstimateNetworkHashesPerSecondSeries(startHash *externalapi.DomainHash,
+	options *externalapi.HashrateEstimationOptions) ([]*externalapi.HashrateEstimate, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	err := s.validateBlockHashExists(stagingArea, startHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var isMinerBlock func(blockHash *externalapi.DomainHash) (bool, bool, error)
+	if options.MinerScriptPublicKey != nil {
+		isMinerBlock = func(blockHash *externalapi.DomainHash) (bool, bool, error) {
+			return s.isBlockMinedBy(stagingArea, blockHash, options.MinerScriptPublicKey)
+		}
+	}
+	return s.difficultyManager.EstimateNetworkHashesPerSecondSeries(startHash, options, isMinerBlock)
+}
+
+// isBlockMinedBy returns whether the coinbase payload of the given block pays the given script
+// public key, and whether that's known at all. It isn't known for blocks whose body was pruned
+// or never downloaded. The genesis is never considered mined by it.
+func (s *consensus) isBlockMinedBy(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash,
+	scriptPublicKey *externalapi.ScriptPublicKey) (isMinedBy bool, isKnown bool, err error) {
+
+	if blockHash.Equal(s.genesisHash) {
+		return false, true, nil
+	}
+	hasBlock, err := s.blockStore.HasBlock(s.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return false, false, err
+	}
+	if !hasBlock {
+		return false, false, nil
+	}
+	block, err := s.blockStore.Block(s.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return false, false, err
+	}
+	coinbaseTransaction := block.Transactions[transactionhelper.CoinbaseTransactionIndex]
+	_, coinbaseData, _, err := s.coinbaseManager.ExtractCoinbaseDataBlueScoreAndSubsidy(coinbaseTransaction)
+	if err != nil {
+		return false, false, err
+	}
+	return coinbaseData.ScriptPublicKey.Equal(scriptPublicKey), true, nil
+}
+
 func (s *consensus) PopulateMass(transaction *externalapi.DomainTransaction) {
 	s.transactionValidator.PopulateMass(transaction)
 }
@@ -953,7 +1404,7 @@ func (s *consensus) ResolveVirtual(progressReportCallback func(uint64, uint64))
 
 func (s *consensus) resolveVirtualChunkWithLock(maxBlocksToResolve uint64) (*externalapi.VirtualChangeSet, bool, error) {
 	s.lock.Lock()
-	defer s.lock.Unlock()
+	defer s.unlock()
 
 	return s.resolveVirtualChunkNoLock(maxBlocksToResolve)
 }
@@ -1024,6 +1475,15 @@ func (s *consensus) ApplyPruningPointProof(pruningPointProof *externalapi.Prunin
 	return nil
 }
 
+func (s *consensus) GetPruningPointProofLevelChains(pruningPointProof *externalapi.PruningPointProof) (
+	[]*externalapi.PruningPointProofLevelChain, error) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.pruningProofManager.PruningPointProofLevelChains(pruningPointProof)
+}
+
 func (s *consensus) BlockDAAWindowHashes(blockHash *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -1146,3 +1606,73 @@ func (s *consensus) isNearlySyncedNoLock() (bool, error) {
 		virtualSelectedParentHeader.TimeInMilliseconds())
 	return false, nil
 }
+
+// CheckIntegrity verifies the consistency of the stored consensus data along the virtual selected parent
+// chain. If shouldRepair is set, the issues that can be repaired are fixed and committed to the database.
+func (s *consensus) CheckIntegrity(shouldRepair bool) (*externalapi.IntegrityReport, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	report, err := s.integrityChecker.CheckIntegrity(stagingArea, shouldRepair)
+	if err != nil {
+		return nil, err
+	}
+
+	if shouldRepair {
+		err = staging.CommitAllChanges(s.databaseContext, stagingArea)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// GetReachabilityReindexStatistics returns the statistics of the reachability tree
+// reindexes since the consensus was started
+func (s *consensus) GetReachabilityReindexStatistics() (*externalapi.ReachabilityReindexStatistics, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.reachabilityManager.ReindexStatistics(), nil
+}
+
+// GetPruningStatistics returns the statistics of the block data deleted by pruning
+// since the consensus was started
+func (s *consensus) GetPruningStatistics() (*externalapi.PruningStatistics, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.pruningManager.PruningStatistics(), nil
+}
+
+// CompactReachabilityIntervals rebuilds the intervals of the whole reachability tree from scratch,
+// and then concentrates them towards the headers selected tip as usual. It is meant to be run
+// offline, mostly after a lot of blocks were pruned.
+func (s *consensus) CompactReachabilityIntervals() (*externalapi.ReachabilityCompactionResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stagingArea := model.NewStagingArea()
+	result, err := s.reachabilityManager.CompactIntervals(stagingArea, model.VirtualGenesisBlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	headersSelectedTip, err := s.headersSelectedTipStore.HeadersSelectedTip(s.databaseContext, stagingArea)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reachabilityManager.UpdateReindexRoot(stagingArea, headersSelectedTip)
+	if err != nil {
+		return nil, err
+	}
+
+	err = staging.CommitAllChanges(s.databaseContext, stagingArea)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
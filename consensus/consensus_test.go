@@ -8,6 +8,7 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/merkle"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
 )
 
@@ -74,3 +75,63 @@ func TestConsensus_GetBlockInfo(t *testing.T) {
 
 	})
 }
+
+func TestConsensus_GetTransactionAcceptanceProof(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		factory := consensus.NewFactory()
+		tc, teardown, err := factory.NewTestConsensus(consensusConfig, "TestConsensus_GetTransactionAcceptanceProof")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		block1, _, err := tc.AddBlock([]*externalapi.DomainHash{consensusConfig.GenesisHash}, nil, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		block2, _, err := tc.AddBlock([]*externalapi.DomainHash{block1}, nil, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		// block3 pays a different script so that it differs from block2
+		block3, _, err := tc.AddBlock([]*externalapi.DomainHash{block1}, &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+			ExtraData:       []byte{},
+		}, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		tipHash, _, err := tc.AddBlock([]*externalapi.DomainHash{block2, block3}, nil, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		tip, _, err := tc.GetBlock(tipHash)
+		if err != nil {
+			t.Fatalf("GetBlock: %+v", err)
+		}
+
+		// The tip accepts the transactions of both of its parents
+		for _, parent := range []*externalapi.DomainHash{block2, block3} {
+			parentBlock, _, err := tc.GetBlock(parent)
+			if err != nil {
+				t.Fatalf("GetBlock: %+v", err)
+			}
+			for _, transaction := range parentBlock.Transactions {
+				proof, err := tc.GetTransactionAcceptanceProof(tipHash, consensushashing.TransactionID(transaction))
+				if err != nil {
+					t.Fatalf("GetTransactionAcceptanceProof: %+v", err)
+				}
+				if !merkle.VerifyIDMerkleProof(transaction, tip.Header.AcceptedIDMerkleRoot(), proof) {
+					t.Fatalf("The acceptance proof of transaction %s doesn't match the accepted ID merkle root of %s",
+						consensushashing.TransactionID(transaction), tipHash)
+				}
+			}
+		}
+
+		// The transactions of a block are accepted by its children, not by the block itself
+		_, err = tc.GetTransactionAcceptanceProof(tipHash, consensushashing.TransactionID(tip.Transactions[0]))
+		if err == nil {
+			t.Fatalf("Expected GetTransactionAcceptanceProof to fail for a transaction of the block itself")
+		}
+	})
+}
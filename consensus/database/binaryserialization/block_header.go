@@ -0,0 +1,85 @@
+package binaryserialization
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+)
+
+const blockHeaderFixedFieldsLength = 2 + 4 + 3*externalapi.DomainHashSize + 8 + 4 + 8 + 8 + 8 + 1 + 32 +
+	externalapi.DomainHashSize
+
+// SerializeBlockHeader serializes a BlockHeader with the compact binary layout. The fields
+// are written in the order of blockheader.NewImmutableBlockHeader's parameters, with the
+// parents written as a count of levels followed by the parents of every level.
+func SerializeBlockHeader(header externalapi.BlockHeader) ([]byte, error) {
+	parents := header.Parents()
+
+	size := blockHeaderFixedFieldsLength
+	for _, blockLevelParents := range parents {
+		size += 4 + len(blockLevelParents)*externalapi.DomainHashSize
+	}
+	w := newRecordWriter(size)
+
+	w.writeUint16(header.Version())
+	w.writeUint32(uint32(len(parents)))
+	for _, blockLevelParents := range parents {
+		w.writeHashes(blockLevelParents)
+	}
+	w.writeHash(header.HashMerkleRoot())
+	w.writeHash(header.AcceptedIDMerkleRoot())
+	w.writeHash(header.UTXOCommitment())
+	w.writeUint64(uint64(header.TimeInMilliseconds()))
+	w.writeUint32(header.Bits())
+	w.writeUint64(header.Nonce())
+	w.writeUint64(header.DAAScore())
+	w.writeUint64(header.BlueScore())
+	err := w.writeBigInt(header.BlueWork())
+	if err != nil {
+		return nil, err
+	}
+	w.writeHash(header.PruningPoint())
+
+	return w.bytes(), nil
+}
+
+// DeserializeBlockHeader deserializes a BlockHeader that was serialized by SerializeBlockHeader
+func DeserializeBlockHeader(headerBytes []byte) (externalapi.BlockHeader, error) {
+	r := newRecordReader(headerBytes)
+
+	version := r.readUint16()
+	parentsCount := r.readCount(4)
+	parents := make([]externalapi.BlockLevelParents, parentsCount)
+	for i := range parents {
+		parents[i] = r.readHashes()
+	}
+	hashMerkleRoot := r.readHash()
+	acceptedIDMerkleRoot := r.readHash()
+	utxoCommitment := r.readHash()
+	timeInMilliseconds := int64(r.readUint64())
+	bits := r.readUint32()
+	nonce := r.readUint64()
+	daaScore := r.readUint64()
+	blueScore := r.readUint64()
+	blueWork := r.readBigInt()
+	pruningPoint := r.readHash()
+
+	err := r.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return blockheader.NewImmutableBlockHeader(
+		version,
+		parents,
+		hashMerkleRoot,
+		acceptedIDMerkleRoot,
+		utxoCommitment,
+		timeInMilliseconds,
+		bits,
+		nonce,
+		daaScore,
+		blueScore,
+		blueWork,
+		pruningPoint,
+	), nil
+}
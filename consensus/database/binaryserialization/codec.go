@@ -0,0 +1,215 @@
+package binaryserialization
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// Records encoded by the compact codecs start with binaryEncodingMarker. A protobuf
+// message never starts with a zero byte, since zero is not a valid field tag, so the
+// marker tells compact records apart from protobuf records stored in the same bucket.
+const binaryEncodingMarker = 0x00
+
+// binaryEncodingVersion is the layout version of the compact codecs
+const binaryEncodingVersion = 1
+
+const binaryEncodingHeaderLength = 2
+
+// IsBinaryEncoded returns whether the given record was encoded by one of the compact codecs
+// of this package, as opposed to protobuf
+func IsBinaryEncoded(recordBytes []byte) bool {
+	return len(recordBytes) > 0 && recordBytes[0] == binaryEncodingMarker
+}
+
+// recordWriter appends the fields of a compact record to a buffer that's allocated once
+type recordWriter struct {
+	buffer []byte
+}
+
+func newRecordWriter(size int) *recordWriter {
+	buffer := make([]byte, 0, binaryEncodingHeaderLength+size)
+	buffer = append(buffer, binaryEncodingMarker, binaryEncodingVersion)
+	return &recordWriter{buffer: buffer}
+}
+
+func (w *recordWriter) writeUint8(value uint8) {
+	w.buffer = append(w.buffer, value)
+}
+
+func (w *recordWriter) writeUint16(value uint16) {
+	w.buffer = binary.LittleEndian.AppendUint16(w.buffer, value)
+}
+
+func (w *recordWriter) writeUint32(value uint32) {
+	w.buffer = binary.LittleEndian.AppendUint32(w.buffer, value)
+}
+
+func (w *recordWriter) writeUint64(value uint64) {
+	w.buffer = binary.LittleEndian.AppendUint64(w.buffer, value)
+}
+
+func (w *recordWriter) writeHash(hash *externalapi.DomainHash) {
+	w.buffer = append(w.buffer, hash.ByteSlice()...)
+}
+
+func (w *recordWriter) writeOptionalHash(hash *externalapi.DomainHash) {
+	if hash == nil {
+		w.writeUint8(0)
+		return
+	}
+	w.writeUint8(1)
+	w.writeHash(hash)
+}
+
+func (w *recordWriter) writeHashes(hashes []*externalapi.DomainHash) {
+	w.writeUint32(uint32(len(hashes)))
+	for _, hash := range hashes {
+		w.writeHash(hash)
+	}
+}
+
+func (w *recordWriter) writeBigInt(value *big.Int) error {
+	valueBytes := value.Bytes()
+	if len(valueBytes) > math.MaxUint8 {
+		return errors.Errorf("big int of %d bytes is too large to encode", len(valueBytes))
+	}
+	w.writeUint8(uint8(len(valueBytes)))
+	w.buffer = append(w.buffer, valueBytes...)
+	return nil
+}
+
+func (w *recordWriter) bytes() []byte {
+	return w.buffer
+}
+
+// recordReader reads the fields of a compact record. The first error it encounters
+// is kept and every following read returns zero values, so callers check err once
+// after all the fields are read.
+type recordReader struct {
+	recordBytes []byte
+	offset      int
+	err         error
+}
+
+func newRecordReader(recordBytes []byte) *recordReader {
+	r := &recordReader{recordBytes: recordBytes}
+	if !IsBinaryEncoded(recordBytes) || len(recordBytes) < binaryEncodingHeaderLength {
+		r.err = errors.New("the record is not binary encoded")
+		return r
+	}
+	if recordBytes[1] != binaryEncodingVersion {
+		r.err = errors.Errorf("unknown binary encoding version %d", recordBytes[1])
+		return r
+	}
+	r.offset = binaryEncodingHeaderLength
+	return r
+}
+
+func (r *recordReader) next(length int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if length < 0 || len(r.recordBytes)-r.offset < length {
+		r.err = errors.Errorf("the record is truncated: expected %d more bytes at offset %d but only %d remain",
+			length, r.offset, len(r.recordBytes)-r.offset)
+		return nil
+	}
+	fieldBytes := r.recordBytes[r.offset : r.offset+length]
+	r.offset += length
+	return fieldBytes
+}
+
+func (r *recordReader) readUint8() uint8 {
+	fieldBytes := r.next(1)
+	if fieldBytes == nil {
+		return 0
+	}
+	return fieldBytes[0]
+}
+
+func (r *recordReader) readUint16() uint16 {
+	fieldBytes := r.next(2)
+	if fieldBytes == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(fieldBytes)
+}
+
+func (r *recordReader) readUint32() uint32 {
+	fieldBytes := r.next(4)
+	if fieldBytes == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(fieldBytes)
+}
+
+func (r *recordReader) readUint64() uint64 {
+	fieldBytes := r.next(8)
+	if fieldBytes == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(fieldBytes)
+}
+
+func (r *recordReader) readHash() *externalapi.DomainHash {
+	fieldBytes := r.next(externalapi.DomainHashSize)
+	if fieldBytes == nil {
+		return nil
+	}
+	var hashArray [externalapi.DomainHashSize]byte
+	copy(hashArray[:], fieldBytes)
+	return externalapi.NewDomainHashFromByteArray(&hashArray)
+}
+
+func (r *recordReader) readOptionalHash() *externalapi.DomainHash {
+	switch flag := r.readUint8(); flag {
+	case 0:
+		return nil
+	case 1:
+		return r.readHash()
+	default:
+		if r.err == nil {
+			r.err = errors.Errorf("invalid optional hash flag %d", flag)
+		}
+		return nil
+	}
+}
+
+func (r *recordReader) readCount(elementSize int) int {
+	count := r.readUint32()
+	// Reject counts the remaining bytes can't hold before allocating for them
+	if r.err == nil && uint64(count)*uint64(elementSize) > uint64(len(r.recordBytes)-r.offset) {
+		r.err = errors.Errorf("count %d at offset %d exceeds the record length", count, r.offset)
+		return 0
+	}
+	return int(count)
+}
+
+func (r *recordReader) readHashes() []*externalapi.DomainHash {
+	count := r.readCount(externalapi.DomainHashSize)
+	hashes := make([]*externalapi.DomainHash, count)
+	for i := range hashes {
+		hashes[i] = r.readHash()
+	}
+	return hashes
+}
+
+func (r *recordReader) readBigInt() *big.Int {
+	length := r.readUint8()
+	return new(big.Int).SetBytes(r.next(int(length)))
+}
+
+// finish returns the first error that was encountered, or an error if the record has trailing bytes
+func (r *recordReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.offset != len(r.recordBytes) {
+		return errors.Errorf("the record has %d trailing bytes", len(r.recordBytes)-r.offset)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package binaryserialization
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+)
+
+func benchmarkHash(i int) *externalapi.DomainHash {
+	var hashBytes [externalapi.DomainHashSize]byte
+	hashBytes[0] = byte(i)
+	hashBytes[1] = byte(i >> 8)
+	hashBytes[31] = 0xaa
+	return externalapi.NewDomainHashFromByteArray(&hashBytes)
+}
+
+func benchmarkHashes(offset, count int) []*externalapi.DomainHash {
+	hashes := make([]*externalapi.DomainHash, count)
+	for i := range hashes {
+		hashes[i] = benchmarkHash(offset + i)
+	}
+	return hashes
+}
+
+// benchmarkHeader returns a header shaped like the ones received during header sync:
+// several direct parents and a few higher block levels
+func benchmarkHeader() externalapi.BlockHeader {
+	parents := []externalapi.BlockLevelParents{
+		benchmarkHashes(0, 10),
+		benchmarkHashes(10, 4),
+		benchmarkHashes(14, 2),
+		benchmarkHashes(16, 1),
+	}
+	blueWork, _ := new(big.Int).SetString("1f2e3d4c5b6a79880706050403020100", 16)
+	return blockheader.NewImmutableBlockHeader(1, parents, benchmarkHash(100), benchmarkHash(101),
+		benchmarkHash(102), 1_700_000_000_000, 0x1e7fffff, 123456789, 50_000_000, 49_000_000, blueWork,
+		benchmarkHash(103))
+}
+
+func benchmarkGHOSTDAGData() *externalapi.BlockGHOSTDAGData {
+	mergeSetBlues := benchmarkHashes(0, 12)
+	bluesAnticoneSizes := make(map[externalapi.DomainHash]externalapi.KType, len(mergeSetBlues))
+	for i, blue := range mergeSetBlues {
+		bluesAnticoneSizes[*blue] = externalapi.KType(i)
+	}
+	blueWork, _ := new(big.Int).SetString("1f2e3d4c5b6a79880706050403020100", 16)
+	return externalapi.NewBlockGHOSTDAGData(49_000_000, blueWork, benchmarkHash(0), mergeSetBlues,
+		benchmarkHashes(20, 3), bluesAnticoneSizes)
+}
+
+func BenchmarkDeserializeBlockHeader(b *testing.B) {
+	header := benchmarkHeader()
+
+	b.Run("protobuf", func(b *testing.B) {
+		headerBytes, err := proto.Marshal(serialization.DomainBlockHeaderToDbBlockHeader(header))
+		if err != nil {
+			b.Fatalf("proto.Marshal: %+v", err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			dbBlockHeader := &serialization.DbBlockHeader{}
+			err := proto.Unmarshal(headerBytes, dbBlockHeader)
+			if err != nil {
+				b.Fatalf("proto.Unmarshal: %+v", err)
+			}
+			_, err = serialization.DbBlockHeaderToDomainBlockHeader(dbBlockHeader)
+			if err != nil {
+				b.Fatalf("DbBlockHeaderToDomainBlockHeader: %+v", err)
+			}
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		headerBytes, err := SerializeBlockHeader(header)
+		if err != nil {
+			b.Fatalf("SerializeBlockHeader: %+v", err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := DeserializeBlockHeader(headerBytes)
+			if err != nil {
+				b.Fatalf("DeserializeBlockHeader: %+v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkDeserializeBlockGHOSTDAGData(b *testing.B) {
+	blockGHOSTDAGData := benchmarkGHOSTDAGData()
+
+	b.Run("protobuf", func(b *testing.B) {
+		dataBytes, err := proto.Marshal(serialization.BlockGHOSTDAGDataToDBBlockGHOSTDAGData(blockGHOSTDAGData))
+		if err != nil {
+			b.Fatalf("proto.Marshal: %+v", err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			dbBlockGHOSTDAGData := &serialization.DbBlockGhostdagData{}
+			err := proto.Unmarshal(dataBytes, dbBlockGHOSTDAGData)
+			if err != nil {
+				b.Fatalf("proto.Unmarshal: %+v", err)
+			}
+			_, err = serialization.DBBlockGHOSTDAGDataToBlockGHOSTDAGData(dbBlockGHOSTDAGData)
+			if err != nil {
+				b.Fatalf("DBBlockGHOSTDAGDataToBlockGHOSTDAGData: %+v", err)
+			}
+		}
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		dataBytes, err := SerializeBlockGHOSTDAGData(blockGHOSTDAGData)
+		if err != nil {
+			b.Fatalf("SerializeBlockGHOSTDAGData: %+v", err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err := DeserializeBlockGHOSTDAGData(dataBytes)
+			if err != nil {
+				b.Fatalf("DeserializeBlockGHOSTDAGData: %+v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package binaryserialization
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/reachabilitydata"
+)
+
+// fuzzSource derives the fields of a consensus object from fuzzer input,
+// returning zero values once the input is exhausted
+type fuzzSource struct {
+	data []byte
+}
+
+func (fs *fuzzSource) take(length int) []byte {
+	taken := make([]byte, length)
+	n := copy(taken, fs.data)
+	fs.data = fs.data[n:]
+	return taken
+}
+
+func (fs *fuzzSource) uint8() uint8 {
+	return fs.take(1)[0]
+}
+
+func (fs *fuzzSource) uint64() uint64 {
+	return binary.LittleEndian.Uint64(fs.take(8))
+}
+
+func (fs *fuzzSource) hash() *externalapi.DomainHash {
+	hash, _ := externalapi.NewDomainHashFromByteSlice(fs.take(externalapi.DomainHashSize))
+	return hash
+}
+
+func (fs *fuzzSource) hashes() []*externalapi.DomainHash {
+	hashes := make([]*externalapi.DomainHash, fs.uint8()%8)
+	for i := range hashes {
+		hashes[i] = fs.hash()
+	}
+	return hashes
+}
+
+func (fs *fuzzSource) bigInt() *big.Int {
+	return new(big.Int).SetBytes(fs.take(int(fs.uint8() % 33)))
+}
+
+func (fs *fuzzSource) optionalHash() *externalapi.DomainHash {
+	if fs.uint8()%2 == 0 {
+		return nil
+	}
+	return fs.hash()
+}
+
+func (fs *fuzzSource) ghostdagData() *externalapi.BlockGHOSTDAGData {
+	blueScore := fs.uint64()
+	blueWork := fs.bigInt()
+	selectedParent := fs.optionalHash()
+	mergeSetBlues := fs.hashes()
+	mergeSetReds := fs.hashes()
+	bluesAnticoneSizes := make(map[externalapi.DomainHash]externalapi.KType)
+	for _, blue := range fs.hashes() {
+		bluesAnticoneSizes[*blue] = externalapi.KType(fs.uint8())
+	}
+	return externalapi.NewBlockGHOSTDAGData(blueScore, blueWork, selectedParent, mergeSetBlues, mergeSetReds,
+		bluesAnticoneSizes)
+}
+
+func (fs *fuzzSource) reachabilityData() model.ReachabilityData {
+	children := fs.hashes()
+	parent := fs.optionalHash()
+	interval := &model.ReachabilityInterval{Start: fs.uint64(), End: fs.uint64()}
+	return reachabilitydata.New(children, parent, interval, fs.hashes())
+}
+
+func (fs *fuzzSource) blockHeader() externalapi.BlockHeader {
+	parents := make([]externalapi.BlockLevelParents, fs.uint8()%4)
+	for i := range parents {
+		parents[i] = fs.hashes()
+	}
+	return blockheader.NewImmutableBlockHeader(
+		uint16(fs.uint64()),
+		parents,
+		fs.hash(),
+		fs.hash(),
+		fs.hash(),
+		int64(fs.uint64()),
+		uint32(fs.uint64()),
+		fs.uint64(),
+		fs.uint64(),
+		fs.uint64(),
+		fs.bigInt(),
+		fs.hash(),
+	)
+}
+
+func addFuzzSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0xff, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b})
+	seed := make([]byte, 512)
+	for i := range seed {
+		seed[i] = byte(i * 7)
+	}
+	f.Add(seed)
+}
+
+func FuzzBlockGHOSTDAGDataRoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expected := (&fuzzSource{data: data}).ghostdagData()
+
+		protobufBytes, err := proto.Marshal(serialization.BlockGHOSTDAGDataToDBBlockGHOSTDAGData(expected))
+		if err != nil {
+			t.Fatalf("proto.Marshal: %+v", err)
+		}
+		if IsBinaryEncoded(protobufBytes) {
+			t.Fatalf("a protobuf record was detected as binary encoded")
+		}
+		dbBlockGHOSTDAGData := &serialization.DbBlockGhostdagData{}
+		err = proto.Unmarshal(protobufBytes, dbBlockGHOSTDAGData)
+		if err != nil {
+			t.Fatalf("proto.Unmarshal: %+v", err)
+		}
+		fromProtobuf, err := serialization.DBBlockGHOSTDAGDataToBlockGHOSTDAGData(dbBlockGHOSTDAGData)
+		if err != nil {
+			t.Fatalf("DBBlockGHOSTDAGDataToBlockGHOSTDAGData: %+v", err)
+		}
+
+		binaryBytes, err := SerializeBlockGHOSTDAGData(expected)
+		if err != nil {
+			t.Fatalf("SerializeBlockGHOSTDAGData: %+v", err)
+		}
+		fromBinary, err := DeserializeBlockGHOSTDAGData(binaryBytes)
+		if err != nil {
+			t.Fatalf("DeserializeBlockGHOSTDAGData: %+v", err)
+		}
+
+		if !fromBinary.Equal(expected) || !fromBinary.Equal(fromProtobuf) {
+			t.Fatalf("the binary round trip doesn't match the protobuf round trip")
+		}
+	})
+}
+
+func FuzzReachabilityDataRoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expected := (&fuzzSource{data: data}).reachabilityData()
+
+		protobufBytes, err := proto.Marshal(serialization.ReachablityDataToDBReachablityData(expected))
+		if err != nil {
+			t.Fatalf("proto.Marshal: %+v", err)
+		}
+		if IsBinaryEncoded(protobufBytes) {
+			t.Fatalf("a protobuf record was detected as binary encoded")
+		}
+		dbReachabilityData := &serialization.DbReachabilityData{}
+		err = proto.Unmarshal(protobufBytes, dbReachabilityData)
+		if err != nil {
+			t.Fatalf("proto.Unmarshal: %+v", err)
+		}
+		fromProtobuf, err := serialization.DBReachablityDataToReachablityData(dbReachabilityData)
+		if err != nil {
+			t.Fatalf("DBReachablityDataToReachablityData: %+v", err)
+		}
+
+		fromBinary, err := DeserializeReachabilityData(SerializeReachabilityData(expected))
+		if err != nil {
+			t.Fatalf("DeserializeReachabilityData: %+v", err)
+		}
+
+		if !fromBinary.Equal(expected) || !fromBinary.Equal(fromProtobuf) {
+			t.Fatalf("the binary round trip doesn't match the protobuf round trip")
+		}
+	})
+}
+
+func FuzzBlockHeaderRoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expected := (&fuzzSource{data: data}).blockHeader()
+
+		protobufBytes, err := proto.Marshal(serialization.DomainBlockHeaderToDbBlockHeader(expected))
+		if err != nil {
+			t.Fatalf("proto.Marshal: %+v", err)
+		}
+		if IsBinaryEncoded(protobufBytes) {
+			t.Fatalf("a protobuf record was detected as binary encoded")
+		}
+		dbBlockHeader := &serialization.DbBlockHeader{}
+		err = proto.Unmarshal(protobufBytes, dbBlockHeader)
+		if err != nil {
+			t.Fatalf("proto.Unmarshal: %+v", err)
+		}
+		fromProtobuf, err := serialization.DbBlockHeaderToDomainBlockHeader(dbBlockHeader)
+		if err != nil {
+			t.Fatalf("DbBlockHeaderToDomainBlockHeader: %+v", err)
+		}
+
+		binaryBytes, err := SerializeBlockHeader(expected)
+		if err != nil {
+			t.Fatalf("SerializeBlockHeader: %+v", err)
+		}
+		fromBinary, err := DeserializeBlockHeader(binaryBytes)
+		if err != nil {
+			t.Fatalf("DeserializeBlockHeader: %+v", err)
+		}
+
+		if !fromBinary.Equal(expected) || !fromBinary.Equal(fromProtobuf) {
+			t.Fatalf("the binary round trip doesn't match the protobuf round trip")
+		}
+	})
+}
+
+// FuzzDeserializeCorrupted makes sure that the decoders reject arbitrary input instead of panicking
+func FuzzDeserializeCorrupted(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Add([]byte{binaryEncodingMarker, binaryEncodingVersion, 0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		recordBytes := append([]byte{binaryEncodingMarker, binaryEncodingVersion}, data...)
+		_, _ = DeserializeBlockGHOSTDAGData(recordBytes)
+		_, _ = DeserializeReachabilityData(recordBytes)
+		_, _ = DeserializeBlockHeader(recordBytes)
+	})
+}
+
+func TestDeserializeRejectsTrailingBytes(t *testing.T) {
+	header := (&fuzzSource{data: []byte{1, 2, 3}}).blockHeader()
+	headerBytes, err := SerializeBlockHeader(header)
+	if err != nil {
+		t.Fatalf("SerializeBlockHeader: %+v", err)
+	}
+	_, err = DeserializeBlockHeader(append(headerBytes, 0))
+	if err == nil {
+		t.Fatalf("expected a header with trailing bytes to be rejected")
+	}
+	_, err = DeserializeBlockHeader(headerBytes[:len(headerBytes)-1])
+	if err == nil {
+		t.Fatalf("expected a truncated header to be rejected")
+	}
+}
+
+func TestSerializeBlockGHOSTDAGDataIsDeterministic(t *testing.T) {
+	seed := make([]byte, 512)
+	for i := range seed {
+		seed[i] = byte(i*13 + 5)
+	}
+	ghostdagData := (&fuzzSource{data: seed}).ghostdagData()
+	if len(ghostdagData.BluesAnticoneSizes()) < 2 {
+		t.Fatalf("expected the seed to produce several blues anticone sizes")
+	}
+
+	expected, err := SerializeBlockGHOSTDAGData(ghostdagData)
+	if err != nil {
+		t.Fatalf("SerializeBlockGHOSTDAGData: %+v", err)
+	}
+	// Map iteration order is randomized, so serializing the same data repeatedly covers different orders
+	for i := 0; i < 100; i++ {
+		serialized, err := SerializeBlockGHOSTDAGData(ghostdagData)
+		if err != nil {
+			t.Fatalf("SerializeBlockGHOSTDAGData: %+v", err)
+		}
+		if !bytes.Equal(serialized, expected) {
+			t.Fatalf("serializing the same GHOSTDAG data twice produced different bytes")
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package binaryserialization
+
+import (
+	"sort"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// SerializeBlockGHOSTDAGData serializes BlockGHOSTDAGData with the compact binary layout:
+// blue score, blue work, optional selected parent, mergeset blues, mergeset reds and the
+// blues anticone sizes as (hash, size) pairs ordered by hash, so that equal data always serializes to the same bytes.
+func SerializeBlockGHOSTDAGData(blockGHOSTDAGData *externalapi.BlockGHOSTDAGData) ([]byte, error) {
+	mergeSetBlues := blockGHOSTDAGData.MergeSetBlues()
+	mergeSetReds := blockGHOSTDAGData.MergeSetReds()
+	bluesAnticoneSizes := blockGHOSTDAGData.BluesAnticoneSizes()
+
+	size := 8 + 1 + 32 + 1 + externalapi.DomainHashSize +
+		4 + len(mergeSetBlues)*externalapi.DomainHashSize +
+		4 + len(mergeSetReds)*externalapi.DomainHashSize +
+		4 + len(bluesAnticoneSizes)*(externalapi.DomainHashSize+1)
+	w := newRecordWriter(size)
+
+	w.writeUint64(blockGHOSTDAGData.BlueScore())
+	err := w.writeBigInt(blockGHOSTDAGData.BlueWork())
+	if err != nil {
+		return nil, err
+	}
+	w.writeOptionalHash(blockGHOSTDAGData.SelectedParent())
+	w.writeHashes(mergeSetBlues)
+	w.writeHashes(mergeSetReds)
+	blueHashes := make([]*externalapi.DomainHash, 0, len(bluesAnticoneSizes))
+	for blueHash := range bluesAnticoneSizes {
+		blueHash := blueHash
+		blueHashes = append(blueHashes, &blueHash)
+	}
+	sort.Slice(blueHashes, func(i, j int) bool {
+		return blueHashes[i].Less(blueHashes[j])
+	})
+	w.writeUint32(uint32(len(blueHashes)))
+	for _, blueHash := range blueHashes {
+		w.writeHash(blueHash)
+		w.writeUint8(uint8(bluesAnticoneSizes[*blueHash]))
+	}
+
+	return w.bytes(), nil
+}
+
+// DeserializeBlockGHOSTDAGData deserializes BlockGHOSTDAGData that was serialized by SerializeBlockGHOSTDAGData
+func DeserializeBlockGHOSTDAGData(blockGHOSTDAGDataBytes []byte) (*externalapi.BlockGHOSTDAGData, error) {
+	r := newRecordReader(blockGHOSTDAGDataBytes)
+
+	blueScore := r.readUint64()
+	blueWork := r.readBigInt()
+	selectedParent := r.readOptionalHash()
+	mergeSetBlues := r.readHashes()
+	mergeSetReds := r.readHashes()
+	bluesAnticoneSizesCount := r.readCount(externalapi.DomainHashSize + 1)
+	bluesAnticoneSizes := make(map[externalapi.DomainHash]externalapi.KType, bluesAnticoneSizesCount)
+	for i := 0; i < bluesAnticoneSizesCount; i++ {
+		blueHash := r.readHash()
+		anticoneSize := r.readUint8()
+		if blueHash != nil {
+			bluesAnticoneSizes[*blueHash] = externalapi.KType(anticoneSize)
+		}
+	}
+
+	err := r.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return externalapi.NewBlockGHOSTDAGData(blueScore, blueWork, selectedParent, mergeSetBlues, mergeSetReds,
+		bluesAnticoneSizes), nil
+}
@@ -0,0 +1,49 @@
+package binaryserialization
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/reachabilitydata"
+)
+
+// SerializeReachabilityData serializes ReachabilityData with the compact binary layout:
+// children, optional parent, interval start and end, and the future covering set.
+func SerializeReachabilityData(reachabilityData model.ReachabilityData) []byte {
+	children := reachabilityData.Children()
+	futureCoveringSet := reachabilityData.FutureCoveringSet()
+
+	size := 4 + len(children)*externalapi.DomainHashSize +
+		1 + externalapi.DomainHashSize +
+		8 + 8 +
+		4 + len(futureCoveringSet)*externalapi.DomainHashSize
+	w := newRecordWriter(size)
+
+	w.writeHashes(children)
+	w.writeOptionalHash(reachabilityData.Parent())
+	interval := reachabilityData.Interval()
+	w.writeUint64(interval.Start)
+	w.writeUint64(interval.End)
+	w.writeHashes(futureCoveringSet)
+
+	return w.bytes()
+}
+
+// DeserializeReachabilityData deserializes ReachabilityData that was serialized by SerializeReachabilityData
+func DeserializeReachabilityData(reachabilityDataBytes []byte) (model.ReachabilityData, error) {
+	r := newRecordReader(reachabilityDataBytes)
+
+	children := r.readHashes()
+	parent := r.readOptionalHash()
+	interval := &model.ReachabilityInterval{
+		Start: r.readUint64(),
+		End:   r.readUint64(),
+	}
+	futureCoveringSet := r.readHashes()
+
+	err := r.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return reachabilitydata.New(children, parent, interval, futureCoveringSet), nil
+}
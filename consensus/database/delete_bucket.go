@@ -0,0 +1,56 @@
+package database
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+// DeleteBucket deletes all the keys in the given bucket, committing a database transaction
+// for every batchSize keys. The keys are collected before any of them is deleted, so that the
+// deletion doesn't run under an open cursor.
+func DeleteBucket(dbManager model.DBManager, bucket model.DBBucket, batchSize int) error {
+	cursor, err := dbManager.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var keys []model.DBKey
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		// The cursor may reuse the memory of its keys
+		suffix := make([]byte, len(key.Suffix()))
+		copy(suffix, key.Suffix())
+		keys = append(keys, bucket.Key(suffix))
+	}
+
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		err := deleteKeys(dbManager, keys[start:end])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteKeys(dbManager model.DBManager, keys []model.DBKey) error {
+	dbTx, err := dbManager.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.RollbackUnlessClosed()
+
+	for _, key := range keys {
+		err := dbTx.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit()
+}
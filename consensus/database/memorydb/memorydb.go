@@ -0,0 +1,99 @@
+package memorydb
+
+import (
+	"sync"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	leveldbErrors "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+)
+
+// MemoryDB is a database that is kept entirely in memory. It's meant for
+// tests and simulations: the memory of overwritten and deleted values is
+// only released when the database is closed.
+type MemoryDB struct {
+	// lock makes the commit of a transaction atomic with regard to reads
+	lock     sync.RWMutex
+	db       *memdb.DB
+	isClosed bool
+}
+
+// New creates a new empty MemoryDB
+func New() *MemoryDB {
+	return &MemoryDB{
+		db: memdb.New(comparer.DefaultComparer, 0),
+	}
+}
+
+// Compact does nothing, since there's nothing to compact in memory
+func (db *MemoryDB) Compact() error {
+	return nil
+}
+
+// Close releases the memory held by the database
+func (db *MemoryDB) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.isClosed {
+		return errors.New("cannot close an already closed database")
+	}
+	db.isClosed = true
+	db.db.Reset()
+	return nil
+}
+
+// Put sets the value for the given key. It overwrites
+// any previous value for that key.
+func (db *MemoryDB) Put(key *database.Key, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return errors.WithStack(db.db.Put(key.Bytes(), value))
+}
+
+// Get gets the value for the given key. It returns
+// ErrNotFound if the given key does not exist.
+func (db *MemoryDB) Get(key *database.Key) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	value, err := db.db.Get(key.Bytes())
+	if err != nil {
+		if errors.Is(err, leveldbErrors.ErrNotFound) {
+			return nil, errors.Wrapf(database.ErrNotFound,
+				"key %s not found", key)
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	// The returned slice points into the memory of the database, so it's
+	// copied in order not to let the caller modify it
+	valueCopy := make([]byte, len(value))
+	copy(valueCopy, value)
+	return valueCopy, nil
+}
+
+// Has returns true if the database does contains the
+// given key.
+func (db *MemoryDB) Has(key *database.Key) (bool, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.db.Contains(key.Bytes()), nil
+}
+
+// Delete deletes the value for the given key. Will not
+// return an error if the key doesn't exist.
+func (db *MemoryDB) Delete(key *database.Key) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	err := db.db.Delete(key.Bytes())
+	if errors.Is(err, leveldbErrors.ErrNotFound) {
+		return nil
+	}
+	return errors.WithStack(err)
+}
@@ -0,0 +1,90 @@
+package memorydb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
+	"github.com/pkg/errors"
+)
+
+func TestMemoryDBTransactionAndCursor(t *testing.T) {
+	db := New()
+	defer db.Close()
+
+	bucket := database.MakeBucket([]byte("bucket"))
+	otherBucket := database.MakeBucket([]byte("other"))
+
+	err := db.Put(otherBucket.Key([]byte("key")), []byte("other"))
+	if err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+
+	transaction, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %s", err)
+	}
+	for _, key := range []string{"c", "a", "b"} {
+		err = transaction.Put(bucket.Key([]byte(key)), []byte("value-"+key))
+		if err != nil {
+			t.Fatalf("Put: %s", err)
+		}
+	}
+	err = transaction.Delete(otherBucket.Key([]byte("key")))
+	if err != nil {
+		t.Fatalf("Delete: %s", err)
+	}
+
+	// Changes are not visible before the commit
+	has, err := db.Has(bucket.Key([]byte("a")))
+	if err != nil {
+		t.Fatalf("Has: %s", err)
+	}
+	if has {
+		t.Fatalf("TestMemoryDBTransactionAndCursor: uncommitted key is visible")
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+
+	_, err = db.Get(otherBucket.Key([]byte("key")))
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("TestMemoryDBTransactionAndCursor: expected ErrNotFound for a deleted key, got: %v", err)
+	}
+
+	cursor, err := db.Cursor(bucket)
+	if err != nil {
+		t.Fatalf("Cursor: %s", err)
+	}
+	defer cursor.Close()
+
+	var keys []string
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			t.Fatalf("Key: %s", err)
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			t.Fatalf("Value: %s", err)
+		}
+		if !bytes.Equal(value, []byte("value-"+string(key.Suffix()))) {
+			t.Fatalf("TestMemoryDBTransactionAndCursor: unexpected value %s for key %s", value, key.Suffix())
+		}
+		keys = append(keys, string(key.Suffix()))
+	}
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Fatalf("TestMemoryDBTransactionAndCursor: unexpected keys: %v", keys)
+	}
+
+	err = cursor.Seek(bucket.Key([]byte("b")))
+	if err != nil {
+		t.Fatalf("Seek: %s", err)
+	}
+	err = cursor.Seek(bucket.Key([]byte("d")))
+	if !errors.Is(err, database.ErrNotFound) {
+		t.Fatalf("TestMemoryDBTransactionAndCursor: expected ErrNotFound when seeking a missing key, got: %v", err)
+	}
+}
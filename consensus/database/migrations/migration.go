@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+// Version is the schema version of the consensus objects stored under a database prefix
+type Version uint64
+
+// BaselineVersion is the schema version of databases that were created before schema
+// versioning was introduced. Such databases carry no schema version key.
+const BaselineVersion Version = 1
+
+// RewriteFunc rewrites a single record of a bucket. It returns the new value of the record
+// and whether it differs from the old one. Records that weren't changed are not written back.
+type RewriteFunc func(key model.DBKey, value []byte) (newValue []byte, isChanged bool, err error)
+
+// Step is a single bucket rewrite within a Migration
+type Step struct {
+	Description string
+
+	// Buckets returns the buckets the step rewrites, given the bucket of the database prefix.
+	// Stores that keep a bucket per block level return one bucket for every level.
+	Buckets func(prefixBucket model.DBBucket) []model.DBBucket
+
+	Rewrite RewriteFunc
+}
+
+// Migration upgrades the stored consensus objects from Version-1 to Version
+type Migration struct {
+	Version     Version
+	Description string
+	Steps       []*Step
+}
+
+// Registry is an ordered list of migrations, each upgrading the schema by exactly one version
+type Registry struct {
+	migrations []*Migration
+}
+
+// NewRegistry creates a Registry out of the given migrations. The migrations must be ordered
+// by version, starting right after BaselineVersion and with no gaps.
+func NewRegistry(migrations ...*Migration) (*Registry, error) {
+	expectedVersion := BaselineVersion + 1
+	for _, migration := range migrations {
+		if migration.Version != expectedVersion {
+			return nil, errors.Errorf("expected migration to version %d but got a migration to version %d",
+				expectedVersion, migration.Version)
+		}
+		if len(migration.Steps) == 0 {
+			return nil, errors.Errorf("migration to version %d has no steps", migration.Version)
+		}
+		for i, step := range migration.Steps {
+			if step.Buckets == nil || step.Rewrite == nil {
+				return nil, errors.Errorf("step %d of the migration to version %d is incomplete", i, migration.Version)
+			}
+		}
+		expectedVersion++
+	}
+
+	return &Registry{migrations: migrations}, nil
+}
+
+// LatestVersion returns the schema version a database is at once all the registered migrations were applied
+func (r *Registry) LatestVersion() Version {
+	return BaselineVersion + Version(len(r.migrations))
+}
+
+// migrationsAfter returns the migrations that need to be applied to a database at the given version
+func (r *Registry) migrationsAfter(version Version) ([]*Migration, error) {
+	if version < BaselineVersion {
+		return nil, errors.Errorf("the database schema version %d is older than the baseline version %d",
+			version, BaselineVersion)
+	}
+	if version > r.LatestVersion() {
+		return nil, errors.Errorf("the database schema version %d is newer than the latest supported version %d",
+			version, r.LatestVersion())
+	}
+	return r.migrations[version-BaselineVersion:], nil
+}
+
+// registeredMigrations are the migrations of the consensus database schema, ordered by version.
+// To change the serialization of a stored object, add its new version here along with a step
+// that rewrites the records of the old version.
+var registeredMigrations = []*Migration{}
+
+// DefaultRegistry returns the Registry of the consensus database schema
+func DefaultRegistry() *Registry {
+	registry, err := NewRegistry(registeredMigrations...)
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
@@ -0,0 +1,312 @@
+package migrations
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+// SchemaVersionKeyName is the name of the database key of the schema version of the stored consensus objects
+var SchemaVersionKeyName = []byte("schema-version")
+var checkpointKeyName = []byte("schema-migration-checkpoint")
+
+// DefaultBatchSize is the number of records a Migrator rewrites in a single database transaction
+const DefaultBatchSize = 10_000
+
+// Migrator applies the migrations of a Registry to the consensus stored under a database prefix.
+// Every batch of rewritten records is committed together with a checkpoint, so a migration that
+// was interrupted resumes from where it stopped.
+type Migrator struct {
+	dbManager    model.DBManager
+	prefixBucket model.DBBucket
+	registry     *Registry
+	batchSize    int
+
+	schemaVersionKey model.DBKey
+	checkpointKey    model.DBKey
+}
+
+// NewMigrator instantiates a new Migrator
+func NewMigrator(dbManager model.DBManager, prefixBucket model.DBBucket, registry *Registry, batchSize int) *Migrator {
+	return &Migrator{
+		dbManager:    dbManager,
+		prefixBucket: prefixBucket,
+		registry:     registry,
+		batchSize:    batchSize,
+
+		schemaVersionKey: prefixBucket.Key(SchemaVersionKeyName),
+		checkpointKey:    prefixBucket.Key(checkpointKeyName),
+	}
+}
+
+// SchemaVersion returns the schema version of the database prefix, and false if
+// the prefix carries no schema version key.
+func (m *Migrator) SchemaVersion() (Version, bool, error) {
+	versionBytes, err := m.dbManager.Get(m.schemaVersionKey)
+	if database.IsNotFoundError(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	version, err := binaryserialization.DeserializeUint64(versionBytes)
+	if err != nil {
+		return 0, false, err
+	}
+	return Version(version), true, nil
+}
+
+// Migrate brings the database prefix up to the latest version of the registry.
+// An empty prefix is stamped with the latest version, and a prefix with no schema
+// version key is assumed to be at BaselineVersion.
+func (m *Migrator) Migrate() error {
+	version, hasVersion, err := m.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	latestVersion := m.registry.LatestVersion()
+	if !hasVersion {
+		isEmpty, err := m.isPrefixEmpty()
+		if err != nil {
+			return err
+		}
+		if isEmpty {
+			return m.dbManager.Put(m.schemaVersionKey, binaryserialization.SerializeUint64(uint64(latestVersion)))
+		}
+		version = BaselineVersion
+	}
+
+	migrations, err := m.registry.migrationsAfter(version)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		log.Infof("Migrating the consensus database schema to version %d: %s", migration.Version, migration.Description)
+		err := m.migrate(migration)
+		if err != nil {
+			return errors.Wrapf(err, "failed migrating the consensus database schema to version %d", migration.Version)
+		}
+	}
+
+	if !hasVersion && version == latestVersion {
+		return m.dbManager.Put(m.schemaVersionKey, binaryserialization.SerializeUint64(uint64(latestVersion)))
+	}
+	return nil
+}
+
+func (m *Migrator) migrate(migration *Migration) error {
+	checkpoint, err := m.checkpoint()
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil && checkpoint.version != migration.Version {
+		return errors.Errorf("found a checkpoint of the migration to version %d while migrating to version %d",
+			checkpoint.version, migration.Version)
+	}
+	if checkpoint == nil {
+		checkpoint = &migrationCheckpoint{version: migration.Version}
+	} else {
+		log.Infof("Resuming the migration to version %d from step %d", migration.Version, checkpoint.stepIndex)
+	}
+
+	for ; checkpoint.stepIndex < uint64(len(migration.Steps)); checkpoint.stepIndex++ {
+		step := migration.Steps[checkpoint.stepIndex]
+		log.Infof("Migration step %d/%d: %s", checkpoint.stepIndex+1, len(migration.Steps), step.Description)
+
+		buckets := step.Buckets(m.prefixBucket)
+		for ; checkpoint.bucketIndex < uint64(len(buckets)); checkpoint.bucketIndex++ {
+			err := m.rewriteBucket(buckets[checkpoint.bucketIndex], step.Rewrite, checkpoint)
+			if err != nil {
+				return err
+			}
+			checkpoint.lastKeySuffix = nil
+		}
+		checkpoint.bucketIndex = 0
+	}
+
+	dbTx, err := m.dbManager.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.RollbackUnlessClosed()
+
+	err = dbTx.Put(m.schemaVersionKey, binaryserialization.SerializeUint64(uint64(migration.Version)))
+	if err != nil {
+		return err
+	}
+	err = dbTx.Delete(m.checkpointKey)
+	if err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// rewriteBucket rewrites the records of bucket that come after the checkpoint, committing
+// the rewritten records of every batch together with an updated checkpoint
+func (m *Migrator) rewriteBucket(bucket model.DBBucket, rewrite RewriteFunc, checkpoint *migrationCheckpoint) error {
+	cursor, err := m.dbManager.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	ok, err := m.seekPastCheckpoint(cursor, bucket, checkpoint)
+	if err != nil {
+		return err
+	}
+
+	var dbTx model.DBTransaction
+	defer func() {
+		if dbTx != nil {
+			dbTx.RollbackUnlessClosed()
+		}
+	}()
+
+	batchCount := 0
+	for ; ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+
+		newValue, isChanged, err := rewrite(key, value)
+		if err != nil {
+			return errors.Wrapf(err, "failed rewriting key %x", key.Bytes())
+		}
+
+		if dbTx == nil {
+			dbTx, err = m.dbManager.Begin()
+			if err != nil {
+				return err
+			}
+		}
+		if isChanged {
+			err = dbTx.Put(key, newValue)
+			if err != nil {
+				return err
+			}
+		}
+
+		checkpoint.lastKeySuffix = append([]byte{}, key.Suffix()...)
+		batchCount++
+		if batchCount == m.batchSize {
+			err = m.commitBatch(dbTx, checkpoint)
+			if err != nil {
+				return err
+			}
+			dbTx = nil
+			batchCount = 0
+		}
+	}
+
+	if dbTx != nil {
+		err = m.commitBatch(dbTx, checkpoint)
+		if err != nil {
+			return err
+		}
+		dbTx = nil
+	}
+	return nil
+}
+
+// seekPastCheckpoint positions cursor on the first record of bucket that comes after the
+// checkpoint, and returns false if there is no such record
+func (m *Migrator) seekPastCheckpoint(cursor model.DBCursor, bucket model.DBBucket,
+	checkpoint *migrationCheckpoint) (bool, error) {
+
+	if checkpoint.lastKeySuffix == nil {
+		return cursor.First(), nil
+	}
+
+	err := cursor.Seek(bucket.Key(checkpoint.lastKeySuffix))
+	if err == nil {
+		return cursor.Next(), nil
+	}
+	if !database.IsNotFoundError(err) {
+		return false, err
+	}
+
+	// Seek only succeeds on an exact match, so if the checkpoint record is gone
+	// the records up to it are skipped one by one
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return false, err
+		}
+		if bytes.Compare(key.Suffix(), checkpoint.lastKeySuffix) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Migrator) commitBatch(dbTx model.DBTransaction, checkpoint *migrationCheckpoint) error {
+	err := dbTx.Put(m.checkpointKey, checkpoint.serialize())
+	if err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+func (m *Migrator) checkpoint() (*migrationCheckpoint, error) {
+	checkpointBytes, err := m.dbManager.Get(m.checkpointKey)
+	if database.IsNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deserializeMigrationCheckpoint(checkpointBytes)
+}
+
+func (m *Migrator) isPrefixEmpty() (bool, error) {
+	cursor, err := m.dbManager.Cursor(m.prefixBucket)
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close()
+
+	return !cursor.First(), nil
+}
+
+// migrationCheckpoint marks the last record that was rewritten by an unfinished migration
+type migrationCheckpoint struct {
+	version       Version
+	stepIndex     uint64
+	bucketIndex   uint64
+	lastKeySuffix []byte
+}
+
+const migrationCheckpointHeaderLength = 3 * 8
+
+func (mc *migrationCheckpoint) serialize() []byte {
+	serialized := make([]byte, migrationCheckpointHeaderLength, migrationCheckpointHeaderLength+len(mc.lastKeySuffix))
+	binary.LittleEndian.PutUint64(serialized[0:8], uint64(mc.version))
+	binary.LittleEndian.PutUint64(serialized[8:16], mc.stepIndex)
+	binary.LittleEndian.PutUint64(serialized[16:24], mc.bucketIndex)
+	return append(serialized, mc.lastKeySuffix...)
+}
+
+func deserializeMigrationCheckpoint(serialized []byte) (*migrationCheckpoint, error) {
+	if len(serialized) < migrationCheckpointHeaderLength {
+		return nil, errors.Errorf("the migration checkpoint is %d bytes which is too short", len(serialized))
+	}
+	checkpoint := &migrationCheckpoint{
+		version:     Version(binary.LittleEndian.Uint64(serialized[0:8])),
+		stepIndex:   binary.LittleEndian.Uint64(serialized[8:16]),
+		bucketIndex: binary.LittleEndian.Uint64(serialized[16:24]),
+	}
+	if len(serialized) > migrationCheckpointHeaderLength {
+		checkpoint.lastKeySuffix = append([]byte{}, serialized[migrationCheckpointHeaderLength:]...)
+	}
+	return checkpoint, nil
+}
@@ -0,0 +1,211 @@
+package migrations
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database/ldb"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+var testBucketName = []byte("test-records")
+
+func setupDB(t *testing.T) (model.DBManager, model.DBBucket, func()) {
+	tmpDir, err := ioutil.TempDir("", "TestMigrator")
+	if err != nil {
+		t.Fatalf("TempDir: %+v", err)
+	}
+	db, err := ldb.NewLevelDB(tmpDir, 8)
+	if err != nil {
+		t.Fatalf("NewLevelDB: %+v", err)
+	}
+	teardown := func() {
+		db.Close()
+		os.RemoveAll(tmpDir)
+	}
+	return database.New(db), database.MakeBucket([]byte{0}), teardown
+}
+
+func putTestRecords(t *testing.T, dbManager model.DBManager, prefixBucket model.DBBucket, count int) {
+	bucket := prefixBucket.Bucket(testBucketName)
+	for i := 0; i < count; i++ {
+		err := dbManager.Put(bucket.Key([]byte(fmt.Sprintf("%04d", i))), []byte("v1"))
+		if err != nil {
+			t.Fatalf("Put: %+v", err)
+		}
+	}
+}
+
+func upgradeRecordsMigration(rewrite RewriteFunc) *Migration {
+	return &Migration{
+		Version:     BaselineVersion + 1,
+		Description: "upgrade test records",
+		Steps: []*Step{{
+			Description: "rewrite test records",
+			Buckets: func(prefixBucket model.DBBucket) []model.DBBucket {
+				return []model.DBBucket{prefixBucket.Bucket(testBucketName)}
+			},
+			Rewrite: rewrite,
+		}},
+	}
+}
+
+func upgradeRecord(key model.DBKey, value []byte) ([]byte, bool, error) {
+	if bytes.Equal(value, []byte("v2")) {
+		return nil, false, errors.Errorf("key %s was rewritten twice", key.Suffix())
+	}
+	return []byte("v2"), true, nil
+}
+
+func TestMigrateFreshPrefix(t *testing.T) {
+	dbManager, prefixBucket, teardown := setupDB(t)
+	defer teardown()
+
+	registry, err := NewRegistry(upgradeRecordsMigration(upgradeRecord))
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	migrator := NewMigrator(dbManager, prefixBucket, registry, DefaultBatchSize)
+	err = migrator.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %+v", err)
+	}
+
+	version, hasVersion, err := migrator.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %+v", err)
+	}
+	if !hasVersion || version != registry.LatestVersion() {
+		t.Fatalf("expected a fresh prefix to be stamped with version %d but got %d (hasVersion: %t)",
+			registry.LatestVersion(), version, hasVersion)
+	}
+}
+
+func TestMigrateResumesFromCheckpoint(t *testing.T) {
+	dbManager, prefixBucket, teardown := setupDB(t)
+	defer teardown()
+
+	const recordCount = 25
+	putTestRecords(t, dbManager, prefixBucket, recordCount)
+
+	// Fail in the middle of the third batch
+	rewriteCount := 0
+	failingRegistry, err := NewRegistry(upgradeRecordsMigration(func(key model.DBKey, value []byte) ([]byte, bool, error) {
+		rewriteCount++
+		if rewriteCount == 13 {
+			return nil, false, errors.New("interrupted")
+		}
+		return upgradeRecord(key, value)
+	}))
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	err = NewMigrator(dbManager, prefixBucket, failingRegistry, 5).Migrate()
+	if err == nil {
+		t.Fatalf("expected the interrupted migration to fail")
+	}
+
+	migrator := NewMigrator(dbManager, prefixBucket, failingRegistry, 5)
+	version, hasVersion, err := migrator.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %+v", err)
+	}
+	if hasVersion {
+		t.Fatalf("expected no schema version after an interrupted migration but got %d", version)
+	}
+
+	registry, err := NewRegistry(upgradeRecordsMigration(upgradeRecord))
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	migrator = NewMigrator(dbManager, prefixBucket, registry, 5)
+	err = migrator.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %+v", err)
+	}
+
+	bucket := prefixBucket.Bucket(testBucketName)
+	for i := 0; i < recordCount; i++ {
+		value, err := dbManager.Get(bucket.Key([]byte(fmt.Sprintf("%04d", i))))
+		if err != nil {
+			t.Fatalf("Get: %+v", err)
+		}
+		if !bytes.Equal(value, []byte("v2")) {
+			t.Fatalf("record %d was not migrated", i)
+		}
+	}
+
+	version, hasVersion, err = migrator.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %+v", err)
+	}
+	if !hasVersion || version != BaselineVersion+1 {
+		t.Fatalf("expected version %d but got %d (hasVersion: %t)", BaselineVersion+1, version, hasVersion)
+	}
+	hasCheckpoint, err := dbManager.Has(prefixBucket.Key(checkpointKeyName))
+	if err != nil {
+		t.Fatalf("Has: %+v", err)
+	}
+	if hasCheckpoint {
+		t.Fatalf("expected the checkpoint to be deleted once the migration is done")
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	dbManager, prefixBucket, teardown := setupDB(t)
+	defer teardown()
+
+	registry, err := NewRegistry(upgradeRecordsMigration(upgradeRecord))
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	err = NewMigrator(dbManager, prefixBucket, registry, DefaultBatchSize).Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %+v", err)
+	}
+
+	olderRegistry, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	err = NewMigrator(dbManager, prefixBucket, olderRegistry, DefaultBatchSize).Migrate()
+	if err == nil {
+		t.Fatalf("expected migrating a database with a newer schema version to fail")
+	}
+}
+
+func TestMigrateOlderThanBaselineVersion(t *testing.T) {
+	dbManager, prefixBucket, teardown := setupDB(t)
+	defer teardown()
+
+	err := dbManager.Put(prefixBucket.Key(SchemaVersionKeyName), binaryserialization.SerializeUint64(uint64(BaselineVersion-1)))
+	if err != nil {
+		t.Fatalf("Put: %+v", err)
+	}
+
+	registry, err := NewRegistry(upgradeRecordsMigration(upgradeRecord))
+	if err != nil {
+		t.Fatalf("NewRegistry: %+v", err)
+	}
+	err = NewMigrator(dbManager, prefixBucket, registry, DefaultBatchSize).Migrate()
+	if err == nil {
+		t.Fatalf("expected migrating a database with a schema version older than the baseline to fail")
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	_, err := NewRegistry(&Migration{Version: BaselineVersion + 2, Steps: upgradeRecordsMigration(upgradeRecord).Steps})
+	if err == nil {
+		t.Fatalf("expected a registry with a version gap to be rejected")
+	}
+	_, err = NewRegistry(&Migration{Version: BaselineVersion + 1})
+	if err == nil {
+		t.Fatalf("expected a migration with no steps to be rejected")
+	}
+}
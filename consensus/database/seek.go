@@ -0,0 +1,29 @@
+package database
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+// SeekAfter positions cursor on the first record whose key comes after the given key, and
+// returns false if there's no such record. Unlike Seek, it doesn't require the key to exist,
+// so that paging can resume from a record that was deleted since the previous page.
+func SeekAfter(cursor model.DBCursor, key model.DBKey) (bool, error) {
+	err := cursor.Seek(key)
+	if err == nil {
+		return cursor.Next(), nil
+	}
+	if !IsNotFoundError(err) {
+		return false, err
+	}
+
+	// Seek only succeeds on an exact match, but it still leaves the cursor on the first
+	// record whose key is greater than the given key, if there's one
+	_, err = cursor.Key()
+	if IsNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
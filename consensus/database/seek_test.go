@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database/ldb"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+)
+
+func TestSeekAfter(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestSeekAfter")
+	if err != nil {
+		t.Fatalf("TempDir: %+v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := ldb.NewLevelDB(tmpDir, 8)
+	if err != nil {
+		t.Fatalf("NewLevelDB: %+v", err)
+	}
+	defer db.Close()
+	dbManager := database.New(db)
+
+	bucket := database.MakeBucket([]byte("records"))
+	for _, suffix := range []string{"a", "c", "e"} {
+		err := dbManager.Put(bucket.Key([]byte(suffix)), []byte(suffix))
+		if err != nil {
+			t.Fatalf("Put: %+v", err)
+		}
+	}
+	// A record of another bucket, right after the records of the tested bucket
+	err = dbManager.Put(database.MakeBucket([]byte("recordt")).Key([]byte("a")), []byte("other"))
+	if err != nil {
+		t.Fatalf("Put: %+v", err)
+	}
+
+	tests := []struct {
+		seekSuffix     string
+		expectedSuffix string
+	}{
+		{seekSuffix: "a", expectedSuffix: "c"},
+		{seekSuffix: "b", expectedSuffix: "c"},
+		{seekSuffix: "0", expectedSuffix: "a"},
+		{seekSuffix: "e", expectedSuffix: ""},
+		{seekSuffix: "f", expectedSuffix: ""},
+	}
+	for _, test := range tests {
+		cursor, err := dbManager.Cursor(bucket)
+		if err != nil {
+			t.Fatalf("Cursor: %+v", err)
+		}
+		found, err := database.SeekAfter(cursor, bucket.Key([]byte(test.seekSuffix)))
+		if err != nil {
+			t.Fatalf("SeekAfter: %+v", err)
+		}
+		if found != (test.expectedSuffix != "") {
+			t.Fatalf("TestSeekAfter: seeking after %s: unexpected found: %t", test.seekSuffix, found)
+		}
+		if found {
+			key, err := cursor.Key()
+			if err != nil {
+				t.Fatalf("Key: %+v", err)
+			}
+			if !bytes.Equal(key.Suffix(), []byte(test.expectedSuffix)) {
+				t.Fatalf("TestSeekAfter: seeking after %s: want: %s, got: %s",
+					test.seekSuffix, test.expectedSuffix, key.Suffix())
+			}
+		}
+		err = cursor.Close()
+		if err != nil {
+			t.Fatalf("Close: %+v", err)
+		}
+	}
+}
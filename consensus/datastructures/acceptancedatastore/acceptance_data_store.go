@@ -2,6 +2,7 @@ package acceptancedatastore
 
 import (
 	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
 	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
@@ -9,7 +10,8 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-var bucketName = []byte("acceptance-data")
+// BucketName is the name of the database bucket of the acceptance data
+var BucketName = []byte("acceptance-data")
 
 // acceptanceDataStore represents a store of AcceptanceData
 type acceptanceDataStore struct {
@@ -23,7 +25,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Acc
 	return &acceptanceDataStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
@@ -62,6 +64,31 @@ func (ads *acceptanceDataStore) Get(dbContext model.DBReader, stagingArea *model
 	return acceptanceData.Clone(), nil
 }
 
+// AcceptanceDataSize returns the size in bytes of the stored acceptanceData associated with the
+// given blockHash, or 0 if there's no such acceptanceData
+func (ads *acceptanceDataStore) AcceptanceDataSize(dbContext model.DBReader, stagingArea *model.StagingArea,
+	blockHash *externalapi.DomainHash) (int, error) {
+
+	stagingShard := ads.stagingShard(stagingArea)
+
+	if acceptanceData, ok := stagingShard.toAdd[*blockHash]; ok {
+		acceptanceDataBytes, err := ads.serializeAcceptanceData(acceptanceData)
+		if err != nil {
+			return 0, err
+		}
+		return len(acceptanceDataBytes), nil
+	}
+
+	acceptanceDataBytes, err := dbContext.Get(ads.hashAsKey(blockHash))
+	if database.IsNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(acceptanceDataBytes), nil
+}
+
 // Delete deletes the acceptanceData associated with the given blockHash
 func (ads *acceptanceDataStore) Delete(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) {
 	stagingShard := ads.stagingShard(stagingArea)
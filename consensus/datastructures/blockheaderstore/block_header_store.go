@@ -3,14 +3,18 @@ package blockheaderstore
 import (
 	"github.com/golang/protobuf/proto"
 	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("block-headers")
-var countKeyName = []byte("block-headers-count")
+// BucketName is the name of the database bucket of the block headers
+var BucketName = []byte("block-headers")
+
+// CountKeyName is the name of the database key of the block header count
+var CountKeyName = []byte("block-headers-count")
 
 // blockHeaderStore represents a store of blocks
 type blockHeaderStore struct {
@@ -19,15 +23,19 @@ type blockHeaderStore struct {
 	countCached uint64
 	bucket      model.DBBucket
 	countKey    model.DBKey
+	encoding    model.StoreEncoding
 }
 
 // New instantiates a new BlockHeaderStore
-func New(dbContext model.DBReader, prefixBucket model.DBBucket, cacheSize int, preallocate bool) (model.BlockHeaderStore, error) {
+func New(dbContext model.DBReader, prefixBucket model.DBBucket, cacheSize int, preallocate bool,
+	encoding model.StoreEncoding) (model.BlockHeaderStore, error) {
+
 	blockHeaderStore := &blockHeaderStore{
 		shardID:  staging.GenerateShardingID(),
 		cache:    lrucache.New(cacheSize, preallocate),
-		bucket:   prefixBucket.Bucket(bucketName),
-		countKey: prefixBucket.Key(countKeyName),
+		bucket:   prefixBucket.Bucket(BucketName),
+		countKey: prefixBucket.Key(CountKeyName),
+		encoding: encoding,
 	}
 
 	err := blockHeaderStore.initializeCount(dbContext)
@@ -154,11 +162,18 @@ func (bhs *blockHeaderStore) hashAsKey(hash *externalapi.DomainHash) model.DBKey
 }
 
 func (bhs *blockHeaderStore) serializeHeader(header externalapi.BlockHeader) ([]byte, error) {
+	if bhs.encoding == model.StoreEncodingBinary {
+		return binaryserialization.SerializeBlockHeader(header)
+	}
 	dbBlockHeader := serialization.DomainBlockHeaderToDbBlockHeader(header)
 	return proto.Marshal(dbBlockHeader)
 }
 
 func (bhs *blockHeaderStore) deserializeHeader(headerBytes []byte) (externalapi.BlockHeader, error) {
+	if binaryserialization.IsBinaryEncoded(headerBytes) {
+		return binaryserialization.DeserializeBlockHeader(headerBytes)
+	}
+
 	dbBlockHeader := &serialization.DbBlockHeader{}
 	err := proto.Unmarshal(headerBytes, dbBlockHeader)
 	if err != nil {
@@ -9,7 +9,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("block-relations")
+// BucketName is the name of the database bucket of the block relations
+var BucketName = []byte("block-relations")
 
 // blockRelationStore represents a store of BlockRelations
 type blockRelationStore struct {
@@ -23,7 +24,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Blo
 	return &blockRelationStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
@@ -9,7 +9,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("block-statuses")
+// BucketName is the name of the database bucket of the block statuses
+var BucketName = []byte("block-statuses")
 
 // blockStatusStore represents a store of BlockStatuses
 type blockStatusStore struct {
@@ -23,7 +24,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Blo
 	return &blockStatusStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
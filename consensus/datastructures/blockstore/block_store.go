@@ -4,13 +4,18 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/karlsen-network/karlsend/v2/util/staging"
 	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
 	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("blocks")
+// BucketName is the name of the database bucket of the blocks
+var BucketName = []byte("blocks")
+
+// CountKeyName is the name of the database key of the block count
+var CountKeyName = []byte("blocks-count")
 
 // blockStore represents a store of blocks
 type blockStore struct {
@@ -26,8 +31,8 @@ func New(dbContext model.DBReader, prefixBucket model.DBBucket, cacheSize int, p
 	blockStore := &blockStore{
 		shardID:  staging.GenerateShardingID(),
 		cache:    lrucache.New(cacheSize, preallocate),
-		bucket:   prefixBucket.Bucket(bucketName),
-		countKey: prefixBucket.Key([]byte("blocks-count")),
+		bucket:   prefixBucket.Bucket(BucketName),
+		countKey: prefixBucket.Key(CountKeyName),
 	}
 
 	err := blockStore.initializeCount(dbContext)
@@ -117,6 +122,29 @@ func (bs *blockStore) HasBlock(dbContext model.DBReader, stagingArea *model.Stag
 	return exists, nil
 }
 
+// BlockSize returns the size in bytes of the stored block associated with the given blockHash,
+// or 0 if there's no such block
+func (bs *blockStore) BlockSize(dbContext model.DBReader, stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (int, error) {
+	stagingShard := bs.stagingShard(stagingArea)
+
+	if block, ok := stagingShard.toAdd[*blockHash]; ok {
+		blockBytes, err := bs.serializeBlock(block)
+		if err != nil {
+			return 0, err
+		}
+		return len(blockBytes), nil
+	}
+
+	blockBytes, err := dbContext.Get(bs.hashAsKey(blockHash))
+	if database.IsNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(blockBytes), nil
+}
+
 // Blocks gets the blocks associated with the given blockHashes
 func (bs *blockStore) Blocks(dbContext model.DBReader, stagingArea *model.StagingArea, blockHashes []*externalapi.DomainHash) ([]*externalapi.DomainBlock, error) {
 	stagingShard := bs.stagingShard(stagingArea)
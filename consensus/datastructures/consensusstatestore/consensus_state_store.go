@@ -24,9 +24,9 @@ func New(prefixBucket model.DBBucket, utxoSetCacheSize int, preallocate bool) mo
 	return &consensusStateStore{
 		shardID:                         staging.GenerateShardingID(),
 		virtualUTXOSetCache:             utxolrucache.New(utxoSetCacheSize, preallocate),
-		tipsKey:                         prefixBucket.Key(tipsKeyName),
+		tipsKey:                         prefixBucket.Key(TipsKeyName),
 		importingPruningPointUTXOSetKey: prefixBucket.Key(importingPruningPointUTXOSetKeyName),
-		utxoSetBucket:                   prefixBucket.Bucket(utxoSetBucketName),
+		utxoSetBucket:                   prefixBucket.Bucket(UTXOSetBucketName),
 	}
 }
 
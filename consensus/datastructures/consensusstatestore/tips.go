@@ -7,7 +7,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 )
 
-var tipsKeyName = []byte("tips")
+// TipsKeyName is the name of the database key of the DAG tips
+var TipsKeyName = []byte("tips")
 
 func (css *consensusStateStore) Tips(stagingArea *model.StagingArea, dbContext model.DBReader) ([]*externalapi.DomainHash, error) {
 	stagingShard := css.stagingShard(stagingArea)
@@ -7,7 +7,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
 )
 
-var utxoSetBucketName = []byte("virtual-utxo-set")
+// UTXOSetBucketName is the name of the database bucket of the virtual UTXO set
+var UTXOSetBucketName = []byte("virtual-utxo-set")
 
 func (css *consensusStateStore) utxoKey(outpoint *externalapi.DomainOutpoint) (model.DBKey, error) {
 	serializedOutpoint, err := serializeOutpoint(outpoint)
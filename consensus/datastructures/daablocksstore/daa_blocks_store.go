@@ -8,8 +8,11 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var daaScoreBucketName = []byte("daa-score")
-var daaAddedBlocksBucketName = []byte("daa-added-blocks")
+// DAAScoreBucketName is the name of the database bucket of the DAA scores
+var DAAScoreBucketName = []byte("daa-score")
+
+// DAAAddedBlocksBucketName is the name of the database bucket of the DAA added blocks
+var DAAAddedBlocksBucketName = []byte("daa-added-blocks")
 
 // daaBlocksStore represents a store of DAABlocksStore
 type daaBlocksStore struct {
@@ -26,8 +29,8 @@ func New(prefixBucket model.DBBucket, daaScoreCacheSize int, daaAddedBlocksCache
 		shardID:                staging.GenerateShardingID(),
 		daaScoreLRUCache:       lrucache.New(daaScoreCacheSize, preallocate),
 		daaAddedBlocksLRUCache: lrucache.New(daaAddedBlocksCacheSize, preallocate),
-		daaScoreBucket:         prefixBucket.Bucket(daaScoreBucketName),
-		daaAddedBlocksBucket:   prefixBucket.Bucket(daaAddedBlocksBucketName),
+		daaScoreBucket:         prefixBucket.Bucket(DAAScoreBucketName),
+		daaAddedBlocksBucket:   prefixBucket.Bucket(DAAAddedBlocksBucketName),
 	}
 }
 
@@ -13,7 +13,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucachehashpairtoblockghostdagdatahashpair"
 )
 
-var bucketName = []byte("daa-window")
+// BucketName is the name of the database bucket of the DAA windows of the blocks with trusted data
+var BucketName = []byte("daa-window")
 
 type daaWindowStore struct {
 	shardID model.StagingShardID
@@ -26,7 +27,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Blo
 	return &daaWindowStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucachehashpairtoblockghostdagdatahashpair.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
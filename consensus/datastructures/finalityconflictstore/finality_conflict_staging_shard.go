@@ -0,0 +1,66 @@
+package finalityconflictstore
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type finalityConflictStagingShard struct {
+	store                *finalityConflictStore
+	conflictsToAdd       map[externalapi.DomainHash]*externalapi.FinalityConflict
+	violatingBlocksToAdd map[externalapi.DomainHash]*externalapi.DomainHash
+	resolutionsToAdd     map[externalapi.DomainHash]bool
+}
+
+func (fcs *finalityConflictStore) stagingShard(stagingArea *model.StagingArea) *finalityConflictStagingShard {
+	return stagingArea.GetOrCreateShard(fcs.shardID, func() model.StagingShard {
+		return &finalityConflictStagingShard{
+			store:                fcs,
+			conflictsToAdd:       make(map[externalapi.DomainHash]*externalapi.FinalityConflict),
+			violatingBlocksToAdd: make(map[externalapi.DomainHash]*externalapi.DomainHash),
+			resolutionsToAdd:     make(map[externalapi.DomainHash]bool),
+		}
+	}).(*finalityConflictStagingShard)
+}
+
+func (fcss *finalityConflictStagingShard) Commit(dbTx model.DBTransaction) error {
+	for conflictID, conflict := range fcss.conflictsToAdd {
+		if _, ok := fcss.resolutionsToAdd[conflictID]; ok {
+			continue
+		}
+		err := dbTx.Put(fcss.store.conflictKey(&conflictID), serializeFinalityConflict(conflict))
+		if err != nil {
+			return err
+		}
+	}
+
+	for blockHash, conflictID := range fcss.violatingBlocksToAdd {
+		blockHash := blockHash
+		err := dbTx.Put(fcss.store.violatingBlockKey(&blockHash), conflictID.ByteSlice())
+		if err != nil {
+			return err
+		}
+	}
+
+	for conflictID, followsViolatingChain := range fcss.resolutionsToAdd {
+		err := dbTx.Put(fcss.store.resolutionKey(&conflictID), serializeResolution(followsViolatingChain))
+		if err != nil {
+			return err
+		}
+		// Resolved conflicts are no longer kept. Their violating blocks are, so that blocks added
+		// on top of them are known to be part of a resolved conflict without walking down its chain.
+		err = dbTx.Delete(fcss.store.conflictKey(&conflictID))
+		if err != nil {
+			return err
+		}
+		if fcss.store.resolutionsCache != nil {
+			fcss.store.resolutionsCache[conflictID] = followsViolatingChain
+		}
+	}
+
+	return nil
+}
+
+func (fcss *finalityConflictStagingShard) isStaged() bool {
+	return len(fcss.conflictsToAdd) != 0 || len(fcss.violatingBlocksToAdd) != 0 || len(fcss.resolutionsToAdd) != 0
+}
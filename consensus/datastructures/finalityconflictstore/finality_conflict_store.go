@@ -0,0 +1,292 @@
+package finalityconflictstore
+
+import (
+	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// ConflictsBucketName is the name of the database bucket of the finality conflicts
+var ConflictsBucketName = []byte("finality-conflicts")
+
+// ViolatingBlocksBucketName is the name of the database bucket of the conflicts of the finality violating blocks
+var ViolatingBlocksBucketName = []byte("finality-conflict-violating-blocks")
+
+// ResolutionsBucketName is the name of the database bucket of the finality conflict resolutions
+var ResolutionsBucketName = []byte("finality-conflict-resolutions")
+
+// finalityConflictStore keeps the unresolved finality conflicts, the conflict each violating block belongs
+// to, and the resolutions of the resolved conflicts. There are few conflicts in practice, so the resolutions
+// are kept in memory once they're read.
+type finalityConflictStore struct {
+	shardID               model.StagingShardID
+	conflictsBucket       model.DBBucket
+	violatingBlocksBucket model.DBBucket
+	resolutionsBucket     model.DBBucket
+	resolutionsCache      map[externalapi.DomainHash]bool
+}
+
+// New instantiates a new FinalityConflictStore
+func New(prefixBucket model.DBBucket) model.FinalityConflictStore {
+	return &finalityConflictStore{
+		shardID:               staging.GenerateShardingID(),
+		conflictsBucket:       prefixBucket.Bucket(ConflictsBucketName),
+		violatingBlocksBucket: prefixBucket.Bucket(ViolatingBlocksBucketName),
+		resolutionsBucket:     prefixBucket.Bucket(ResolutionsBucketName),
+	}
+}
+
+// StageFinalityConflict stages the tip and the finality point of the given conflict, replacing
+// the previous record of the same conflict. Its violating blocks are staged with StageViolatingBlocks.
+func (fcs *finalityConflictStore) StageFinalityConflict(stagingArea *model.StagingArea, conflict *externalapi.FinalityConflict) {
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	stagingShard.conflictsToAdd[*conflict.ID()] = &externalapi.FinalityConflict{
+		LowestViolatingBlockHash: conflict.LowestViolatingBlockHash,
+		ViolatingBlockHash:       conflict.ViolatingBlockHash,
+		FinalityPointHash:        conflict.FinalityPointHash,
+	}
+}
+
+// StageViolatingBlocks stages the given blocks as violating blocks of the given conflict
+func (fcs *finalityConflictStore) StageViolatingBlocks(stagingArea *model.StagingArea, conflictID *externalapi.DomainHash,
+	violatingBlockHashes []*externalapi.DomainHash) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	for _, violatingBlockHash := range violatingBlockHashes {
+		stagingShard.violatingBlocksToAdd[*violatingBlockHash] = conflictID
+	}
+}
+
+// ConflictIDOfViolatingBlock returns the ID of the conflict the given block is a violating block of,
+// whether the conflict is resolved or not
+func (fcs *finalityConflictStore) ConflictIDOfViolatingBlock(dbContext model.DBReader, stagingArea *model.StagingArea,
+	blockHash *externalapi.DomainHash) (*externalapi.DomainHash, error) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	if conflictID, ok := stagingShard.violatingBlocksToAdd[*blockHash]; ok {
+		return conflictID, nil
+	}
+
+	conflictIDBytes, err := dbContext.Get(fcs.violatingBlockKey(blockHash))
+	if err != nil {
+		return nil, err
+	}
+	return externalapi.NewDomainHashFromByteSlice(conflictIDBytes)
+}
+
+// StageResolution stages the resolution of the given conflict, which removes its record
+func (fcs *finalityConflictStore) StageResolution(stagingArea *model.StagingArea, conflictID *externalapi.DomainHash,
+	followsViolatingChain bool) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	stagingShard.resolutionsToAdd[*conflictID] = followsViolatingChain
+}
+
+func (fcs *finalityConflictStore) IsStaged(stagingArea *model.StagingArea) bool {
+	return fcs.stagingShard(stagingArea).isStaged()
+}
+
+// FinalityConflict returns the record of the given unresolved conflict
+func (fcs *finalityConflictStore) FinalityConflict(dbContext model.DBReader, stagingArea *model.StagingArea,
+	conflictID *externalapi.DomainHash) (*externalapi.FinalityConflict, error) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	if _, ok := stagingShard.resolutionsToAdd[*conflictID]; ok {
+		return nil, errors.Wrapf(database.ErrNotFound, "finality conflict %s is resolved", conflictID)
+	}
+	if conflict, ok := stagingShard.conflictsToAdd[*conflictID]; ok {
+		return conflict, nil
+	}
+
+	conflictBytes, err := dbContext.Get(fcs.conflictKey(conflictID))
+	if err != nil {
+		return nil, err
+	}
+	return deserializeFinalityConflict(conflictID, conflictBytes)
+}
+
+// FinalityConflicts returns the records of all the unresolved conflicts
+func (fcs *finalityConflictStore) FinalityConflicts(dbContext model.DBReader, stagingArea *model.StagingArea) (
+	[]*externalapi.FinalityConflict, error) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	cursor, err := dbContext.Cursor(fcs.conflictsBucket)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	conflicts := make([]*externalapi.FinalityConflict, 0, len(stagingShard.conflictsToAdd))
+	for _, conflict := range stagingShard.conflictsToAdd {
+		if _, ok := stagingShard.resolutionsToAdd[*conflict.ID()]; ok {
+			continue
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return nil, err
+		}
+		conflictID, err := externalapi.NewDomainHashFromByteSlice(key.Suffix())
+		if err != nil {
+			return nil, err
+		}
+		conflictBytes, err := cursor.Value()
+		if err != nil {
+			return nil, err
+		}
+		conflict, err := deserializeFinalityConflict(conflictID, conflictBytes)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := stagingShard.conflictsToAdd[*conflict.ID()]; ok {
+			continue
+		}
+		if _, ok := stagingShard.resolutionsToAdd[*conflict.ID()]; ok {
+			continue
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
+// IsResolved returns whether the given conflict was resolved
+func (fcs *finalityConflictStore) IsResolved(dbContext model.DBReader, stagingArea *model.StagingArea,
+	conflictID *externalapi.DomainHash) (bool, error) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	if _, ok := stagingShard.resolutionsToAdd[*conflictID]; ok {
+		return true, nil
+	}
+	resolutions, err := fcs.resolutions(dbContext)
+	if err != nil {
+		return false, err
+	}
+	_, ok := resolutions[*conflictID]
+	return ok, nil
+}
+
+// FollowedViolatingChains returns the IDs of the conflicts that were resolved by following the violating chain
+func (fcs *finalityConflictStore) FollowedViolatingChains(dbContext model.DBReader, stagingArea *model.StagingArea) (
+	[]*externalapi.DomainHash, error) {
+
+	stagingShard := fcs.stagingShard(stagingArea)
+
+	resolutions, err := fcs.resolutions(dbContext)
+	if err != nil {
+		return nil, err
+	}
+
+	var followedViolatingChains []*externalapi.DomainHash
+	for conflictID, followsViolatingChain := range resolutions {
+		if _, ok := stagingShard.resolutionsToAdd[conflictID]; ok {
+			continue
+		}
+		if followsViolatingChain {
+			conflictID := conflictID
+			followedViolatingChains = append(followedViolatingChains, &conflictID)
+		}
+	}
+	for conflictID, followsViolatingChain := range stagingShard.resolutionsToAdd {
+		if followsViolatingChain {
+			conflictID := conflictID
+			followedViolatingChains = append(followedViolatingChains, &conflictID)
+		}
+	}
+	return followedViolatingChains, nil
+}
+
+func (fcs *finalityConflictStore) resolutions(dbContext model.DBReader) (map[externalapi.DomainHash]bool, error) {
+	if fcs.resolutionsCache != nil {
+		return fcs.resolutionsCache, nil
+	}
+
+	cursor, err := dbContext.Cursor(fcs.resolutionsBucket)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	resolutions := make(map[externalapi.DomainHash]bool)
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return nil, err
+		}
+		conflictID, err := externalapi.NewDomainHashFromByteSlice(key.Suffix())
+		if err != nil {
+			return nil, err
+		}
+		resolutionBytes, err := cursor.Value()
+		if err != nil {
+			return nil, err
+		}
+		followsViolatingChain, err := deserializeResolution(resolutionBytes)
+		if err != nil {
+			return nil, err
+		}
+		resolutions[*conflictID] = followsViolatingChain
+	}
+
+	fcs.resolutionsCache = resolutions
+	return resolutions, nil
+}
+
+func (fcs *finalityConflictStore) conflictKey(conflictID *externalapi.DomainHash) model.DBKey {
+	return fcs.conflictsBucket.Key(conflictID.ByteSlice())
+}
+
+func (fcs *finalityConflictStore) violatingBlockKey(blockHash *externalapi.DomainHash) model.DBKey {
+	return fcs.violatingBlocksBucket.Key(blockHash.ByteSlice())
+}
+
+func (fcs *finalityConflictStore) resolutionKey(conflictID *externalapi.DomainHash) model.DBKey {
+	return fcs.resolutionsBucket.Key(conflictID.ByteSlice())
+}
+
+// serializeFinalityConflict serializes the violating block and the finality point, in that order.
+// The conflict ID is the key of the record, and the violating blocks are stored separately.
+func serializeFinalityConflict(conflict *externalapi.FinalityConflict) []byte {
+	return binaryserialization.SerializeHashes([]*externalapi.DomainHash{conflict.ViolatingBlockHash, conflict.FinalityPointHash})
+}
+
+func deserializeFinalityConflict(conflictID *externalapi.DomainHash, conflictBytes []byte) (*externalapi.FinalityConflict, error) {
+	hashes, err := binaryserialization.DeserializeHashes(conflictBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(hashes) != 2 {
+		return nil, errors.Errorf("a serialized finality conflict has 2 hashes but got %d", len(hashes))
+	}
+	return &externalapi.FinalityConflict{
+		LowestViolatingBlockHash: conflictID,
+		ViolatingBlockHash:       hashes[0],
+		FinalityPointHash:        hashes[1],
+	}, nil
+}
+
+func serializeResolution(followsViolatingChain bool) []byte {
+	if followsViolatingChain {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func deserializeResolution(resolutionBytes []byte) (bool, error) {
+	if len(resolutionBytes) != 1 {
+		return false, errors.Errorf("a serialized finality conflict resolution is 1 byte but got %d bytes",
+			len(resolutionBytes))
+	}
+	return resolutionBytes[0] == 1, nil
+}
@@ -7,7 +7,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("finality-points")
+// BucketName is the name of the database bucket of the finality points
+var BucketName = []byte("finality-points")
 
 type finalityStore struct {
 	shardID model.StagingShardID
@@ -20,7 +21,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Fin
 	return &finalityStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
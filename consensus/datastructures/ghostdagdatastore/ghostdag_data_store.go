@@ -3,14 +3,18 @@ package ghostdagdatastore
 import (
 	"github.com/golang/protobuf/proto"
 	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucacheghostdagdata"
 )
 
-var ghostdagDataBucketName = []byte("block-ghostdag-data")
-var trustedDataBucketName = []byte("block-with-trusted-data-ghostdag-data")
+// GHOSTDAGDataBucketName is the name of the database bucket of the GHOSTDAG data
+var GHOSTDAGDataBucketName = []byte("block-ghostdag-data")
+
+// TrustedDataBucketName is the name of the database bucket of the GHOSTDAG data of the blocks with trusted data
+var TrustedDataBucketName = []byte("block-with-trusted-data-ghostdag-data")
 
 // ghostdagDataStore represents a store of BlockGHOSTDAGData
 type ghostdagDataStore struct {
@@ -18,15 +22,17 @@ type ghostdagDataStore struct {
 	cache              *lrucacheghostdagdata.LRUCache
 	ghostdagDataBucket model.DBBucket
 	trustedDataBucket  model.DBBucket
+	encoding           model.StoreEncoding
 }
 
 // New instantiates a new GHOSTDAGDataStore
-func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.GHOSTDAGDataStore {
+func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool, encoding model.StoreEncoding) model.GHOSTDAGDataStore {
 	return &ghostdagDataStore{
 		shardID:            staging.GenerateShardingID(),
 		cache:              lrucacheghostdagdata.New(cacheSize, preallocate),
-		ghostdagDataBucket: prefixBucket.Bucket(ghostdagDataBucketName),
-		trustedDataBucket:  prefixBucket.Bucket(trustedDataBucketName),
+		ghostdagDataBucket: prefixBucket.Bucket(GHOSTDAGDataBucketName),
+		trustedDataBucket:  prefixBucket.Bucket(TrustedDataBucketName),
+		encoding:           encoding,
 	}
 }
 
@@ -83,10 +89,17 @@ func (gds *ghostdagDataStore) serializeKey(k key) model.DBKey {
 }
 
 func (gds *ghostdagDataStore) serializeBlockGHOSTDAGData(blockGHOSTDAGData *externalapi.BlockGHOSTDAGData) ([]byte, error) {
+	if gds.encoding == model.StoreEncodingBinary {
+		return binaryserialization.SerializeBlockGHOSTDAGData(blockGHOSTDAGData)
+	}
 	return proto.Marshal(serialization.BlockGHOSTDAGDataToDBBlockGHOSTDAGData(blockGHOSTDAGData))
 }
 
 func (gds *ghostdagDataStore) deserializeBlockGHOSTDAGData(blockGHOSTDAGDataBytes []byte) (*externalapi.BlockGHOSTDAGData, error) {
+	if binaryserialization.IsBinaryEncoded(blockGHOSTDAGDataBytes) {
+		return binaryserialization.DeserializeBlockGHOSTDAGData(blockGHOSTDAGDataBytes)
+	}
+
 	dbBlockGHOSTDAGData := &serialization.DbBlockGhostdagData{}
 	err := proto.Unmarshal(blockGHOSTDAGDataBytes, dbBlockGHOSTDAGData)
 	if err != nil {
@@ -14,9 +14,14 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucacheuint64tohash"
 )
 
-var bucketChainBlockHashByIndexName = []byte("chain-block-hash-by-index")
-var bucketChainBlockIndexByHashName = []byte("chain-block-index-by-hash")
-var highestChainBlockIndexKeyName = []byte("highest-chain-block-index")
+// ChainBlockHashByIndexBucketName is the name of the database bucket of the headers selected chain blocks by their index
+var ChainBlockHashByIndexBucketName = []byte("chain-block-hash-by-index")
+
+// ChainBlockIndexByHashBucketName is the name of the database bucket of the indexes of the headers selected chain blocks
+var ChainBlockIndexByHashBucketName = []byte("chain-block-index-by-hash")
+
+// HighestChainBlockIndexKeyName is the name of the database key of the index of the headers selected tip
+var HighestChainBlockIndexKeyName = []byte("highest-chain-block-index")
 
 type headersSelectedChainStore struct {
 	shardID                     model.StagingShardID
@@ -34,9 +39,9 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Hea
 		shardID:                     staging.GenerateShardingID(),
 		cacheByIndex:                lrucacheuint64tohash.New(cacheSize, preallocate),
 		cacheByHash:                 lrucache.New(cacheSize, preallocate),
-		bucketChainBlockHashByIndex: prefixBucket.Bucket(bucketChainBlockHashByIndexName),
-		bucketChainBlockIndexByHash: prefixBucket.Bucket(bucketChainBlockIndexByHashName),
-		highestChainBlockIndexKey:   prefixBucket.Key(highestChainBlockIndexKeyName),
+		bucketChainBlockHashByIndex: prefixBucket.Bucket(ChainBlockHashByIndexBucketName),
+		bucketChainBlockIndexByHash: prefixBucket.Bucket(ChainBlockIndexByHashBucketName),
+		highestChainBlockIndexKey:   prefixBucket.Key(HighestChainBlockIndexKeyName),
 	}
 }
 
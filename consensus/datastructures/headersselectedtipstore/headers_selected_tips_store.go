@@ -8,7 +8,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 )
 
-var keyName = []byte("headers-selected-tip")
+// KeyName is the name of the database key of the headers selected tip
+var KeyName = []byte("headers-selected-tip")
 
 type headerSelectedTipStore struct {
 	shardID model.StagingShardID
@@ -20,7 +21,7 @@ type headerSelectedTipStore struct {
 func New(prefixBucket model.DBBucket) model.HeaderSelectedTipStore {
 	return &headerSelectedTipStore{
 		shardID: staging.GenerateShardingID(),
-		key:     prefixBucket.Key(keyName),
+		key:     prefixBucket.Key(KeyName),
 	}
 }
 
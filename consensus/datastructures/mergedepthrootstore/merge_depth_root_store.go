@@ -7,7 +7,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("merge-depth-roots")
+// BucketName is the name of the database bucket of the merge depth roots
+var BucketName = []byte("merge-depth-roots")
 
 type mergeDepthRootStore struct {
 	shardID model.StagingShardID
@@ -20,7 +21,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Mer
 	return &mergeDepthRootStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
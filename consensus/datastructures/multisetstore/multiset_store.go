@@ -9,7 +9,8 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var bucketName = []byte("multisets")
+// BucketName is the name of the database bucket of the multisets
+var BucketName = []byte("multisets")
 
 // multisetStore represents a store of Multisets
 type multisetStore struct {
@@ -23,7 +24,7 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Mul
 	return &multisetStore{
 		shardID: staging.GenerateShardingID(),
 		cache:   lrucache.New(cacheSize, preallocate),
-		bucket:  prefixBucket.Bucket(bucketName),
+		bucket:  prefixBucket.Bucket(BucketName),
 	}
 }
 
@@ -14,11 +14,18 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucacheuint64tohash"
 )
 
-var currentPruningPointIndexKeyName = []byte("pruning-block-index")
-var candidatePruningPointHashKeyName = []byte("candidate-pruning-point-hash")
-var pruningPointUTXOSetBucketName = []byte("pruning-point-utxo-set")
+// CurrentPruningPointIndexKeyName is the name of the database key of the index of the current pruning point
+var CurrentPruningPointIndexKeyName = []byte("pruning-block-index")
+
+// CandidatePruningPointHashKeyName is the name of the database key of the pruning point candidate
+var CandidatePruningPointHashKeyName = []byte("candidate-pruning-point-hash")
+
+// PruningPointUTXOSetBucketName is the name of the database bucket of the pruning point UTXO set
+var PruningPointUTXOSetBucketName = []byte("pruning-point-utxo-set")
 var updatingPruningPointUTXOSetKeyName = []byte("updating-pruning-point-utxo-set")
-var pruningPointByIndexBucketName = []byte("pruning-point-by-index")
+
+// PruningPointByIndexBucketName is the name of the database bucket of the pruning points by their index
+var PruningPointByIndexBucketName = []byte("pruning-point-by-index")
 
 // pruningStore represents a store for the current pruning state
 type pruningStore struct {
@@ -41,13 +48,13 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.Pru
 	return &pruningStore{
 		shardID:                         staging.GenerateShardingID(),
 		pruningPointByIndexCache:        lrucacheuint64tohash.New(cacheSize, preallocate),
-		currentPruningPointIndexKey:     prefixBucket.Key(currentPruningPointIndexKeyName),
-		candidatePruningPointHashKey:    prefixBucket.Key(candidatePruningPointHashKeyName),
-		pruningPointUTXOSetBucket:       prefixBucket.Bucket(pruningPointUTXOSetBucketName),
+		currentPruningPointIndexKey:     prefixBucket.Key(CurrentPruningPointIndexKeyName),
+		candidatePruningPointHashKey:    prefixBucket.Key(CandidatePruningPointHashKeyName),
+		pruningPointUTXOSetBucket:       prefixBucket.Bucket(PruningPointUTXOSetBucketName),
 		importedPruningPointUTXOsBucket: prefixBucket.Bucket(importedPruningPointUTXOsBucketName),
 		updatingPruningPointUTXOSetKey:  prefixBucket.Key(updatingPruningPointUTXOSetKeyName),
 		importedPruningPointMultisetKey: prefixBucket.Key(importedPruningPointMultisetKeyName),
-		pruningPointByIndexBucket:       prefixBucket.Bucket(pruningPointByIndexBucketName),
+		pruningPointByIndexBucket:       prefixBucket.Bucket(PruningPointByIndexBucketName),
 	}
 }
 
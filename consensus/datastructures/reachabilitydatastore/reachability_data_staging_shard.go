@@ -31,7 +31,7 @@ func (rdss *reachabilityDataStagingShard) Commit(dbTx model.DBTransaction) error
 		if err != nil {
 			return err
 		}
-		rdss.store.reachabilityReindexRootCache = rdss.reachabilityReindexRoot
+		rdss.store.cacheReachabilityReindexRoot(rdss.reachabilityReindexRoot)
 	}
 	for hash, reachabilityData := range rdss.reachabilityData {
 		reachabilityDataBytes, err := rdss.store.serializeReachabilityData(reachabilityData)
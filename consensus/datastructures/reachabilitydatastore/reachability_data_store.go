@@ -1,36 +1,47 @@
 package reachabilitydatastore
 
 import (
+	"sync"
+
 	"github.com/golang/protobuf/proto"
 	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
 	"github.com/karlsen-network/karlsend/v2/util/staging"
 	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/database/serialization"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var reachabilityDataBucketName = []byte("reachability-data")
-var reachabilityReindexRootKeyName = []byte("reachability-reindex-root")
+// ReachabilityDataBucketName is the name of the database bucket of the reachability data
+var ReachabilityDataBucketName = []byte("reachability-data")
+
+// ReachabilityReindexRootKeyName is the name of the database key of the reachability reindex root
+var ReachabilityReindexRootKeyName = []byte("reachability-reindex-root")
 
 // reachabilityDataStore represents a store of ReachabilityData
 type reachabilityDataStore struct {
 	shardID                      model.StagingShardID
 	reachabilityDataCache        *lrucache.LRUCache
 	reachabilityReindexRootCache *externalapi.DomainHash
+	// reachabilityReindexRootLock guards reachabilityReindexRootCache, since the
+	// store is read from several goroutines by the GHOSTDAG batch manager
+	reachabilityReindexRootLock sync.RWMutex
 
 	reachabilityDataBucket     model.DBBucket
 	reachabilityReindexRootKey model.DBKey
+	encoding                   model.StoreEncoding
 }
 
 // New instantiates a new ReachabilityDataStore
-func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.ReachabilityDataStore {
+func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool, encoding model.StoreEncoding) model.ReachabilityDataStore {
 	return &reachabilityDataStore{
 		shardID:                    staging.GenerateShardingID(),
 		reachabilityDataCache:      lrucache.New(cacheSize, preallocate),
-		reachabilityDataBucket:     prefixBucket.Bucket(reachabilityDataBucketName),
-		reachabilityReindexRootKey: prefixBucket.Key(reachabilityReindexRootKeyName),
+		reachabilityDataBucket:     prefixBucket.Bucket(ReachabilityDataBucketName),
+		reachabilityReindexRootKey: prefixBucket.Key(ReachabilityReindexRootKeyName),
+		encoding:                   encoding,
 	}
 }
 
@@ -126,8 +137,8 @@ func (rds *reachabilityDataStore) ReachabilityReindexRoot(dbContext model.DBRead
 		return stagingShard.reachabilityReindexRoot, nil
 	}
 
-	if rds.reachabilityReindexRootCache != nil {
-		return rds.reachabilityReindexRootCache, nil
+	if reachabilityReindexRoot := rds.cachedReachabilityReindexRoot(); reachabilityReindexRoot != nil {
+		return reachabilityReindexRoot, nil
 	}
 
 	reachabilityReindexRootBytes, err := dbContext.Get(rds.reachabilityReindexRootKey)
@@ -139,19 +150,40 @@ func (rds *reachabilityDataStore) ReachabilityReindexRoot(dbContext model.DBRead
 	if err != nil {
 		return nil, err
 	}
-	rds.reachabilityReindexRootCache = reachabilityReindexRoot
+	rds.cacheReachabilityReindexRoot(reachabilityReindexRoot)
 	return reachabilityReindexRoot, nil
 }
 
+func (rds *reachabilityDataStore) cachedReachabilityReindexRoot() *externalapi.DomainHash {
+	rds.reachabilityReindexRootLock.RLock()
+	defer rds.reachabilityReindexRootLock.RUnlock()
+
+	return rds.reachabilityReindexRootCache
+}
+
+func (rds *reachabilityDataStore) cacheReachabilityReindexRoot(reachabilityReindexRoot *externalapi.DomainHash) {
+	rds.reachabilityReindexRootLock.Lock()
+	defer rds.reachabilityReindexRootLock.Unlock()
+
+	rds.reachabilityReindexRootCache = reachabilityReindexRoot
+}
+
 func (rds *reachabilityDataStore) reachabilityDataBlockHashAsKey(hash *externalapi.DomainHash) model.DBKey {
 	return rds.reachabilityDataBucket.Key(hash.ByteSlice())
 }
 
 func (rds *reachabilityDataStore) serializeReachabilityData(reachabilityData model.ReachabilityData) ([]byte, error) {
+	if rds.encoding == model.StoreEncodingBinary {
+		return binaryserialization.SerializeReachabilityData(reachabilityData), nil
+	}
 	return proto.Marshal(serialization.ReachablityDataToDBReachablityData(reachabilityData))
 }
 
 func (rds *reachabilityDataStore) deserializeReachabilityData(reachabilityDataBytes []byte) (model.ReachabilityData, error) {
+	if binaryserialization.IsBinaryEncoded(reachabilityDataBytes) {
+		return binaryserialization.DeserializeReachabilityData(reachabilityDataBytes)
+	}
+
 	dbReachabilityData := &serialization.DbReachabilityData{}
 	err := proto.Unmarshal(reachabilityDataBytes, dbReachabilityData)
 	if err != nil {
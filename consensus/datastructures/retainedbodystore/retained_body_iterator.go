@@ -0,0 +1,137 @@
+package retainedbodystore
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+)
+
+// retainedBodyIterator goes over the retention decisions of one bucket from the lowest blue score up,
+// merging the decisions staged in the staging area with the ones in the database. Decisions deleted
+// from the staging area while iterating are skipped.
+type retainedBodyIterator struct {
+	cursor             model.DBCursor
+	stagingShard       *retainedBodyStagingShard
+	staged             []*model.RetainedBody
+	paysRetainedScript bool
+
+	stagedIndex   int
+	cursorBody    *model.RetainedBody
+	current       *model.RetainedBody
+	err           error
+	isClosed      bool
+	isCursorValid bool
+}
+
+func newRetainedBodyIterator(cursor model.DBCursor, stagingShard *retainedBodyStagingShard,
+	staged []*model.RetainedBody, paysRetainedScript bool) *retainedBodyIterator {
+
+	sort.Slice(staged, func(i, j int) bool {
+		return bytes.Compare(retainedBodyKeySuffix(staged[i]), retainedBodyKeySuffix(staged[j])) < 0
+	})
+	return &retainedBodyIterator{
+		cursor:             cursor,
+		stagingShard:       stagingShard,
+		staged:             staged,
+		paysRetainedScript: paysRetainedScript,
+	}
+}
+
+func (rbi *retainedBodyIterator) First() bool {
+	if rbi.isClosed {
+		panic("Tried using a closed retainedBodyIterator")
+	}
+	rbi.stagedIndex = 0
+	rbi.err = nil
+	rbi.isCursorValid = rbi.cursor.First()
+	rbi.readCursorBody()
+	return rbi.Next()
+}
+
+func (rbi *retainedBodyIterator) Next() bool {
+	if rbi.isClosed {
+		panic("Tried using a closed retainedBodyIterator")
+	}
+	for rbi.err == nil {
+		var stagedBody *model.RetainedBody
+		if rbi.stagedIndex < len(rbi.staged) {
+			stagedBody = rbi.staged[rbi.stagedIndex]
+		}
+		if stagedBody == nil && rbi.cursorBody == nil {
+			break
+		}
+
+		if rbi.cursorBody == nil || (stagedBody != nil &&
+			bytes.Compare(retainedBodyKeySuffix(stagedBody), retainedBodyKeySuffix(rbi.cursorBody)) < 0) {
+
+			rbi.stagedIndex++
+			if rbi.stagingShard.toAdd[*stagedBody.BlockHash] == stagedBody {
+				rbi.current = stagedBody
+				return true
+			}
+			continue
+		}
+
+		cursorBody := rbi.cursorBody
+		rbi.isCursorValid = rbi.cursor.Next()
+		rbi.readCursorBody()
+		_, isDeleted := rbi.stagingShard.toDelete[*cursorBody.BlockHash]
+		_, isRestaged := rbi.stagingShard.toAdd[*cursorBody.BlockHash]
+		if !isDeleted && !isRestaged {
+			rbi.current = cursorBody
+			return true
+		}
+	}
+	rbi.current = nil
+	// An error is reported by Get, so the caller has to get to it
+	return rbi.err != nil
+}
+
+// readCursorBody reads the retention decision the cursor points at, if it's valid
+func (rbi *retainedBodyIterator) readCursorBody() {
+	rbi.cursorBody = nil
+	if !rbi.isCursorValid {
+		return
+	}
+	key, err := rbi.cursor.Key()
+	if err != nil {
+		rbi.err = err
+		return
+	}
+	value, err := rbi.cursor.Value()
+	if err != nil {
+		rbi.err = err
+		return
+	}
+	rbi.cursorBody, rbi.err = deserializeRetainedBody(key.Suffix(), value, rbi.paysRetainedScript)
+}
+
+func (rbi *retainedBodyIterator) Get() (*model.RetainedBody, error) {
+	if rbi.isClosed {
+		return nil, errors.New("Tried using a closed retainedBodyIterator")
+	}
+	if rbi.err != nil {
+		return nil, rbi.err
+	}
+	if rbi.current == nil {
+		return nil, errors.New("Tried using an exhausted retainedBodyIterator")
+	}
+	return rbi.current, nil
+}
+
+func (rbi *retainedBodyIterator) Close() error {
+	if rbi.isClosed {
+		return errors.New("Tried closing an already closed retainedBodyIterator")
+	}
+	rbi.isClosed = true
+	err := rbi.cursor.Close()
+	if err != nil {
+		return err
+	}
+	rbi.cursor = nil
+	rbi.staged = nil
+	rbi.current = nil
+	return nil
+}
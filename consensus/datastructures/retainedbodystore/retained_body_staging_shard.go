@@ -0,0 +1,71 @@
+package retainedbodystore
+
+import (
+	"encoding/binary"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type retainedBodyStagingShard struct {
+	store    *retainedBodyStore
+	toAdd    map[externalapi.DomainHash]*model.RetainedBody
+	toDelete map[externalapi.DomainHash]*model.RetainedBody
+}
+
+func (rbs *retainedBodyStore) stagingShard(stagingArea *model.StagingArea) *retainedBodyStagingShard {
+	return stagingArea.GetOrCreateShard(rbs.shardID, func() model.StagingShard {
+		return &retainedBodyStagingShard{
+			store:    rbs,
+			toAdd:    make(map[externalapi.DomainHash]*model.RetainedBody),
+			toDelete: make(map[externalapi.DomainHash]*model.RetainedBody),
+		}
+	}).(*retainedBodyStagingShard)
+}
+
+func (rbss *retainedBodyStagingShard) Commit(dbTx model.DBTransaction) error {
+	if !rbss.isStaged() {
+		return nil
+	}
+
+	for _, retainedBody := range rbss.toAdd {
+		err := dbTx.Put(rbss.store.retainedBodyKey(retainedBody), serializeRetainedBodySize(retainedBody))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, retainedBody := range rbss.toDelete {
+		err := dbTx.Delete(rbss.store.retainedBodyKey(retainedBody))
+		if err != nil {
+			return err
+		}
+	}
+
+	count, size, err := rbss.store.committedTotals(dbTx)
+	if err != nil {
+		return err
+	}
+	stagedCount, stagedSize := rbss.stagedTotals()
+	serializedTotals := make([]byte, 16)
+	binary.LittleEndian.PutUint64(serializedTotals[:8], uint64(int64(count)+stagedCount))
+	binary.LittleEndian.PutUint64(serializedTotals[8:], uint64(int64(size)+stagedSize))
+	return dbTx.Put(rbss.store.totalsKey, serializedTotals)
+}
+
+// stagedTotals returns how much the staged changes add to the number and the total size of the retained bodies
+func (rbss *retainedBodyStagingShard) stagedTotals() (count int64, size int64) {
+	for _, retainedBody := range rbss.toAdd {
+		count++
+		size += int64(retainedBody.Size)
+	}
+	for _, retainedBody := range rbss.toDelete {
+		count--
+		size -= int64(retainedBody.Size)
+	}
+	return count, size
+}
+
+func (rbss *retainedBodyStagingShard) isStaged() bool {
+	return len(rbss.toAdd) != 0 || len(rbss.toDelete) != 0
+}
@@ -0,0 +1,161 @@
+package retainedbodystore
+
+import (
+	"encoding/binary"
+
+	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// BucketName is the name of the database bucket of the retention decisions of the retained block bodies
+var BucketName = []byte("retained-bodies")
+
+var timeRetainedBucketName = []byte("time-retained")
+var scriptRetainedBucketName = []byte("script-retained")
+var totalsKeyName = []byte("totals")
+
+// retainedBodyKeySuffixSize is the size of a big endian blue score followed by a block hash
+const retainedBodyKeySuffixSize = 8 + externalapi.DomainHashSize
+
+// retainedBodyStore keeps the retention decision of every body the retention policy keeps below
+// the pruning point. The bodies kept by their blue score and the bodies kept by their scripts are
+// kept in separate buckets, each ordered by blue score, so that the pruning only ever goes over the
+// lowest bodies it deletes. The count and total size of the bodies are kept aside so that checking
+// the disk budget doesn't require going over them.
+type retainedBodyStore struct {
+	shardID              model.StagingShardID
+	timeRetainedBucket   model.DBBucket
+	scriptRetainedBucket model.DBBucket
+	totalsKey            model.DBKey
+}
+
+// New instantiates a new RetainedBodyStore
+func New(prefixBucket model.DBBucket) model.RetainedBodyStore {
+	bucket := prefixBucket.Bucket(BucketName)
+	return &retainedBodyStore{
+		shardID:              staging.GenerateShardingID(),
+		timeRetainedBucket:   bucket.Bucket(timeRetainedBucketName),
+		scriptRetainedBucket: bucket.Bucket(scriptRetainedBucketName),
+		totalsKey:            bucket.Key(totalsKeyName),
+	}
+}
+
+// Stage stages the given retention decision
+func (rbs *retainedBodyStore) Stage(stagingArea *model.StagingArea, retainedBody *model.RetainedBody) {
+	stagingShard := rbs.stagingShard(stagingArea)
+
+	stagingShard.toAdd[*retainedBody.BlockHash] = retainedBody
+	delete(stagingShard.toDelete, *retainedBody.BlockHash)
+}
+
+func (rbs *retainedBodyStore) IsStaged(stagingArea *model.StagingArea) bool {
+	return rbs.stagingShard(stagingArea).isStaged()
+}
+
+// RetainedBodies returns an iterator over the retention decisions of the bodies kept by their scripts if
+// paysRetainedScript is true, or by their blue score otherwise, from the lowest blue score up
+func (rbs *retainedBodyStore) RetainedBodies(dbContext model.DBReader, stagingArea *model.StagingArea,
+	paysRetainedScript bool) (model.RetainedBodyIterator, error) {
+
+	stagingShard := rbs.stagingShard(stagingArea)
+
+	cursor, err := dbContext.Cursor(rbs.retainedBodiesBucket(paysRetainedScript))
+	if err != nil {
+		return nil, err
+	}
+
+	staged := make([]*model.RetainedBody, 0, len(stagingShard.toAdd))
+	for _, retainedBody := range stagingShard.toAdd {
+		if retainedBody.PaysRetainedScript == paysRetainedScript {
+			staged = append(staged, retainedBody)
+		}
+	}
+	return newRetainedBodyIterator(cursor, stagingShard, staged, paysRetainedScript), nil
+}
+
+// Totals returns the number and the total size of the retained bodies
+func (rbs *retainedBodyStore) Totals(dbContext model.DBReader, stagingArea *model.StagingArea) (
+	count uint64, size uint64, err error) {
+
+	count, size, err = rbs.committedTotals(dbContext)
+	if err != nil {
+		return 0, 0, err
+	}
+	stagedCount, stagedSize := rbs.stagingShard(stagingArea).stagedTotals()
+	return uint64(int64(count) + stagedCount), uint64(int64(size) + stagedSize), nil
+}
+
+func (rbs *retainedBodyStore) committedTotals(dbContext model.DBReader) (count uint64, size uint64, err error) {
+	serialized, err := dbContext.Get(rbs.totalsKey)
+	if database.IsNotFoundError(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(serialized) != 16 {
+		return 0, 0, errors.Errorf("serialized retained body totals are 16 bytes but got %d bytes", len(serialized))
+	}
+	return binary.LittleEndian.Uint64(serialized[:8]), binary.LittleEndian.Uint64(serialized[8:]), nil
+}
+
+// Delete deletes the given retention decision, once its body is deleted
+func (rbs *retainedBodyStore) Delete(stagingArea *model.StagingArea, retainedBody *model.RetainedBody) {
+	stagingShard := rbs.stagingShard(stagingArea)
+
+	if _, ok := stagingShard.toAdd[*retainedBody.BlockHash]; ok {
+		delete(stagingShard.toAdd, *retainedBody.BlockHash)
+		return
+	}
+	stagingShard.toDelete[*retainedBody.BlockHash] = retainedBody
+}
+
+func (rbs *retainedBodyStore) retainedBodiesBucket(paysRetainedScript bool) model.DBBucket {
+	if paysRetainedScript {
+		return rbs.scriptRetainedBucket
+	}
+	return rbs.timeRetainedBucket
+}
+
+func (rbs *retainedBodyStore) retainedBodyKey(retainedBody *model.RetainedBody) model.DBKey {
+	return rbs.retainedBodiesBucket(retainedBody.PaysRetainedScript).Key(retainedBodyKeySuffix(retainedBody))
+}
+
+// retainedBodyKeySuffix orders the retention decisions by blue score, and then by hash
+func retainedBodyKeySuffix(retainedBody *model.RetainedBody) []byte {
+	suffix := make([]byte, retainedBodyKeySuffixSize)
+	binary.BigEndian.PutUint64(suffix[:8], retainedBody.BlueScore)
+	copy(suffix[8:], retainedBody.BlockHash.ByteSlice())
+	return suffix
+}
+
+func serializeRetainedBodySize(retainedBody *model.RetainedBody) []byte {
+	serialized := make([]byte, 8)
+	binary.LittleEndian.PutUint64(serialized, retainedBody.Size)
+	return serialized
+}
+
+func deserializeRetainedBody(keySuffix []byte, serializedSize []byte, paysRetainedScript bool) (
+	*model.RetainedBody, error) {
+
+	if len(keySuffix) != retainedBodyKeySuffixSize {
+		return nil, errors.Errorf("a retained body key is %d bytes but got %d bytes",
+			retainedBodyKeySuffixSize, len(keySuffix))
+	}
+	if len(serializedSize) != 8 {
+		return nil, errors.Errorf("a serialized retained body size is 8 bytes but got %d bytes", len(serializedSize))
+	}
+	blockHash, err := externalapi.NewDomainHashFromByteSlice(keySuffix[8:])
+	if err != nil {
+		return nil, err
+	}
+	return &model.RetainedBody{
+		BlockHash:          blockHash,
+		BlueScore:          binary.BigEndian.Uint64(keySuffix[:8]),
+		Size:               binary.LittleEndian.Uint64(serializedSize),
+		PaysRetainedScript: paysRetainedScript,
+	}, nil
+}
@@ -10,8 +10,11 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
-var utxoDiffBucketName = []byte("utxo-diffs")
-var utxoDiffChildBucketName = []byte("utxo-diff-children")
+// UTXODiffBucketName is the name of the database bucket of the UTXO diffs
+var UTXODiffBucketName = []byte("utxo-diffs")
+
+// UTXODiffChildBucketName is the name of the database bucket of the UTXO diff children
+var UTXODiffChildBucketName = []byte("utxo-diff-children")
 
 // utxoDiffStore represents a store of UTXODiffs
 type utxoDiffStore struct {
@@ -28,8 +31,8 @@ func New(prefixBucket model.DBBucket, cacheSize int, preallocate bool) model.UTX
 		shardID:             staging.GenerateShardingID(),
 		utxoDiffCache:       lrucache.New(cacheSize, preallocate),
 		utxoDiffChildCache:  lrucache.New(cacheSize, preallocate),
-		utxoDiffBucket:      prefixBucket.Bucket(utxoDiffBucketName),
-		utxoDiffChildBucket: prefixBucket.Bucket(utxoDiffChildBucketName),
+		utxoDiffBucket:      prefixBucket.Bucket(UTXODiffBucketName),
+		utxoDiffChildBucket: prefixBucket.Bucket(UTXODiffChildBucketName),
 	}
 }
 
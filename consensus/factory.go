@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"sync"
@@ -21,6 +22,8 @@ import (
 	infrastructuredatabase "github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
 	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database/ldb"
 	consensusdatabase "github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/memorydb"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/migrations"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/acceptancedatastore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockheaderstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockrelationstore"
@@ -28,6 +31,7 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/consensusstatestore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/daablocksstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/finalityconflictstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/finalitystore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/ghostdagdatastore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/headersselectedchainstore"
@@ -35,13 +39,16 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/multisetstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/pruningstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/reachabilitydatastore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/retainedbodystore"
 	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/utxodiffstore"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/blockbuilder"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/blockprocessor"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/blocktemplatemanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/blockvalidator"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/coinbasemanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/consensuseventsmanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/consensusstatemanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/dagtopologymanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/dagtraversalmanager"
@@ -49,11 +56,13 @@ import (
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/finalitymanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/ghostdagmanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/headersselectedtipmanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/integritychecker"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/mergedepthmanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/pastmediantimemanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/pruningmanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/reachabilitymanager"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/syncmanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/transactionselector"
 	"github.com/zilong-dai/karlsen-miner/consensus/processes/transactionvalidator"
 	"github.com/zilong-dai/karlsen-miner/dagconfig"
 )
@@ -69,10 +78,36 @@ type Config struct {
 	dagconfig.Params
 	// IsArchival tells the consensus if it should not prune old blocks
 	IsArchival bool
+	// RetentionPolicy selects the block bodies a non-archival consensus keeps below the pruning point
+	RetentionPolicy model.RetentionPolicy
 	// EnableSanityCheckPruningUTXOSet checks the full pruning point utxo set against the commitment at every pruning movement
 	EnableSanityCheckPruningUTXOSet bool
 
 	SkipAddingGenesis bool
+
+	// LocalSubnetworkID is the subnetwork the node is a partial node of, or nil for a full node.
+	// A partial node accepts payloads only in the built-in subnetworks and in its own subnetwork.
+	LocalSubnetworkID *externalapi.DomainSubnetworkID
+
+	// GHOSTDAGImplementation selects the GHOSTDAG implementation of every block level
+	GHOSTDAGImplementation GHOSTDAGImplementation
+
+	// ReachabilityReindexSlack and ReachabilityReindexWindow tune the reindexing of the
+	// reachability tree. Zero selects the default value of each.
+	ReachabilityReindexSlack  uint64
+	ReachabilityReindexWindow uint64
+
+	// StoreEncodings selects the encoding of the records written by the hot stores
+	StoreEncodings StoreEncodings
+}
+
+// StoreEncodings holds the encoding of every store that supports more than one. The zero
+// value keeps all of them on protobuf. Switching an encoding requires no migration, since
+// the stores read the records of both encodings.
+type StoreEncodings struct {
+	GHOSTDAGData     model.StoreEncoding
+	ReachabilityData model.StoreEncoding
+	BlockHeaders     model.StoreEncoding
 }
 
 // Factory instantiates new Consensuses
@@ -80,6 +115,9 @@ type Factory interface {
 	NewConsensus(config *Config, db infrastructuredatabase.Database, dbPrefix *prefix.Prefix,
 		consensusEventsChan chan externalapi.ConsensusEvent) (
 		externalapi.Consensus, bool, error)
+	NewConsensusFromSnapshot(config *Config, db infrastructuredatabase.Database, dbPrefix *prefix.Prefix,
+		snapshotReader io.ReadSeeker, consensusEventsChan chan externalapi.ConsensusEvent) (
+		externalapi.Consensus, error)
 	NewTestConsensus(config *Config, testName string) (
 		tc testapi.TestConsensus, teardown func(keepDataDir bool), err error)
 
@@ -87,6 +125,7 @@ type Factory interface {
 	SetTestGHOSTDAGManager(ghostdagConstructor GHOSTDAGManagerConstructor)
 	SetTestLevelDBCacheSize(cacheSizeMiB int)
 	SetTestPreAllocateCache(preallocateCaches bool)
+	SetTestInMemoryDatabase(inMemoryDatabase bool)
 	SetTestPastMedianTimeManager(medianTimeConstructor PastMedianTimeManagerConstructor)
 	SetTestDifficultyManager(difficultyConstructor DifficultyManagerConstructor)
 }
@@ -98,6 +137,7 @@ type factory struct {
 	difficultyConstructor    DifficultyManagerConstructor
 	cacheSizeMiB             *int
 	preallocateCaches        *bool
+	inMemoryDatabase         bool
 }
 
 // NewFactory creates a new Consensus factory
@@ -109,14 +149,27 @@ func NewFactory() Factory {
 	}
 }
 
-// NewConsensus instantiates a new Consensus
+// NewConsensus instantiates a new Consensus. Pending schema migrations of the stored consensus
+// objects are applied in place first. shouldMigrate is unrelated to them: it reports that the
+// consensus should be rebuilt under a new prefix since its reachability data is outdated.
 func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Database, dbPrefix *prefix.Prefix,
 	consensusEventsChan chan externalapi.ConsensusEvent) (
 	consensusInstance externalapi.Consensus, shouldMigrate bool, err error) {
 
+	err = validateGHOSTDAGImplementation(config)
+	if err != nil {
+		return nil, false, err
+	}
+
 	dbManager := consensusdatabase.New(db)
 	prefixBucket := consensusdatabase.MakeBucket(dbPrefix.Serialize())
 
+	// Schema migrations rewrite the stored objects in place, so they run before any store reads them
+	err = migrations.NewMigrator(dbManager, prefixBucket, migrations.DefaultRegistry(), migrations.DefaultBatchSize).Migrate()
+	if err != nil {
+		return nil, false, err
+	}
+
 	pruningWindowSizeForCaches := int(config.PruningDepth())
 
 	var preallocateCaches bool
@@ -138,7 +191,8 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 	if err != nil {
 		return nil, false, err
 	}
-	blockHeaderStore, err := blockheaderstore.New(dbManager, prefixBucket, 10_000, preallocateCaches)
+	blockHeaderStore, err := blockheaderstore.New(dbManager, prefixBucket, 10_000, preallocateCaches,
+		config.StoreEncodings.BlockHeaders)
 	if err != nil {
 		return nil, false, err
 	}
@@ -151,25 +205,32 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 
 	headersSelectedTipStore := headersselectedtipstore.New(prefixBucket)
 	finalityStore := finalitystore.New(prefixBucket, 200, preallocateCaches)
+	finalityConflictStore := finalityconflictstore.New(prefixBucket)
+	retainedBodyStore := retainedbodystore.New(prefixBucket)
 	headersSelectedChainStore := headersselectedchainstore.New(prefixBucket, pruningWindowSizeForCaches, preallocateCaches)
 	daaBlocksStore := daablocksstore.New(prefixBucket, pruningWindowSizeForCaches, int(config.FinalityDepth()), preallocateCaches)
 	windowHeapSliceStore := blockwindowheapslicestore.New(2000, preallocateCaches)
 
-	newReachabilityDataStore := reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache*2, preallocateCaches)
+	newReachabilityDataStore := reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache*2, preallocateCaches,
+		config.StoreEncodings.ReachabilityData)
 	blockRelationStores, reachabilityDataStores, ghostdagDataStores := dagStores(config, prefixBucket, pruningWindowSizePlusFinalityDepthForCache, pruningWindowSizeForCaches, preallocateCaches)
-	oldReachabilityManager := reachabilitymanager.New(
+	oldReachabilityManager := reachabilitymanager.NewWithReindexParameters(
 		dbManager,
 		ghostdagDataStores[0],
-		reachabilityDataStores[0])
+		reachabilityDataStores[0],
+		config.ReachabilityReindexSlack,
+		config.ReachabilityReindexWindow)
 	isOldReachabilityInitialized, err := reachabilityDataStores[0].HasReachabilityData(dbManager, model.NewStagingArea(), model.VirtualGenesisBlockHash)
 	if err != nil {
 		return nil, false, err
 	}
 
-	newReachabilityManager := reachabilitymanager.New(
+	newReachabilityManager := reachabilitymanager.NewWithReindexParameters(
 		dbManager,
 		ghostdagDataStores[0],
-		newReachabilityDataStore)
+		newReachabilityDataStore,
+		config.ReachabilityReindexSlack,
+		config.ReachabilityReindexWindow)
 	reachabilityManager := newReachabilityManager
 	if isOldReachabilityInitialized {
 		reachabilityManager = oldReachabilityManager
@@ -215,6 +276,7 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		config.GenesisHash)
 	transactionValidator := transactionvalidator.New(config.BlockCoinbaseMaturity,
 		config.EnableNonNativeSubnetworks,
+		config.LocalSubnetworkID,
 		config.MaxCoinbasePayloadLength,
 		config.K,
 		config.CoinbasePayloadScriptPublicKeyMaxLength,
@@ -277,6 +339,7 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		daaBlocksStore,
 		pruningStore,
 		finalityStore)
+	consensusEventsManager := consensuseventsmanager.New()
 	consensusStateManager, err := consensusstatemanager.New(
 		dbManager,
 		config.MaxBlockParents,
@@ -292,6 +355,7 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		mergeDepthManager,
 		finalityManager,
 		difficultyManager,
+		consensusEventsManager,
 
 		blockStatusStore,
 		ghostdagDataStore,
@@ -304,7 +368,8 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		blockHeaderStore,
 		headersSelectedTipStore,
 		pruningStore,
-		daaBlocksStore)
+		daaBlocksStore,
+		finalityConflictStore)
 	if err != nil {
 		return nil, false, err
 	}
@@ -329,8 +394,10 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		daaBlocksStore,
 		reachabilityDataStore,
 		daaWindowStore,
+		retainedBodyStore,
 
 		config.IsArchival,
+		config.RetentionPolicy,
 		genesisHash,
 		config.FinalityDepth(),
 		config.PruningDepth(),
@@ -351,6 +418,7 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		config.TargetTimePerBlock,
 		config.HFDAAScore,
 		config.MaxBlockLevel,
+		subnetworkGasLimits(config),
 
 		dbManager,
 		difficultyManager,
@@ -417,6 +485,9 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		daaBlocksStore,
 	)
 
+	blockTemplateManager := blocktemplatemanager.New(blockBuilder, config.CoinbasePayloadScriptPublicKeyMaxLength)
+	transactionSelector := transactionselector.New(transactionValidator)
+
 	blockProcessor := blockprocessor.New(
 		genesisHash,
 		config.TargetTimePerBlock,
@@ -432,6 +503,7 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		coinbaseManager,
 		headerTipsManager,
 		syncManager,
+		consensusEventsManager,
 
 		acceptanceDataStore,
 		blockStore,
@@ -474,34 +546,55 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 		config.MaxBlockLevel,
 	)
 
+	integrityChecker := integritychecker.New(
+		dbManager,
+		genesisHash,
+
+		ghostdagManager,
+
+		blockHeaderStore,
+		blockRelationStore,
+		ghostdagDataStore,
+		reachabilityDataStore,
+		multisetStore,
+		pruningStore,
+	)
+
 	c := &consensus{
 		lock:            &sync.Mutex{},
 		databaseContext: dbManager,
+		prefixBucket:    prefixBucket,
 
 		genesisBlock: config.GenesisBlock,
 		genesisHash:  config.GenesisHash,
+		dagParams:    &config.Params,
 
 		expectedDAAWindowDurationInMilliseconds: config.TargetTimePerBlock.Milliseconds() *
 			int64(config.DifficultyAdjustmentWindowSize),
-
-		blockProcessor:        blockProcessor,
-		blockBuilder:          blockBuilder,
-		consensusStateManager: consensusStateManager,
-		transactionValidator:  transactionValidator,
-		syncManager:           syncManager,
-		pastMedianTimeManager: pastMedianTimeManager,
-		blockValidator:        blockValidator,
-		coinbaseManager:       coinbaseManager,
-		dagTopologyManagers:   dagTopologyManagers,
-		dagTraversalManager:   dagTraversalManager,
-		difficultyManager:     difficultyManager,
-		ghostdagManagers:      ghostdagManagers,
-		headerTipsManager:     headerTipsManager,
-		mergeDepthManager:     mergeDepthManager,
-		pruningManager:        pruningManager,
-		reachabilityManager:   reachabilityManager,
-		finalityManager:       finalityManager,
-		pruningProofManager:   pruningProofManager,
+		maxBlockMass: config.MaxBlockMass,
+
+		blockProcessor:         blockProcessor,
+		blockBuilder:           blockBuilder,
+		blockTemplateManager:   blockTemplateManager,
+		transactionSelector:    transactionSelector,
+		consensusStateManager:  consensusStateManager,
+		transactionValidator:   transactionValidator,
+		syncManager:            syncManager,
+		pastMedianTimeManager:  pastMedianTimeManager,
+		blockValidator:         blockValidator,
+		coinbaseManager:        coinbaseManager,
+		dagTopologyManagers:    dagTopologyManagers,
+		dagTraversalManager:    dagTraversalManager,
+		difficultyManager:      difficultyManager,
+		ghostdagManagers:       ghostdagManagers,
+		headerTipsManager:      headerTipsManager,
+		mergeDepthManager:      mergeDepthManager,
+		pruningManager:         pruningManager,
+		reachabilityManager:    reachabilityManager,
+		finalityManager:        finalityManager,
+		pruningProofManager:    pruningProofManager,
+		integrityChecker:       integrityChecker,
+		consensusEventsManager: consensusEventsManager,
 
 		acceptanceDataStore:                 acceptanceDataStore,
 		blockStore:                          blockStore,
@@ -567,27 +660,57 @@ func (f *factory) NewConsensus(config *Config, db infrastructuredatabase.Databas
 	return c, false, nil
 }
 
-func (f *factory) NewTestConsensus(config *Config, testName string) (
-	tc testapi.TestConsensus, teardown func(keepDataDir bool), err error) {
-	datadir := f.dataDir
-	if datadir == "" {
-		datadir, err = ioutil.TempDir("", testName)
-		if err != nil {
-			return nil, nil, err
-		}
+// NewConsensusFromSnapshot imports a snapshot that was written by Consensus.ExportSnapshot into
+// the given, empty, database prefix and instantiates a new Consensus over it
+func (f *factory) NewConsensusFromSnapshot(config *Config, db infrastructuredatabase.Database, dbPrefix *prefix.Prefix,
+	snapshotReader io.ReadSeeker, consensusEventsChan chan externalapi.ConsensusEvent) (
+	externalapi.Consensus, error) {
+
+	dbManager := consensusdatabase.New(db)
+	prefixBucket := consensusdatabase.MakeBucket(dbPrefix.Serialize())
+
+	err := importSnapshot(config, dbManager, prefixBucket, snapshotReader)
+	if err != nil {
+		return nil, err
 	}
-	var cacheSizeMiB int
-	if f.cacheSizeMiB != nil {
-		cacheSizeMiB = *f.cacheSizeMiB
-	} else {
-		cacheSizeMiB = defaultTestLeveldbCacheSizeMiB
+
+	consensusInstance, shouldMigrate, err := f.NewConsensus(config, db, dbPrefix, consensusEventsChan)
+	if err != nil {
+		return nil, err
+	}
+	if shouldMigrate {
+		return nil, errors.Errorf("a consensus imported from a snapshot should never require a migration")
 	}
+
+	return consensusInstance, nil
+}
+
+func (f *factory) NewTestConsensus(config *Config, testName string) (
+	tc testapi.TestConsensus, teardown func(keepDataDir bool), err error) {
 	if f.preallocateCaches == nil {
 		f.SetTestPreAllocateCache(defaultTestPreallocateCaches)
 	}
-	db, err := ldb.NewLevelDB(datadir, cacheSizeMiB)
-	if err != nil {
-		return nil, nil, err
+	var db infrastructuredatabase.Database
+	if f.inMemoryDatabase {
+		db = memorydb.New()
+	} else {
+		datadir := f.dataDir
+		if datadir == "" {
+			datadir, err = ioutil.TempDir("", testName)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		var cacheSizeMiB int
+		if f.cacheSizeMiB != nil {
+			cacheSizeMiB = *f.cacheSizeMiB
+		} else {
+			cacheSizeMiB = defaultTestLeveldbCacheSizeMiB
+		}
+		db, err = ldb.NewLevelDB(datadir, cacheSizeMiB)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	testConsensusDBPrefix := &prefix.Prefix{}
@@ -616,7 +739,7 @@ func (f *factory) NewTestConsensus(config *Config, testName string) (
 	tstConsensus.testBlockBuilder = blockbuilder.NewTestBlockBuilder(consensusAsImplementation.blockBuilder, tstConsensus)
 	teardown = func(keepDataDir bool) {
 		db.Close()
-		if !keepDataDir {
+		if !keepDataDir && !f.inMemoryDatabase {
 			err := os.RemoveAll(f.dataDir)
 			if err != nil {
 				log.Errorf("Error removing data directory for test consensus: %s", err)
@@ -650,6 +773,12 @@ func (f *factory) SetTestPreAllocateCache(preallocateCaches bool) {
 	f.preallocateCaches = &preallocateCaches
 }
 
+// SetTestInMemoryDatabase makes NewTestConsensus keep its database in memory instead of in a
+// LevelDB data directory
+func (f *factory) SetTestInMemoryDatabase(inMemoryDatabase bool) {
+	f.inMemoryDatabase = inMemoryDatabase
+}
+
 func dagStores(config *Config,
 	prefixBucket model.DBBucket,
 	pruningWindowSizePlusFinalityDepthForCache, pruningWindowSizeForCaches int,
@@ -668,12 +797,14 @@ func dagStores(config *Config,
 		prefixBucket := prefixBucket.Bucket([]byte{byte(i)})
 		if i == 0 {
 			blockRelationStores[i] = blockrelationstore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache, preallocateCaches)
-			reachabilityDataStores[i] = reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache*2, preallocateCaches)
-			ghostdagDataStores[i] = ghostdagdatastore.New(prefixBucket, ghostdagDataCacheSize, preallocateCaches)
+			reachabilityDataStores[i] = reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache*2, preallocateCaches,
+				config.StoreEncodings.ReachabilityData)
+			ghostdagDataStores[i] = ghostdagdatastore.New(prefixBucket, ghostdagDataCacheSize, preallocateCaches, config.StoreEncodings.GHOSTDAGData)
 		} else {
 			blockRelationStores[i] = blockrelationstore.New(prefixBucket, 200, false)
-			reachabilityDataStores[i] = reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache, false)
-			ghostdagDataStores[i] = ghostdagdatastore.New(prefixBucket, 200, false)
+			reachabilityDataStores[i] = reachabilitydatastore.New(prefixBucket, pruningWindowSizePlusFinalityDepthForCache, false,
+				config.StoreEncodings.ReachabilityData)
+			ghostdagDataStores[i] = ghostdagdatastore.New(prefixBucket, 200, false, config.StoreEncodings.GHOSTDAGData)
 		}
 	}
 
@@ -698,18 +829,23 @@ func (f *factory) dagProcesses(config *Config,
 	dagTopologyManagers := make([]model.DAGTopologyManager, config.MaxBlockLevel+1)
 	ghostdagManagers := make([]model.GHOSTDAGManager, config.MaxBlockLevel+1)
 	dagTraversalManagers := make([]model.DAGTraversalManager, config.MaxBlockLevel+1)
+	ghostdagConstructor := f.ghostdagManagerConstructor(config.GHOSTDAGImplementation)
 
-	newReachabilityManager := reachabilitymanager.New(
+	newReachabilityManager := reachabilitymanager.NewWithReindexParameters(
 		dbManager,
 		ghostdagDataStores[0],
-		reachabilityDataStores[0])
+		reachabilityDataStores[0],
+		config.ReachabilityReindexSlack,
+		config.ReachabilityReindexWindow)
 
 	for i := 0; i <= config.MaxBlockLevel; i++ {
 		if isOldReachabilityInitialized {
-			reachabilityManagers[i] = reachabilitymanager.New(
+			reachabilityManagers[i] = reachabilitymanager.NewWithReindexParameters(
 				dbManager,
 				ghostdagDataStores[i],
-				reachabilityDataStores[i])
+				reachabilityDataStores[i],
+				config.ReachabilityReindexSlack,
+				config.ReachabilityReindexWindow)
 		} else {
 			reachabilityManagers[i] = newReachabilityManager
 		}
@@ -720,7 +856,7 @@ func (f *factory) dagProcesses(config *Config,
 			blockRelationStores[i],
 			ghostdagDataStores[i])
 
-		ghostdagManagers[i] = f.ghostdagConstructor(
+		ghostdagManagers[i] = ghostdagConstructor(
 			dbManager,
 			dagTopologyManagers[i],
 			ghostdagDataStores[i],
@@ -133,7 +133,15 @@ func TestFinality(t *testing.T) {
 			t.Fatalf("virtual's finalityPoint is still genesis after adding finalityInterval + 1 blocks to the main chain")
 		}
 
-		// TODO: Make sure that a finality conflict notification is sent
+		subscription, err := consensus.SubscribeEvents(&externalapi.ConsensusEventSubscriptionOptions{
+			EventTypes: []externalapi.ConsensusEventType{externalapi.ConsensusEventTypeFinalityConflict},
+			BufferSize: 10,
+		})
+		if err != nil {
+			t.Fatalf("TestFinality: Failed subscribing to consensus events: %v", err)
+		}
+		defer subscription.Unsubscribe()
+
 		// Add two more blocks to the side chain, so that it violates finality and gets status UTXOPendingVerification even
 		// though it is the block with the highest blue score.
 		for i := uint64(0); i < 2; i++ {
@@ -144,6 +152,24 @@ func TestFinality(t *testing.T) {
 			sideChainTipHash = consensushashing.BlockHash(sideChainTip)
 		}
 
+		// Make sure that a finality conflict notification is sent for the side chain tip
+		var lastFinalityConflict *externalapi.FinalityConflict
+		for len(subscription.Events()) > 0 {
+			event := <-subscription.Events()
+			lastFinalityConflict = event.Event.(*externalapi.FinalityConflict)
+		}
+		if lastFinalityConflict == nil {
+			t.Fatalf("TestFinality: No finality conflict notification was sent")
+		}
+		if !lastFinalityConflict.ViolatingBlockHash.Equal(sideChainTipHash) {
+			t.Fatalf("TestFinality: Expected a finality conflict notification for %s but got one for %s",
+				sideChainTipHash, lastFinalityConflict.ViolatingBlockHash)
+		}
+		if !lastFinalityConflict.FinalityPointHash.Equal(virtualFinality) {
+			t.Fatalf("TestFinality: Expected the finality conflict of finality point %s but got %s",
+				virtualFinality, lastFinalityConflict.FinalityPointHash)
+		}
+
 		// Check that sideChainTip hash higher blue score than the selected parent
 		selectedTip, err = consensus.GetVirtualSelectedParent()
 		if err != nil {
@@ -614,3 +640,129 @@ func TestFinalityResolveVirtual(t *testing.T) {
 		}
 	})
 }
+
+func TestFinalityConflictResolution(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		// Set finalityInterval to 20 blocks, so that test runs quickly
+		consensusConfig.FinalityDuration = 20 * consensusConfig.TargetTimePerBlock
+
+		for _, followViolatingChain := range []bool{false, true} {
+			factory := consensus.NewFactory()
+			tc, teardown, err := factory.NewTestConsensus(consensusConfig,
+				fmt.Sprintf("TestFinalityConflictResolution_%t", followViolatingChain))
+			if err != nil {
+				t.Fatalf("Error setting up consensus: %+v", err)
+			}
+
+			// Build a main chain until the finality point moves from the genesis
+			mainChain := []*externalapi.DomainHash{}
+			tip := consensusConfig.GenesisHash
+			for {
+				tip, _, err = tc.AddBlock([]*externalapi.DomainHash{tip}, nil, nil)
+				if err != nil {
+					t.Fatalf("AddBlock: %+v", err)
+				}
+				mainChain = append(mainChain, tip)
+
+				virtualFinalityPoint, err := tc.FinalityManager().VirtualFinalityPoint(model.NewStagingArea())
+				if err != nil {
+					t.Fatalf("VirtualFinalityPoint: %+v", err)
+				}
+				if !virtualFinalityPoint.Equal(consensusConfig.GenesisHash) {
+					break
+				}
+			}
+			finalityPoint, err := tc.FinalityManager().VirtualFinalityPoint(model.NewStagingArea())
+			if err != nil {
+				t.Fatalf("VirtualFinalityPoint: %+v", err)
+			}
+
+			subscription, err := tc.SubscribeEvents(&externalapi.ConsensusEventSubscriptionOptions{
+				EventTypes: []externalapi.ConsensusEventType{externalapi.ConsensusEventTypeFinalityConflictResolved},
+				BufferSize: 1,
+			})
+			if err != nil {
+				t.Fatalf("SubscribeEvents: %+v", err)
+			}
+
+			// Build a longer side chain from the genesis. Its blocks pay a different script so that they differ
+			// from the main chain blocks.
+			sideChainCoinbaseData := &externalapi.DomainCoinbaseData{
+				ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+				ExtraData:       []byte{},
+			}
+			sideChain := []*externalapi.DomainHash{}
+			sideChainTip := consensusConfig.GenesisHash
+			for i := 0; i < len(mainChain)+2; i++ {
+				sideChainTip, _, err = tc.AddBlock([]*externalapi.DomainHash{sideChainTip}, sideChainCoinbaseData, nil)
+				if err != nil {
+					t.Fatalf("AddBlock: %+v", err)
+				}
+				sideChain = append(sideChain, sideChainTip)
+			}
+
+			conflicts, err := tc.FinalityConflicts()
+			if err != nil {
+				t.Fatalf("FinalityConflicts: %+v", err)
+			}
+			if len(conflicts) != 1 {
+				t.Fatalf("Expected a single finality conflict but got %d", len(conflicts))
+			}
+			conflict := conflicts[0]
+			if !conflict.ID().Equal(sideChain[0]) || !conflict.ViolatingBlockHash.Equal(sideChainTip) ||
+				!conflict.FinalityPointHash.Equal(finalityPoint) ||
+				!externalapi.HashesEqual(conflict.ViolatingBlockHashes, sideChain) {
+				t.Fatalf("Unexpected finality conflict: lowest violating block %s, violating block %s, "+
+					"finality point %s, violating blocks %s", conflict.LowestViolatingBlockHash,
+					conflict.ViolatingBlockHash, conflict.FinalityPointHash, conflict.ViolatingBlockHashes)
+			}
+
+			err = tc.ResolveFinalityConflict(mainChain[0], followViolatingChain)
+			if !errors.Is(err, model.ErrFinalityConflictNotFound) {
+				t.Fatalf("Expected ErrFinalityConflictNotFound when resolving with a main chain block but got: %+v", err)
+			}
+
+			// Any violating block identifies the conflict
+			err = tc.ResolveFinalityConflict(sideChain[len(sideChain)/2], followViolatingChain)
+			if err != nil {
+				t.Fatalf("ResolveFinalityConflict: %+v", err)
+			}
+			event := <-subscription.Events()
+			resolved := event.Event.(*externalapi.FinalityConflictResolved)
+			if !resolved.Conflict.ViolatingBlockHash.Equal(sideChainTip) ||
+				!externalapi.HashesEqual(resolved.Conflict.ViolatingBlockHashes, sideChain) ||
+				resolved.FollowsViolatingChain != followViolatingChain {
+				t.Fatalf("Unexpected FinalityConflictResolved event: %+v", resolved)
+			}
+
+			// The resolved conflict isn't recorded again as the side chain grows
+			sideChainTip, _, err = tc.AddBlock([]*externalapi.DomainHash{sideChainTip}, sideChainCoinbaseData, nil)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+			conflicts, err = tc.FinalityConflicts()
+			if err != nil {
+				t.Fatalf("FinalityConflicts: %+v", err)
+			}
+			if len(conflicts) != 0 {
+				t.Fatalf("Expected no finality conflicts after the resolution but got %d", len(conflicts))
+			}
+
+			expectedVirtualSelectedParent := mainChain[len(mainChain)-1]
+			if followViolatingChain {
+				expectedVirtualSelectedParent = sideChainTip
+			}
+			virtualSelectedParent, err := tc.GetVirtualSelectedParent()
+			if err != nil {
+				t.Fatalf("GetVirtualSelectedParent: %+v", err)
+			}
+			if !virtualSelectedParent.Equal(expectedVirtualSelectedParent) {
+				t.Fatalf("Expected the virtual selected parent to be %s when following the violating chain is %t, "+
+					"but got %s", expectedVirtualSelectedParent, followViolatingChain, virtualSelectedParent)
+			}
+
+			subscription.Unsubscribe()
+			teardown(false)
+		}
+	})
+}
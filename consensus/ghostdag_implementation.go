@@ -0,0 +1,74 @@
+package consensus
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/ghostdag2"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/ghostdagdifferential"
+)
+
+// GHOSTDAGImplementation selects the GHOSTDAG implementation a consensus runs
+type GHOSTDAGImplementation uint8
+
+const (
+	// GHOSTDAGImplementationDefault runs ghostdagmanager, or the implementation set
+	// with Factory.SetTestGHOSTDAGManager
+	GHOSTDAGImplementationDefault GHOSTDAGImplementation = iota
+
+	// GHOSTDAGImplementationAlternative runs ghostdag2. It walks the whole selected
+	// chain of every block, so it's only suitable for archival nodes and tests.
+	GHOSTDAGImplementationAlternative
+
+	// GHOSTDAGImplementationDifferential runs the default implementation, and also runs
+	// ghostdag2 on every block to report where their results diverge. Like
+	// GHOSTDAGImplementationAlternative, it's only suitable for archival nodes and tests.
+	GHOSTDAGImplementationDifferential
+)
+
+func (gi GHOSTDAGImplementation) String() string {
+	switch gi {
+	case GHOSTDAGImplementationDefault:
+		return "default"
+	case GHOSTDAGImplementationAlternative:
+		return "alternative"
+	case GHOSTDAGImplementationDifferential:
+		return "differential"
+	}
+	return "unknown"
+}
+
+// validateGHOSTDAGImplementation returns an error if the configured GHOSTDAG implementation
+// can't run on the configured node
+func validateGHOSTDAGImplementation(config *Config) error {
+	switch config.GHOSTDAGImplementation {
+	case GHOSTDAGImplementationDefault:
+		return nil
+	case GHOSTDAGImplementationAlternative, GHOSTDAGImplementationDifferential:
+		if !config.IsArchival {
+			return errors.Errorf("the %s GHOSTDAG implementation runs ghostdag2, which walks the whole "+
+				"selected chain of every block, so it can only run on archival nodes", config.GHOSTDAGImplementation)
+		}
+		return nil
+	}
+	return errors.Errorf("unknown GHOSTDAG implementation %d", config.GHOSTDAGImplementation)
+}
+
+func (f *factory) ghostdagManagerConstructor(implementation GHOSTDAGImplementation) GHOSTDAGManagerConstructor {
+	switch implementation {
+	case GHOSTDAGImplementationAlternative:
+		return ghostdag2.New
+	case GHOSTDAGImplementationDifferential:
+		return func(databaseContext model.DBReader, dagTopologyManager model.DAGTopologyManager,
+			ghostdagDataStore model.GHOSTDAGDataStore, headerStore model.BlockHeaderStore, k externalapi.KType,
+			genesisHash *externalapi.DomainHash) model.GHOSTDAGManager {
+
+			primary := f.ghostdagConstructor(databaseContext, dagTopologyManager, ghostdagDataStore, headerStore, k, genesisHash)
+			newSecondary := func(secondaryGHOSTDAGDataStore model.GHOSTDAGDataStore) model.GHOSTDAGManager {
+				return ghostdag2.New(databaseContext, dagTopologyManager, secondaryGHOSTDAGDataStore, headerStore, k, genesisHash)
+			}
+			return ghostdagdifferential.New(databaseContext, ghostdagDataStore, primary, newSecondary, nil)
+		}
+	}
+	return f.ghostdagConstructor
+}
@@ -0,0 +1,134 @@
+package consensus_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
+)
+
+func TestGHOSTDAGImplementations(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		// A small K, so that the merging block has red blocks in its merge set
+		consensusConfig.K = 2
+		// The alternative implementation only runs on archival nodes
+		consensusConfig.IsArchival = true
+
+		type result struct {
+			blueScore       uint64
+			mergeSetBlues   int
+			mergeSetReds    int
+			sortedMergeSet  string
+			virtualParents  int
+			virtualBlueWork string
+		}
+		results := make(map[consensus.GHOSTDAGImplementation]*result)
+
+		implementations := []consensus.GHOSTDAGImplementation{
+			consensus.GHOSTDAGImplementationDefault,
+			consensus.GHOSTDAGImplementationAlternative,
+			consensus.GHOSTDAGImplementationDifferential,
+		}
+		for _, implementation := range implementations {
+			consensusConfig.GHOSTDAGImplementation = implementation
+			tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestGHOSTDAGImplementations")
+			if err != nil {
+				t.Fatalf("Error setting up consensus: %+v", err)
+			}
+
+			addBlock := func(parentHashes ...*externalapi.DomainHash) *externalapi.DomainHash {
+				blockHash, _, err := tc.AddBlock(parentHashes, nil, nil)
+				if err != nil {
+					t.Fatalf("%s: AddBlock: %+v", implementation, err)
+				}
+				return blockHash
+			}
+
+			chainTip := addBlock(consensusConfig.GenesisHash)
+			forkPoint := chainTip
+			for i := 0; i < 3; i++ {
+				chainTip = addBlock(chainTip)
+			}
+			mergingBlockParents := []*externalapi.DomainHash{chainTip}
+			for i := 0; i < 4; i++ {
+				mergingBlockParents = append(mergingBlockParents, addBlock(forkPoint))
+			}
+			mergingBlock := addBlock(mergingBlockParents...)
+
+			stagingArea := model.NewStagingArea()
+			ghostdagData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), stagingArea, mergingBlock, false)
+			if err != nil {
+				t.Fatalf("%s: GHOSTDAGDataStore.Get: %+v", implementation, err)
+			}
+			sortedMergeSet, err := tc.GHOSTDAGManager().GetSortedMergeSet(stagingArea, mergingBlock)
+			if err != nil {
+				t.Fatalf("%s: GetSortedMergeSet: %+v", implementation, err)
+			}
+			if len(sortedMergeSet) != len(ghostdagData.MergeSetBlues())+len(ghostdagData.MergeSetReds()) ||
+				!sortedMergeSet[0].Equal(ghostdagData.SelectedParent()) {
+				t.Fatalf("%s: expected the sorted merge set %s to start with the selected parent %s and "+
+					"contain the whole merge set", implementation, sortedMergeSet, ghostdagData.SelectedParent())
+			}
+			for i := 2; i < len(sortedMergeSet); i++ {
+				previousData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), stagingArea, sortedMergeSet[i-1], false)
+				if err != nil {
+					t.Fatalf("%s: GHOSTDAGDataStore.Get: %+v", implementation, err)
+				}
+				currentData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), stagingArea, sortedMergeSet[i], false)
+				if err != nil {
+					t.Fatalf("%s: GHOSTDAGDataStore.Get: %+v", implementation, err)
+				}
+				if !tc.GHOSTDAGManager().Less(sortedMergeSet[i-1], previousData, sortedMergeSet[i], currentData) {
+					t.Fatalf("%s: expected the sorted merge set %s to be sorted by blue work and then by hash",
+						implementation, sortedMergeSet)
+				}
+			}
+			virtualGHOSTDAGData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), stagingArea, model.VirtualBlockHash, false)
+			if err != nil {
+				t.Fatalf("%s: GHOSTDAGDataStore.Get: %+v", implementation, err)
+			}
+			results[implementation] = &result{
+				blueScore:       ghostdagData.BlueScore(),
+				mergeSetBlues:   len(ghostdagData.MergeSetBlues()),
+				mergeSetReds:    len(ghostdagData.MergeSetReds()),
+				sortedMergeSet:  fmt.Sprint(sortedMergeSet),
+				virtualParents:  len(virtualGHOSTDAGData.MergeSetBlues()) + len(virtualGHOSTDAGData.MergeSetReds()),
+				virtualBlueWork: virtualGHOSTDAGData.BlueWork().String(),
+			}
+			teardown(false)
+		}
+
+		expected := results[consensus.GHOSTDAGImplementationDefault]
+		if expected.mergeSetReds == 0 {
+			t.Fatalf("expected the merging block to have red blocks in its merge set")
+		}
+		for _, implementation := range implementations {
+			if *results[implementation] != *expected {
+				t.Fatalf("the %s implementation resulted in %+v, while the default implementation resulted in %+v",
+					implementation, *results[implementation], *expected)
+			}
+		}
+	})
+}
+
+func TestGHOSTDAGImplementationRequiresArchival(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		consensusConfig.IsArchival = false
+
+		for _, implementation := range []consensus.GHOSTDAGImplementation{
+			consensus.GHOSTDAGImplementationAlternative,
+			consensus.GHOSTDAGImplementationDifferential,
+		} {
+			consensusConfig.GHOSTDAGImplementation = implementation
+			_, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig,
+				"TestGHOSTDAGImplementationRequiresArchival")
+			if err == nil {
+				teardown(false)
+				t.Fatalf("Expected the %s GHOSTDAG implementation to be rejected on a non archival node", implementation)
+			}
+		}
+	})
+}
@@ -9,3 +9,7 @@ var ErrBlockNotInSelectedParentChain = errors.New("Block is not in selected pare
 // ErrReachedMaxTraversalAllowed is returned from AnticoneFromBlocks if `maxTraversalAllowed` was specified
 // and the traversal passed it
 var ErrReachedMaxTraversalAllowed = errors.New("Traversal searching for anticone passed the maxTraversalAllowed limit")
+
+// ErrFinalityConflictNotFound is returned from ResolveFinalityConflict if the given block isn't a violating block
+// of any unresolved finality conflict
+var ErrFinalityConflictNotFound = errors.New("Block is not a violating block of an unresolved finality conflict")
@@ -0,0 +1,82 @@
+package externalapi
+
+// BlockParentsDiagnostics explains whether a block with a given set of parents would pass the
+// merge set size limit and the bounded merge depth rule, and how the virtual parent selection
+// would trim the set if its blocks were the DAG tips
+type BlockParentsDiagnostics struct {
+	Parents        []*DomainHash
+	SelectedParent *DomainHash
+
+	// MergeDepthRoot is the merge depth root of a block with these parents. Reds that don't have
+	// it in their past must be in the past of a kosherizing blue
+	MergeDepthRoot *DomainHash
+
+	// MergeSetBlues and MergeSetReds are the merge set of a block with these parents, as colored by GHOSTDAG
+	MergeSetBlues []*DomainHash
+	MergeSetReds  []*DomainHash
+
+	MergeSetSize      uint64
+	MergeSetSizeLimit uint64
+
+	// KosherizingBlues are the merge set blues that have the merge depth root in their selected chain
+	KosherizingBlues []*DomainHash
+
+	// BoundedMergeViolations are the merge set reds that break the bounded merge depth rule
+	BoundedMergeViolations []*BoundedMergeViolation
+
+	// VirtualParentsSelection is how the virtual parent selection trims the parents when they are the DAG tips
+	VirtualParentsSelection *VirtualParentsSelectionTrace
+}
+
+// ExceedsMergeSetSizeLimit returns whether a block with these parents merges too many blocks
+func (bpd *BlockParentsDiagnostics) ExceedsMergeSetSizeLimit() bool {
+	return bpd.MergeSetSize > bpd.MergeSetSizeLimit
+}
+
+// ViolatesBoundedMergeDepth returns whether a block with these parents breaks the bounded merge depth rule
+func (bpd *BlockParentsDiagnostics) ViolatesBoundedMergeDepth() bool {
+	return len(bpd.BoundedMergeViolations) > 0
+}
+
+// BoundedMergeViolation is a red block that is merged below the merge depth root without being
+// in the past of any kosherizing blue
+type BoundedMergeViolation struct {
+	RedBlockHash *DomainHash
+
+	// NonKosherizingBluesInFuture are the merge set blues that have the red block in their past. Any of
+	// them would have kosherized it had it had the merge depth root in its selected chain
+	NonKosherizingBluesInFuture []*DomainHash
+
+	// ViolatingParents are the parents that have the red block in their past. Removing all of them
+	// removes the violation
+	ViolatingParents []*DomainHash
+}
+
+// VirtualParentsSelectionTrace describes the steps in which the virtual parent selection trims a set of tips
+type VirtualParentsSelectionTrace struct {
+	Tips []*DomainHash
+
+	// DisqualifiedSelectedParentCandidates are the candidates that were passed over as the selected parent
+	// since they aren't UTXO valid
+	DisqualifiedSelectedParentCandidates []*DomainHash
+	SelectedParent                       *DomainHash
+
+	// DroppedCandidates are the candidates that weren't considered at all since there were
+	// more than 3 times the maximum number of parents of them
+	DroppedCandidates []*DomainHash
+
+	// MergeSetSizeLimitReplacements are the candidates that would have increased the merge set
+	// beyond its limit, along with the ancestors proposed instead of them
+	MergeSetSizeLimitReplacements []*VirtualParentReplacement
+
+	// BoundedMergeBreakingParents are the parents that were removed since they break the bounded merge depth rule
+	BoundedMergeBreakingParents []*DomainHash
+
+	Parents []*DomainHash
+}
+
+// VirtualParentReplacement is a virtual parent candidate that was replaced by one of its ancestors
+type VirtualParentReplacement struct {
+	Candidate   *DomainHash
+	Replacement *DomainHash
+}
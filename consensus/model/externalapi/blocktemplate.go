@@ -6,6 +6,10 @@ type DomainBlockTemplate struct {
 	CoinbaseData         *DomainCoinbaseData
 	CoinbaseHasRedReward bool
 	IsNearlySynced       bool
+
+	// JobID identifies the virtual state and transactions the template was built with.
+	// It's kept when only the timestamp or the coinbase data of the template are refreshed.
+	JobID uint64
 }
 
 // Clone returns a clone of DomainBlockTemplate
@@ -15,5 +19,6 @@ func (bt *DomainBlockTemplate) Clone() *DomainBlockTemplate {
 		CoinbaseData:         bt.CoinbaseData.Clone(),
 		CoinbaseHasRedReward: bt.CoinbaseHasRedReward,
 		IsNearlySynced:       bt.IsNearlySynced,
+		JobID:                bt.JobID,
 	}
 }
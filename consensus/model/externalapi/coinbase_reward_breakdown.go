@@ -0,0 +1,59 @@
+package externalapi
+
+// CoinbaseRewardBreakdown explains how the coinbase transaction of a block
+// pays out the rewards of the blocks in its merge set
+type CoinbaseRewardBreakdown struct {
+	BlockHash *DomainHash
+
+	// BlueScore, Subsidy and CoinbaseData are the fields decoded from the block's coinbase payload
+	BlueScore    uint64
+	Subsidy      uint64
+	CoinbaseData *DomainCoinbaseData
+
+	// MergedBlueBlocks are paid each with an output to the script public key in their own coinbase payload
+	MergedBlueBlocks []*MergedBlockReward
+
+	// MergedRedBlocks are paid all together with a single output to the script public key of this block
+	MergedRedBlocks []*MergedBlockReward
+
+	Outputs []*CoinbaseOutputBreakdown
+}
+
+// TotalReward returns the sum of the values of all the coinbase outputs
+func (crb *CoinbaseRewardBreakdown) TotalReward() uint64 {
+	totalReward := uint64(0)
+	for _, output := range crb.Outputs {
+		totalReward += output.Value
+	}
+	return totalReward
+}
+
+// MergedBlockReward is the reward a block pays for one of the blocks it merges: the subsidy that
+// the merged block committed to in its coinbase payload plus the fees of its accepted transactions.
+// Merged blocks that weren't added to the DAA window of the merging block aren't rewarded.
+type MergedBlockReward struct {
+	BlockHash  *DomainHash
+	IsRewarded bool
+	Subsidy    uint64
+	Fees       uint64
+
+	// ScriptPublicKey is the script public key in the coinbase payload of the merged block
+	ScriptPublicKey *ScriptPublicKey
+}
+
+// TotalReward returns the subsidy plus the fees of the merged block
+func (mbr *MergedBlockReward) TotalReward() uint64 {
+	return mbr.Subsidy + mbr.Fees
+}
+
+// CoinbaseOutputBreakdown describes a single coinbase output and the merged blocks it pays for
+type CoinbaseOutputBreakdown struct {
+	Index           uint32
+	Value           uint64
+	ScriptPublicKey *ScriptPublicKey
+
+	// Address is the address of ScriptPublicKey, or an empty string if it isn't a standard script
+	Address string
+
+	RewardedBlocks []*DomainHash
+}
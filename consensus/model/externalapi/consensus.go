@@ -1,10 +1,16 @@
 package externalapi
 
+import "io"
+
 // Consensus maintains the current core state of the node
 type Consensus interface {
 	Init(skipAddingGenesis bool) error
 	BuildBlock(coinbaseData *DomainCoinbaseData, transactions []*DomainTransaction) (*DomainBlock, error)
 	BuildBlockTemplate(coinbaseData *DomainCoinbaseData, transactions []*DomainTransaction) (*DomainBlockTemplate, error)
+	BuildBlockTemplateFromCandidates(coinbaseData *DomainCoinbaseData, candidates []*DomainTransaction,
+		options *TransactionSelectionOptions) (*DomainBlockTemplate, error)
+	RefreshBlockTemplate(coinbaseData *DomainCoinbaseData) (*DomainBlockTemplate, error)
+	IsBlockTemplateJobStale(jobID uint64) bool
 	ValidateAndInsertBlock(block *DomainBlock, updateVirtual bool) error
 	ValidateAndInsertBlockWithTrustedData(block *BlockWithTrustedData, validateUTXO bool) error
 	ValidateTransactionAndPopulateWithConsensusData(transaction *DomainTransaction) error
@@ -12,6 +18,7 @@ type Consensus interface {
 	BuildPruningPointProof() (*PruningPointProof, error)
 	ValidatePruningPointProof(pruningPointProof *PruningPointProof) error
 	ApplyPruningPointProof(pruningPointProof *PruningPointProof) error
+	GetPruningPointProofLevelChains(pruningPointProof *PruningPointProof) ([]*PruningPointProofLevelChain, error)
 
 	GetBlock(blockHash *DomainHash) (*DomainBlock, bool, error)
 	GetBlockEvenIfHeaderOnly(blockHash *DomainHash) (*DomainBlock, error)
@@ -20,12 +27,16 @@ type Consensus interface {
 	GetBlockRelations(blockHash *DomainHash) (parents []*DomainHash, children []*DomainHash, err error)
 	GetBlockAcceptanceData(blockHash *DomainHash) (AcceptanceData, error)
 	GetBlocksAcceptanceData(blockHashes []*DomainHash) ([]AcceptanceData, error)
+	GetTransactionInclusionProof(blockHash *DomainHash, transactionID *DomainTransactionID) (*MerkleProof, error)
+	GetTransactionAcceptanceProof(blockHash *DomainHash, transactionID *DomainTransactionID) (*MerkleProof, error)
+	GetCoinbaseRewardBreakdown(blockHash *DomainHash) (*CoinbaseRewardBreakdown, error)
 
 	GetHashesBetween(lowHash, highHash *DomainHash, maxBlocks uint64) (hashes []*DomainHash, actualHighHash *DomainHash, err error)
 	GetAnticone(blockHash, contextHash *DomainHash, maxBlocks uint64) (hashes []*DomainHash, err error)
 	GetMissingBlockBodyHashes(highHash *DomainHash) ([]*DomainHash, error)
 	GetPruningPointUTXOs(expectedPruningPointHash *DomainHash, fromOutpoint *DomainOutpoint, limit int) ([]*OutpointAndUTXOEntryPair, error)
 	GetVirtualUTXOs(expectedVirtualParents []*DomainHash, fromOutpoint *DomainOutpoint, limit int) ([]*OutpointAndUTXOEntryPair, error)
+	GetVirtualUTXODiffFromPruningPoint(expectedPruningPointHash *DomainHash) (utxoDiff UTXODiff, virtualParents []*DomainHash, err error)
 	PruningPoint() (*DomainHash, error)
 	PruningPointHeaders() ([]BlockHeader, error)
 	PruningPointAndItsAnticone() ([]*DomainHash, error)
@@ -47,6 +58,7 @@ type Consensus interface {
 	GetHeadersSelectedTip() (*DomainHash, error)
 	Anticone(blockHash *DomainHash) ([]*DomainHash, error)
 	EstimateNetworkHashesPerSecond(startHash *DomainHash, windowSize int) (uint64, error)
+	EstimateNetworkHashesPerSecondSeries(startHash *DomainHash, options *HashrateEstimationOptions) ([]*HashrateEstimate, error)
 	PopulateMass(transaction *DomainTransaction)
 	ResolveVirtual(progressReportCallback func(uint64, uint64)) error
 	BlockDAAWindowHashes(blockHash *DomainHash) ([]*DomainHash, error)
@@ -56,4 +68,13 @@ type Consensus interface {
 	IsChainBlock(blockHash *DomainHash) (bool, error)
 	VirtualMergeDepthRoot() (*DomainHash, error)
 	IsNearlySynced() (bool, error)
+	ExportSnapshot(writer io.Writer) error
+	CheckIntegrity(shouldRepair bool) (*IntegrityReport, error)
+	GetReachabilityReindexStatistics() (*ReachabilityReindexStatistics, error)
+	GetPruningStatistics() (*PruningStatistics, error)
+	CompactReachabilityIntervals() (*ReachabilityCompactionResult, error)
+	SubscribeEvents(options *ConsensusEventSubscriptionOptions) (ConsensusEventSubscription, error)
+	FinalityConflicts() ([]*FinalityConflict, error)
+	ResolveFinalityConflict(violatingBlockHash *DomainHash, followViolatingChain bool) error
+	DiagnoseBlockParents(parents []*DomainHash) (*BlockParentsDiagnostics, error)
 }
@@ -0,0 +1,65 @@
+package externalapi
+
+// BackpressurePolicy decides what happens to an event when the buffer of a subscription is full
+type BackpressurePolicy uint8
+
+const (
+	// BackpressureDropNewest drops the event that doesn't fit in the buffer
+	BackpressureDropNewest BackpressurePolicy = iota
+
+	// BackpressureDropOldest drops the oldest buffered event to make room for the new one
+	BackpressureDropOldest
+
+	// BackpressureBlock waits until the subscriber makes room in the buffer. Events are delivered
+	// once the consensus lock is released, but before the call that raised them returns, so a slow
+	// subscriber with this policy slows down the callers that process blocks.
+	BackpressureBlock
+)
+
+func (policy BackpressurePolicy) String() string {
+	switch policy {
+	case BackpressureDropNewest:
+		return "DropNewest"
+	case BackpressureDropOldest:
+		return "DropOldest"
+	case BackpressureBlock:
+		return "Block"
+	}
+	return "Unknown"
+}
+
+// ConsensusEventSubscriptionOptions configures a ConsensusEventSubscription
+type ConsensusEventSubscriptionOptions struct {
+	// EventTypes are the types of events delivered to the subscription. If empty, all events are delivered.
+	EventTypes []ConsensusEventType
+
+	// BufferSize is the number of events that may wait for the subscriber
+	BufferSize int
+
+	BackpressurePolicy BackpressurePolicy
+}
+
+// SequencedConsensusEvent is a ConsensusEvent delivered to a subscription.
+// SequenceNumber counts the events that matched the filter of the subscription,
+// starting from 1, so a gap in the sequence means that events were dropped.
+type SequencedConsensusEvent struct {
+	SequenceNumber uint64
+	Event          ConsensusEvent
+}
+
+// ConsensusEventSubscription delivers the events raised by consensus to a single subscriber
+type ConsensusEventSubscription interface {
+	// Events returns the channel the events are delivered on. It's closed once the subscription is canceled.
+	Events() <-chan *SequencedConsensusEvent
+
+	// DroppedEventsCount returns the number of events that were dropped since the buffer was full
+	DroppedEventsCount() uint64
+
+	// HandleEvents passes the events of the subscription to handleEvent, in order, until the subscription
+	// is canceled or a handler returns an error. When there's a gap in the sequence, handleDroppedEvents
+	// is called with the number of events that were dropped before the next event is handled.
+	HandleEvents(handleEvent func(event ConsensusEvent) error, handleDroppedEvents func(droppedCount uint64) error) error
+
+	// Unsubscribe cancels the subscription
+	Unsubscribe()
+}
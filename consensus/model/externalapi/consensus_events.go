@@ -3,6 +3,38 @@ package externalapi
 // ConsensusEvent is an interface type that is implemented by all events raised by consensus
 type ConsensusEvent interface {
 	isConsensusEvent()
+	Type() ConsensusEventType
+}
+
+// ConsensusEventType identifies the type of a ConsensusEvent
+type ConsensusEventType uint8
+
+// These are the types of events raised by consensus
+const (
+	ConsensusEventTypeBlockAdded ConsensusEventType = iota
+	ConsensusEventTypeVirtualChangeSet
+	ConsensusEventTypeFinalityConflict
+	ConsensusEventTypeNewBlockTemplate
+	ConsensusEventTypeFinalityConflictResolved
+
+	// NumberOfConsensusEventTypes is the number of event types. It's not an event type by itself.
+	NumberOfConsensusEventTypes
+)
+
+var consensusEventTypeStrings = map[ConsensusEventType]string{
+	ConsensusEventTypeBlockAdded:               "BlockAdded",
+	ConsensusEventTypeVirtualChangeSet:         "VirtualChangeSet",
+	ConsensusEventTypeFinalityConflict:         "FinalityConflict",
+	ConsensusEventTypeNewBlockTemplate:         "NewBlockTemplate",
+	ConsensusEventTypeFinalityConflictResolved: "FinalityConflictResolved",
+}
+
+func (eventType ConsensusEventType) String() string {
+	eventTypeString, ok := consensusEventTypeStrings[eventType]
+	if !ok {
+		return "Unknown"
+	}
+	return eventTypeString
 }
 
 // BlockAdded is an event raised by consensus when a block was added to the dag
@@ -12,6 +44,9 @@ type BlockAdded struct {
 
 func (*BlockAdded) isConsensusEvent() {}
 
+// Type returns ConsensusEventTypeBlockAdded
+func (*BlockAdded) Type() ConsensusEventType { return ConsensusEventTypeBlockAdded }
+
 // VirtualChangeSet is an event raised by consensus when virtual changes
 type VirtualChangeSet struct {
 	VirtualSelectedParentChainChanges *SelectedChainPath
@@ -23,6 +58,67 @@ type VirtualChangeSet struct {
 
 func (*VirtualChangeSet) isConsensusEvent() {}
 
+// Type returns ConsensusEventTypeVirtualChangeSet
+func (*VirtualChangeSet) Type() ConsensusEventType { return ConsensusEventTypeVirtualChangeSet }
+
+// FinalityConflict is an event raised by consensus when a block that would otherwise
+// become the virtual selected parent is not in the selected chain of the finality point.
+// Consensus keeps a record of every conflict until it's resolved by the operator.
+type FinalityConflict struct {
+	// LowestViolatingBlockHash is the lowest block in the selected chain of ViolatingBlockHash that isn't
+	// in the selected chain of FinalityPointHash. It identifies the conflict as the violating chain grows.
+	LowestViolatingBlockHash *DomainHash
+
+	// ViolatingBlockHash is the tip of the chain that violates finality
+	ViolatingBlockHash *DomainHash
+
+	// FinalityPointHash is the finality point that isn't in the selected chain of ViolatingBlockHash
+	FinalityPointHash *DomainHash
+
+	// ViolatingBlockHashes are the blocks in the selected chain of ViolatingBlockHash from LowestViolatingBlockHash
+	// up to ViolatingBlockHash. The violating chain may be long, so it's only filled in when the conflicts are
+	// queried or resolved, and is nil in FinalityConflict events.
+	ViolatingBlockHashes []*DomainHash
+}
+
+func (*FinalityConflict) isConsensusEvent() {}
+
+// Type returns ConsensusEventTypeFinalityConflict
+func (*FinalityConflict) Type() ConsensusEventType { return ConsensusEventTypeFinalityConflict }
+
+// ID returns the lowest violating block, which identifies the conflict as the violating chain grows
+func (fc *FinalityConflict) ID() *DomainHash {
+	return fc.LowestViolatingBlockHash
+}
+
+// FinalityConflictResolved is an event raised by consensus when the operator resolves a finality conflict
+type FinalityConflictResolved struct {
+	Conflict *FinalityConflict
+
+	// FollowsViolatingChain is whether consensus switched to the violating chain, rather than
+	// keeping the selected chain of the finality point
+	FollowsViolatingChain bool
+}
+
+func (*FinalityConflictResolved) isConsensusEvent() {}
+
+// Type returns ConsensusEventTypeFinalityConflictResolved
+func (*FinalityConflictResolved) Type() ConsensusEventType {
+	return ConsensusEventTypeFinalityConflictResolved
+}
+
+// NewBlockTemplate is an event raised by consensus when the virtual changes and the last
+// block template is rebuilt over the new virtual. The same template is delivered to
+// every subscriber, so it must be cloned before it's modified.
+type NewBlockTemplate struct {
+	Template *DomainBlockTemplate
+}
+
+func (*NewBlockTemplate) isConsensusEvent() {}
+
+// Type returns ConsensusEventTypeNewBlockTemplate
+func (*NewBlockTemplate) Type() ConsensusEventType { return ConsensusEventTypeNewBlockTemplate }
+
 // SelectedChainPath is a path the of the selected chains between two blocks.
 type SelectedChainPath struct {
 	Added   []*DomainHash
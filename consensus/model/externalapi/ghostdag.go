@@ -65,3 +65,42 @@ func (bgd *BlockGHOSTDAGData) MergeSetReds() []*DomainHash {
 func (bgd *BlockGHOSTDAGData) BluesAnticoneSizes() map[DomainHash]KType {
 	return bgd.bluesAnticoneSizes
 }
+
+// If this doesn't compile, it means the type definition has been changed, so it's
+// an indication to update Equal accordingly.
+var _ = &BlockGHOSTDAGData{0, nil, nil, nil, nil, nil}
+
+// Equal returns whether bgd equals to other
+func (bgd *BlockGHOSTDAGData) Equal(other *BlockGHOSTDAGData) bool {
+	if bgd == nil || other == nil {
+		return bgd == other
+	}
+	if bgd.blueScore != other.blueScore {
+		return false
+	}
+	if (bgd.blueWork == nil || other.blueWork == nil) && bgd.blueWork != other.blueWork {
+		return false
+	}
+	if bgd.blueWork != nil && bgd.blueWork.Cmp(other.blueWork) != 0 {
+		return false
+	}
+	if !bgd.selectedParent.Equal(other.selectedParent) {
+		return false
+	}
+	if !HashesEqual(bgd.mergeSetBlues, other.mergeSetBlues) {
+		return false
+	}
+	if !HashesEqual(bgd.mergeSetReds, other.mergeSetReds) {
+		return false
+	}
+	if len(bgd.bluesAnticoneSizes) != len(other.bluesAnticoneSizes) {
+		return false
+	}
+	for hash, size := range bgd.bluesAnticoneSizes {
+		otherSize, ok := other.bluesAnticoneSizes[hash]
+		if !ok || size != otherSize {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package externalapi
+
+import (
+	"math/big"
+	"testing"
+)
+
+func initTestBlockGHOSTDAGDataForEqual() (*BlockGHOSTDAGData, []struct {
+	ghostdagData   *BlockGHOSTDAGData
+	expectedResult bool
+}) {
+	hash1 := NewDomainHashFromByteArray(&[DomainHashSize]byte{1})
+	hash2 := NewDomainHashFromByteArray(&[DomainHashSize]byte{2})
+	hash3 := NewDomainHashFromByteArray(&[DomainHashSize]byte{3})
+
+	base := NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+		map[DomainHash]KType{*hash1: 0, *hash2: 1})
+
+	tests := []struct {
+		ghostdagData   *BlockGHOSTDAGData
+		expectedResult bool
+	}{
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: true,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(6, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(8), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash2, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash2, hash1}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, nil,
+				map[DomainHash]KType{*hash1: 0, *hash2: 1}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0, *hash2: 2}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData: NewBlockGHOSTDAGData(5, big.NewInt(7), hash1, []*DomainHash{hash1, hash2}, []*DomainHash{hash3},
+				map[DomainHash]KType{*hash1: 0}),
+			expectedResult: false,
+		},
+		{
+			ghostdagData:   nil,
+			expectedResult: false,
+		},
+	}
+	return base, tests
+}
+
+func TestBlockGHOSTDAGData_Equal(t *testing.T) {
+	base, tests := initTestBlockGHOSTDAGDataForEqual()
+	for i, test := range tests {
+		result1 := base.Equal(test.ghostdagData)
+		if result1 != test.expectedResult {
+			t.Fatalf("Test #%d: Expected %t but got %t", i, test.expectedResult, result1)
+		}
+		result2 := test.ghostdagData.Equal(base)
+		if result2 != test.expectedResult {
+			t.Fatalf("Test #%d: Expected %t but got %t", i, test.expectedResult, result2)
+		}
+	}
+
+	var nilGHOSTDAGData *BlockGHOSTDAGData
+	if !nilGHOSTDAGData.Equal(nil) {
+		t.Fatalf("Expected two nil GHOSTDAG data to be equal")
+	}
+}
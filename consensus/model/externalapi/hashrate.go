@@ -0,0 +1,49 @@
+package externalapi
+
+// MaxHashrateEstimationSpan is the maximal number of blue blocks the windows of a hashrate
+// estimation series may span together. The whole span is gone over while the consensus is
+// locked, and the bodies of its blue blocks are read if a miner is selected.
+const MaxHashrateEstimationSpan = 100_000
+
+// HashrateEstimationOptions selects the windows of a hashrate estimation series. Options whose
+// windows span more than MaxHashrateEstimationSpan blue blocks are rejected.
+type HashrateEstimationOptions struct {
+	// WindowSize is the number of blue blocks every window spans
+	WindowSize uint64
+	// WindowStep is the number of blue blocks between the ends of consecutive windows.
+	// Zero selects WindowSize, so that the windows don't overlap.
+	WindowStep uint64
+	// WindowCount is the maximal number of windows to estimate. Fewer windows are
+	// returned if the selected chain runs out.
+	WindowCount int
+
+	// MinerScriptPublicKey optionally selects a miner, identified by the script public key
+	// in the coinbase payloads of its blocks, whose share of the blue work is estimated
+	MinerScriptPublicKey *ScriptPublicKey
+}
+
+// HashrateEstimate is a hashrate estimation over a window of the selected chain. The window
+// spans the blue blocks merged by the chain blocks after StartHash up to and including EndHash.
+type HashrateEstimate struct {
+	StartHash               *DomainHash
+	EndHash                 *DomainHash
+	StartTimeInMilliseconds int64
+	EndTimeInMilliseconds   int64
+
+	// BlueBlocks is the number of blue blocks in the window
+	BlueBlocks      uint64
+	HashesPerSecond uint64
+	// LowerBound and UpperBound are an approximate 95% confidence interval of HashesPerSecond.
+	// Finding blocks is a Poisson process, so the relative error of the estimation is about
+	// one over the square root of BlueBlocks.
+	LowerBound uint64
+	UpperBound uint64
+
+	// MinerBlueWorkShare is the share of the blue work of the window that was done by
+	// the miner selected in the options, or zero if no miner was selected
+	MinerBlueWorkShare float64
+	// UnknownMinerBlueWorkShare is the share of the blue work of the window that was done
+	// by blocks whose miner is unknown, since their bodies were pruned or never downloaded.
+	// The miner share may be up to this much higher. It's zero if no miner was selected.
+	UnknownMinerBlueWorkShare float64
+}
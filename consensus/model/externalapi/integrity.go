@@ -0,0 +1,61 @@
+package externalapi
+
+import "fmt"
+
+// IntegrityIssueKind identifies the store an IntegrityIssue was found in
+type IntegrityIssueKind uint8
+
+// These are the kinds of issues an integrity check may report
+const (
+	IntegrityIssueBlockRelations IntegrityIssueKind = iota
+	IntegrityIssueGHOSTDAGData
+	IntegrityIssueReachability
+	IntegrityIssueUTXOCommitment
+)
+
+var integrityIssueKindStrings = map[IntegrityIssueKind]string{
+	IntegrityIssueBlockRelations: "BlockRelations",
+	IntegrityIssueGHOSTDAGData:   "GHOSTDAGData",
+	IntegrityIssueReachability:   "Reachability",
+	IntegrityIssueUTXOCommitment: "UTXOCommitment",
+}
+
+func (kind IntegrityIssueKind) String() string {
+	kindString, ok := integrityIssueKindStrings[kind]
+	if !ok {
+		return "Unknown"
+	}
+	return kindString
+}
+
+// IntegrityIssue is a single inconsistency found by an integrity check
+type IntegrityIssue struct {
+	Kind        IntegrityIssueKind
+	BlockHash   *DomainHash
+	Description string
+	IsRepaired  bool
+}
+
+func (issue *IntegrityIssue) String() string {
+	repairedString := ""
+	if issue.IsRepaired {
+		repairedString = " (repaired)"
+	}
+	return fmt.Sprintf("[%s] %s: %s%s", issue.Kind, issue.BlockHash, issue.Description, repairedString)
+}
+
+// IntegrityReport is the result of an integrity check over the consensus stores
+type IntegrityReport struct {
+	CheckedChainBlocks uint64
+	Issues             []*IntegrityIssue
+}
+
+// IsConsistent returns whether no unrepaired issues were found
+func (report *IntegrityReport) IsConsistent() bool {
+	for _, issue := range report.Issues {
+		if !issue.IsRepaired {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package externalapi
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// merkleProofVersion is the layout version of serialized merkle proofs
+const merkleProofVersion = 1
+
+// MaxMerkleProofDepth is the maximal number of siblings in a merkle proof. A leaf index
+// is a uint32, so no tree is deeper than that.
+const MaxMerkleProofDepth = 32
+
+// MerkleProof proves that a leaf is included in a merkle tree. Siblings are ordered
+// from the leaf up to the root, and a missing right sibling is represented by the
+// zero hash, the same way the merkle root calculation pads the tree.
+type MerkleProof struct {
+	LeafIndex uint32
+	Siblings  []*DomainHash
+}
+
+// If this doesn't compile, it means the type definition has been changed, so it's
+// an indication to update Equal and Clone accordingly.
+var _ = MerkleProof{0, []*DomainHash{}}
+
+// Equal returns whether proof equals to other
+func (proof *MerkleProof) Equal(other *MerkleProof) bool {
+	if proof == nil || other == nil {
+		return proof == other
+	}
+	return proof.LeafIndex == other.LeafIndex && HashesEqual(proof.Siblings, other.Siblings)
+}
+
+// Clone returns a clone of MerkleProof
+func (proof *MerkleProof) Clone() *MerkleProof {
+	return &MerkleProof{
+		LeafIndex: proof.LeafIndex,
+		Siblings:  CloneHashes(proof.Siblings),
+	}
+}
+
+// Serialize serializes the proof as a version byte, the little-endian leaf index,
+// the number of siblings and the siblings themselves
+func (proof *MerkleProof) Serialize() []byte {
+	serialized := make([]byte, 0, 1+4+1+len(proof.Siblings)*DomainHashSize)
+	serialized = append(serialized, merkleProofVersion)
+	serialized = binary.LittleEndian.AppendUint32(serialized, proof.LeafIndex)
+	serialized = append(serialized, uint8(len(proof.Siblings)))
+	for _, sibling := range proof.Siblings {
+		serialized = append(serialized, sibling.ByteSlice()...)
+	}
+	return serialized
+}
+
+// DeserializeMerkleProof deserializes a proof serialized by MerkleProof.Serialize
+func DeserializeMerkleProof(serialized []byte) (*MerkleProof, error) {
+	const headerLength = 1 + 4 + 1
+	if len(serialized) < headerLength {
+		return nil, errors.Errorf("merkle proof of %d bytes is too short", len(serialized))
+	}
+	if serialized[0] != merkleProofVersion {
+		return nil, errors.Errorf("unknown merkle proof version %d", serialized[0])
+	}
+	leafIndex := binary.LittleEndian.Uint32(serialized[1:5])
+	siblingCount := int(serialized[5])
+	if siblingCount > MaxMerkleProofDepth {
+		return nil, errors.Errorf("merkle proof has %d siblings, more than the maximum of %d",
+			siblingCount, MaxMerkleProofDepth)
+	}
+	if len(serialized) != headerLength+siblingCount*DomainHashSize {
+		return nil, errors.Errorf("merkle proof with %d siblings has an unexpected length of %d bytes",
+			siblingCount, len(serialized))
+	}
+
+	siblings := make([]*DomainHash, siblingCount)
+	for i := range siblings {
+		offset := headerLength + i*DomainHashSize
+		var hashArray [DomainHashSize]byte
+		copy(hashArray[:], serialized[offset:offset+DomainHashSize])
+		siblings[i] = NewDomainHashFromByteArray(&hashArray)
+	}
+	return &MerkleProof{LeafIndex: leafIndex, Siblings: siblings}, nil
+}
@@ -0,0 +1,22 @@
+package externalapi
+
+// PruningStatistics describes the block data deleted by pruning since the consensus was
+// instantiated. Sizes are the sizes of the deleted records, so the disk space actually
+// reclaimed depends on the compaction of the database.
+type PruningStatistics struct {
+	// Prunings is the number of times the blocks below a new pruning point were pruned
+	Prunings uint64
+	// PrunedBlocks is the number of blocks whose data was deleted
+	PrunedBlocks uint64
+	// ReclaimedBytes is the total size of the deleted block bodies and acceptance data, and
+	// LastReclaimedBytes is the part of it that was deleted by the latest pruning
+	ReclaimedBytes     uint64
+	LastReclaimedBytes uint64
+
+	// RetainedBodies and RetainedBytes are the number and the total size of the bodies the
+	// retention policy kept below the pruning point, as of the latest pruning
+	RetainedBodies uint64
+	RetainedBytes  uint64
+	// BudgetEvictions is the number of retained bodies that were deleted to fit the disk budget
+	BudgetEvictions uint64
+}
@@ -1,6 +1,33 @@
 package externalapi
 
+import "math/big"
+
 // PruningPointProof is the data structure holding the pruning point proof
 type PruningPointProof struct {
 	Headers [][]BlockHeader
 }
+
+// PruningPointProofLevelChain is the selected chain of a single level of a pruning point proof,
+// as computed by running GHOSTDAG over the headers of that level alone
+type PruningPointProofLevelChain struct {
+	Level       int
+	HeaderCount int
+	// Chain goes from the lowest chain block of the level up to its selected tip
+	Chain []*PruningPointProofChainBlock
+}
+
+// PruningPointProofChainBlock is a chain block of a pruning point proof level, along with
+// its GHOSTDAG data within the level
+type PruningPointProofChainBlock struct {
+	Hash      *DomainHash
+	BlueScore uint64
+	BlueWork  *big.Int
+}
+
+// SelectedTip returns the highest block of the chain, or nil if the level is empty
+func (levelChain *PruningPointProofLevelChain) SelectedTip() *PruningPointProofChainBlock {
+	if len(levelChain.Chain) == 0 {
+		return nil
+	}
+	return levelChain.Chain[len(levelChain.Chain)-1]
+}
@@ -0,0 +1,51 @@
+package externalapi
+
+import "time"
+
+// ReachabilityReindexStatistics describes the work spent on reindexing the reachability
+// tree since the consensus was started. Reindexes are counted when they are staged, so
+// the reindexes of blocks that were eventually rejected are counted as well.
+type ReachabilityReindexStatistics struct {
+	ReindexSlack  uint64
+	ReindexWindow uint64
+
+	// Reindexes counts the reindexes triggered by blocks whose reachability
+	// tree parent had no interval space left
+	Reindexes uint64
+	// SlackReclaims counts the reindexes that were resolved by reclaiming the
+	// slack of the chain blocks below the reindex root
+	SlackReclaims uint64
+	// TotalReindexDepth and MaxReindexDepth count the tree levels that were climbed
+	// in order to find an ancestor with enough interval space
+	TotalReindexDepth uint64
+	MaxReindexDepth   uint64
+	// TotalReindexedBlocks and MaxReindexedBlocks count the intervals reallocated by reindexes
+	TotalReindexedBlocks uint64
+	MaxReindexedBlocks   uint64
+	TotalReindexDuration time.Duration
+	MaxReindexDuration   time.Duration
+
+	// ReindexRootUpdates counts the moves of the reindex root, and ConcentratedBlocks
+	// counts the intervals reallocated in order to concentrate the interval space
+	// towards the new reindex roots
+	ReindexRootUpdates uint64
+	ConcentratedBlocks uint64
+}
+
+// AverageReindexDepth returns the average number of tree levels climbed by a reindex
+func (s *ReachabilityReindexStatistics) AverageReindexDepth() float64 {
+	if s.Reindexes == 0 {
+		return 0
+	}
+	return float64(s.TotalReindexDepth) / float64(s.Reindexes)
+}
+
+// ReachabilityCompactionResult describes a rebuild of the intervals of the reachability tree
+type ReachabilityCompactionResult struct {
+	// Blocks is the number of blocks whose intervals were rebuilt
+	Blocks uint64
+	// RemovedTreeChildren and RemovedFutureCoveringSetEntries count the references
+	// to blocks without reachability data, such as pruned blocks, that were removed
+	RemovedTreeChildren             uint64
+	RemovedFutureCoveringSetEntries uint64
+}
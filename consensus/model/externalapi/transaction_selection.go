@@ -0,0 +1,42 @@
+package externalapi
+
+// TransactionSelectionMode decides how the transactions of a new block are chosen out of the candidates
+type TransactionSelectionMode uint8
+
+const (
+	// TransactionSelectionByFeeRate deterministically picks the candidates with the highest fee per
+	// mass unit first, skipping the ones that don't fit in the remaining mass
+	TransactionSelectionByFeeRate TransactionSelectionMode = iota
+
+	// TransactionSelectionRandomizedWeighted picks the candidates at random, with a probability that
+	// grows with their fee rate, so that miners working on the same candidates don't all pick the same
+	// transactions
+	TransactionSelectionRandomizedWeighted
+)
+
+func (mode TransactionSelectionMode) String() string {
+	switch mode {
+	case TransactionSelectionByFeeRate:
+		return "ByFeeRate"
+	case TransactionSelectionRandomizedWeighted:
+		return "RandomizedWeighted"
+	}
+	return "Unknown"
+}
+
+// TransactionSelectionOptions configures the selection of the transactions of a new block
+type TransactionSelectionOptions struct {
+	Mode TransactionSelectionMode
+
+	// MaxMass is the total mass the selected transactions may have. If zero, the max
+	// block mass minus the mass of the coinbase transaction is used.
+	MaxMass uint64
+}
+
+// TransactionSelection is the set of transactions chosen for a new block
+type TransactionSelection struct {
+	// Transactions are sorted by subnetwork, as required in a block
+	Transactions []*DomainTransaction
+	TotalMass    uint64
+	TotalFees    uint64
+}
@@ -8,5 +8,6 @@ type AcceptanceDataStore interface {
 	Stage(stagingArea *StagingArea, blockHash *externalapi.DomainHash, acceptanceData externalapi.AcceptanceData)
 	IsStaged(stagingArea *StagingArea) bool
 	Get(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (externalapi.AcceptanceData, error)
+	AcceptanceDataSize(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (int, error)
 	Delete(stagingArea *StagingArea, blockHash *externalapi.DomainHash)
 }
@@ -9,6 +9,7 @@ type BlockStore interface {
 	IsStaged(stagingArea *StagingArea) bool
 	Block(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (*externalapi.DomainBlock, error)
 	HasBlock(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (bool, error)
+	BlockSize(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (int, error)
 	Blocks(dbContext DBReader, stagingArea *StagingArea, blockHashes []*externalapi.DomainHash) ([]*externalapi.DomainBlock, error)
 	Delete(stagingArea *StagingArea, blockHash *externalapi.DomainHash)
 	Count(stagingArea *StagingArea) uint64
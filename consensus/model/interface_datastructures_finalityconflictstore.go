@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// FinalityConflictStore represents a store for the finality conflicts detected by consensus
+// and the way the operator resolved them. Conflicts are identified by FinalityConflict.ID.
+// Every violating block is kept along with the ID of its conflict, so that a growing violating
+// chain only needs its new blocks to be stored.
+type FinalityConflictStore interface {
+	Store
+	IsStaged(stagingArea *StagingArea) bool
+	StageFinalityConflict(stagingArea *StagingArea, conflict *externalapi.FinalityConflict)
+	StageViolatingBlocks(stagingArea *StagingArea, conflictID *externalapi.DomainHash, violatingBlockHashes []*externalapi.DomainHash)
+	StageResolution(stagingArea *StagingArea, conflictID *externalapi.DomainHash, followsViolatingChain bool)
+	FinalityConflict(dbContext DBReader, stagingArea *StagingArea, conflictID *externalapi.DomainHash) (*externalapi.FinalityConflict, error)
+	FinalityConflicts(dbContext DBReader, stagingArea *StagingArea) ([]*externalapi.FinalityConflict, error)
+	ConflictIDOfViolatingBlock(dbContext DBReader, stagingArea *StagingArea, blockHash *externalapi.DomainHash) (*externalapi.DomainHash, error)
+	IsResolved(dbContext DBReader, stagingArea *StagingArea, conflictID *externalapi.DomainHash) (bool, error)
+	FollowedViolatingChains(dbContext DBReader, stagingArea *StagingArea) ([]*externalapi.DomainHash, error)
+}
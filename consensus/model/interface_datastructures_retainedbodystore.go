@@ -0,0 +1,20 @@
+package model
+
+// RetainedBodyStore represents a store of the retention decisions of the block bodies
+// kept below the pruning point
+type RetainedBodyStore interface {
+	Store
+	Stage(stagingArea *StagingArea, retainedBody *RetainedBody)
+	IsStaged(stagingArea *StagingArea) bool
+	RetainedBodies(dbContext DBReader, stagingArea *StagingArea, paysRetainedScript bool) (RetainedBodyIterator, error)
+	Totals(dbContext DBReader, stagingArea *StagingArea) (count uint64, size uint64, err error)
+	Delete(stagingArea *StagingArea, retainedBody *RetainedBody)
+}
+
+// RetainedBodyIterator is an iterator over retention decisions, from the lowest blue score up
+type RetainedBodyIterator interface {
+	First() bool
+	Next() bool
+	Get() (*RetainedBody, error)
+	Close() error
+}
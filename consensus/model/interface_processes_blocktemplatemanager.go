@@ -0,0 +1,14 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// BlockTemplateManager keeps the last block template built over the virtual,
+// and refreshes it cheaply for as long as the virtual doesn't change
+type BlockTemplateManager interface {
+	BuildBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData,
+		transactions []*externalapi.DomainTransaction) (*externalapi.DomainBlockTemplate, error)
+	RefreshBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData) (*externalapi.DomainBlockTemplate, error)
+	HasBlockTemplate() bool
+	InvalidateBlockTemplate()
+	IsJobStale(jobID uint64) bool
+}
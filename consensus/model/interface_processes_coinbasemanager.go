@@ -9,4 +9,5 @@ type CoinbaseManager interface {
 		coinbaseData *externalapi.DomainCoinbaseData) (expectedTransaction *externalapi.DomainTransaction, hasRedReward bool, err error)
 	CalcBlockSubsidy(stagingArea *StagingArea, blockHash *externalapi.DomainHash) (uint64, error)
 	ExtractCoinbaseDataBlueScoreAndSubsidy(coinbaseTx *externalapi.DomainTransaction) (blueScore uint64, coinbaseData *externalapi.DomainCoinbaseData, subsidy uint64, err error)
+	CoinbaseRewardBreakdown(stagingArea *StagingArea, blockHash *externalapi.DomainHash) (*externalapi.CoinbaseRewardBreakdown, error)
 }
@@ -0,0 +1,13 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// ConsensusEventsManager dispatches the events raised by consensus to its subscribers
+type ConsensusEventsManager interface {
+	Publish(event externalapi.ConsensusEvent)
+	DeliverPublishedEvents()
+	StageEvent(stagingArea *StagingArea, event externalapi.ConsensusEvent)
+	PublishStagedEvents(stagingArea *StagingArea)
+	HasSubscribers(eventType externalapi.ConsensusEventType) bool
+	Subscribe(options *externalapi.ConsensusEventSubscriptionOptions) (externalapi.ConsensusEventSubscription, error)
+}
@@ -14,4 +14,7 @@ type ConsensusStateManager interface {
 	RecoverUTXOIfRequired() error
 	ReverseUTXODiffs(tipHash *externalapi.DomainHash, reversalData *UTXODiffReversalData) error
 	ResolveVirtual(maxBlocksToResolve uint64) (*externalapi.VirtualChangeSet, bool, error)
+	FinalityConflicts(stagingArea *StagingArea) ([]*externalapi.FinalityConflict, error)
+	ResolveFinalityConflict(stagingArea *StagingArea, violatingBlockHash *externalapi.DomainHash, followViolatingChain bool) (*externalapi.FinalityConflict, error)
+	DiagnoseBlockParents(stagingArea *StagingArea, parents []*externalapi.DomainHash) (*externalapi.BlockParentsDiagnostics, error)
 }
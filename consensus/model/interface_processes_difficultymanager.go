@@ -10,5 +10,8 @@ type DifficultyManager interface {
 	StageDAADataAndReturnRequiredDifficulty(stagingArea *StagingArea, blockHash *externalapi.DomainHash, isBlockWithTrustedData bool) (uint32, error)
 	RequiredDifficulty(stagingArea *StagingArea, blockHash *externalapi.DomainHash) (uint32, error)
 	EstimateNetworkHashesPerSecond(startHash *externalapi.DomainHash, windowSize int) (uint64, error)
+	EstimateNetworkHashesPerSecondSeries(startHash *externalapi.DomainHash, options *externalapi.HashrateEstimationOptions,
+		isMinerBlock func(blockHash *externalapi.DomainHash) (isMinerBlock bool, isKnown bool, err error)) (
+		[]*externalapi.HashrateEstimate, error)
 	GenesisDifficulty() uint32
 }
@@ -0,0 +1,12 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// GHOSTDAGBatchManager resolves the GHOSTDAG data of many blocks at once, such as the
+// headers received during header-first sync
+type GHOSTDAGBatchManager interface {
+	// GHOSTDAGBatch stages the GHOSTDAG data of the given blocks and adds them to the
+	// reachability tree. The blocks must be sorted topologically and their relations
+	// must already be staged.
+	GHOSTDAGBatch(stagingArea *StagingArea, blockHashes []*externalapi.DomainHash) error
+}
@@ -0,0 +1,8 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// IntegrityChecker audits the consistency of the consensus stores along the virtual selected parent chain
+type IntegrityChecker interface {
+	CheckIntegrity(stagingArea *StagingArea, shouldRepair bool) (*externalapi.IntegrityReport, error)
+}
@@ -7,4 +7,5 @@ type PruningProofManager interface {
 	BuildPruningPointProof(stagingArea *StagingArea) (*externalapi.PruningPointProof, error)
 	ValidatePruningPointProof(pruningPointProof *externalapi.PruningPointProof) error
 	ApplyPruningPointProof(pruningPointProof *externalapi.PruningPointProof) error
+	PruningPointProofLevelChains(pruningPointProof *externalapi.PruningPointProof) ([]*externalapi.PruningPointProofLevelChain, error)
 }
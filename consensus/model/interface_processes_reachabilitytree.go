@@ -11,4 +11,6 @@ type ReachabilityManager interface {
 	IsDAGAncestorOf(stagingArea *StagingArea, blockHashA *externalapi.DomainHash, blockHashB *externalapi.DomainHash) (bool, error)
 	UpdateReindexRoot(stagingArea *StagingArea, selectedTip *externalapi.DomainHash) error
 	FindNextAncestor(stagingArea *StagingArea, descendant, ancestor *externalapi.DomainHash) (*externalapi.DomainHash, error)
+	CompactIntervals(stagingArea *StagingArea, root *externalapi.DomainHash) (*externalapi.ReachabilityCompactionResult, error)
+	ReindexStatistics() *externalapi.ReachabilityReindexStatistics
 }
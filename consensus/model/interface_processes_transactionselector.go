@@ -0,0 +1,9 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// TransactionSelector chooses the transactions of a new block out of a set of candidates
+type TransactionSelector interface {
+	SelectTransactions(candidates []*externalapi.DomainTransaction,
+		options *externalapi.TransactionSelectionOptions) (*externalapi.TransactionSelection, error)
+}
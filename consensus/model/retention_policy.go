@@ -0,0 +1,43 @@
+package model
+
+import "github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+
+// RetentionPolicy selects which block bodies a non-archival node keeps below the pruning point.
+// Headers are always kept, and so is the data the GHOSTDAG and reachability of the DAG rely on.
+// The zero value keeps headers only: the bodies, and the data derived from them, of every block
+// below the pruning point are deleted.
+type RetentionPolicy struct {
+	// PruningPeriods keeps the bodies of the blocks whose blue score is less than this many
+	// pruning periods below the blue score of the pruning point. A pruning period is the
+	// finality interval the pruning point advances by.
+	PruningPeriods uint64
+
+	// ScriptPublicKeys keeps the bodies of the blocks that have a transaction output paying
+	// one of these scripts
+	ScriptPublicKeys []*externalapi.ScriptPublicKey
+
+	// DiskBudgetBytes limits the total size of the bodies kept by the rules above. Once the
+	// budget is exceeded, the bodies kept only by PruningPeriods are deleted first, from the
+	// lowest blue score up. If that isn't enough, the bodies kept by ScriptPublicKeys are
+	// deleted too, from the lowest blue score up, so the budget takes precedence over
+	// ScriptPublicKeys. Zero means no limit.
+	DiskBudgetBytes uint64
+}
+
+// RetainsBodies returns whether the policy keeps any bodies below the pruning point
+func (rp *RetentionPolicy) RetainsBodies() bool {
+	return rp.PruningPeriods > 0 || len(rp.ScriptPublicKeys) > 0
+}
+
+// RetainedBody is the retention decision taken for a block body once its block was pruned.
+// It's kept for as long as the body is, so that the body doesn't have to be read again
+// whenever the pruning point moves.
+type RetainedBody struct {
+	BlockHash *externalapi.DomainHash
+	BlueScore uint64
+	Size      uint64
+
+	// PaysRetainedScript is whether the block pays one of the script public keys of the policy,
+	// in which case the body is kept regardless of its blue score
+	PaysRetainedScript bool
+}
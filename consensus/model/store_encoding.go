@@ -0,0 +1,25 @@
+package model
+
+// StoreEncoding selects how a store encodes the records it writes. Records of
+// either encoding are always readable, so the encoding of a store can be changed
+// without rewriting the records it already holds.
+type StoreEncoding uint8
+
+const (
+	// StoreEncodingProtobuf encodes records as the protobuf messages of database/serialization
+	StoreEncodingProtobuf StoreEncoding = iota
+
+	// StoreEncodingBinary encodes records with the compact fixed-layout codecs of
+	// database/binaryserialization, which are cheaper to decode
+	StoreEncodingBinary
+)
+
+func (se StoreEncoding) String() string {
+	switch se {
+	case StoreEncodingProtobuf:
+		return "protobuf"
+	case StoreEncodingBinary:
+		return "binary"
+	}
+	return "unknown"
+}
@@ -2,7 +2,6 @@ package blockbuilder
 
 import (
 	"math/big"
-	"sort"
 
 	"github.com/pkg/errors"
 	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
@@ -12,7 +11,6 @@ import (
 	"github.com/karlsen-network/karlsend/v2/util/mstime"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
-	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/merkle"
 )
@@ -308,22 +306,7 @@ func (bb *blockBuilder) newBlockAcceptedIDMerkleRoot(stagingArea *model.StagingA
 }
 
 func (bb *blockBuilder) calculateAcceptedIDMerkleRoot(acceptanceData externalapi.AcceptanceData) (*externalapi.DomainHash, error) {
-	var acceptedTransactions []*externalapi.DomainTransaction
-	for _, blockAcceptanceData := range acceptanceData {
-		for _, transactionAcceptance := range blockAcceptanceData.TransactionAcceptanceData {
-			if !transactionAcceptance.IsAccepted {
-				continue
-			}
-			acceptedTransactions = append(acceptedTransactions, transactionAcceptance.Transaction)
-		}
-	}
-	sort.Slice(acceptedTransactions, func(i, j int) bool {
-		acceptedTransactionIID := consensushashing.TransactionID(acceptedTransactions[i])
-		acceptedTransactionJID := consensushashing.TransactionID(acceptedTransactions[j])
-		return acceptedTransactionIID.Less(acceptedTransactionJID)
-	})
-
-	return merkle.CalculateIDMerkleRoot(acceptedTransactions), nil
+	return merkle.CalculateAcceptedIDMerkleRoot(acceptanceData), nil
 }
 
 func (bb *blockBuilder) newBlockUTXOCommitment(stagingArea *model.StagingArea) (*externalapi.DomainHash, error) {
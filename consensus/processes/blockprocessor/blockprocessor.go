@@ -18,17 +18,18 @@ type blockProcessor struct {
 	databaseContext    model.DBManager
 	blockLogger        *blocklogger.BlockLogger
 
-	consensusStateManager model.ConsensusStateManager
-	pruningManager        model.PruningManager
-	blockValidator        model.BlockValidator
-	dagTopologyManager    model.DAGTopologyManager
-	reachabilityManager   model.ReachabilityManager
-	difficultyManager     model.DifficultyManager
-	pastMedianTimeManager model.PastMedianTimeManager
-	coinbaseManager       model.CoinbaseManager
-	headerTipsManager     model.HeadersSelectedTipManager
-	syncManager           model.SyncManager
-	finalityManager       model.FinalityManager
+	consensusStateManager  model.ConsensusStateManager
+	pruningManager         model.PruningManager
+	blockValidator         model.BlockValidator
+	dagTopologyManager     model.DAGTopologyManager
+	reachabilityManager    model.ReachabilityManager
+	difficultyManager      model.DifficultyManager
+	pastMedianTimeManager  model.PastMedianTimeManager
+	coinbaseManager        model.CoinbaseManager
+	headerTipsManager      model.HeadersSelectedTipManager
+	syncManager            model.SyncManager
+	finalityManager        model.FinalityManager
+	consensusEventsManager model.ConsensusEventsManager
 
 	acceptanceDataStore                 model.AcceptanceDataStore
 	blockStore                          model.BlockStore
@@ -67,6 +68,7 @@ func New(
 	coinbaseManager model.CoinbaseManager,
 	headerTipsManager model.HeadersSelectedTipManager,
 	syncManager model.SyncManager,
+	consensusEventsManager model.ConsensusEventsManager,
 
 	acceptanceDataStore model.AcceptanceDataStore,
 	blockStore model.BlockStore,
@@ -87,20 +89,21 @@ func New(
 ) model.BlockProcessor {
 
 	return &blockProcessor{
-		genesisHash:           genesisHash,
-		targetTimePerBlock:    targetTimePerBlock,
-		maxBlockLevel:         maxBlockLevel,
-		databaseContext:       databaseContext,
-		blockLogger:           blocklogger.NewBlockLogger(),
-		pruningManager:        pruningManager,
-		blockValidator:        blockValidator,
-		dagTopologyManager:    dagTopologyManager,
-		reachabilityManager:   reachabilityManager,
-		difficultyManager:     difficultyManager,
-		pastMedianTimeManager: pastMedianTimeManager,
-		coinbaseManager:       coinbaseManager,
-		headerTipsManager:     headerTipsManager,
-		syncManager:           syncManager,
+		genesisHash:            genesisHash,
+		targetTimePerBlock:     targetTimePerBlock,
+		maxBlockLevel:          maxBlockLevel,
+		databaseContext:        databaseContext,
+		blockLogger:            blocklogger.NewBlockLogger(),
+		pruningManager:         pruningManager,
+		blockValidator:         blockValidator,
+		dagTopologyManager:     dagTopologyManager,
+		reachabilityManager:    reachabilityManager,
+		difficultyManager:      difficultyManager,
+		pastMedianTimeManager:  pastMedianTimeManager,
+		coinbaseManager:        coinbaseManager,
+		headerTipsManager:      headerTipsManager,
+		syncManager:            syncManager,
+		consensusEventsManager: consensusEventsManager,
 
 		consensusStateManager:               consensusStateManager,
 		acceptanceDataStore:                 acceptanceDataStore,
@@ -162,6 +162,7 @@ func (bp *blockProcessor) validateAndInsertBlock(stagingArea *model.StagingArea,
 	if err != nil {
 		return nil, externalapi.StatusInvalid, err
 	}
+	bp.consensusEventsManager.PublishStagedEvents(stagingArea)
 
 	if reversalData != nil {
 		err = bp.consensusStateManager.ReverseUTXODiffs(blockHash, reversalData)
@@ -0,0 +1,167 @@
+package blocktemplatemanager
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/karlsen-network/karlsend/v2/util/mstime"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/coinbasemanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/merkle"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/transactionhelper"
+)
+
+type blockTemplateManager struct {
+	blockBuilder                            model.BlockBuilder
+	coinbasePayloadScriptPublicKeyMaxLength uint8
+
+	template     *externalapi.DomainBlockTemplate
+	transactions []*externalapi.DomainTransaction
+
+	// isTemplateValid is unset once the virtual changes, since the cached
+	// template then no longer points to the current virtual parents
+	isTemplateValid bool
+
+	lastJobID uint64
+
+	// firstJobIDOfCurrentVirtual is the first job ID given to a template
+	// built over the current virtual. Every earlier job is stale.
+	firstJobIDOfCurrentVirtual uint64
+}
+
+// New instantiates a new BlockTemplateManager
+func New(blockBuilder model.BlockBuilder, coinbasePayloadScriptPublicKeyMaxLength uint8) model.BlockTemplateManager {
+	return &blockTemplateManager{
+		blockBuilder:                            blockBuilder,
+		coinbasePayloadScriptPublicKeyMaxLength: coinbasePayloadScriptPublicKeyMaxLength,
+
+		firstJobIDOfCurrentVirtual: 1,
+	}
+}
+
+// BuildBlockTemplate builds a new block template over the virtual with the given coinbase data
+// and transactions, and caches it for the following calls to RefreshBlockTemplate
+func (btm *blockTemplateManager) BuildBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData,
+	transactions []*externalapi.DomainTransaction) (*externalapi.DomainBlockTemplate, error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "BuildBlockTemplate")
+	defer onEnd()
+
+	block, hasRedReward, err := btm.blockBuilder.BuildBlock(coinbaseData, transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	btm.lastJobID++
+	btm.template = &externalapi.DomainBlockTemplate{
+		Block:                block,
+		CoinbaseData:         coinbaseData,
+		CoinbaseHasRedReward: hasRedReward,
+		JobID:                btm.lastJobID,
+	}
+	btm.transactions = transactions
+	btm.isTemplateValid = true
+
+	log.Debugf("Built block template job %d with %d transactions", btm.lastJobID, len(transactions))
+
+	return btm.template.Clone(), nil
+}
+
+// RefreshBlockTemplate returns the cached block template with the given coinbase data and an updated
+// timestamp. If coinbaseData is nil, the coinbase data of the cached template is kept.
+// If the virtual changed since the cached template was built, a new template is built with the
+// same transactions, except for the ones that are no longer valid over the new virtual.
+func (btm *blockTemplateManager) RefreshBlockTemplate(
+	coinbaseData *externalapi.DomainCoinbaseData) (*externalapi.DomainBlockTemplate, error) {
+
+	if btm.template == nil {
+		return nil, errors.New("no block template was built yet")
+	}
+	if coinbaseData == nil {
+		coinbaseData = btm.template.CoinbaseData
+	}
+
+	if !btm.isTemplateValid {
+		return btm.rebuildBlockTemplate(coinbaseData)
+	}
+
+	template := btm.template.Clone()
+	if !template.CoinbaseData.Equal(coinbaseData) {
+		err := btm.modifyCoinbase(template, coinbaseData)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mutableHeader := template.Block.Header.ToMutable()
+	newTimestamp := mstime.Now().UnixMilliseconds()
+	if newTimestamp >= mutableHeader.TimeInMilliseconds() {
+		// Only if new time stamp is later than current, update the header. Otherwise,
+		// we keep the previous time as built by internal consensus median time logic
+		mutableHeader.SetTimeInMilliseconds(newTimestamp)
+	}
+	template.Block.Header = mutableHeader.ToImmutable()
+
+	btm.template = template
+	return template.Clone(), nil
+}
+
+// modifyCoinbase replaces the coinbase data of the given template. The accepted ID merkle root
+// and the UTXO commitment don't depend on the coinbase, so only the hash merkle root is recalculated.
+func (btm *blockTemplateManager) modifyCoinbase(template *externalapi.DomainBlockTemplate,
+	coinbaseData *externalapi.DomainCoinbaseData) error {
+
+	coinbaseTransaction := template.Block.Transactions[transactionhelper.CoinbaseTransactionIndex]
+	newPayload, err := coinbasemanager.ModifyCoinbasePayload(
+		coinbaseTransaction.Payload, coinbaseData, btm.coinbasePayloadScriptPublicKeyMaxLength)
+	if err != nil {
+		return err
+	}
+	coinbaseTransaction.Payload = newPayload
+	if template.CoinbaseHasRedReward {
+		// The last output is always the coinbase red blocks reward
+		coinbaseTransaction.Outputs[len(coinbaseTransaction.Outputs)-1].ScriptPublicKey = coinbaseData.ScriptPublicKey
+	}
+
+	mutableHeader := template.Block.Header.ToMutable()
+	mutableHeader.SetHashMerkleRoot(merkle.CalculateHashMerkleRoot(template.Block.Transactions))
+	template.Block.Header = mutableHeader.ToImmutable()
+	template.CoinbaseData = coinbaseData
+	return nil
+}
+
+func (btm *blockTemplateManager) rebuildBlockTemplate(
+	coinbaseData *externalapi.DomainCoinbaseData) (*externalapi.DomainBlockTemplate, error) {
+
+	template, err := btm.BuildBlockTemplate(coinbaseData, btm.transactions)
+	invalidTransactionsErr := ruleerrors.ErrInvalidTransactionsInNewBlock{}
+	if !errors.As(err, &invalidTransactionsErr) {
+		return template, err
+	}
+
+	// Some of the transactions were accepted or double spent by the new virtual
+	validTransactions := invalidTransactionsErr.WithoutInvalidTransactions(btm.transactions)
+	log.Debugf("Removed %d transactions that became invalid from the block template",
+		len(btm.transactions)-len(validTransactions))
+
+	return btm.BuildBlockTemplate(coinbaseData, validTransactions)
+}
+
+// HasBlockTemplate returns whether a block template was built, and may therefore be refreshed
+func (btm *blockTemplateManager) HasBlockTemplate() bool {
+	return btm.template != nil
+}
+
+// InvalidateBlockTemplate marks the cached template, and every job given so far, as stale.
+// It's called whenever the virtual changes.
+func (btm *blockTemplateManager) InvalidateBlockTemplate() {
+	btm.isTemplateValid = false
+	btm.firstJobIDOfCurrentVirtual = btm.lastJobID + 1
+}
+
+// IsJobStale returns whether the template with the given job ID was built over a virtual
+// that has changed since, so blocks mined from it are less likely to be merged
+func (btm *blockTemplateManager) IsJobStale(jobID uint64) bool {
+	return jobID < btm.firstJobIDOfCurrentVirtual || jobID > btm.lastJobID
+}
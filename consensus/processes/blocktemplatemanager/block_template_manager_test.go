@@ -0,0 +1,132 @@
+package blocktemplatemanager
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/coinbasemanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/merkle"
+)
+
+const scriptPublicKeyMaxLength = 150
+
+type fakeBlockBuilder struct {
+	buildCount          int
+	invalidTransactions map[*externalapi.DomainTransaction]bool
+}
+
+func (fbb *fakeBlockBuilder) BuildBlock(coinbaseData *externalapi.DomainCoinbaseData,
+	transactions []*externalapi.DomainTransaction) (*externalapi.DomainBlock, bool, error) {
+
+	invalidTransactions := make([]ruleerrors.InvalidTransaction, 0)
+	for _, transaction := range transactions {
+		if fbb.invalidTransactions[transaction] {
+			invalidTransactions = append(invalidTransactions,
+				ruleerrors.InvalidTransaction{Transaction: transaction, Error: &ruleerrors.ErrDoubleSpendInSameBlock})
+		}
+	}
+	if len(invalidTransactions) > 0 {
+		return nil, false, ruleerrors.NewErrInvalidTransactionsInNewBlock(invalidTransactions)
+	}
+	fbb.buildCount++
+
+	payload, err := coinbasemanager.ModifyCoinbasePayload(make([]byte, 16), coinbaseData, scriptPublicKeyMaxLength)
+	if err != nil {
+		return nil, false, err
+	}
+	coinbase := &externalapi.DomainTransaction{
+		Outputs: []*externalapi.DomainTransactionOutput{{Value: 1, ScriptPublicKey: coinbaseData.ScriptPublicKey}},
+		Payload: payload,
+	}
+	blockTransactions := append([]*externalapi.DomainTransaction{coinbase}, transactions...)
+	header := blockheader.NewImmutableBlockHeader(1, nil, merkle.CalculateHashMerkleRoot(blockTransactions),
+		&externalapi.DomainHash{}, &externalapi.DomainHash{}, 0, 0, 0, 0, 0, big.NewInt(0), &externalapi.DomainHash{})
+
+	return &externalapi.DomainBlock{Header: header, Transactions: blockTransactions}, true, nil
+}
+
+func coinbaseData(extraData string) *externalapi.DomainCoinbaseData {
+	return &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1, 2, 3}},
+		ExtraData:       []byte(extraData),
+	}
+}
+
+func TestRefreshBlockTemplate(t *testing.T) {
+	builder := &fakeBlockBuilder{}
+	manager := New(builder, scriptPublicKeyMaxLength)
+
+	_, err := manager.RefreshBlockTemplate(nil)
+	if err == nil {
+		t.Fatalf("expected RefreshBlockTemplate to fail before any template was built")
+	}
+
+	template, err := manager.BuildBlockTemplate(coinbaseData("a"), nil)
+	if err != nil {
+		t.Fatalf("BuildBlockTemplate: %+v", err)
+	}
+
+	refreshed, err := manager.RefreshBlockTemplate(coinbaseData("bb"))
+	if err != nil {
+		t.Fatalf("RefreshBlockTemplate: %+v", err)
+	}
+	if builder.buildCount != 1 {
+		t.Fatalf("expected RefreshBlockTemplate not to rebuild the block")
+	}
+	if refreshed.JobID != template.JobID {
+		t.Fatalf("expected the job ID %d to be kept, got %d", template.JobID, refreshed.JobID)
+	}
+	if refreshed.Block.Header.TimeInMilliseconds() <= template.Block.Header.TimeInMilliseconds() {
+		t.Fatalf("expected the timestamp to be updated")
+	}
+	expectedHashMerkleRoot := merkle.CalculateHashMerkleRoot(refreshed.Block.Transactions)
+	if !refreshed.Block.Header.HashMerkleRoot().Equal(expectedHashMerkleRoot) ||
+		refreshed.Block.Header.HashMerkleRoot().Equal(template.Block.Header.HashMerkleRoot()) {
+		t.Fatalf("expected the hash merkle root to match the modified coinbase")
+	}
+	if !refreshed.CoinbaseData.Equal(coinbaseData("bb")) {
+		t.Fatalf("expected the coinbase data to be replaced")
+	}
+	if manager.IsJobStale(template.JobID) {
+		t.Fatalf("expected job %d not to be stale", template.JobID)
+	}
+}
+
+func TestRefreshBlockTemplateAfterVirtualChange(t *testing.T) {
+	validTransaction := &externalapi.DomainTransaction{Version: 1}
+	acceptedTransaction := &externalapi.DomainTransaction{Version: 2}
+	builder := &fakeBlockBuilder{}
+	manager := New(builder, scriptPublicKeyMaxLength)
+
+	template, err := manager.BuildBlockTemplate(coinbaseData("a"),
+		[]*externalapi.DomainTransaction{validTransaction, acceptedTransaction})
+	if err != nil {
+		t.Fatalf("BuildBlockTemplate: %+v", err)
+	}
+
+	manager.InvalidateBlockTemplate()
+	builder.invalidTransactions = map[*externalapi.DomainTransaction]bool{acceptedTransaction: true}
+	if !manager.IsJobStale(template.JobID) {
+		t.Fatalf("expected job %d to be stale after the virtual changed", template.JobID)
+	}
+
+	rebuilt, err := manager.RefreshBlockTemplate(nil)
+	if err != nil {
+		t.Fatalf("RefreshBlockTemplate: %+v", err)
+	}
+	if builder.buildCount != 2 {
+		t.Fatalf("expected the block to be rebuilt once, got %d builds", builder.buildCount)
+	}
+	if rebuilt.JobID == template.JobID || manager.IsJobStale(rebuilt.JobID) {
+		t.Fatalf("expected the rebuilt template to get a new, current job ID")
+	}
+	if len(rebuilt.Block.Transactions) != 2 || !rebuilt.Block.Transactions[1].Equal(validTransaction) {
+		t.Fatalf("expected only the valid transaction to be kept")
+	}
+	if !rebuilt.CoinbaseData.Equal(coinbaseData("a")) {
+		t.Fatalf("expected the coinbase data of the last template to be kept")
+	}
+}
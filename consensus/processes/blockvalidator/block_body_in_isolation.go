@@ -213,11 +213,41 @@ func (v *blockValidator) checkBlockHasNoChainedTransactions(block *externalapi.D
 	return nil
 }
 
+// validateGasLimit sums the gas of the block's transactions per subnetwork, and makes sure that
+// no subnetwork exceeds its gas limit. The gas limits are defined per network, so that all the nodes
+// of a network agree on the validity of a block. The native and built-in subnetworks have a gas limit
+// of 0, and custom subnetworks that have no gas limit in their network are unlimited.
 func (v *blockValidator) validateGasLimit(block *externalapi.DomainBlock) error {
-	// TODO: implement this
+	gasPerSubnetwork := make(map[externalapi.DomainSubnetworkID]uint64)
+	for _, transaction := range block.Transactions {
+		gasLimit, hasGasLimit := v.subnetworkGasLimit(transaction.SubnetworkID)
+		if !hasGasLimit {
+			continue
+		}
+
+		gasBefore := gasPerSubnetwork[transaction.SubnetworkID]
+		gas := gasBefore + transaction.Gas
+		if gas < gasBefore {
+			return errors.Wrapf(ruleerrors.ErrSubnetworkGasLimitExceeded, "the gas of subnetwork %s "+
+				"overflows", transaction.SubnetworkID)
+		}
+		if gas > gasLimit {
+			return errors.Wrapf(ruleerrors.ErrSubnetworkGasLimitExceeded, "the transactions of subnetwork %s "+
+				"use at least %d gas, while its gas limit is %d", transaction.SubnetworkID, gas, gasLimit)
+		}
+		gasPerSubnetwork[transaction.SubnetworkID] = gas
+	}
 	return nil
 }
 
+func (v *blockValidator) subnetworkGasLimit(subnetworkID externalapi.DomainSubnetworkID) (gasLimit uint64, hasGasLimit bool) {
+	if subnetworks.IsBuiltInOrNative(subnetworkID) {
+		return 0, true
+	}
+	gasLimit, hasGasLimit = v.subnetworkGasLimits[subnetworkID]
+	return gasLimit, hasGasLimit
+}
+
 func (v *blockValidator) checkBlockMass(block *externalapi.DomainBlock) error {
 	mass := uint64(0)
 	for _, transaction := range block.Transactions {
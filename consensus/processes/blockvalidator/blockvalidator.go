@@ -26,6 +26,7 @@ type blockValidator struct {
 	targetTimePerBlock          time.Duration
 	hfDAAScore                  uint64
 	maxBlockLevel               int
+	subnetworkGasLimits         map[externalapi.DomainSubnetworkID]uint64
 
 	databaseContext       model.DBReader
 	difficultyManager     model.DifficultyManager
@@ -66,6 +67,7 @@ func New(powMax *big.Int,
 	targetTimePerBlock time.Duration,
 	hfDAAScore uint64,
 	maxBlockLevel int,
+	subnetworkGasLimits map[externalapi.DomainSubnetworkID]uint64,
 
 	databaseContext model.DBReader,
 
@@ -106,6 +108,7 @@ func New(powMax *big.Int,
 		maxBlockParents:            maxBlockParents,
 		hfDAAScore:                 hfDAAScore,
 		maxBlockLevel:              maxBlockLevel,
+		subnetworkGasLimits:        subnetworkGasLimits,
 
 		timestampDeviationTolerance: timestampDeviationTolerance,
 		targetTimePerBlock:          targetTimePerBlock,
@@ -0,0 +1,98 @@
+package blockvalidator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/subnetworks"
+)
+
+func TestValidateGasLimit(t *testing.T) {
+	limitedSubnetworkID := externalapi.DomainSubnetworkID{0xaa}
+	otherLimitedSubnetworkID := externalapi.DomainSubnetworkID{0xbb}
+	unlimitedSubnetworkID := externalapi.DomainSubnetworkID{0xcc}
+	validator := &blockValidator{
+		subnetworkGasLimits: map[externalapi.DomainSubnetworkID]uint64{
+			limitedSubnetworkID:            100,
+			otherLimitedSubnetworkID:       100,
+			subnetworks.SubnetworkIDNative: 100,
+		},
+	}
+
+	type transactionGas struct {
+		subnetworkID externalapi.DomainSubnetworkID
+		gas          uint64
+	}
+	tests := []struct {
+		name          string
+		transactions  []transactionGas
+		expectedError error
+	}{
+		{
+			name: "native, coinbase and registry without gas",
+			transactions: []transactionGas{
+				{subnetworks.SubnetworkIDCoinbase, 0},
+				{subnetworks.SubnetworkIDNative, 0},
+				{subnetworks.SubnetworkIDRegistry, 0},
+			},
+		},
+		{
+			name:          "native with gas, even though a limit is configured for it",
+			transactions:  []transactionGas{{subnetworks.SubnetworkIDNative, 1}},
+			expectedError: ruleerrors.ErrSubnetworkGasLimitExceeded,
+		},
+		{
+			name:          "coinbase with gas",
+			transactions:  []transactionGas{{subnetworks.SubnetworkIDCoinbase, 1}},
+			expectedError: ruleerrors.ErrSubnetworkGasLimitExceeded,
+		},
+		{
+			name:          "registry with gas",
+			transactions:  []transactionGas{{subnetworks.SubnetworkIDRegistry, 1}},
+			expectedError: ruleerrors.ErrSubnetworkGasLimitExceeded,
+		},
+		{
+			name:         "custom subnetwork exactly at its limit",
+			transactions: []transactionGas{{limitedSubnetworkID, 40}, {limitedSubnetworkID, 60}},
+		},
+		{
+			name:          "custom subnetwork above its limit",
+			transactions:  []transactionGas{{limitedSubnetworkID, 40}, {limitedSubnetworkID, 61}},
+			expectedError: ruleerrors.ErrSubnetworkGasLimitExceeded,
+		},
+		{
+			name:         "custom subnetworks are accounted separately",
+			transactions: []transactionGas{{limitedSubnetworkID, 60}, {otherLimitedSubnetworkID, 60}},
+		},
+		{
+			name:         "custom subnetwork without a limit",
+			transactions: []transactionGas{{unlimitedSubnetworkID, math.MaxUint64}, {unlimitedSubnetworkID, 1}},
+		},
+		{
+			name:          "custom subnetwork gas overflow",
+			transactions:  []transactionGas{{limitedSubnetworkID, 1}, {limitedSubnetworkID, math.MaxUint64}},
+			expectedError: ruleerrors.ErrSubnetworkGasLimitExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		block := &externalapi.DomainBlock{}
+		for _, transaction := range test.transactions {
+			block.Transactions = append(block.Transactions, &externalapi.DomainTransaction{
+				SubnetworkID: transaction.subnetworkID,
+				Gas:          transaction.gas,
+			})
+		}
+
+		err := validator.validateGasLimit(block)
+		if test.expectedError == nil && err != nil {
+			t.Errorf("%s: unexpected error: %+v", test.name, err)
+		}
+		if test.expectedError != nil && !errors.Is(err, test.expectedError) {
+			t.Errorf("%s: expected error %s but got %v", test.name, test.expectedError, err)
+		}
+	}
+}
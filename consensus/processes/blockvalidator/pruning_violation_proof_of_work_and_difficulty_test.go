@@ -373,3 +373,9 @@ func (dm *mocDifficultyManager) StageDAADataAndReturnRequiredDifficulty(stagingA
 func (dm *mocDifficultyManager) EstimateNetworkHashesPerSecond(startHash *externalapi.DomainHash, windowSize int) (uint64, error) {
 	return 0, nil
 }
+
+func (dm *mocDifficultyManager) EstimateNetworkHashesPerSecondSeries(*externalapi.DomainHash,
+	*externalapi.HashrateEstimationOptions, func(*externalapi.DomainHash) (bool, bool, error)) (
+	[]*externalapi.HashrateEstimate, error) {
+	return nil, nil
+}
@@ -56,3 +56,71 @@ func TestExtractCoinbaseDataBlueScoreAndSubsidy(t *testing.T) {
 
 	})
 }
+
+func TestCoinbaseRewardBreakdown(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		factory := consensus.NewFactory()
+		tc, teardown, err := factory.NewTestConsensus(consensusConfig, "TestCoinbaseRewardBreakdown")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		// A pay-to-pubkey script, so that the output paying for the merged block has an address
+		payToPubKeyScript := append([]byte{0x20}, make([]byte, 32)...)
+		payToPubKeyScript = append(payToPubKeyScript, 0xac)
+		minerCoinbaseData := &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: payToPubKeyScript, Version: 0},
+			ExtraData:       []byte("miner"),
+		}
+		mergingCoinbaseData := &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: nil, Version: 0},
+			ExtraData:       []byte("merging"),
+		}
+
+		minedBlockHash, _, err := tc.AddBlock([]*externalapi.DomainHash{consensusConfig.GenesisHash}, minerCoinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		mergingBlockHash, _, err := tc.AddBlock([]*externalapi.DomainHash{minedBlockHash}, mergingCoinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+
+		breakdown, err := tc.GetCoinbaseRewardBreakdown(mergingBlockHash)
+		if err != nil {
+			t.Fatalf("GetCoinbaseRewardBreakdown: %+v", err)
+		}
+		if !breakdown.CoinbaseData.Equal(mergingCoinbaseData) {
+			t.Fatalf("expected the decoded coinbase data to be the one the block was mined with")
+		}
+
+		var minedBlockReward *externalapi.MergedBlockReward
+		for _, reward := range breakdown.MergedBlueBlocks {
+			if reward.BlockHash.Equal(minedBlockHash) {
+				minedBlockReward = reward
+			}
+		}
+		if minedBlockReward == nil || !minedBlockReward.IsRewarded {
+			t.Fatalf("expected the selected parent to be a rewarded merged blue block")
+		}
+		if !minedBlockReward.ScriptPublicKey.Equal(minerCoinbaseData.ScriptPublicKey) {
+			t.Fatalf("expected the merged block reward to carry the script public key of its miner")
+		}
+
+		if len(breakdown.Outputs) != 1 {
+			t.Fatalf("expected a single coinbase output, got %d", len(breakdown.Outputs))
+		}
+		output := breakdown.Outputs[0]
+		if output.Value != minedBlockReward.TotalReward() || breakdown.TotalReward() != output.Value {
+			t.Fatalf("expected the output value %d to be the merged block reward %d",
+				output.Value, minedBlockReward.TotalReward())
+		}
+		if len(output.RewardedBlocks) != 1 || !output.RewardedBlocks[0].Equal(minedBlockHash) {
+			t.Fatalf("expected the output to pay for the merged block")
+		}
+		if output.Address == "" {
+			t.Fatalf("expected the output to a pay-to-pubkey script to have an address")
+		}
+	})
+}
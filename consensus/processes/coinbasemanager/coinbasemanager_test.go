@@ -93,7 +93,7 @@ func TestBuildSubsidyTable(t *testing.T) {
 	if deflationaryPhaseBaseSubsidy != 44*constants.SompiPerKarlsen {
 		t.Errorf("TestBuildSubsidyTable: table generation function was not updated to reflect "+
 			"the new base subsidy %d. Please fix the constant above and replace subsidyByDeflationaryMonthTable "+
-			"in coinbasemanager.go with the printed table", deflationaryPhaseBaseSubsidy)
+			"in utils/emission/subsidy.go with the printed table", deflationaryPhaseBaseSubsidy)
 	}
 	coinbaseManagerInterface := New(
 		nil,
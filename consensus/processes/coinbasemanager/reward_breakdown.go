@@ -0,0 +1,95 @@
+package coinbasemanager
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/transactionhelper"
+)
+
+// CoinbaseRewardBreakdown returns the rewards paid by the coinbase transaction of blockHash:
+// the decoded payload of the coinbase, the reward of every merged block, and which merged
+// blocks are paid by each of the coinbase outputs.
+// The addresses of the outputs are left empty, since they depend on the network's address prefix.
+func (c *coinbaseManager) CoinbaseRewardBreakdown(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (
+	*externalapi.CoinbaseRewardBreakdown, error) {
+
+	block, err := c.blockStore.Block(c.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	coinbaseTransaction := block.Transactions[transactionhelper.CoinbaseTransactionIndex]
+	blueScore, coinbaseData, subsidy, err := c.ExtractCoinbaseDataBlueScoreAndSubsidy(coinbaseTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	ghostdagData, err := c.mergingBlockGHOSTDAGData(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	acceptanceData, err := c.acceptanceDataStore.Get(c.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	daaAddedBlocksSet, err := c.daaAddedBlocksSet(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	acceptanceDataMap := acceptanceDataFromArrayToMap(acceptanceData)
+
+	breakdown := &externalapi.CoinbaseRewardBreakdown{
+		BlockHash:        blockHash,
+		BlueScore:        blueScore,
+		Subsidy:          subsidy,
+		CoinbaseData:     coinbaseData,
+		MergedBlueBlocks: make([]*externalapi.MergedBlockReward, 0, len(ghostdagData.MergeSetBlues())),
+		MergedRedBlocks:  make([]*externalapi.MergedBlockReward, 0, len(ghostdagData.MergeSetReds())),
+	}
+
+	// The outputs follow the order of ExpectedCoinbaseTransaction: an output for every rewarded
+	// blue block, followed by a single output for all the rewarded red blocks
+	var rewardedBlocksByOutput [][]*externalapi.DomainHash
+	for _, blue := range ghostdagData.MergeSetBlues() {
+		reward, err := c.mergedBlockReward(stagingArea, blue, acceptanceDataMap[*blue], daaAddedBlocksSet)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.MergedBlueBlocks = append(breakdown.MergedBlueBlocks, reward)
+		if reward.TotalReward() > 0 {
+			rewardedBlocksByOutput = append(rewardedBlocksByOutput, []*externalapi.DomainHash{blue})
+		}
+	}
+
+	var rewardedRedBlocks []*externalapi.DomainHash
+	for _, red := range ghostdagData.MergeSetReds() {
+		reward, err := c.mergedBlockReward(stagingArea, red, acceptanceDataMap[*red], daaAddedBlocksSet)
+		if err != nil {
+			return nil, err
+		}
+		breakdown.MergedRedBlocks = append(breakdown.MergedRedBlocks, reward)
+		if reward.TotalReward() > 0 {
+			rewardedRedBlocks = append(rewardedRedBlocks, red)
+		}
+	}
+	if len(rewardedRedBlocks) > 0 {
+		rewardedBlocksByOutput = append(rewardedBlocksByOutput, rewardedRedBlocks)
+	}
+
+	if len(rewardedBlocksByOutput) != len(coinbaseTransaction.Outputs) {
+		return nil, errors.Errorf("the coinbase transaction of block %s has %d outputs but %d were expected",
+			blockHash, len(coinbaseTransaction.Outputs), len(rewardedBlocksByOutput))
+	}
+
+	breakdown.Outputs = make([]*externalapi.CoinbaseOutputBreakdown, len(coinbaseTransaction.Outputs))
+	for i, output := range coinbaseTransaction.Outputs {
+		breakdown.Outputs[i] = &externalapi.CoinbaseOutputBreakdown{
+			Index:           uint32(i),
+			Value:           output.Value,
+			ScriptPublicKey: output.ScriptPublicKey,
+			RewardedBlocks:  rewardedBlocksByOutput[i],
+		}
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,159 @@
+package consensuseventsmanager
+
+import (
+	"sync"
+
+	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type consensusEventsManager struct {
+	shardID          model.StagingShardID
+	lock             sync.RWMutex
+	subscriptions    map[*subscription]struct{}
+	subscriberCounts [externalapi.NumberOfConsensusEventTypes]int
+
+	// publishedEventsLock guards the events that were published but not delivered yet, and
+	// whether some goroutine is delivering them
+	publishedEventsLock sync.Mutex
+	publishedEvents     []externalapi.ConsensusEvent
+	isDelivering        bool
+}
+
+// New instantiates a new ConsensusEventsManager
+func New() model.ConsensusEventsManager {
+	return &consensusEventsManager{
+		shardID:       model.StagingShardID(staging.GenerateShardingID()),
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscription to the events matching the given options
+func (cem *consensusEventsManager) Subscribe(options *externalapi.ConsensusEventSubscriptionOptions) (
+	externalapi.ConsensusEventSubscription, error) {
+
+	if options.BufferSize <= 0 {
+		return nil, errors.Errorf("the buffer size must be positive, got %d", options.BufferSize)
+	}
+	if options.BackpressurePolicy > externalapi.BackpressureBlock {
+		return nil, errors.Errorf("unknown backpressure policy %d", options.BackpressurePolicy)
+	}
+
+	var filter [externalapi.NumberOfConsensusEventTypes]bool
+	for _, eventType := range options.EventTypes {
+		if eventType >= externalapi.NumberOfConsensusEventTypes {
+			return nil, errors.Errorf("unknown consensus event type %d", eventType)
+		}
+		filter[eventType] = true
+	}
+	if len(options.EventTypes) == 0 {
+		for eventType := range filter {
+			filter[eventType] = true
+		}
+	}
+
+	sub := &subscription{
+		manager: cem,
+		filter:  filter,
+		policy:  options.BackpressurePolicy,
+		events:  make(chan *externalapi.SequencedConsensusEvent, options.BufferSize),
+		done:    make(chan struct{}),
+	}
+
+	cem.lock.Lock()
+	defer cem.lock.Unlock()
+
+	cem.subscriptions[sub] = struct{}{}
+	for eventType, isSubscribed := range filter {
+		if isSubscribed {
+			cem.subscriberCounts[eventType]++
+		}
+	}
+
+	return sub, nil
+}
+
+// HasSubscribers returns whether any subscription accepts events of the given type.
+// It lets the caller skip building events nobody is going to receive.
+func (cem *consensusEventsManager) HasSubscribers(eventType externalapi.ConsensusEventType) bool {
+	cem.lock.RLock()
+	defer cem.lock.RUnlock()
+
+	return cem.subscriberCounts[eventType] > 0
+}
+
+// Publish queues the given event to be delivered by DeliverPublishedEvents. It never blocks,
+// so it may be called while the consensus lock is held.
+func (cem *consensusEventsManager) Publish(event externalapi.ConsensusEvent) {
+	cem.publishedEventsLock.Lock()
+	defer cem.publishedEventsLock.Unlock()
+
+	cem.publishedEvents = append(cem.publishedEvents, event)
+}
+
+// DeliverPublishedEvents delivers the published events to every subscription that accepts their
+// type, in the order they were published. Subscriptions with the BackpressureBlock policy may block
+// the delivery, so it must not be called while the consensus lock is held. If another goroutine is
+// already delivering, it delivers the queued events too, and DeliverPublishedEvents returns
+// immediately. This way a subscriber that calls back into consensus never waits for its own delivery.
+func (cem *consensusEventsManager) DeliverPublishedEvents() {
+	cem.publishedEventsLock.Lock()
+	if cem.isDelivering {
+		cem.publishedEventsLock.Unlock()
+		return
+	}
+	cem.isDelivering = true
+	cem.publishedEventsLock.Unlock()
+
+	for {
+		cem.publishedEventsLock.Lock()
+		events := cem.publishedEvents
+		cem.publishedEvents = nil
+		if len(events) == 0 {
+			cem.isDelivering = false
+			cem.publishedEventsLock.Unlock()
+			return
+		}
+		cem.publishedEventsLock.Unlock()
+
+		for _, event := range events {
+			cem.deliver(event)
+		}
+	}
+}
+
+func (cem *consensusEventsManager) deliver(event externalapi.ConsensusEvent) {
+	eventType := event.Type()
+
+	// The subscriptions are collected first so that a subscriber blocking the delivery
+	// doesn't prevent other subscribers from unsubscribing
+	cem.lock.RLock()
+	subscriptions := make([]*subscription, 0, cem.subscriberCounts[eventType])
+	for sub := range cem.subscriptions {
+		if sub.filter[eventType] {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+	cem.lock.RUnlock()
+
+	for _, sub := range subscriptions {
+		sub.deliver(event)
+	}
+}
+
+func (cem *consensusEventsManager) removeSubscription(sub *subscription) {
+	cem.lock.Lock()
+	defer cem.lock.Unlock()
+
+	if _, ok := cem.subscriptions[sub]; !ok {
+		return
+	}
+	delete(cem.subscriptions, sub)
+	for eventType, isSubscribed := range sub.filter {
+		if isSubscribed {
+			cem.subscriberCounts[eventType]--
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package consensuseventsmanager
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+func finalityConflict(i byte) *externalapi.FinalityConflict {
+	var hashBytes [externalapi.DomainHashSize]byte
+	hashBytes[0] = i
+	return &externalapi.FinalityConflict{ViolatingBlockHash: externalapi.NewDomainHashFromByteArray(&hashBytes)}
+}
+
+func TestSubscriptionFilter(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		EventTypes: []externalapi.ConsensusEventType{externalapi.ConsensusEventTypeFinalityConflict},
+		BufferSize: 10,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if manager.HasSubscribers(externalapi.ConsensusEventTypeBlockAdded) {
+		t.Fatalf("expected no BlockAdded subscribers")
+	}
+	if !manager.HasSubscribers(externalapi.ConsensusEventTypeFinalityConflict) {
+		t.Fatalf("expected a FinalityConflict subscriber")
+	}
+
+	manager.Publish(&externalapi.BlockAdded{})
+	manager.Publish(finalityConflict(1))
+	manager.DeliverPublishedEvents()
+
+	event := <-sub.Events()
+	if event.SequenceNumber != 1 || event.Event.Type() != externalapi.ConsensusEventTypeFinalityConflict {
+		t.Fatalf("expected FinalityConflict #1 but got %s #%d", event.Event.Type(), event.SequenceNumber)
+	}
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %s", event.Event.Type())
+	default:
+	}
+}
+
+func TestSubscriptionDropNewest(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		BufferSize:         2,
+		BackpressurePolicy: externalapi.BackpressureDropNewest,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := byte(1); i <= 4; i++ {
+		manager.Publish(finalityConflict(i))
+	}
+	manager.DeliverPublishedEvents()
+
+	if sub.DroppedEventsCount() != 2 {
+		t.Fatalf("expected 2 dropped events but got %d", sub.DroppedEventsCount())
+	}
+	for _, expectedSequenceNumber := range []uint64{1, 2} {
+		event := <-sub.Events()
+		if event.SequenceNumber != expectedSequenceNumber {
+			t.Fatalf("expected sequence number %d but got %d", expectedSequenceNumber, event.SequenceNumber)
+		}
+	}
+}
+
+func TestSubscriptionDropOldest(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		BufferSize:         2,
+		BackpressurePolicy: externalapi.BackpressureDropOldest,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := byte(1); i <= 4; i++ {
+		manager.Publish(finalityConflict(i))
+	}
+	manager.DeliverPublishedEvents()
+
+	if sub.DroppedEventsCount() != 2 {
+		t.Fatalf("expected 2 dropped events but got %d", sub.DroppedEventsCount())
+	}
+	for _, expectedSequenceNumber := range []uint64{3, 4} {
+		event := <-sub.Events()
+		if event.SequenceNumber != expectedSequenceNumber {
+			t.Fatalf("expected sequence number %d but got %d", expectedSequenceNumber, event.SequenceNumber)
+		}
+	}
+}
+
+func TestSubscriptionBlockReleasedByUnsubscribe(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		BufferSize:         1,
+		BackpressurePolicy: externalapi.BackpressureBlock,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+
+	manager.Publish(finalityConflict(1))
+	manager.DeliverPublishedEvents()
+
+	published := make(chan struct{})
+	go func() {
+		manager.Publish(finalityConflict(2))
+		manager.DeliverPublishedEvents()
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatalf("expected DeliverPublishedEvents to block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sub.Unsubscribe()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Unsubscribe to release the blocked DeliverPublishedEvents")
+	}
+
+	if manager.HasSubscribers(externalapi.ConsensusEventTypeFinalityConflict) {
+		t.Fatalf("expected no subscribers after Unsubscribe")
+	}
+	for range sub.Events() {
+	}
+	manager.Publish(finalityConflict(3))
+	manager.DeliverPublishedEvents()
+}
+
+func TestPublishDoesNotBlock(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		BufferSize:         1,
+		BackpressurePolicy: externalapi.BackpressureBlock,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+
+	// Publishing only queues the events, so it doesn't wait for a full buffer
+	for i := byte(1); i <= 3; i++ {
+		manager.Publish(finalityConflict(i))
+	}
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %s before the published events are delivered", event.Event.Type())
+	default:
+	}
+
+	// A subscriber that raises events of its own while handling an event, like one that calls back
+	// into consensus, doesn't wait for the delivery it's blocking
+	var handled []byte
+	handlingDone := make(chan error)
+	go func() {
+		handlingDone <- sub.HandleEvents(func(event externalapi.ConsensusEvent) error {
+			i := event.(*externalapi.FinalityConflict).ViolatingBlockHash.ByteSlice()[0]
+			handled = append(handled, i)
+			if i == 1 {
+				manager.Publish(finalityConflict(4))
+				manager.DeliverPublishedEvents()
+			}
+			if i == 4 {
+				sub.Unsubscribe()
+			}
+			return nil
+		}, func(droppedCount uint64) error {
+			return errors.Errorf("%d events were dropped", droppedCount)
+		})
+	}()
+
+	delivered := make(chan struct{})
+	go func() {
+		manager.DeliverPublishedEvents()
+		close(delivered)
+	}()
+
+	select {
+	case err := <-handlingDone:
+		if err != nil {
+			t.Fatalf("HandleEvents: %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the subscriber to handle its events without a deadlock")
+	}
+	<-delivered
+	if !reflect.DeepEqual(handled, []byte{1, 2, 3, 4}) {
+		t.Fatalf("expected the events to be handled in the order they were published, but got %v", handled)
+	}
+}
+
+func TestSubscribeInvalidOptions(t *testing.T) {
+	manager := New()
+	invalidOptions := []*externalapi.ConsensusEventSubscriptionOptions{
+		{BufferSize: 0},
+		{BufferSize: 1, BackpressurePolicy: externalapi.BackpressureBlock + 1},
+		{BufferSize: 1, EventTypes: []externalapi.ConsensusEventType{externalapi.NumberOfConsensusEventTypes}},
+	}
+	for i, options := range invalidOptions {
+		_, err := manager.Subscribe(options)
+		if err == nil {
+			t.Fatalf("expected options #%d to be rejected", i)
+		}
+	}
+}
+
+func TestStagedEvents(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{BufferSize: 10})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Events staged in a staging area that is never committed are never published
+	discardedStagingArea := model.NewStagingArea()
+	manager.StageEvent(discardedStagingArea, finalityConflict(1))
+
+	stagingArea := model.NewStagingArea()
+	manager.StageEvent(stagingArea, finalityConflict(2))
+	manager.StageEvent(stagingArea, finalityConflict(3))
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %s before the staged events are published", event.Event.Type())
+	default:
+	}
+
+	manager.PublishStagedEvents(stagingArea)
+	manager.DeliverPublishedEvents()
+	for i := byte(2); i <= 3; i++ {
+		event := <-sub.Events()
+		if !event.Event.(*externalapi.FinalityConflict).ViolatingBlockHash.Equal(finalityConflict(i).ViolatingBlockHash) {
+			t.Fatalf("expected the staged events to be published in the order they were staged")
+		}
+	}
+
+	// The staged events are published only once
+	manager.PublishStagedEvents(stagingArea)
+	manager.DeliverPublishedEvents()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %s after the staged events were published", event.Event.Type())
+	default:
+	}
+}
+
+func TestSubscriptionHandleEvents(t *testing.T) {
+	manager := New()
+	sub, err := manager.Subscribe(&externalapi.ConsensusEventSubscriptionOptions{
+		BufferSize:         2,
+		BackpressurePolicy: externalapi.BackpressureDropOldest,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe: %+v", err)
+	}
+
+	for i := byte(1); i <= 4; i++ {
+		manager.Publish(finalityConflict(i))
+	}
+	manager.DeliverPublishedEvents()
+	sub.Unsubscribe()
+
+	var handled []byte
+	var droppedCounts []uint64
+	err = sub.HandleEvents(func(event externalapi.ConsensusEvent) error {
+		handled = append(handled, event.(*externalapi.FinalityConflict).ViolatingBlockHash.ByteSlice()[0])
+		return nil
+	}, func(droppedCount uint64) error {
+		droppedCounts = append(droppedCounts, droppedCount)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleEvents: %+v", err)
+	}
+	if !reflect.DeepEqual(handled, []byte{3, 4}) {
+		t.Fatalf("expected events 3 and 4 to be handled but got %v", handled)
+	}
+	if !reflect.DeepEqual(droppedCounts, []uint64{2}) {
+		t.Fatalf("expected a single gap of 2 dropped events but got %v", droppedCounts)
+	}
+}
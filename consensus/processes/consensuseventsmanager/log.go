@@ -0,0 +1,7 @@
+package consensuseventsmanager
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("BDAG")
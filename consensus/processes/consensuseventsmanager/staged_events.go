@@ -0,0 +1,43 @@
+package consensuseventsmanager
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// stagedEventsShard collects the events raised while staging changes. The events aren't
+// persisted, so committing the shard does nothing: they're published by PublishStagedEvents
+// once the whole staging area is committed.
+type stagedEventsShard struct {
+	events []externalapi.ConsensusEvent
+}
+
+func (cem *consensusEventsManager) stagedEventsShard(stagingArea *model.StagingArea) *stagedEventsShard {
+	return stagingArea.GetOrCreateShard(cem.shardID, func() model.StagingShard {
+		return &stagedEventsShard{}
+	}).(*stagedEventsShard)
+}
+
+func (ses *stagedEventsShard) Commit(_ model.DBTransaction) error {
+	return nil
+}
+
+// StageEvent collects the given event in the staging area, to be published only once
+// the changes it describes are committed
+func (cem *consensusEventsManager) StageEvent(stagingArea *model.StagingArea, event externalapi.ConsensusEvent) {
+	stagingShard := cem.stagedEventsShard(stagingArea)
+	stagingShard.events = append(stagingShard.events, event)
+}
+
+// PublishStagedEvents publishes the events staged in the given staging area, in the order
+// they were staged. It must be called only after the staging area was committed. Like Publish,
+// it only queues the events for DeliverPublishedEvents.
+func (cem *consensusEventsManager) PublishStagedEvents(stagingArea *model.StagingArea) {
+	stagingShard := cem.stagedEventsShard(stagingArea)
+	events := stagingShard.events
+	stagingShard.events = nil
+
+	for _, event := range events {
+		cem.Publish(event)
+	}
+}
@@ -0,0 +1,118 @@
+package consensuseventsmanager
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type subscription struct {
+	manager *consensusEventsManager
+	filter  [externalapi.NumberOfConsensusEventTypes]bool
+	policy  externalapi.BackpressurePolicy
+
+	// lock serializes deliveries, and makes sure the events channel
+	// isn't closed while an event is being delivered on it
+	lock               sync.Mutex
+	events             chan *externalapi.SequencedConsensusEvent
+	done               chan struct{}
+	unsubscribeOnce    sync.Once
+	isClosed           bool
+	lastSequenceNumber uint64
+	droppedEventsCount uint64
+}
+
+func (sub *subscription) Events() <-chan *externalapi.SequencedConsensusEvent {
+	return sub.events
+}
+
+func (sub *subscription) DroppedEventsCount() uint64 {
+	return atomic.LoadUint64(&sub.droppedEventsCount)
+}
+
+func (sub *subscription) HandleEvents(handleEvent func(event externalapi.ConsensusEvent) error,
+	handleDroppedEvents func(droppedCount uint64) error) error {
+
+	var lastSequenceNumber uint64
+	for event := range sub.events {
+		if event.SequenceNumber != lastSequenceNumber+1 {
+			err := handleDroppedEvents(event.SequenceNumber - lastSequenceNumber - 1)
+			if err != nil {
+				return err
+			}
+		}
+		lastSequenceNumber = event.SequenceNumber
+
+		err := handleEvent(event.Event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sub *subscription) Unsubscribe() {
+	sub.unsubscribeOnce.Do(func() {
+		sub.manager.removeSubscription(sub)
+
+		// done is closed before taking the lock in order to release a blocked delivery
+		close(sub.done)
+
+		sub.lock.Lock()
+		defer sub.lock.Unlock()
+		sub.isClosed = true
+		close(sub.events)
+	})
+}
+
+func (sub *subscription) deliver(event externalapi.ConsensusEvent) {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	if sub.isClosed {
+		return
+	}
+
+	sub.lastSequenceNumber++
+	sequencedEvent := &externalapi.SequencedConsensusEvent{
+		SequenceNumber: sub.lastSequenceNumber,
+		Event:          event,
+	}
+
+	switch sub.policy {
+	case externalapi.BackpressureBlock:
+		select {
+		case sub.events <- sequencedEvent:
+		case <-sub.done:
+		}
+
+	case externalapi.BackpressureDropOldest:
+		for {
+			select {
+			case sub.events <- sequencedEvent:
+				return
+			default:
+			}
+			select {
+			case <-sub.events:
+				sub.drop()
+			default:
+			}
+		}
+
+	default:
+		select {
+		case sub.events <- sequencedEvent:
+		default:
+			sub.drop()
+		}
+	}
+}
+
+func (sub *subscription) drop() {
+	dropped := atomic.AddUint64(&sub.droppedEventsCount, 1)
+	if dropped == 1 || dropped%1000 == 0 {
+		log.Warnf("A consensus event subscriber is falling behind: %d events were dropped so far", dropped)
+	}
+}
@@ -36,8 +36,15 @@ func (csm *consensusStateManager) AddBlock(stagingArea *model.StagingArea, block
 			}
 
 			if shouldNotify {
-				//TODO: Send finality conflict notification
-				log.Warnf("Finality Violation Detected! Block %s violates finality!", blockHash)
+				conflict, shouldPublish, err := csm.recordFinalityConflict(stagingArea, blockHash)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				if shouldPublish {
+					log.Warnf("Finality Violation Detected! Block %s violates finality!", blockHash)
+					// The conflict is published once the block is committed, along with its record
+					csm.consensusEventsManager.StageEvent(stagingArea, conflict)
+				}
 			}
 
 			if !isViolatingFinality {
@@ -0,0 +1,69 @@
+package consensusstatemanager
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// DiagnoseBlockParents explains whether a block with the given parents would pass the merge set size limit and
+// the bounded merge depth rule, and how the virtual parent selection would trim the parents if they were the tips.
+// It stages the given parents as the virtual's parents, so the staging area must not be committed.
+func (csm *consensusStateManager) DiagnoseBlockParents(stagingArea *model.StagingArea,
+	parents []*externalapi.DomainHash) (*externalapi.BlockParentsDiagnostics, error) {
+
+	log.Tracef("DiagnoseBlockParents start for parents: %s", parents)
+	defer log.Tracef("DiagnoseBlockParents end for parents: %s", parents)
+
+	// The virtual parent selection sets the virtual parents to the parents it picks, so it must
+	// run before they're set to the given parents
+	virtualParentsSelection, err := csm.pickVirtualParentsAndTrace(stagingArea, parents)
+	if err != nil {
+		return nil, err
+	}
+
+	badReds, mergeDepthRoot, kosherizingBlues, err := csm.boundedMergeBadReds(stagingArea, parents)
+	if err != nil {
+		return nil, err
+	}
+	ghostdagData, err := csm.ghostdagDataStore.Get(csm.databaseContext, stagingArea, model.VirtualBlockHash, false)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := &externalapi.BlockParentsDiagnostics{
+		Parents:                 parents,
+		SelectedParent:          ghostdagData.SelectedParent(),
+		MergeDepthRoot:          mergeDepthRoot,
+		MergeSetBlues:           ghostdagData.MergeSetBlues(),
+		MergeSetReds:            ghostdagData.MergeSetReds(),
+		MergeSetSize:            uint64(len(ghostdagData.MergeSetBlues()) + len(ghostdagData.MergeSetReds())),
+		MergeSetSizeLimit:       csm.mergeSetSizeLimit,
+		KosherizingBlues:        kosherizingBlues,
+		VirtualParentsSelection: virtualParentsSelection,
+	}
+
+	for _, badRed := range badReds {
+		violation := &externalapi.BoundedMergeViolation{RedBlockHash: badRed}
+		for _, blue := range ghostdagData.MergeSetBlues() {
+			isBadRedInPastOfBlue, err := csm.dagTopologyManager.IsAncestorOf(stagingArea, badRed, blue)
+			if err != nil {
+				return nil, err
+			}
+			if isBadRedInPastOfBlue {
+				violation.NonKosherizingBluesInFuture = append(violation.NonKosherizingBluesInFuture, blue)
+			}
+		}
+		for _, parent := range parents {
+			isBadRedInPastOfParent, err := csm.dagTopologyManager.IsAncestorOf(stagingArea, badRed, parent)
+			if err != nil {
+				return nil, err
+			}
+			if isBadRedInPastOfParent {
+				violation.ViolatingParents = append(violation.ViolatingParents, parent)
+			}
+		}
+		diagnostics.BoundedMergeViolations = append(diagnostics.BoundedMergeViolations, violation)
+	}
+
+	return diagnostics, nil
+}
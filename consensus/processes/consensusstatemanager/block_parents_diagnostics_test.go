@@ -0,0 +1,129 @@
+package consensusstatemanager_test
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
+)
+
+func TestDiagnoseBlockParents(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		consensusConfig.K = 5
+		consensusConfig.MergeDepth = 7
+		consensusConfig.FinalityDuration = 20 * consensusConfig.TargetTimePerBlock
+
+		tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestDiagnoseBlockParents")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		addChain := func(root *externalapi.DomainHash, length uint64,
+			coinbaseData *externalapi.DomainCoinbaseData) []*externalapi.DomainHash {
+
+			chain := make([]*externalapi.DomainHash, 0, length)
+			tip := root
+			for i := uint64(0); i < length; i++ {
+				tip, _, err = tc.AddBlock([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+				if err != nil {
+					t.Fatalf("AddBlock: %+v", err)
+				}
+				chain = append(chain, tip)
+			}
+			return chain
+		}
+
+		// Build a selected chain deeper than the merge depth, and a shorter chain next to it.
+		// The blocks of the second chain pay a different script so that they differ from the selected chain blocks.
+		block1 := addChain(consensusConfig.GenesisHash, 1, nil)[0]
+		selectedChain := addChain(block1, consensusConfig.MergeDepth+2, nil)
+		sideChain := addChain(block1, consensusConfig.MergeDepth+1, &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+			ExtraData:       []byte{},
+		})
+		selectedTip := selectedChain[len(selectedChain)-1]
+
+		virtualInfoBefore, err := tc.GetVirtualInfo()
+		if err != nil {
+			t.Fatalf("GetVirtualInfo: %+v", err)
+		}
+
+		// Merging the bottom of the side chain directly from the selected tip breaks the bounded merge depth rule
+		violatingParents := []*externalapi.DomainHash{sideChain[0], selectedTip}
+		diagnostics, err := tc.DiagnoseBlockParents(violatingParents)
+		if err != nil {
+			t.Fatalf("DiagnoseBlockParents: %+v", err)
+		}
+		if !diagnostics.SelectedParent.Equal(selectedTip) {
+			t.Fatalf("Expected the selected parent to be %s but got %s", selectedTip, diagnostics.SelectedParent)
+		}
+		if !diagnostics.ViolatesBoundedMergeDepth() || len(diagnostics.BoundedMergeViolations) != 1 {
+			t.Fatalf("Expected a single bounded merge violation but got %d", len(diagnostics.BoundedMergeViolations))
+		}
+		violation := diagnostics.BoundedMergeViolations[0]
+		if !violation.RedBlockHash.Equal(sideChain[0]) {
+			t.Fatalf("Expected the violating red to be %s but got %s", sideChain[0], violation.RedBlockHash)
+		}
+		if !externalapi.HashesEqual(violation.ViolatingParents, []*externalapi.DomainHash{sideChain[0]}) {
+			t.Fatalf("Expected the violating parents to be %s but got %s", sideChain[0], violation.ViolatingParents)
+		}
+		if len(violation.NonKosherizingBluesInFuture) != 0 {
+			t.Fatalf("Expected no blues in the future of the violating red but got %s", violation.NonKosherizingBluesInFuture)
+		}
+		if diagnostics.ExceedsMergeSetSizeLimit() {
+			t.Fatalf("Expected the merge set size %d to be within the limit %d",
+				diagnostics.MergeSetSize, diagnostics.MergeSetSizeLimit)
+		}
+
+		// The virtual parent selection keeps only the selected tip out of these parents
+		selection := diagnostics.VirtualParentsSelection
+		if !selection.SelectedParent.Equal(selectedTip) {
+			t.Fatalf("Expected the virtual selected parent to be %s but got %s", selectedTip, selection.SelectedParent)
+		}
+		if !externalapi.HashesEqual(selection.BoundedMergeBreakingParents, []*externalapi.DomainHash{sideChain[0]}) {
+			t.Fatalf("Expected the bounded merge breaking parents to be %s but got %s",
+				sideChain[0], selection.BoundedMergeBreakingParents)
+		}
+		if !externalapi.HashesEqual(selection.Parents, []*externalapi.DomainHash{selectedTip}) {
+			t.Fatalf("Expected the virtual parents to be %s but got %s", selectedTip, selection.Parents)
+		}
+
+		// Merging the side chain from a block that is itself blue and above the merge depth root is kosher
+		kosherParents := []*externalapi.DomainHash{sideChain[len(sideChain)-3], selectedChain[len(selectedChain)-3]}
+		diagnostics, err = tc.DiagnoseBlockParents(kosherParents)
+		if err != nil {
+			t.Fatalf("DiagnoseBlockParents: %+v", err)
+		}
+		if diagnostics.ViolatesBoundedMergeDepth() {
+			t.Fatalf("Expected no bounded merge violations but got %d", len(diagnostics.BoundedMergeViolations))
+		}
+		isSelectedParentKosherizing := false
+		for _, kosherizingBlue := range diagnostics.KosherizingBlues {
+			if kosherizingBlue.Equal(diagnostics.SelectedParent) {
+				isSelectedParentKosherizing = true
+				break
+			}
+		}
+		if !isSelectedParentKosherizing {
+			t.Fatalf("Expected the selected parent %s to be a kosherizing blue, but the kosherizing blues are %s",
+				diagnostics.SelectedParent, diagnostics.KosherizingBlues)
+		}
+
+		// Diagnosing parents doesn't change the virtual
+		virtualInfoAfter, err := tc.GetVirtualInfo()
+		if err != nil {
+			t.Fatalf("GetVirtualInfo: %+v", err)
+		}
+		if !externalapi.HashesEqual(virtualInfoBefore.ParentHashes, virtualInfoAfter.ParentHashes) {
+			t.Fatalf("Expected the virtual parents to stay %s but got %s",
+				virtualInfoBefore.ParentHashes, virtualInfoAfter.ParentHashes)
+		}
+
+		_, err = tc.DiagnoseBlockParents(nil)
+		if err == nil {
+			t.Fatalf("Expected DiagnoseBlockParents to fail without parents")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package consensusstatemanager
 
 import (
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 )
@@ -17,11 +18,48 @@ func (csm *consensusStateManager) isViolatingFinality(stagingArea *model.Staging
 		return false, false, nil
 	}
 
-	var finalityPoint *externalapi.DomainHash
-	virtualFinalityPoint, err := csm.finalityManager.VirtualFinalityPoint(stagingArea)
+	finalityPoint, isFinalityPointInPastOfPruningPoint, err := csm.finalityPointForViolationCheck(stagingArea)
+	if err != nil {
+		return false, false, err
+	}
+
+	isInSelectedParentChainOfFinalityPoint, err :=
+		csm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, finalityPoint, blockHash)
 	if err != nil {
 		return false, false, err
 	}
+
+	if !isInSelectedParentChainOfFinalityPoint {
+		if !isFinalityPointInPastOfPruningPoint {
+			isInFollowedViolatingChain, err := csm.isInFollowedViolatingChain(stagingArea, blockHash)
+			if err != nil {
+				return false, false, err
+			}
+			if isInFollowedViolatingChain {
+				log.Debugf("Block %s violates finality, but its chain was chosen to be followed", blockHash)
+				return false, false, nil
+			}
+			return true, true, nil
+		}
+		// On IBD it's pretty normal to get blocks in the anticone of the pruning
+		// point, so we don't notify on cases when the pruning point is in the future
+		// of the finality point.
+		log.Debugf("Block %s violates finality, but karlsend is currently doing IBD, so this is normal", blockHash)
+		return true, false, nil
+	}
+	log.Debugf("Block %s does not violate finality", blockHash)
+
+	return false, false, nil
+}
+
+// finalityPointForViolationCheck returns the finality point the selected chains of blocks are required to contain
+func (csm *consensusStateManager) finalityPointForViolationCheck(stagingArea *model.StagingArea) (
+	finalityPoint *externalapi.DomainHash, isFinalityPointInPastOfPruningPoint bool, err error) {
+
+	virtualFinalityPoint, err := csm.finalityManager.VirtualFinalityPoint(stagingArea)
+	if err != nil {
+		return nil, false, err
+	}
 	log.Debugf("The virtual finality point is: %s", virtualFinalityPoint)
 
 	// There can be a situation where the virtual points close to the pruning point (or even in the past
@@ -31,40 +69,45 @@ func (csm *consensusStateManager) isViolatingFinality(stagingArea *model.Staging
 	// the virtual selected parent chain don't include the pruning point.
 	pruningPoint, err := csm.pruningStore.PruningPoint(csm.databaseContext, stagingArea)
 	if err != nil {
-		return false, false, err
+		return nil, false, err
 	}
 	log.Debugf("The pruning point is: %s", pruningPoint)
 
-	isFinalityPointInPastOfPruningPoint, err := csm.dagTopologyManager.IsAncestorOf(stagingArea, virtualFinalityPoint, pruningPoint)
+	isFinalityPointInPastOfPruningPoint, err = csm.dagTopologyManager.IsAncestorOf(stagingArea, virtualFinalityPoint, pruningPoint)
 	if err != nil {
-		return false, false, err
+		return nil, false, err
 	}
 
 	if !isFinalityPointInPastOfPruningPoint {
-		finalityPoint = virtualFinalityPoint
-	} else {
-		log.Debugf("The virtual finality point is %s in the past of the pruning point, so finality is validated "+
-			"using the pruning point", virtualFinalityPoint)
-		finalityPoint = pruningPoint
+		return virtualFinalityPoint, false, nil
 	}
+	log.Debugf("The virtual finality point is %s in the past of the pruning point, so finality is validated "+
+		"using the pruning point", virtualFinalityPoint)
+	return pruningPoint, true, nil
+}
 
-	isInSelectedParentChainOfFinalityPoint, err :=
-		csm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, finalityPoint, blockHash)
+// isInFollowedViolatingChain returns whether the selected chain of the given block contains a violating
+// chain the operator chose to follow when resolving a finality conflict
+func (csm *consensusStateManager) isInFollowedViolatingChain(stagingArea *model.StagingArea,
+	blockHash *externalapi.DomainHash) (bool, error) {
+
+	followedViolatingChains, err := csm.finalityConflictStore.FollowedViolatingChains(csm.databaseContext, stagingArea)
 	if err != nil {
-		return false, false, err
+		return false, err
 	}
-
-	if !isInSelectedParentChainOfFinalityPoint {
-		if !isFinalityPointInPastOfPruningPoint {
-			return true, true, nil
+	for _, followedViolatingChain := range followedViolatingChains {
+		isInFollowedViolatingChain, err :=
+			csm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, followedViolatingChain, blockHash)
+		if database.IsNotFoundError(err) {
+			// The followed chain was pruned since
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if isInFollowedViolatingChain {
+			return true, nil
 		}
-		// On IBD it's pretty normal to get blocks in the anticone of the pruning
-		// point, so we don't notify on cases when the pruning point is in the future
-		// of the finality point.
-		log.Debugf("Block %s violates finality, but karlsend is currently doing IBD, so this is normal", blockHash)
-		return true, false, nil
 	}
-	log.Debugf("Block %s does not violate finality", blockHash)
-
-	return false, false, nil
+	return false, nil
 }
@@ -12,15 +12,16 @@ type consensusStateManager struct {
 	genesisHash       *externalapi.DomainHash
 	databaseContext   model.DBManager
 
-	ghostdagManager       model.GHOSTDAGManager
-	dagTopologyManager    model.DAGTopologyManager
-	dagTraversalManager   model.DAGTraversalManager
-	pastMedianTimeManager model.PastMedianTimeManager
-	transactionValidator  model.TransactionValidator
-	coinbaseManager       model.CoinbaseManager
-	mergeDepthManager     model.MergeDepthManager
-	finalityManager       model.FinalityManager
-	difficultyManager     model.DifficultyManager
+	ghostdagManager        model.GHOSTDAGManager
+	dagTopologyManager     model.DAGTopologyManager
+	dagTraversalManager    model.DAGTraversalManager
+	pastMedianTimeManager  model.PastMedianTimeManager
+	transactionValidator   model.TransactionValidator
+	coinbaseManager        model.CoinbaseManager
+	mergeDepthManager      model.MergeDepthManager
+	finalityManager        model.FinalityManager
+	difficultyManager      model.DifficultyManager
+	consensusEventsManager model.ConsensusEventsManager
 
 	headersSelectedTipStore model.HeaderSelectedTipStore
 	blockStatusStore        model.BlockStatusStore
@@ -34,6 +35,7 @@ type consensusStateManager struct {
 	blockHeaderStore        model.BlockHeaderStore
 	pruningStore            model.PruningStore
 	daaBlocksStore          model.DAABlocksStore
+	finalityConflictStore   model.FinalityConflictStore
 
 	stores []model.Store
 }
@@ -54,6 +56,7 @@ func New(
 	mergeDepthManager model.MergeDepthManager,
 	finalityManager model.FinalityManager,
 	difficultyManager model.DifficultyManager,
+	consensusEventsManager model.ConsensusEventsManager,
 
 	blockStatusStore model.BlockStatusStore,
 	ghostdagDataStore model.GHOSTDAGDataStore,
@@ -66,7 +69,8 @@ func New(
 	blockHeaderStore model.BlockHeaderStore,
 	headersSelectedTipStore model.HeaderSelectedTipStore,
 	pruningStore model.PruningStore,
-	daaBlocksStore model.DAABlocksStore) (model.ConsensusStateManager, error) {
+	daaBlocksStore model.DAABlocksStore,
+	finalityConflictStore model.FinalityConflictStore) (model.ConsensusStateManager, error) {
 
 	csm := &consensusStateManager{
 		maxBlockParents:   maxBlockParents,
@@ -75,15 +79,16 @@ func New(
 
 		databaseContext: databaseContext,
 
-		ghostdagManager:       ghostdagManager,
-		dagTopologyManager:    dagTopologyManager,
-		dagTraversalManager:   dagTraversalManager,
-		pastMedianTimeManager: pastMedianTimeManager,
-		transactionValidator:  transactionValidator,
-		coinbaseManager:       coinbaseManager,
-		mergeDepthManager:     mergeDepthManager,
-		finalityManager:       finalityManager,
-		difficultyManager:     difficultyManager,
+		ghostdagManager:        ghostdagManager,
+		dagTopologyManager:     dagTopologyManager,
+		dagTraversalManager:    dagTraversalManager,
+		pastMedianTimeManager:  pastMedianTimeManager,
+		transactionValidator:   transactionValidator,
+		coinbaseManager:        coinbaseManager,
+		mergeDepthManager:      mergeDepthManager,
+		finalityManager:        finalityManager,
+		difficultyManager:      difficultyManager,
+		consensusEventsManager: consensusEventsManager,
 
 		multisetStore:           multisetStore,
 		blockStore:              blockStore,
@@ -97,6 +102,7 @@ func New(
 		headersSelectedTipStore: headersSelectedTipStore,
 		pruningStore:            pruningStore,
 		daaBlocksStore:          daaBlocksStore,
+		finalityConflictStore:   finalityConflictStore,
 
 		stores: []model.Store{
 			consensusStateStore,
@@ -111,6 +117,7 @@ func New(
 			blockHeaderStore,
 			headersSelectedTipStore,
 			pruningStore,
+			finalityConflictStore,
 		},
 	}
 
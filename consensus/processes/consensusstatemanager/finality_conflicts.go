@@ -0,0 +1,189 @@
+package consensusstatemanager
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// recordFinalityConflict stages the record of the finality conflict the given violating block is the tip of.
+// Only the violating blocks that aren't known to be part of a conflict yet are stored, so a growing violating
+// chain costs a single block per new tip.
+// shouldNotify is false if the conflict was already resolved, or if it's already recorded with the same tip
+// and finality point, so that a conflict is only published once every time it changes.
+func (csm *consensusStateManager) recordFinalityConflict(stagingArea *model.StagingArea,
+	violatingBlockHash *externalapi.DomainHash) (conflict *externalapi.FinalityConflict, shouldNotify bool, err error) {
+
+	finalityPoint, _, err := csm.finalityPointForViolationCheck(stagingArea)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Walk down the selected chain of the violating block until reaching either a block of a known
+	// conflict, or the selected chain of the finality point
+	var conflictID *externalapi.DomainHash
+	var newViolatingBlockHashes []*externalapi.DomainHash
+	current := violatingBlockHash
+	for {
+		conflictID, err = csm.finalityConflictStore.ConflictIDOfViolatingBlock(csm.databaseContext, stagingArea, current)
+		if err == nil {
+			break
+		}
+		if !database.IsNotFoundError(err) {
+			return nil, false, err
+		}
+
+		isInSelectedParentChainOfFinalityPoint, err :=
+			csm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, current, finalityPoint)
+		if err != nil {
+			return nil, false, err
+		}
+		if isInSelectedParentChainOfFinalityPoint {
+			break
+		}
+		newViolatingBlockHashes = append(newViolatingBlockHashes, current)
+
+		ghostdagData, err := csm.ghostdagDataStore.Get(csm.databaseContext, stagingArea, current, false)
+		if err != nil {
+			return nil, false, err
+		}
+		current = ghostdagData.SelectedParent()
+	}
+	if conflictID == nil {
+		if len(newViolatingBlockHashes) == 0 {
+			return nil, false, errors.Errorf("block %s is in the selected chain of the finality point %s, "+
+				"so it doesn't violate finality", violatingBlockHash, finalityPoint)
+		}
+		conflictID = newViolatingBlockHashes[len(newViolatingBlockHashes)-1]
+	}
+	csm.finalityConflictStore.StageViolatingBlocks(stagingArea, conflictID, newViolatingBlockHashes)
+
+	conflict = &externalapi.FinalityConflict{
+		LowestViolatingBlockHash: conflictID,
+		ViolatingBlockHash:       violatingBlockHash,
+		FinalityPointHash:        finalityPoint,
+	}
+
+	isResolved, err := csm.finalityConflictStore.IsResolved(csm.databaseContext, stagingArea, conflictID)
+	if err != nil {
+		return nil, false, err
+	}
+	if isResolved {
+		log.Debugf("Block %s violates finality, but its finality conflict %s was already resolved",
+			violatingBlockHash, conflictID)
+		return conflict, false, nil
+	}
+
+	previousRecord, err := csm.finalityConflictStore.FinalityConflict(csm.databaseContext, stagingArea, conflictID)
+	if err != nil && !database.IsNotFoundError(err) {
+		return nil, false, err
+	}
+	if err == nil && previousRecord.ViolatingBlockHash.Equal(violatingBlockHash) &&
+		previousRecord.FinalityPointHash.Equal(finalityPoint) {
+		return conflict, false, nil
+	}
+
+	csm.finalityConflictStore.StageFinalityConflict(stagingArea, conflict)
+	return conflict, true, nil
+}
+
+// FinalityConflicts returns the finality conflicts that weren't resolved yet
+func (csm *consensusStateManager) FinalityConflicts(stagingArea *model.StagingArea) ([]*externalapi.FinalityConflict, error) {
+	conflicts, err := csm.finalityConflictStore.FinalityConflicts(csm.databaseContext, stagingArea)
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range conflicts {
+		conflict.ViolatingBlockHashes, err = csm.violatingChain(stagingArea, conflict)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return conflicts, nil
+}
+
+// violatingChain returns the selected chain of the tip of the given conflict, from its lowest violating
+// block up to its tip. Blocks that were pruned since the conflict was recorded are left out.
+func (csm *consensusStateManager) violatingChain(stagingArea *model.StagingArea,
+	conflict *externalapi.FinalityConflict) ([]*externalapi.DomainHash, error) {
+
+	var violatingBlockHashes []*externalapi.DomainHash
+	current := conflict.ViolatingBlockHash
+	for {
+		violatingBlockHashes = append(violatingBlockHashes, current)
+		if current.Equal(conflict.LowestViolatingBlockHash) {
+			break
+		}
+
+		ghostdagData, err := csm.ghostdagDataStore.Get(csm.databaseContext, stagingArea, current, false)
+		if database.IsNotFoundError(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		current = ghostdagData.SelectedParent()
+	}
+	for i, j := 0, len(violatingBlockHashes)-1; i < j; i, j = i+1, j-1 {
+		violatingBlockHashes[i], violatingBlockHashes[j] = violatingBlockHashes[j], violatingBlockHashes[i]
+	}
+	return violatingBlockHashes, nil
+}
+
+// ResolveFinalityConflict stages the resolution of the unresolved finality conflict the given block is a violating
+// block of. If followViolatingChain is true, blocks whose selected chain contains the violating chain are no longer
+// considered to violate finality, so that the violating chain can become the selected chain once the virtual is
+// resolved. Otherwise, the conflict is dismissed and the violating chain keeps violating finality.
+func (csm *consensusStateManager) ResolveFinalityConflict(stagingArea *model.StagingArea,
+	violatingBlockHash *externalapi.DomainHash, followViolatingChain bool) (*externalapi.FinalityConflict, error) {
+
+	conflictID, err := csm.finalityConflictStore.ConflictIDOfViolatingBlock(csm.databaseContext, stagingArea, violatingBlockHash)
+	if database.IsNotFoundError(err) {
+		return nil, errors.Wrapf(model.ErrFinalityConflictNotFound, "block %s", violatingBlockHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conflict, err := csm.finalityConflictStore.FinalityConflict(csm.databaseContext, stagingArea, conflictID)
+	if database.IsNotFoundError(err) {
+		// The conflict of the block was already resolved
+		return nil, errors.Wrapf(model.ErrFinalityConflictNotFound, "block %s", violatingBlockHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if followViolatingChain {
+		// The UTXO set of the pruning point can't be reverted, so only chains that contain it can be followed
+		pruningPoint, err := csm.pruningStore.PruningPoint(csm.databaseContext, stagingArea)
+		if err != nil {
+			return nil, err
+		}
+		isPruningPointInViolatingChain, err :=
+			csm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, pruningPoint, conflict.ViolatingBlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if !isPruningPointInViolatingChain {
+			return nil, errors.Errorf("the violating chain of %s can't be followed since it doesn't contain "+
+				"the pruning point %s", conflict.ViolatingBlockHash, pruningPoint)
+		}
+	}
+
+	violatingBlockHashes, err := csm.violatingChain(stagingArea, conflict)
+	if err != nil {
+		return nil, err
+	}
+	resolvedConflict := &externalapi.FinalityConflict{
+		LowestViolatingBlockHash: conflict.LowestViolatingBlockHash,
+		ViolatingBlockHash:       conflict.ViolatingBlockHash,
+		FinalityPointHash:        conflict.FinalityPointHash,
+		ViolatingBlockHashes:     violatingBlockHashes,
+	}
+
+	csm.finalityConflictStore.StageResolution(stagingArea, conflict.ID(), followViolatingChain)
+	log.Infof("Resolved the finality conflict of %s. Following the violating chain: %t",
+		conflict.ViolatingBlockHash, followViolatingChain)
+	return resolvedConflict, nil
+}
@@ -11,9 +11,23 @@ import (
 )
 
 func (csm *consensusStateManager) pickVirtualParents(stagingArea *model.StagingArea, tips []*externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+	trace, err := csm.pickVirtualParentsAndTrace(stagingArea, tips)
+	if err != nil {
+		return nil, err
+	}
+	return trace.Parents, nil
+}
+
+// pickVirtualParentsAndTrace picks the virtual parents out of the given tips, and returns them along with
+// the steps in which the tips were trimmed down to them
+func (csm *consensusStateManager) pickVirtualParentsAndTrace(stagingArea *model.StagingArea,
+	tips []*externalapi.DomainHash) (*externalapi.VirtualParentsSelectionTrace, error) {
+
 	onEnd := logger.LogAndMeasureExecutionTime(log, "pickVirtualParents")
 	defer onEnd()
 
+	trace := &externalapi.VirtualParentsSelectionTrace{Tips: tips}
+
 	log.Debugf("pickVirtualParents start for tips len: %d", len(tips))
 
 	log.Debugf("Pushing all tips into a DownHeap")
@@ -29,11 +43,13 @@ func (csm *consensusStateManager) pickVirtualParents(stagingArea *model.StagingA
 	// it cannot be virtual's parent, since it will make it virtual's selectedParent - disqualifying virtual itself.
 	// Therefore, in such a case we remove it from the list of virtual parent candidates, and replace with
 	// its parents that have no disqualified children
-	virtualSelectedParent, err := csm.selectVirtualSelectedParent(stagingArea, candidatesHeap)
+	virtualSelectedParent, disqualifiedCandidates, err := csm.selectVirtualSelectedParent(stagingArea, candidatesHeap)
 	if err != nil {
 		return nil, err
 	}
 	log.Debugf("The selected parent of the virtual is: %s", virtualSelectedParent)
+	trace.SelectedParent = virtualSelectedParent
+	trace.DisqualifiedSelectedParentCandidates = disqualifiedCandidates
 
 	// Limit to maxBlockParents*3 candidates, that way we don't go over thousands of tips when the network isn't healthy.
 	// There's no specific reason for a factor of 3, and its not a consensus rule, just an estimation saying we probably
@@ -44,6 +60,7 @@ func (csm *consensusStateManager) pickVirtualParents(stagingArea *model.StagingA
 	for len(candidates) < maxCandidates && candidatesHeap.Len() > 0 {
 		candidates = append(candidates, candidatesHeap.Pop())
 	}
+	trace.DroppedCandidates = candidatesHeap.ToSlice()
 
 	// prioritize half the blocks with highest blueWork and half with lowest, so the network will merge splits faster.
 	if len(candidates) >= int(csm.maxBlockParents) {
@@ -92,6 +109,8 @@ func (csm *consensusStateManager) pickVirtualParents(stagingArea *model.StagingA
 			return nil, err
 		}
 		candidates = append(candidates, newCandidate)
+		trace.MergeSetSizeLimitReplacements = append(trace.MergeSetSizeLimitReplacements,
+			&externalapi.VirtualParentReplacement{Candidate: candidate, Replacement: newCandidate})
 		log.Debugf("Block %s increases merge set too much, instead adding its ancestor %s", candidate, newCandidate)
 	}
 
@@ -113,7 +132,9 @@ func (csm *consensusStateManager) pickVirtualParents(stagingArea *model.StagingA
 		}
 	}
 	log.Debugf("The virtual parents resolved to be: %s", selectedVirtualParents)
-	return selectedVirtualParents, nil
+	trace.BoundedMergeBreakingParents = boundedMergeBreakingParents
+	trace.Parents = selectedVirtualParents
+	return trace, nil
 }
 
 func (csm *consensusStateManager) removeHashesInFutureOf(stagingArea *model.StagingArea, hashes []*externalapi.DomainHash,
@@ -134,42 +155,46 @@ func (csm *consensusStateManager) removeHashesInFutureOf(stagingArea *model.Stag
 	return hashes[:i], nil
 }
 
+// selectVirtualSelectedParent pops candidates off candidatesHeap until it finds one that can be the virtual's selected
+// parent. It returns it along with the candidates that were disqualified on the way, in the order they were popped.
 func (csm *consensusStateManager) selectVirtualSelectedParent(stagingArea *model.StagingArea,
-	candidatesHeap model.BlockHeap) (*externalapi.DomainHash, error) {
+	candidatesHeap model.BlockHeap) (
+	selectedParent *externalapi.DomainHash, disqualifiedCandidates []*externalapi.DomainHash, err error) {
 
 	onEnd := logger.LogAndMeasureExecutionTime(log, "selectVirtualSelectedParent")
 	defer onEnd()
 
-	disqualifiedCandidates := hashset.New()
+	disqualifiedCandidatesSet := hashset.New()
 
 	for {
 		if candidatesHeap.Len() == 0 {
-			return nil, errors.New("virtual has no valid parent candidates")
+			return nil, nil, errors.New("virtual has no valid parent candidates")
 		}
 		selectedParentCandidate := candidatesHeap.Pop()
 
 		log.Debugf("Checking block %s for selected parent eligibility", selectedParentCandidate)
 		selectedParentCandidateStatus, err := csm.blockStatusStore.Get(csm.databaseContext, stagingArea, selectedParentCandidate)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if selectedParentCandidateStatus == externalapi.StatusUTXOValid {
 			log.Debugf("Block %s is valid. Returning it as the selected parent", selectedParentCandidate)
-			return selectedParentCandidate, nil
+			return selectedParentCandidate, disqualifiedCandidates, nil
 		}
 
 		log.Debugf("Block %s is not valid. Adding it to the disqualified set", selectedParentCandidate)
-		disqualifiedCandidates.Add(selectedParentCandidate)
+		disqualifiedCandidatesSet.Add(selectedParentCandidate)
+		disqualifiedCandidates = append(disqualifiedCandidates, selectedParentCandidate)
 
 		candidateParents, err := csm.dagTopologyManager.Parents(stagingArea, selectedParentCandidate)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		log.Debugf("The parents of block %s are: %s", selectedParentCandidate, candidateParents)
 		for _, parent := range candidateParents {
 			allParentChildren, err := csm.dagTopologyManager.Children(stagingArea, parent)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			log.Debugf("The children of block %s are: %s", parent, allParentChildren)
 
@@ -182,7 +207,7 @@ func (csm *consensusStateManager) selectVirtualSelectedParent(stagingArea *model
 
 				parentChildStatus, err := csm.blockStatusStore.Get(csm.databaseContext, stagingArea, parentChild)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				if parentChildStatus == externalapi.StatusHeaderOnly {
 					continue
@@ -191,12 +216,12 @@ func (csm *consensusStateManager) selectVirtualSelectedParent(stagingArea *model
 			}
 			log.Debugf("The non-virtual, non-headers-only children of block %s are: %s", parent, nonHeadersOnlyParentChildren)
 
-			if disqualifiedCandidates.ContainsAllInSlice(nonHeadersOnlyParentChildren) {
+			if disqualifiedCandidatesSet.ContainsAllInSlice(nonHeadersOnlyParentChildren) {
 				log.Debugf("The disqualified set contains all the "+
 					"children of %s. Adding it to the candidate heap", nonHeadersOnlyParentChildren)
 				err := candidatesHeap.Push(parent)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 			}
 		}
@@ -271,41 +296,74 @@ func (csm *consensusStateManager) boundedMergeBreakingParents(stagingArea *model
 
 	log.Tracef("boundedMergeBreakingParents start for parents: %s", parents)
 
-	log.Debug("Temporarily setting virtual to all parents, so that we can run ghostdag on it")
-	err := csm.dagTopologyManager.SetParents(stagingArea, model.VirtualBlockHash, parents)
+	badReds, _, _, err := csm.boundedMergeBadReds(stagingArea, parents)
 	if err != nil {
 		return nil, err
 	}
 
+	var boundedMergeBreakingParents []*externalapi.DomainHash
+	for _, parent := range parents {
+		log.Debugf("Checking whether parent %s breaks the bounded merge set", parent)
+		isBadRedInPast := false
+		for _, badRedBlock := range badReds {
+			isBadRedInPast, err = csm.dagTopologyManager.IsAncestorOf(stagingArea, badRedBlock, parent)
+			if err != nil {
+				return nil, err
+			}
+			if isBadRedInPast {
+				log.Debugf("Parent %s is a descendant of bad red %s", parent, badRedBlock)
+				break
+			}
+		}
+		if isBadRedInPast {
+			log.Debugf("Adding parent %s to the bounded merge breaking parents set", parent)
+			boundedMergeBreakingParents = append(boundedMergeBreakingParents, parent)
+		}
+	}
+
+	return boundedMergeBreakingParents, nil
+}
+
+// boundedMergeBadReds temporarily sets the virtual parents to the given parents and returns the reds in the
+// resulting merge set that break the bounded merge depth rule, along with the virtual merge depth root and
+// the potentially kosherizing blocks they were checked against
+func (csm *consensusStateManager) boundedMergeBadReds(stagingArea *model.StagingArea, parents []*externalapi.DomainHash) (
+	badReds []*externalapi.DomainHash, virtualMergeDepthRoot *externalapi.DomainHash,
+	potentiallyKosherizingBlocks []*externalapi.DomainHash, err error) {
+
+	log.Debug("Temporarily setting virtual to all parents, so that we can run ghostdag on it")
+	err = csm.dagTopologyManager.SetParents(stagingArea, model.VirtualBlockHash, parents)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	err = csm.ghostdagManager.GHOSTDAG(stagingArea, model.VirtualBlockHash)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	virtualMergeDepthRoot, err := csm.mergeDepthManager.VirtualMergeDepthRoot(stagingArea)
+	virtualMergeDepthRoot, err = csm.mergeDepthManager.VirtualMergeDepthRoot(stagingArea)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	log.Debugf("The merge depth root of virtual is: %s", virtualMergeDepthRoot)
 
-	potentiallyKosherizingBlocks, err :=
+	potentiallyKosherizingBlocks, err =
 		csm.mergeDepthManager.NonBoundedMergeDepthViolatingBlues(stagingArea, model.VirtualBlockHash, virtualMergeDepthRoot)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	log.Debugf("The potentially kosherizing blocks are: %s", potentiallyKosherizingBlocks)
 
-	var badReds []*externalapi.DomainHash
-
 	virtualGHOSTDAGData, err := csm.ghostdagDataStore.Get(csm.databaseContext, stagingArea, model.VirtualBlockHash, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	for _, redBlock := range virtualGHOSTDAGData.MergeSetReds() {
 		log.Debugf("Check whether red block %s is kosherized", redBlock)
 		isMergeDepthRootInPast, err := csm.dagTopologyManager.IsAncestorOf(stagingArea, virtualMergeDepthRoot, redBlock)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 		if isMergeDepthRootInPast {
 			log.Debugf("Skipping red block %s because it has the virtual's"+
@@ -317,7 +375,7 @@ func (csm *consensusStateManager) boundedMergeBreakingParents(stagingArea *model
 		for _, potentiallyKosherizingBlock := range potentiallyKosherizingBlocks {
 			isKosherized, err = csm.dagTopologyManager.IsAncestorOf(stagingArea, redBlock, potentiallyKosherizingBlock)
 			if err != nil {
-				return nil, err
+				return nil, nil, nil, err
 			}
 			log.Debugf("Red block %s is an ancestor of potentially kosherizing "+
 				"block %s, therefore the red block is kosher", redBlock, potentiallyKosherizingBlock)
@@ -331,25 +389,5 @@ func (csm *consensusStateManager) boundedMergeBreakingParents(stagingArea *model
 		}
 	}
 
-	var boundedMergeBreakingParents []*externalapi.DomainHash
-	for _, parent := range parents {
-		log.Debugf("Checking whether parent %s breaks the bounded merge set", parent)
-		isBadRedInPast := false
-		for _, badRedBlock := range badReds {
-			isBadRedInPast, err = csm.dagTopologyManager.IsAncestorOf(stagingArea, badRedBlock, parent)
-			if err != nil {
-				return nil, err
-			}
-			if isBadRedInPast {
-				log.Debugf("Parent %s is a descendant of bad red %s", parent, badRedBlock)
-				break
-			}
-		}
-		if isBadRedInPast {
-			log.Debugf("Adding parent %s to the bounded merge breaking parents set", parent)
-			boundedMergeBreakingParents = append(boundedMergeBreakingParents, parent)
-		}
-	}
-
-	return boundedMergeBreakingParents, nil
+	return badReds, virtualMergeDepthRoot, potentiallyKosherizingBlocks, nil
 }
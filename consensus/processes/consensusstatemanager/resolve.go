@@ -35,6 +35,9 @@ func (csm *consensusStateManager) tipsInDecreasingGHOSTDAGParentSelectionOrder(s
 	return tips, nil
 }
 
+// findNextPendingTip returns the highest tip that doesn't violate finality and may become the virtual selected
+// parent. The finality conflicts of the violating tips are recorded in the given staging area, and are
+// published once it's committed.
 func (csm *consensusStateManager) findNextPendingTip(stagingArea *model.StagingArea) (*externalapi.DomainHash, externalapi.BlockStatus, error) {
 	orderedTips, err := csm.tipsInDecreasingGHOSTDAGParentSelectionOrder(stagingArea)
 	if err != nil {
@@ -50,8 +53,14 @@ func (csm *consensusStateManager) findNextPendingTip(stagingArea *model.StagingA
 
 		if isViolatingFinality {
 			if shouldNotify {
-				//TODO: Send finality conflict notification
-				log.Warnf("Skipping %s tip resolution because it violates finality", tip)
+				conflict, shouldPublish, err := csm.recordFinalityConflict(stagingArea, tip)
+				if err != nil {
+					return nil, externalapi.StatusInvalid, err
+				}
+				if shouldPublish {
+					log.Warnf("Skipping %s tip resolution because it violates finality", tip)
+					csm.consensusEventsManager.StageEvent(stagingArea, conflict)
+				}
 			}
 			continue
 		}
@@ -68,6 +77,20 @@ func (csm *consensusStateManager) findNextPendingTip(stagingArea *model.StagingA
 	return nil, externalapi.StatusInvalid, nil
 }
 
+// commitFinalityConflicts commits the finality conflicts recorded in the given staging area and publishes
+// them, for when resolving the virtual ends without committing the staging area along with the virtual
+func (csm *consensusStateManager) commitFinalityConflicts(stagingArea *model.StagingArea) error {
+	if !csm.finalityConflictStore.IsStaged(stagingArea) {
+		return nil
+	}
+	err := staging.CommitAllChanges(csm.databaseContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	csm.consensusEventsManager.PublishStagedEvents(stagingArea)
+	return nil
+}
+
 // getGHOSTDAGLowerTips returns the set of tips which are lower in GHOSTDAG parent selection order than `pendingTip`. i.e.,
 // they can be added to virtual parents but `pendingTip` will remain the virtual selected parent
 func (csm *consensusStateManager) getGHOSTDAGLowerTips(stagingArea *model.StagingArea, pendingTip *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
@@ -100,14 +123,17 @@ func (csm *consensusStateManager) ResolveVirtual(maxBlocksToResolve uint64) (*ex
 	// confusion with the resolve/updateVirtual staging areas below
 	readStagingArea := model.NewStagingArea()
 
-	pendingTip, pendingTipStatus, err := csm.findNextPendingTip(readStagingArea)
+	// The pending tip is looked for in the staging area the virtual is updated in, so that the finality
+	// conflicts recorded on the way are committed, and published, along with the virtual
+	updateVirtualStagingArea := model.NewStagingArea()
+	pendingTip, pendingTipStatus, err := csm.findNextPendingTip(updateVirtualStagingArea)
 	if err != nil {
 		return nil, false, err
 	}
 
 	if pendingTip == nil {
 		log.Warnf("None of the DAG tips are valid")
-		return nil, true, nil
+		return nil, true, csm.commitFinalityConflicts(updateVirtualStagingArea)
 	}
 
 	previousVirtualSelectedParent, err := csm.virtualSelectedParent(readStagingArea)
@@ -116,7 +142,7 @@ func (csm *consensusStateManager) ResolveVirtual(maxBlocksToResolve uint64) (*ex
 	}
 
 	if pendingTipStatus == externalapi.StatusUTXOValid && previousVirtualSelectedParent.Equal(pendingTip) {
-		return nil, true, nil
+		return nil, true, csm.commitFinalityConflicts(updateVirtualStagingArea)
 	}
 
 	// Resolve a chunk from the pending chain
@@ -179,8 +205,6 @@ func (csm *consensusStateManager) ResolveVirtual(maxBlocksToResolve uint64) (*ex
 	isActualTip := processingPoint.Equal(pendingTip)
 	isCompletelyResolved := isActualTip && processingPointStatus == externalapi.StatusUTXOValid
 
-	updateVirtualStagingArea := model.NewStagingArea()
-
 	virtualParents := []*externalapi.DomainHash{processingPoint}
 	// If `isCompletelyResolved`, set virtual correctly with all tips which have less blue work than pending
 	if isCompletelyResolved {
@@ -205,6 +229,7 @@ func (csm *consensusStateManager) ResolveVirtual(maxBlocksToResolve uint64) (*ex
 	if err != nil {
 		return nil, false, err
 	}
+	csm.consensusEventsManager.PublishStagedEvents(updateVirtualStagingArea)
 
 	selectedParentChainChanges, err := csm.dagTraversalManager.
 		CalculateChainPath(updateVirtualStagingArea, previousVirtualSelectedParent, processingPoint)
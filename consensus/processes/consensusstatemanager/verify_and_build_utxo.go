@@ -1,8 +1,6 @@
 package consensusstatemanager
 
 import (
-	"sort"
-
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/transactionhelper"
 
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
@@ -134,22 +132,7 @@ func calculateAcceptedIDMerkleRoot(multiblockAcceptanceData externalapi.Acceptan
 	log.Tracef("calculateAcceptedIDMerkleRoot start")
 	defer log.Tracef("calculateAcceptedIDMerkleRoot end")
 
-	var acceptedTransactions []*externalapi.DomainTransaction
-
-	for _, blockAcceptanceData := range multiblockAcceptanceData {
-		for _, transactionAcceptance := range blockAcceptanceData.TransactionAcceptanceData {
-			if !transactionAcceptance.IsAccepted {
-				continue
-			}
-			acceptedTransactions = append(acceptedTransactions, transactionAcceptance.Transaction)
-		}
-	}
-	sort.Slice(acceptedTransactions, func(i, j int) bool {
-		return consensushashing.TransactionID(acceptedTransactions[i]).Less(
-			consensushashing.TransactionID(acceptedTransactions[j]))
-	})
-
-	return merkle.CalculateIDMerkleRoot(acceptedTransactions)
+	return merkle.CalculateAcceptedIDMerkleRoot(multiblockAcceptanceData)
 }
 
 func (csm *consensusStateManager) validateCoinbaseTransaction(stagingArea *model.StagingArea,
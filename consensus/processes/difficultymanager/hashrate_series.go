@@ -0,0 +1,217 @@
+package difficultymanager
+
+import (
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/karlsen-network/karlsend/v2/util/difficulty"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// confidenceZScore is the z-score of a 95% confidence interval
+const confidenceZScore = 1.96
+
+// hashrateChainBlock is a selected chain block along with what the hashrate series needs to know about it
+type hashrateChainBlock struct {
+	hash               *externalapi.DomainHash
+	blueScore          uint64
+	blueWork           *big.Int
+	timeInMilliseconds int64
+
+	// minerBlueWorkAbove is the blue work done by the miner that was merged by the chain blocks above this one
+	minerBlueWorkAbove *big.Int
+	// unknownMinerBlueWorkAbove is the blue work of unknown miners that was merged by the chain blocks above this one
+	unknownMinerBlueWorkAbove *big.Int
+}
+
+// EstimateNetworkHashesPerSecondSeries estimates the hashrate over sliding windows along the selected
+// chain of startHash, starting from the most recent window. Every window is measured in blue blocks,
+// and its hashrate is the blue work merged in the window divided by the time between the chain
+// blocks at its ends. isMinerBlock may be nil if no miner share is estimated.
+func (dm *difficultyManager) EstimateNetworkHashesPerSecondSeries(startHash *externalapi.DomainHash,
+	options *externalapi.HashrateEstimationOptions,
+	isMinerBlock func(blockHash *externalapi.DomainHash) (bool, bool, error)) ([]*externalapi.HashrateEstimate, error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "EstimateNetworkHashesPerSecondSeries")
+	defer onEnd()
+
+	if options.WindowSize == 0 {
+		return nil, errors.New("windowSize must be positive")
+	}
+	if options.WindowCount <= 0 {
+		return nil, errors.New("windowCount must be positive")
+	}
+	windowStep := options.WindowStep
+	if windowStep == 0 {
+		windowStep = options.WindowSize
+	}
+	windowsSpan, err := hashrateWindowsSpan(options, windowStep)
+	if err != nil {
+		return nil, err
+	}
+
+	stagingArea := model.NewStagingArea()
+	chain, err := dm.hashrateChain(stagingArea, startHash, windowsSpan, isMinerBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := make([]*externalapi.HashrateEstimate, 0, options.WindowCount)
+	startBlueScore := chain[0].blueScore
+	for i := 0; i < options.WindowCount; i++ {
+		offset := uint64(i) * windowStep
+		if offset+options.WindowSize > startBlueScore {
+			break
+		}
+		endIndex := chainIndexAtBlueScore(chain, startBlueScore-offset)
+		startIndex := chainIndexAtBlueScore(chain, startBlueScore-offset-options.WindowSize)
+		if startIndex == len(chain) || startIndex == endIndex {
+			break
+		}
+		estimates = append(estimates, hashrateEstimate(chain[startIndex], chain[endIndex], isMinerBlock != nil))
+	}
+	return estimates, nil
+}
+
+// hashrateWindowsSpan returns the number of blue blocks the windows selected by the options span
+// together, or an error if it's above MaxHashrateEstimationSpan
+func hashrateWindowsSpan(options *externalapi.HashrateEstimationOptions, windowStep uint64) (uint64, error) {
+	const maxSpan = externalapi.MaxHashrateEstimationSpan
+	if options.WindowSize > maxSpan || windowStep > maxSpan || uint64(options.WindowCount-1) > maxSpan {
+		return 0, errors.Errorf("the windows must span at most %d blue blocks", maxSpan)
+	}
+	windowsSpan := uint64(options.WindowCount-1)*windowStep + options.WindowSize
+	if windowsSpan > maxSpan {
+		return 0, errors.Errorf("the windows must span at most %d blue blocks, but they span %d",
+			maxSpan, windowsSpan)
+	}
+	return windowsSpan, nil
+}
+
+// hashrateChain returns the selected chain of startHash, from startHash down to the lowest
+// chain block any of the windows may start at, or down to the genesis
+func (dm *difficultyManager) hashrateChain(stagingArea *model.StagingArea, startHash *externalapi.DomainHash,
+	windowsSpan uint64, isMinerBlock func(blockHash *externalapi.DomainHash) (bool, bool, error)) (
+	[]*hashrateChainBlock, error) {
+
+	var chain []*hashrateChainBlock
+	minerBlueWorkAbove := big.NewInt(0)
+	unknownMinerBlueWorkAbove := big.NewInt(0)
+	lowestBlueScore := uint64(0)
+	current := startHash
+	for {
+		ghostdagData, err := dm.ghostdagStore.Get(dm.databaseContext, stagingArea, current, false)
+		if err != nil {
+			return nil, err
+		}
+		header, err := dm.headerStore.BlockHeader(dm.databaseContext, stagingArea, current)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, &hashrateChainBlock{
+			hash:               current,
+			blueScore:          ghostdagData.BlueScore(),
+			blueWork:           ghostdagData.BlueWork(),
+			timeInMilliseconds: header.TimeInMilliseconds(),
+
+			minerBlueWorkAbove:        minerBlueWorkAbove,
+			unknownMinerBlueWorkAbove: unknownMinerBlueWorkAbove,
+		})
+
+		if len(chain) == 1 {
+			if windowsSpan < ghostdagData.BlueScore() {
+				lowestBlueScore = ghostdagData.BlueScore() - windowsSpan
+			}
+		}
+		if ghostdagData.BlueScore() <= lowestBlueScore || current.Equal(dm.genesisHash) ||
+			ghostdagData.SelectedParent() == nil || ghostdagData.SelectedParent().Equal(model.VirtualGenesisBlockHash) {
+			break
+		}
+
+		if isMinerBlock != nil {
+			minerBlueWork, unknownMinerBlueWork, err := dm.minerBlueWork(stagingArea, ghostdagData.MergeSetBlues(),
+				isMinerBlock)
+			if err != nil {
+				return nil, err
+			}
+			minerBlueWorkAbove = new(big.Int).Add(minerBlueWorkAbove, minerBlueWork)
+			unknownMinerBlueWorkAbove = new(big.Int).Add(unknownMinerBlueWorkAbove, unknownMinerBlueWork)
+		}
+		current = ghostdagData.SelectedParent()
+	}
+	return chain, nil
+}
+
+// minerBlueWork returns the work of the given blue blocks that were mined by the miner, and the work
+// of the ones whose miner is unknown
+func (dm *difficultyManager) minerBlueWork(stagingArea *model.StagingArea, blues []*externalapi.DomainHash,
+	isMinerBlock func(blockHash *externalapi.DomainHash) (bool, bool, error)) (
+	minerBlueWork *big.Int, unknownMinerBlueWork *big.Int, err error) {
+
+	minerBlueWork = big.NewInt(0)
+	unknownMinerBlueWork = big.NewInt(0)
+	for _, blue := range blues {
+		isMiner, isKnown, err := isMinerBlock(blue)
+		if err != nil {
+			return nil, nil, err
+		}
+		var blueWork *big.Int
+		switch {
+		case !isKnown:
+			blueWork = unknownMinerBlueWork
+		case isMiner:
+			blueWork = minerBlueWork
+		default:
+			continue
+		}
+		header, err := dm.headerStore.BlockHeader(dm.databaseContext, stagingArea, blue)
+		if err != nil {
+			return nil, nil, err
+		}
+		blueWork.Add(blueWork, difficulty.CalcWork(header.Bits()))
+	}
+	return minerBlueWork, unknownMinerBlueWork, nil
+}
+
+// chainIndexAtBlueScore returns the index of the highest chain block whose blue score is at most
+// blueScore, or len(chain) if there's no such block
+func chainIndexAtBlueScore(chain []*hashrateChainBlock, blueScore uint64) int {
+	return sort.Search(len(chain), func(i int) bool {
+		return chain[i].blueScore <= blueScore
+	})
+}
+
+func hashrateEstimate(start, end *hashrateChainBlock, isMinerSelected bool) *externalapi.HashrateEstimate {
+	estimate := &externalapi.HashrateEstimate{
+		StartHash:               start.hash,
+		EndHash:                 end.hash,
+		StartTimeInMilliseconds: start.timeInMilliseconds,
+		EndTimeInMilliseconds:   end.timeInMilliseconds,
+		BlueBlocks:              end.blueScore - start.blueScore,
+	}
+
+	blueWork := new(big.Int).Sub(end.blueWork, start.blueWork)
+	if isMinerSelected && blueWork.Sign() > 0 {
+		minerBlueWork := new(big.Int).Sub(start.minerBlueWorkAbove, end.minerBlueWorkAbove)
+		estimate.MinerBlueWorkShare, _ = new(big.Rat).SetFrac(minerBlueWork, blueWork).Float64()
+		unknownMinerBlueWork := new(big.Int).Sub(start.unknownMinerBlueWorkAbove, end.unknownMinerBlueWorkAbove)
+		estimate.UnknownMinerBlueWorkShare, _ = new(big.Rat).SetFrac(unknownMinerBlueWork, blueWork).Float64()
+	}
+
+	milliseconds := end.timeInMilliseconds - start.timeInMilliseconds
+	if milliseconds <= 0 || estimate.BlueBlocks == 0 {
+		return estimate
+	}
+	hashesPerSecond := new(big.Int).Mul(blueWork, big.NewInt(1000))
+	hashesPerSecond.Div(hashesPerSecond, big.NewInt(milliseconds))
+	estimate.HashesPerSecond = hashesPerSecond.Uint64()
+
+	relativeError := confidenceZScore / math.Sqrt(float64(estimate.BlueBlocks))
+	estimate.LowerBound = uint64(math.Max(0, float64(estimate.HashesPerSecond)*(1-relativeError)))
+	estimate.UpperBound = uint64(float64(estimate.HashesPerSecond) * (1 + relativeError))
+	return estimate
+}
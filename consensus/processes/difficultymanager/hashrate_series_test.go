@@ -0,0 +1,141 @@
+package difficultymanager_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/karlsen-network/karlsend/v2/util/difficulty"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+func TestEstimateNetworkHashesPerSecondSeries(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestEstimateNetworkHashesPerSecondSeries")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	minerA := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	minerB := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{2}, Version: 0},
+		ExtraData:       []byte{},
+	}
+
+	// Mine a chain with a block every second, alternating between the two miners. The chain is
+	// shorter than the DAA window, so every block has the genesis difficulty.
+	const chainLength = 300
+	tip := consensusConfig.GenesisHash
+	tipTime := consensusConfig.GenesisBlock.Header.TimeInMilliseconds()
+	for i := 0; i < chainLength; i++ {
+		coinbaseData := minerA
+		if i%2 == 1 {
+			coinbaseData = minerB
+		}
+		block, _, err := tc.BuildBlockWithParents([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+		if err != nil {
+			t.Fatalf("BuildBlockWithParents: %+v", err)
+		}
+		tipTime += 1000
+		header := block.Header.ToMutable()
+		header.SetTimeInMilliseconds(tipTime)
+		block.Header = header.ToImmutable()
+		err = tc.ValidateAndInsertBlock(block, true)
+		if err != nil {
+			t.Fatalf("ValidateAndInsertBlock: %+v", err)
+		}
+		tip = consensushashing.BlockHash(block)
+	}
+
+	estimates, err := tc.EstimateNetworkHashesPerSecondSeries(tip, &externalapi.HashrateEstimationOptions{
+		WindowSize:           100,
+		WindowStep:           50,
+		WindowCount:          10,
+		MinerScriptPublicKey: minerA.ScriptPublicKey,
+	})
+	if err != nil {
+		t.Fatalf("EstimateNetworkHashesPerSecondSeries: %+v", err)
+	}
+
+	// The windows end every 50 blue blocks from the tip, as long as 100 blue blocks fit below them
+	if len(estimates) != 5 {
+		t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected window count: want: 5, got: %d", len(estimates))
+	}
+	if !estimates[0].EndHash.Equal(tip) {
+		t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: expected the first window to end at the tip")
+	}
+
+	expectedHashesPerSecond := difficulty.CalcWork(consensusConfig.GenesisBlock.Header.Bits()).Uint64()
+	for i, estimate := range estimates {
+		if estimate.BlueBlocks != 100 {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected blue blocks in window %d: want: 100, got: %d",
+				i, estimate.BlueBlocks)
+		}
+		if estimate.EndTimeInMilliseconds-estimate.StartTimeInMilliseconds != 100_000 {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected duration of window %d: %dms",
+				i, estimate.EndTimeInMilliseconds-estimate.StartTimeInMilliseconds)
+		}
+		if estimate.HashesPerSecond != expectedHashesPerSecond {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected hashrate in window %d: want: %d, got: %d",
+				i, expectedHashesPerSecond, estimate.HashesPerSecond)
+		}
+		if estimate.LowerBound >= estimate.HashesPerSecond || estimate.UpperBound <= estimate.HashesPerSecond {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected bounds in window %d: [%d, %d]",
+				i, estimate.LowerBound, estimate.UpperBound)
+		}
+		if math.Abs(estimate.MinerBlueWorkShare-0.5) > 0.01 {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected miner share in window %d: want: 0.5, got: %f",
+				i, estimate.MinerBlueWorkShare)
+		}
+		if estimate.UnknownMinerBlueWorkShare != 0 {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: unexpected unknown miner share in window %d: "+
+				"want: 0, got: %f", i, estimate.UnknownMinerBlueWorkShare)
+		}
+		if i > 0 && estimate.EndTimeInMilliseconds != estimates[i-1].EndTimeInMilliseconds-50_000 {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeries: expected window %d to end 50 blocks before window %d",
+				i, i-1)
+		}
+	}
+}
+
+func TestEstimateNetworkHashesPerSecondSeriesRejectsLongSpans(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig,
+		"TestEstimateNetworkHashesPerSecondSeriesRejectsLongSpans")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	tests := []struct {
+		name    string
+		options *externalapi.HashrateEstimationOptions
+	}{
+		{
+			name:    "window too large",
+			options: &externalapi.HashrateEstimationOptions{WindowSize: externalapi.MaxHashrateEstimationSpan + 1, WindowCount: 1},
+		},
+		{
+			name:    "too many windows",
+			options: &externalapi.HashrateEstimationOptions{WindowSize: 1000, WindowCount: 101},
+		},
+		{
+			name: "overflowing span",
+			options: &externalapi.HashrateEstimationOptions{WindowSize: 1, WindowStep: math.MaxUint64,
+				WindowCount: 2},
+		},
+	}
+	for _, test := range tests {
+		_, err := tc.EstimateNetworkHashesPerSecondSeries(consensusConfig.GenesisHash, test.options)
+		if err == nil {
+			t.Fatalf("TestEstimateNetworkHashesPerSecondSeriesRejectsLongSpans: %s: expected an error", test.name)
+		}
+	}
+}
@@ -49,6 +49,12 @@ func (gh *ghostdagHelper) GHOSTDAG(stagingArea *model.StagingArea, blockCandidat
 	if err != nil {
 		return err
 	}
+	// Genesis's blue score and blue work are defined to be 0
+	if len(blockParents) == 0 {
+		e := externalapi.NewBlockGHOSTDAGData(0, new(big.Int), nil, nil, nil, nil)
+		gh.dataStore.Stage(stagingArea, blockCandidate, e, false)
+		return nil
+	}
 	var selectedParent = blockParents[0]
 	for _, parent := range blockParents {
 		blockData, err := gh.dataStore.Get(gh.dbAccess, stagingArea, parent, false)
@@ -109,6 +115,10 @@ func (gh *ghostdagHelper) GHOSTDAG(stagingArea *model.StagingArea, blockCandidat
 
 	// We add up all the *work*(not blueWork) that all our blues and selected parent did
 	for _, blue := range mergeSetBlues {
+		// We don't count the work of the virtual genesis
+		if blue.Equal(model.VirtualGenesisBlockHash) {
+			continue
+		}
 		header, err := gh.headerStore.BlockHeader(gh.dbAccess, stagingArea, blue)
 		if err != nil {
 			return err
@@ -404,13 +414,58 @@ func (gh *ghostdagHelper) BlockData(stagingArea *model.StagingArea, blockHash *e
 	return gh.dataStore.Get(gh.dbAccess, stagingArea, blockHash, false)
 }
 func (gh *ghostdagHelper) ChooseSelectedParent(stagingArea *model.StagingArea, blockHashes ...*externalapi.DomainHash) (*externalapi.DomainHash, error) {
-	panic("implement me")
+	selectedParent := blockHashes[0]
+	selectedParentData, err := gh.BlockData(stagingArea, selectedParent)
+	if err != nil {
+		return nil, err
+	}
+	for _, blockHash := range blockHashes[1:] {
+		blockData, err := gh.BlockData(stagingArea, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		if gh.Less(selectedParent, selectedParentData, blockHash, blockData) {
+			selectedParent = blockHash
+			selectedParentData = blockData
+		}
+	}
+	return selectedParent, nil
 }
 
-func (gh *ghostdagHelper) Less(blockHashA *externalapi.DomainHash, ghostdagDataA *externalapi.BlockGHOSTDAGData, blockHashB *externalapi.DomainHash, ghostdagDataB *externalapi.BlockGHOSTDAGData) bool {
-	panic("implement me")
+func (gh *ghostdagHelper) Less(blockHashA *externalapi.DomainHash, ghostdagDataA *externalapi.BlockGHOSTDAGData,
+	blockHashB *externalapi.DomainHash, ghostdagDataB *externalapi.BlockGHOSTDAGData) bool {
+
+	workComparison := ghostdagDataA.BlueWork().Cmp(ghostdagDataB.BlueWork())
+	if workComparison != 0 {
+		return workComparison == -1
+	}
+	return ismoreHash(blockHashB, blockHashA)
 }
 
-func (gh *ghostdagHelper) GetSortedMergeSet(*model.StagingArea, *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
-	panic("implement me")
+/* ----------------GetSortedMergeSet------------------- */
+/* The selected parent, followed by the rest of the merge set sorted by blue work and then by hash,
+   which is the topological order ghostdagmanager sorts merge sets by */
+func (gh *ghostdagHelper) GetSortedMergeSet(stagingArea *model.StagingArea, current *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+	currentData, err := gh.BlockData(stagingArea, current)
+	if err != nil {
+		return nil, err
+	}
+	blues := currentData.MergeSetBlues()
+	reds := currentData.MergeSetReds()
+	if len(blues) == 0 {
+		return []*externalapi.DomainHash{}, nil
+	}
+
+	// The merge set is sorted here rather than relying on the order the blues and reds were colored in
+	mergeSetWithoutSelectedParent := make([]*externalapi.DomainHash, 0, len(blues)-1+len(reds))
+	mergeSetWithoutSelectedParent = append(mergeSetWithoutSelectedParent, blues[1:]...)
+	mergeSetWithoutSelectedParent = append(mergeSetWithoutSelectedParent, reds...)
+	err = gh.sortByBlueWork(stagingArea, mergeSetWithoutSelectedParent)
+	if err != nil {
+		return nil, err
+	}
+
+	sortedMergeSet := make([]*externalapi.DomainHash, 0, len(blues)+len(reds))
+	sortedMergeSet = append(sortedMergeSet, blues[0])
+	return append(sortedMergeSet, mergeSetWithoutSelectedParent...), nil
 }
@@ -0,0 +1,152 @@
+package ghostdagdifferential
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// Divergence describes how the results of the secondary GHOSTDAG implementation
+// differ from the results of the primary one for a single block
+type Divergence struct {
+	BlockHash   *externalapi.DomainHash
+	Differences []string
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("GHOSTDAG implementations diverge on block %s: %s",
+		d.BlockHash, strings.Join(d.Differences, "; "))
+}
+
+// differentialGHOSTDAGManager runs two GHOSTDAG implementations side by side and reports
+// every divergence between them. Only the results of the primary implementation are used.
+type differentialGHOSTDAGManager struct {
+	databaseContext            model.DBReader
+	ghostdagDataStore          model.GHOSTDAGDataStore
+	secondaryGHOSTDAGDataStore model.GHOSTDAGDataStore
+
+	primary      model.GHOSTDAGManager
+	secondary    model.GHOSTDAGManager
+	onDivergence func(divergence *Divergence)
+}
+
+// New instantiates a new GHOSTDAGManager that runs a secondary implementation along with primary.
+// primary is expected to stage its results in ghostdagDataStore. The secondary implementation is
+// built by newSecondary over a store of its own, whose data is never written to the database.
+// If onDivergence is nil, divergences are logged.
+func New(
+	databaseContext model.DBReader,
+	ghostdagDataStore model.GHOSTDAGDataStore,
+	primary model.GHOSTDAGManager,
+	newSecondary func(ghostdagDataStore model.GHOSTDAGDataStore) model.GHOSTDAGManager,
+	onDivergence func(divergence *Divergence)) model.GHOSTDAGManager {
+
+	if onDivergence == nil {
+		onDivergence = func(divergence *Divergence) {
+			log.Warnf("%s", divergence)
+		}
+	}
+
+	secondaryGHOSTDAGDataStore := newSecondaryGHOSTDAGDataStore(ghostdagDataStore)
+	return &differentialGHOSTDAGManager{
+		databaseContext:            databaseContext,
+		ghostdagDataStore:          ghostdagDataStore,
+		secondaryGHOSTDAGDataStore: secondaryGHOSTDAGDataStore,
+		primary:                    primary,
+		secondary:                  newSecondary(secondaryGHOSTDAGDataStore),
+		onDivergence:               onDivergence,
+	}
+}
+
+// GHOSTDAG runs both implementations and reports where their results differ.
+// Failures of the secondary implementation are reported as divergences.
+func (dm *differentialGHOSTDAGManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	var secondaryData *externalapi.BlockGHOSTDAGData
+	secondaryErr := dm.secondary.GHOSTDAG(stagingArea, blockHash)
+	if secondaryErr == nil {
+		secondaryData, secondaryErr = dm.secondaryGHOSTDAGDataStore.Get(dm.databaseContext, stagingArea, blockHash, false)
+	}
+
+	err := dm.primary.GHOSTDAG(stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	primaryData, err := dm.ghostdagDataStore.Get(dm.databaseContext, stagingArea, blockHash, false)
+	if err != nil {
+		return err
+	}
+
+	if secondaryErr != nil {
+		dm.report(blockHash, []string{fmt.Sprintf("the secondary implementation failed: %s", secondaryErr)})
+		return nil
+	}
+	dm.report(blockHash, ghostdagDataDifferences(primaryData, secondaryData))
+	return nil
+}
+
+func (dm *differentialGHOSTDAGManager) ChooseSelectedParent(stagingArea *model.StagingArea,
+	blockHashes ...*externalapi.DomainHash) (*externalapi.DomainHash, error) {
+
+	return dm.primary.ChooseSelectedParent(stagingArea, blockHashes...)
+}
+
+func (dm *differentialGHOSTDAGManager) Less(blockHashA *externalapi.DomainHash, ghostdagDataA *externalapi.BlockGHOSTDAGData,
+	blockHashB *externalapi.DomainHash, ghostdagDataB *externalapi.BlockGHOSTDAGData) bool {
+
+	return dm.primary.Less(blockHashA, ghostdagDataA, blockHashB, ghostdagDataB)
+}
+
+// GetSortedMergeSet returns the sorted merge set of the primary implementation, and
+// reports it if the secondary implementation sorts the merge set differently
+func (dm *differentialGHOSTDAGManager) GetSortedMergeSet(stagingArea *model.StagingArea,
+	current *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+
+	sortedMergeSet, err := dm.primary.GetSortedMergeSet(stagingArea, current)
+	if err != nil {
+		return nil, err
+	}
+
+	secondarySortedMergeSet, err := dm.secondary.GetSortedMergeSet(stagingArea, current)
+	if err != nil {
+		dm.report(current, []string{fmt.Sprintf("the secondary implementation failed to sort the merge set: %s", err)})
+	} else if !externalapi.HashesEqual(sortedMergeSet, secondarySortedMergeSet) {
+		dm.report(current, []string{fmt.Sprintf("sorted merge set %v != %v", sortedMergeSet, secondarySortedMergeSet)})
+	}
+	return sortedMergeSet, nil
+}
+
+func (dm *differentialGHOSTDAGManager) report(blockHash *externalapi.DomainHash, differences []string) {
+	if len(differences) == 0 {
+		return
+	}
+	dm.onDivergence(&Divergence{BlockHash: blockHash, Differences: differences})
+}
+
+// ghostdagDataDifferences compares everything but the blues anticone sizes, which
+// are an optimization of the primary implementation
+func ghostdagDataDifferences(primaryData, secondaryData *externalapi.BlockGHOSTDAGData) []string {
+	var differences []string
+	if !primaryData.SelectedParent().Equal(secondaryData.SelectedParent()) {
+		differences = append(differences, fmt.Sprintf("selected parent %s != %s",
+			primaryData.SelectedParent(), secondaryData.SelectedParent()))
+	}
+	if primaryData.BlueScore() != secondaryData.BlueScore() {
+		differences = append(differences, fmt.Sprintf("blue score %d != %d",
+			primaryData.BlueScore(), secondaryData.BlueScore()))
+	}
+	if primaryData.BlueWork().Cmp(secondaryData.BlueWork()) != 0 {
+		differences = append(differences, fmt.Sprintf("blue work %s != %s",
+			primaryData.BlueWork(), secondaryData.BlueWork()))
+	}
+	if !externalapi.HashesEqual(primaryData.MergeSetBlues(), secondaryData.MergeSetBlues()) {
+		differences = append(differences, fmt.Sprintf("merge set blues %v != %v",
+			primaryData.MergeSetBlues(), secondaryData.MergeSetBlues()))
+	}
+	if !externalapi.HashesEqual(primaryData.MergeSetReds(), secondaryData.MergeSetReds()) {
+		differences = append(differences, fmt.Sprintf("merge set reds %v != %v",
+			primaryData.MergeSetReds(), secondaryData.MergeSetReds()))
+	}
+	return differences
+}
@@ -0,0 +1,127 @@
+package ghostdagdifferential
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/ghostdagdatastore"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// fakeGHOSTDAGManager stages the same GHOSTDAG data for every block
+type fakeGHOSTDAGManager struct {
+	model.GHOSTDAGManager
+	ghostdagDataStore model.GHOSTDAGDataStore
+	ghostdagData      *externalapi.BlockGHOSTDAGData
+	sortedMergeSet    []*externalapi.DomainHash
+	err               error
+}
+
+func (fm *fakeGHOSTDAGManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	if fm.err != nil {
+		return fm.err
+	}
+	fm.ghostdagDataStore.Stage(stagingArea, blockHash, fm.ghostdagData, false)
+	return nil
+}
+
+func (fm *fakeGHOSTDAGManager) GetSortedMergeSet(*model.StagingArea, *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+	return fm.sortedMergeSet, fm.err
+}
+
+func TestDifferentialGHOSTDAG(t *testing.T) {
+	ghostdagDataStore := ghostdagdatastore.New(database.MakeBucket(nil), 10, false, model.StoreEncodingProtobuf)
+	selectedParent := externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{1})
+	red := externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{2})
+	primaryData := externalapi.NewBlockGHOSTDAGData(2, big.NewInt(10), selectedParent,
+		[]*externalapi.DomainHash{selectedParent}, []*externalapi.DomainHash{red}, nil)
+	primary := &fakeGHOSTDAGManager{
+		ghostdagDataStore: ghostdagDataStore,
+		ghostdagData:      primaryData,
+		sortedMergeSet:    []*externalapi.DomainHash{selectedParent, red},
+	}
+	secondary := &fakeGHOSTDAGManager{}
+	newSecondary := func(secondaryGHOSTDAGDataStore model.GHOSTDAGDataStore) model.GHOSTDAGManager {
+		secondary.ghostdagDataStore = secondaryGHOSTDAGDataStore
+		return secondary
+	}
+
+	var divergences []*Divergence
+	manager := New(nil, ghostdagDataStore, primary, newSecondary, func(divergence *Divergence) {
+		divergences = append(divergences, divergence)
+	})
+	blockHash := externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{3})
+
+	tests := []struct {
+		name                string
+		secondaryData       *externalapi.BlockGHOSTDAGData
+		secondarySortedSet  []*externalapi.DomainHash
+		secondaryErr        error
+		expectedDifferences int
+	}{
+		{
+			name:               "identical results",
+			secondaryData:      primaryData,
+			secondarySortedSet: []*externalapi.DomainHash{selectedParent, red},
+		},
+		{
+			name: "red block colored blue",
+			secondaryData: externalapi.NewBlockGHOSTDAGData(3, big.NewInt(11), selectedParent,
+				[]*externalapi.DomainHash{selectedParent, red}, nil, nil),
+			secondarySortedSet:  []*externalapi.DomainHash{selectedParent, red},
+			expectedDifferences: 4,
+		},
+		{
+			name:                "different merge set order",
+			secondaryData:       primaryData,
+			secondarySortedSet:  []*externalapi.DomainHash{red, selectedParent},
+			expectedDifferences: 1,
+		},
+		{
+			name:                "secondary failure",
+			secondaryErr:        errors.New("secondary failure"),
+			expectedDifferences: 2,
+		},
+	}
+
+	for _, test := range tests {
+		divergences = nil
+		secondary.ghostdagData = test.secondaryData
+		secondary.sortedMergeSet = test.secondarySortedSet
+		secondary.err = test.secondaryErr
+		stagingArea := model.NewStagingArea()
+
+		err := manager.GHOSTDAG(stagingArea, blockHash)
+		if err != nil {
+			t.Fatalf("%s: GHOSTDAG: %+v", test.name, err)
+		}
+		stagedData, err := ghostdagDataStore.Get(nil, stagingArea, blockHash, false)
+		if err != nil {
+			t.Fatalf("%s: Get: %+v", test.name, err)
+		}
+		if !stagedData.Equal(primaryData) {
+			t.Fatalf("%s: expected the GHOSTDAG data of the primary implementation to be staged", test.name)
+		}
+		sortedMergeSet, err := manager.GetSortedMergeSet(stagingArea, blockHash)
+		if err != nil {
+			t.Fatalf("%s: GetSortedMergeSet: %+v", test.name, err)
+		}
+		if !externalapi.HashesEqual(sortedMergeSet, primary.sortedMergeSet) {
+			t.Fatalf("%s: expected the sorted merge set of the primary implementation", test.name)
+		}
+
+		differences := 0
+		for _, divergence := range divergences {
+			if !divergence.BlockHash.Equal(blockHash) {
+				t.Fatalf("%s: unexpected divergence on block %s", test.name, divergence.BlockHash)
+			}
+			differences += len(divergence.Differences)
+		}
+		if differences != test.expectedDifferences {
+			t.Fatalf("%s: expected %d differences, got %d: %v", test.name, test.expectedDifferences, differences, divergences)
+		}
+	}
+}
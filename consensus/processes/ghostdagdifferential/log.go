@@ -0,0 +1,7 @@
+package ghostdagdifferential
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+)
+
+var log = logger.RegisterSubSystem("BDAG")
@@ -0,0 +1,72 @@
+package ghostdagdifferential
+
+import (
+	"github.com/karlsen-network/karlsend/v2/util/staging"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// secondaryGHOSTDAGDataStore keeps the GHOSTDAG data of the secondary implementation apart
+// from the data of the primary one. Its data is only staged, and never written to the database,
+// so blocks that were committed are read from the primary store. This way every block is compared
+// given the same GHOSTDAG data of its past, and a divergence doesn't spread to its future.
+type secondaryGHOSTDAGDataStore struct {
+	shardID           model.StagingShardID
+	ghostdagDataStore model.GHOSTDAGDataStore
+}
+
+type secondaryGHOSTDAGDataKey struct {
+	hash          externalapi.DomainHash
+	isTrustedData bool
+}
+
+type secondaryGHOSTDAGDataStagingShard struct {
+	toAdd map[secondaryGHOSTDAGDataKey]*externalapi.BlockGHOSTDAGData
+}
+
+func newSecondaryGHOSTDAGDataStore(ghostdagDataStore model.GHOSTDAGDataStore) *secondaryGHOSTDAGDataStore {
+	return &secondaryGHOSTDAGDataStore{
+		shardID:           model.StagingShardID(staging.GenerateShardingID()),
+		ghostdagDataStore: ghostdagDataStore,
+	}
+}
+
+func (sgds *secondaryGHOSTDAGDataStore) stagingShard(stagingArea *model.StagingArea) *secondaryGHOSTDAGDataStagingShard {
+	return stagingArea.GetOrCreateShard(sgds.shardID, func() model.StagingShard {
+		return &secondaryGHOSTDAGDataStagingShard{
+			toAdd: make(map[secondaryGHOSTDAGDataKey]*externalapi.BlockGHOSTDAGData),
+		}
+	}).(*secondaryGHOSTDAGDataStagingShard)
+}
+
+// Commit discards the staged data, since only the data of the primary implementation is written
+func (sgdss *secondaryGHOSTDAGDataStagingShard) Commit(model.DBTransaction) error {
+	return nil
+}
+
+func (sgds *secondaryGHOSTDAGDataStore) Stage(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash,
+	blockGHOSTDAGData *externalapi.BlockGHOSTDAGData, isTrustedData bool) {
+
+	key := secondaryGHOSTDAGDataKey{hash: *blockHash, isTrustedData: isTrustedData}
+	sgds.stagingShard(stagingArea).toAdd[key] = blockGHOSTDAGData
+}
+
+func (sgds *secondaryGHOSTDAGDataStore) IsStaged(stagingArea *model.StagingArea) bool {
+	return len(sgds.stagingShard(stagingArea).toAdd) != 0
+}
+
+// Get returns the GHOSTDAG data the secondary implementation staged for the block, or the data
+// of the primary implementation if it didn't stage any
+func (sgds *secondaryGHOSTDAGDataStore) Get(dbContext model.DBReader, stagingArea *model.StagingArea,
+	blockHash *externalapi.DomainHash, isTrustedData bool) (*externalapi.BlockGHOSTDAGData, error) {
+
+	key := secondaryGHOSTDAGDataKey{hash: *blockHash, isTrustedData: isTrustedData}
+	if blockGHOSTDAGData, ok := sgds.stagingShard(stagingArea).toAdd[key]; ok {
+		return blockGHOSTDAGData, nil
+	}
+	return sgds.ghostdagDataStore.Get(dbContext, stagingArea, blockHash, isTrustedData)
+}
+
+func (sgds *secondaryGHOSTDAGDataStore) UnstageAll(stagingArea *model.StagingArea) {
+	sgds.stagingShard(stagingArea).toAdd = make(map[secondaryGHOSTDAGDataKey]*externalapi.BlockGHOSTDAGData)
+}
@@ -0,0 +1,193 @@
+package ghostdagmanager
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// ghostdagBatchManager resolves the GHOSTDAG data of batches of blocks
+type ghostdagBatchManager struct {
+	databaseContext     model.DBReader
+	dagTopologyManager  model.DAGTopologyManager
+	reachabilityManager model.ReachabilityManager
+	ghostdagDataStore   model.GHOSTDAGDataStore
+	headerStore         model.BlockHeaderStore
+
+	k           externalapi.KType
+	genesisHash *externalapi.DomainHash
+	maxWorkers  int
+}
+
+// NewBatch instantiates a new GHOSTDAGBatchManager that computes the GHOSTDAG data of
+// up to maxWorkers blocks concurrently. A non-positive maxWorkers means GOMAXPROCS.
+//
+// The stores behind dagTopologyManager, reachabilityManager, ghostdagDataStore and
+// headerStore are only read from one goroutine at a time, see batchCaches.
+func NewBatch(
+	databaseContext model.DBReader,
+	dagTopologyManager model.DAGTopologyManager,
+	reachabilityManager model.ReachabilityManager,
+	ghostdagDataStore model.GHOSTDAGDataStore,
+	headerStore model.BlockHeaderStore,
+	k externalapi.KType,
+	genesisHash *externalapi.DomainHash,
+	maxWorkers int) model.GHOSTDAGBatchManager {
+
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.GOMAXPROCS(0)
+	}
+
+	return &ghostdagBatchManager{
+		databaseContext:     databaseContext,
+		dagTopologyManager:  dagTopologyManager,
+		reachabilityManager: reachabilityManager,
+		ghostdagDataStore:   ghostdagDataStore,
+		headerStore:         headerStore,
+		k:                   k,
+		genesisHash:         genesisHash,
+		maxWorkers:          maxWorkers,
+	}
+}
+
+// GHOSTDAGBatch splits the given blocks into layers, where every block only has
+// parents in the previous layers or outside of the batch. The blocks of a layer
+// are independent of each other, so their GHOSTDAG data, along with all the
+// reachability queries it requires, is computed concurrently. Afterwards the
+// layer is staged and added to the reachability tree, one block at a time.
+//
+// All the blocks of the batch share the same caches of GHOSTDAG data, parents, headers,
+// reachability query results and blue anticone sizes, which are mostly about the same
+// few selected chains.
+func (bm *ghostdagBatchManager) GHOSTDAGBatch(stagingArea *model.StagingArea, blockHashes []*externalapi.DomainHash) error {
+	layers, err := bm.layers(stagingArea, blockHashes)
+	if err != nil {
+		return err
+	}
+
+	gm := &ghostdagManager{
+		databaseContext:    bm.databaseContext,
+		dagTopologyManager: bm.dagTopologyManager,
+		ghostdagDataStore:  bm.ghostdagDataStore,
+		headerStore:        bm.headerStore,
+		k:                  bm.k,
+		genesisHash:        bm.genesisHash,
+		caches:             newBatchCaches(),
+	}
+
+	for _, layer := range layers {
+		ghostdagData, err := bm.ghostdagLayer(gm, stagingArea, layer)
+		if err != nil {
+			return err
+		}
+
+		for i, blockHash := range layer {
+			bm.ghostdagDataStore.Stage(stagingArea, blockHash, ghostdagData[i], false)
+			gm.caches.addGHOSTDAGData(blockHash, ghostdagData[i])
+
+			err := bm.reachabilityManager.AddBlock(stagingArea, blockHash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// layers groups the given blocks by their depth within the batch, keeping their order
+func (bm *ghostdagBatchManager) layers(stagingArea *model.StagingArea, blockHashes []*externalapi.DomainHash) (
+	[][]*externalapi.DomainHash, error) {
+
+	inBatch := make(map[externalapi.DomainHash]struct{}, len(blockHashes))
+	for _, blockHash := range blockHashes {
+		inBatch[*blockHash] = struct{}{}
+	}
+
+	layerIndexes := make(map[externalapi.DomainHash]int, len(blockHashes))
+	var layers [][]*externalapi.DomainHash
+	for _, blockHash := range blockHashes {
+		if _, ok := layerIndexes[*blockHash]; ok {
+			return nil, errors.Errorf("block %s appears more than once in the batch", blockHash)
+		}
+
+		parents, err := bm.dagTopologyManager.Parents(stagingArea, blockHash)
+		if err != nil {
+			return nil, err
+		}
+
+		layerIndex := 0
+		for _, parent := range parents {
+			parentLayerIndex, ok := layerIndexes[*parent]
+			if !ok {
+				if _, ok := inBatch[*parent]; ok {
+					return nil, errors.Errorf("the batch is not sorted topologically: "+
+						"block %s appears before its parent %s", blockHash, parent)
+				}
+				continue
+			}
+			if parentLayerIndex+1 > layerIndex {
+				layerIndex = parentLayerIndex + 1
+			}
+		}
+
+		layerIndexes[*blockHash] = layerIndex
+		if layerIndex == len(layers) {
+			layers = append(layers, nil)
+		}
+		layers[layerIndex] = append(layers[layerIndex], blockHash)
+	}
+
+	return layers, nil
+}
+
+// ghostdagLayer computes the GHOSTDAG data of the given independent blocks, without staging it
+func (bm *ghostdagBatchManager) ghostdagLayer(gm *ghostdagManager, stagingArea *model.StagingArea,
+	layer []*externalapi.DomainHash) ([]*externalapi.BlockGHOSTDAGData, error) {
+
+	ghostdagData := make([]*externalapi.BlockGHOSTDAGData, len(layer))
+
+	workers := bm.maxWorkers
+	if workers > len(layer) {
+		workers = len(layer)
+	}
+	if workers == 1 {
+		for i, blockHash := range layer {
+			var err error
+			ghostdagData[i], err = gm.ghostdag(stagingArea, blockHash)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return ghostdagData, nil
+	}
+
+	errs := make([]error, len(layer))
+	indexes := make(chan int, len(layer))
+	for i := range layer {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				ghostdagData[i], errs[i] = gm.ghostdag(stagingArea, layer[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ghostdagData, nil
+}
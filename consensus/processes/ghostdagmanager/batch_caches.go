@@ -0,0 +1,191 @@
+package ghostdagmanager
+
+import (
+	"sync"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type hashPair struct {
+	first  externalapi.DomainHash
+	second externalapi.DomainHash
+}
+
+// batchCaches are shared by all the GHOSTDAG computations of a single batch.
+// Everything they hold never changes once it is known, so they may be shared
+// between goroutines and between the blocks of the batch.
+// A nil *batchCaches caches nothing.
+//
+// The stores, their caches and the staging area aren't safe for concurrent use,
+// so the computations of a batch only read them through the batch caches, which
+// read them from one goroutine at a time on a cache miss.
+type batchCaches struct {
+	lock      sync.RWMutex
+	storeLock sync.Mutex
+
+	ghostdagData      map[externalapi.DomainHash]*externalapi.BlockGHOSTDAGData
+	parents           map[externalapi.DomainHash][]*externalapi.DomainHash
+	headers           map[externalapi.DomainHash]externalapi.BlockHeader
+	isAncestorOf      map[hashPair]bool
+	blueAnticoneSizes map[hashPair]externalapi.KType
+}
+
+func newBatchCaches() *batchCaches {
+	return &batchCaches{
+		ghostdagData:      make(map[externalapi.DomainHash]*externalapi.BlockGHOSTDAGData),
+		parents:           make(map[externalapi.DomainHash][]*externalapi.DomainHash),
+		headers:           make(map[externalapi.DomainHash]externalapi.BlockHeader),
+		isAncestorOf:      make(map[hashPair]bool),
+		blueAnticoneSizes: make(map[hashPair]externalapi.KType),
+	}
+}
+
+func (bc *batchCaches) addGHOSTDAGData(blockHash *externalapi.DomainHash, ghostdagData *externalapi.BlockGHOSTDAGData) {
+	if bc == nil {
+		return
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	bc.ghostdagData[*blockHash] = ghostdagData
+}
+
+// blueAnticoneSize returns the blue anticone size of block from the worldview of chainBlock
+func (bc *batchCaches) blueAnticoneSize(block, chainBlock *externalapi.DomainHash) (externalapi.KType, bool) {
+	if bc == nil {
+		return 0, false
+	}
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+
+	blueAnticoneSize, ok := bc.blueAnticoneSizes[hashPair{first: *block, second: *chainBlock}]
+	return blueAnticoneSize, ok
+}
+
+func (bc *batchCaches) addBlueAnticoneSize(block *externalapi.DomainHash, chainBlocks []*externalapi.DomainHash,
+	blueAnticoneSize externalapi.KType) {
+
+	if bc == nil || len(chainBlocks) == 0 {
+		return
+	}
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	for _, chainBlock := range chainBlocks {
+		bc.blueAnticoneSizes[hashPair{first: *block, second: *chainBlock}] = blueAnticoneSize
+	}
+}
+
+// ghostdagData returns the GHOSTDAG data of the given block, going through the batch
+// caches if there are any. Trusted GHOSTDAG data isn't cached.
+func (gm *ghostdagManager) ghostdagData(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash,
+	isTrustedData bool) (*externalapi.BlockGHOSTDAGData, error) {
+
+	if gm.caches == nil {
+		return gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, blockHash, isTrustedData)
+	}
+
+	if !isTrustedData {
+		gm.caches.lock.RLock()
+		ghostdagData, ok := gm.caches.ghostdagData[*blockHash]
+		gm.caches.lock.RUnlock()
+		if ok {
+			return ghostdagData, nil
+		}
+	}
+
+	gm.caches.storeLock.Lock()
+	ghostdagData, err := gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, blockHash, isTrustedData)
+	gm.caches.storeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !isTrustedData {
+		gm.caches.addGHOSTDAGData(blockHash, ghostdagData)
+	}
+	return ghostdagData, nil
+}
+
+// parents is a cached dagTopologyManager.Parents
+func (gm *ghostdagManager) parents(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (
+	[]*externalapi.DomainHash, error) {
+
+	if gm.caches == nil {
+		return gm.dagTopologyManager.Parents(stagingArea, blockHash)
+	}
+
+	gm.caches.lock.RLock()
+	parents, ok := gm.caches.parents[*blockHash]
+	gm.caches.lock.RUnlock()
+	if ok {
+		return parents, nil
+	}
+
+	gm.caches.storeLock.Lock()
+	parents, err := gm.dagTopologyManager.Parents(stagingArea, blockHash)
+	gm.caches.storeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	gm.caches.lock.Lock()
+	gm.caches.parents[*blockHash] = parents
+	gm.caches.lock.Unlock()
+	return parents, nil
+}
+
+// blockHeader is a cached headerStore.BlockHeader
+func (gm *ghostdagManager) blockHeader(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (
+	externalapi.BlockHeader, error) {
+
+	if gm.caches == nil {
+		return gm.headerStore.BlockHeader(gm.databaseContext, stagingArea, blockHash)
+	}
+
+	gm.caches.lock.RLock()
+	header, ok := gm.caches.headers[*blockHash]
+	gm.caches.lock.RUnlock()
+	if ok {
+		return header, nil
+	}
+
+	gm.caches.storeLock.Lock()
+	header, err := gm.headerStore.BlockHeader(gm.databaseContext, stagingArea, blockHash)
+	gm.caches.storeLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	gm.caches.lock.Lock()
+	gm.caches.headers[*blockHash] = header
+	gm.caches.lock.Unlock()
+	return header, nil
+}
+
+// isAncestorOf is a cached dagTopologyManager.IsAncestorOf. Ancestry never changes
+// once both blocks are known, so the answers remain valid throughout the batch.
+func (gm *ghostdagManager) isAncestorOf(stagingArea *model.StagingArea, blockHashA, blockHashB *externalapi.DomainHash) (
+	bool, error) {
+
+	if gm.caches == nil {
+		return gm.dagTopologyManager.IsAncestorOf(stagingArea, blockHashA, blockHashB)
+	}
+
+	key := hashPair{first: *blockHashA, second: *blockHashB}
+	gm.caches.lock.RLock()
+	isAncestorOf, ok := gm.caches.isAncestorOf[key]
+	gm.caches.lock.RUnlock()
+	if ok {
+		return isAncestorOf, nil
+	}
+
+	gm.caches.storeLock.Lock()
+	isAncestorOf, err := gm.dagTopologyManager.IsAncestorOf(stagingArea, blockHashA, blockHashB)
+	gm.caches.storeLock.Unlock()
+	if err != nil {
+		return false, err
+	}
+	gm.caches.lock.Lock()
+	gm.caches.isAncestorOf[key] = isAncestorOf
+	gm.caches.lock.Unlock()
+	return isAncestorOf, nil
+}
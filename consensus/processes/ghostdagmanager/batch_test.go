@@ -0,0 +1,357 @@
+package ghostdagmanager_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/processes/ghostdagmanager"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+var reachabilityDAGFiles = []string{
+	"../../testdata/reachability/noattack-dag-blocks--2^12-delay-factor--1-k--18.json.gz",
+	"../../testdata/reachability/attack-dag-blocks--2^12-delay-factor--1-k--18.json.gz",
+}
+
+// TestGHOSTDAGBatchDAGs checks that computing the GHOSTDAG data of all the blocks
+// of the test DAGs in a single batch gives the expected results
+func TestGHOSTDAGBatchDAGs(t *testing.T) {
+	genesisHeader := dagconfig.SimnetParams.GenesisBlock.Header
+	for _, fileName := range testDAGFiles(t) {
+		test := readTestDAG(t, fileName)
+		dagTopology, ghostdagDataStore, blockHeadersStore, blockHashes := buildTestDAG(test, genesisHeader)
+		genesisHash := StringToDomainHash(test.GenesisID)
+
+		for _, maxWorkers := range []int{1, 4} {
+			batchManager := ghostdagmanager.NewBatch(nil, dagTopology, &reachabilityManagerImpl{}, ghostdagDataStore,
+				blockHeadersStore, test.K, genesisHash, maxWorkers)
+			err := batchManager.GHOSTDAGBatch(nil, blockHashes)
+			if err != nil {
+				t.Fatalf("%s: GHOSTDAGBatch: %+v", fileName, err)
+			}
+
+			for _, testBlockData := range test.Blocks {
+				ghostdagData, err := ghostdagDataStore.Get(nil, nil, StringToDomainHash(testBlockData.ID), false)
+				if err != nil {
+					t.Fatalf("%s: Get: %+v", fileName, err)
+				}
+				if testBlockData.Score != ghostdagData.BlueScore() {
+					t.Fatalf("%s: block %s: expected blue score %d but got %d",
+						fileName, testBlockData.ID, testBlockData.Score, ghostdagData.BlueScore())
+				}
+				if !StringToDomainHash(testBlockData.SelectedParent).Equal(ghostdagData.SelectedParent()) {
+					t.Fatalf("%s: block %s: expected selected parent %s but got %s",
+						fileName, testBlockData.ID, testBlockData.SelectedParent, ghostdagData.SelectedParent())
+				}
+				if !reflect.DeepEqual(StringToDomainHashSlice(testBlockData.MergeSetBlues), ghostdagData.MergeSetBlues()) {
+					t.Fatalf("%s: block %s: expected merge set blues %v but got %v",
+						fileName, testBlockData.ID, testBlockData.MergeSetBlues, hashesToStrings(ghostdagData.MergeSetBlues()))
+				}
+				if !reflect.DeepEqual(StringToDomainHashSlice(testBlockData.MergeSetReds), ghostdagData.MergeSetReds()) {
+					t.Fatalf("%s: block %s: expected merge set reds %v but got %v",
+						fileName, testBlockData.ID, testBlockData.MergeSetReds, hashesToStrings(ghostdagData.MergeSetReds()))
+				}
+			}
+		}
+	}
+}
+
+func TestGHOSTDAGBatchNotSortedTopologically(t *testing.T) {
+	genesisHeader := dagconfig.SimnetParams.GenesisBlock.Header
+	test := readTestDAG(t, testDAGFiles(t)[0])
+	dagTopology, ghostdagDataStore, blockHeadersStore, blockHashes := buildTestDAG(test, genesisHeader)
+
+	reversedBlockHashes := make([]*externalapi.DomainHash, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		reversedBlockHashes[len(blockHashes)-1-i] = blockHash
+	}
+
+	batchManager := ghostdagmanager.NewBatch(nil, dagTopology, &reachabilityManagerImpl{}, ghostdagDataStore,
+		blockHeadersStore, test.K, StringToDomainHash(test.GenesisID), 0)
+	err := batchManager.GHOSTDAGBatch(nil, reversedBlockHashes)
+	if err == nil {
+		t.Fatalf("expected GHOSTDAGBatch to fail on blocks that are not sorted topologically")
+	}
+}
+
+// TestGHOSTDAGBatchReachabilityDAGs checks that a batch over the reachability test DAGs
+// stages the same GHOSTDAG data as computing it one block at a time
+func TestGHOSTDAGBatchReachabilityDAGs(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestGHOSTDAGBatchReachabilityDAGs")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	for _, fileName := range reachabilityDAGFiles {
+		sequentialStagingArea := model.NewStagingArea()
+		blockHashes := stageReachabilityDAG(t, tc, sequentialStagingArea, fileName)
+		for _, blockHash := range blockHashes {
+			err := tc.GHOSTDAGManager().GHOSTDAG(sequentialStagingArea, blockHash)
+			if err != nil {
+				t.Fatalf("GHOSTDAG: %+v", err)
+			}
+			err = tc.ReachabilityManager().AddBlock(sequentialStagingArea, blockHash)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+		}
+
+		batchStagingArea := model.NewStagingArea()
+		stageReachabilityDAG(t, tc, batchStagingArea, fileName)
+		err := newBatchManager(tc, consensusConfig, 0).GHOSTDAGBatch(batchStagingArea, blockHashes)
+		if err != nil {
+			t.Fatalf("GHOSTDAGBatch: %+v", err)
+		}
+
+		for _, blockHash := range blockHashes {
+			expectedGHOSTDAGData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), sequentialStagingArea, blockHash, false)
+			if err != nil {
+				t.Fatalf("Get: %+v", err)
+			}
+			ghostdagData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), batchStagingArea, blockHash, false)
+			if err != nil {
+				t.Fatalf("Get: %+v", err)
+			}
+			if !ghostdagData.Equal(expectedGHOSTDAGData) {
+				t.Fatalf("%s: the batch GHOSTDAG data of block %s is different from the sequential one",
+					fileName, blockHash)
+			}
+		}
+	}
+}
+
+func BenchmarkGHOSTDAGBatchDAGs(b *testing.B) {
+	genesisHeader := dagconfig.SimnetParams.GenesisBlock.Header
+	for _, fileName := range testDAGFiles(b) {
+		test := readTestDAG(b, fileName)
+		genesisHash := StringToDomainHash(test.GenesisID)
+
+		b.Run(filepath.Base(fileName)+"/sequential", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dagTopology, ghostdagDataStore, blockHeadersStore, blockHashes := buildTestDAG(test, genesisHeader)
+				manager := ghostdagmanager.New(nil, dagTopology, ghostdagDataStore, blockHeadersStore, test.K, genesisHash)
+				for _, blockHash := range blockHashes {
+					err := manager.GHOSTDAG(nil, blockHash)
+					if err != nil {
+						b.Fatalf("GHOSTDAG: %+v", err)
+					}
+				}
+			}
+		})
+
+		for _, maxWorkers := range []int{1, 0} {
+			b.Run(fmt.Sprintf("%s/batch-workers-%d", filepath.Base(fileName), maxWorkers), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					dagTopology, ghostdagDataStore, blockHeadersStore, blockHashes := buildTestDAG(test, genesisHeader)
+					batchManager := ghostdagmanager.NewBatch(nil, dagTopology, &reachabilityManagerImpl{}, ghostdagDataStore,
+						blockHeadersStore, test.K, genesisHash, maxWorkers)
+					err := batchManager.GHOSTDAGBatch(nil, blockHashes)
+					if err != nil {
+						b.Fatalf("GHOSTDAGBatch: %+v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGHOSTDAGBatchReachabilityDAGs(b *testing.B) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "BenchmarkGHOSTDAGBatchReachabilityDAGs")
+	if err != nil {
+		b.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	for _, fileName := range reachabilityDAGFiles {
+		b.Run(filepath.Base(fileName)+"/sequential", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				stagingArea := model.NewStagingArea()
+				blockHashes := stageReachabilityDAG(b, tc, stagingArea, fileName)
+				b.StartTimer()
+
+				for _, blockHash := range blockHashes {
+					err := tc.GHOSTDAGManager().GHOSTDAG(stagingArea, blockHash)
+					if err != nil {
+						b.Fatalf("GHOSTDAG: %+v", err)
+					}
+					err = tc.ReachabilityManager().AddBlock(stagingArea, blockHash)
+					if err != nil {
+						b.Fatalf("AddBlock: %+v", err)
+					}
+				}
+			}
+		})
+
+		for _, maxWorkers := range []int{1, 0} {
+			b.Run(fmt.Sprintf("%s/batch-workers-%d", filepath.Base(fileName), maxWorkers), func(b *testing.B) {
+				batchManager := newBatchManager(tc, consensusConfig, maxWorkers)
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					stagingArea := model.NewStagingArea()
+					blockHashes := stageReachabilityDAG(b, tc, stagingArea, fileName)
+					b.StartTimer()
+
+					err := batchManager.GHOSTDAGBatch(stagingArea, blockHashes)
+					if err != nil {
+						b.Fatalf("GHOSTDAGBatch: %+v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func newBatchManager(tc testapi.TestConsensus, consensusConfig *consensus.Config, maxWorkers int) model.GHOSTDAGBatchManager {
+	return ghostdagmanager.NewBatch(tc.DatabaseContext(), tc.DAGTopologyManager(), tc.ReachabilityManager(),
+		tc.GHOSTDAGDataStore(), tc.BlockHeaderStore(), consensusConfig.K, consensusConfig.GenesisHash, maxWorkers)
+}
+
+func testDAGFiles(tb testing.TB) []string {
+	fileNames, err := filepath.Glob("../../testdata/dags/*.json")
+	if err != nil {
+		tb.Fatalf("Glob: %+v", err)
+	}
+	if len(fileNames) == 0 {
+		tb.Fatalf("no test DAGs were found")
+	}
+	return fileNames
+}
+
+func readTestDAG(tb testing.TB, fileName string) *testDag {
+	jsonFile, err := os.Open(fileName)
+	if err != nil {
+		tb.Fatalf("failed opening the json file %s: %v", fileName, err)
+	}
+	defer jsonFile.Close()
+
+	test := &testDag{}
+	decoder := json.NewDecoder(jsonFile)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(test)
+	if err != nil {
+		tb.Fatalf("failed decoding json: %v", err)
+	}
+	return test
+}
+
+// buildTestDAG sets up the fake stores of the given test DAG, and returns its blocks in their topological order
+func buildTestDAG(test *testDag, genesisHeader externalapi.BlockHeader) (
+	*DAGTopologyManagerImpl, *GHOSTDAGDataStoreImpl, *blockHeadersStore, []*externalapi.DomainHash) {
+
+	genesisHash := StringToDomainHash(test.GenesisID)
+	dagTopology := &DAGTopologyManagerImpl{
+		parentsMap: map[externalapi.DomainHash][]*externalapi.DomainHash{*genesisHash: nil},
+	}
+	ghostdagDataStore := &GHOSTDAGDataStoreImpl{
+		dagMap: map[externalapi.DomainHash]*externalapi.BlockGHOSTDAGData{
+			*genesisHash: externalapi.NewBlockGHOSTDAGData(0, new(big.Int), nil, nil, nil, nil),
+		},
+	}
+	blockHeadersStore := &blockHeadersStore{
+		dagMap: map[externalapi.DomainHash]externalapi.BlockHeader{*genesisHash: genesisHeader},
+	}
+
+	blockHashes := make([]*externalapi.DomainHash, 0, len(test.Blocks))
+	for _, testBlockData := range test.Blocks {
+		blockHash := StringToDomainHash(testBlockData.ID)
+		parents := StringToDomainHashSlice(testBlockData.Parents)
+		dagTopology.parentsMap[*blockHash] = parents
+		blockHeadersStore.dagMap[*blockHash] = newTestHeader(parents, genesisHeader.Bits())
+		blockHashes = append(blockHashes, blockHash)
+	}
+	return dagTopology, ghostdagDataStore, blockHeadersStore, blockHashes
+}
+
+type reachabilityDAGBlock struct {
+	ID      string   `json:"id"`
+	Parents []string `json:"parents"`
+}
+
+// stageReachabilityDAG stages the headers and the relations of the blocks of the given
+// reachability test DAG, and returns them in their topological order. The first block
+// of the DAG is the genesis.
+func stageReachabilityDAG(tb testing.TB, tc testapi.TestConsensus, stagingArea *model.StagingArea,
+	fileName string) []*externalapi.DomainHash {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		tb.Fatalf("Open: %+v", err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		tb.Fatalf("NewReader: %+v", err)
+	}
+	defer gzipReader.Close()
+
+	var blocks []*reachabilityDAGBlock
+	err = json.NewDecoder(gzipReader).Decode(&blocks)
+	if err != nil {
+		tb.Fatalf("Decode: %+v", err)
+	}
+
+	genesisHash := tc.DAGParams().GenesisHash
+	bits := tc.DAGParams().GenesisBlock.Header.Bits()
+	blockHashesByID := map[string]*externalapi.DomainHash{blocks[0].ID: genesisHash}
+	blockHashes := make([]*externalapi.DomainHash, 0, len(blocks)-1)
+	for _, block := range blocks[1:] {
+		blockHash := StringToDomainHash("block-" + block.ID)
+		parents := make([]*externalapi.DomainHash, len(block.Parents))
+		for i, parentID := range block.Parents {
+			parents[i] = blockHashesByID[parentID]
+		}
+		blockHashesByID[block.ID] = blockHash
+
+		tc.BlockHeaderStore().Stage(stagingArea, blockHash, newTestHeader(parents, bits))
+		err := tc.DAGTopologyManager().SetParents(stagingArea, blockHash, parents)
+		if err != nil {
+			tb.Fatalf("SetParents: %+v", err)
+		}
+		blockHashes = append(blockHashes, blockHash)
+	}
+	return blockHashes
+}
+
+func newTestHeader(parents []*externalapi.DomainHash, bits uint32) externalapi.BlockHeader {
+	return blockheader.NewImmutableBlockHeader(
+		constants.BlockVersionKHashV1,
+		[]externalapi.BlockLevelParents{parents},
+		nil,
+		nil,
+		nil,
+		0,
+		bits,
+		0,
+		0,
+		0,
+		big.NewInt(0),
+		nil,
+	)
+}
+
+// reachabilityManagerImpl ignores the blocks added to it, since DAGTopologyManagerImpl
+// answers reachability queries from the parents alone
+type reachabilityManagerImpl struct {
+	model.ReachabilityManager
+}
+
+func (rm *reachabilityManagerImpl) AddBlock(*model.StagingArea, *externalapi.DomainHash) error {
+	return nil
+}
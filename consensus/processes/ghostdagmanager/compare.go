@@ -35,12 +35,12 @@ func (gm *ghostdagManager) less(stagingArea *model.StagingArea, blockHashA, bloc
 
 func (gm *ghostdagManager) ChooseSelectedParent(stagingArea *model.StagingArea, blockHashes ...*externalapi.DomainHash) (*externalapi.DomainHash, error) {
 	selectedParent := blockHashes[0]
-	selectedParentGHOSTDAGData, err := gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, selectedParent, false)
+	selectedParentGHOSTDAGData, err := gm.ghostdagData(stagingArea, selectedParent, false)
 	if err != nil {
 		return nil, err
 	}
 	for _, blockHash := range blockHashes {
-		blockGHOSTDAGData, err := gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, blockHash, false)
+		blockGHOSTDAGData, err := gm.ghostdagData(stagingArea, blockHash, false)
 		if err != nil {
 			return nil, err
 		}
@@ -41,6 +41,20 @@ func (bg *blockGHOSTDAGData) toModel() *externalapi.BlockGHOSTDAGData {
 //
 // For further details see the article https://eprint.iacr.org/2018/104.pdf
 func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	ghostdagData, err := gm.ghostdag(stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+
+	gm.ghostdagDataStore.Stage(stagingArea, blockHash, ghostdagData, false)
+
+	return nil
+}
+
+// ghostdag calculates the BlockGHOSTDAGData of the given block without staging it
+func (gm *ghostdagManager) ghostdag(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (
+	*externalapi.BlockGHOSTDAGData, error) {
+
 	newBlockData := &blockGHOSTDAGData{
 		blueWork:           new(big.Int),
 		mergeSetBlues:      make([]*externalapi.DomainHash, 0),
@@ -48,16 +62,16 @@ func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *e
 		bluesAnticoneSizes: make(map[externalapi.DomainHash]externalapi.KType),
 	}
 
-	blockParents, err := gm.dagTopologyManager.Parents(stagingArea, blockHash)
+	blockParents, err := gm.parents(stagingArea, blockHash)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	isGenesis := len(blockParents) == 0
 	if !isGenesis {
 		selectedParent, err := gm.findSelectedParent(stagingArea, blockParents)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		newBlockData.selectedParent = selectedParent
@@ -68,14 +82,14 @@ func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *e
 	mergeSetWithoutSelectedParent, err := gm.mergeSetWithoutSelectedParent(
 		stagingArea, newBlockData.selectedParent, blockParents)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, blueCandidate := range mergeSetWithoutSelectedParent {
 		isBlue, candidateAnticoneSize, candidateBluesAnticoneSizes, err := gm.checkBlueCandidate(
 			stagingArea, newBlockData.toModel(), blueCandidate)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if isBlue {
@@ -91,9 +105,9 @@ func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *e
 	}
 
 	if !isGenesis {
-		selectedParentGHOSTDAGData, err := gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, newBlockData.selectedParent, false)
+		selectedParentGHOSTDAGData, err := gm.ghostdagData(stagingArea, newBlockData.selectedParent, false)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		newBlockData.blueScore = selectedParentGHOSTDAGData.BlueScore() + uint64(len(newBlockData.mergeSetBlues))
 		// We inherit the bluework from the selected parent
@@ -105,9 +119,9 @@ func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *e
 				continue
 			}
 
-			header, err := gm.headerStore.BlockHeader(gm.databaseContext, stagingArea, blue)
+			header, err := gm.blockHeader(stagingArea, blue)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			newBlockData.blueWork.Add(newBlockData.blueWork, difficulty.CalcWork(header.Bits()))
 		}
@@ -117,9 +131,7 @@ func (gm *ghostdagManager) GHOSTDAG(stagingArea *model.StagingArea, blockHash *e
 		newBlockData.blueWork.SetUint64(0)
 	}
 
-	gm.ghostdagDataStore.Stage(stagingArea, blockHash, newBlockData.toModel(), false)
-
-	return nil
+	return newBlockData.toModel(), nil
 }
 
 type chainBlockData struct {
@@ -162,7 +174,7 @@ func (gm *ghostdagManager) checkBlueCandidate(stagingArea *model.StagingArea, ne
 			return false, 0, nil, nil
 		}
 
-		selectedParentGHOSTDAGData, err := gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, chainBlock.blockData.SelectedParent(), false)
+		selectedParentGHOSTDAGData, err := gm.ghostdagData(stagingArea, chainBlock.blockData.SelectedParent(), false)
 		if err != nil {
 			return false, 0, nil, err
 		}
@@ -191,7 +203,7 @@ func (gm *ghostdagManager) checkBlueCandidateWithChainBlock(stagingArea *model.S
 
 	// We check if chainBlock is not the new block by checking if it has a hash.
 	if chainBlock.hash != nil {
-		isAncestorOfBlueCandidate, err := gm.dagTopologyManager.IsAncestorOf(stagingArea, chainBlock.hash, blueCandidate)
+		isAncestorOfBlueCandidate, err := gm.isAncestorOf(stagingArea, chainBlock.hash, blueCandidate)
 		if err != nil {
 			return false, false, err
 		}
@@ -202,7 +214,7 @@ func (gm *ghostdagManager) checkBlueCandidateWithChainBlock(stagingArea *model.S
 
 	for _, block := range chainBlock.blockData.MergeSetBlues() {
 		// Skip blocks that exist in the past of blueCandidate.
-		isAncestorOfBlueCandidate, err := gm.dagTopologyManager.IsAncestorOf(stagingArea, block, blueCandidate)
+		isAncestorOfBlueCandidate, err := gm.isAncestorOf(stagingArea, block, blueCandidate)
 		if err != nil {
 			return false, false, err
 		}
@@ -243,23 +255,37 @@ func (gm *ghostdagManager) checkBlueCandidateWithChainBlock(stagingArea *model.S
 func (gm *ghostdagManager) blueAnticoneSize(stagingArea *model.StagingArea,
 	block *externalapi.DomainHash, context *externalapi.BlockGHOSTDAGData) (externalapi.KType, error) {
 
+	// The blue anticone size of 'block' is the same from the worldview of every chain
+	// block visited before it is found, so they are all remembered in the batch caches
+	var visitedChainBlocks []*externalapi.DomainHash
+
 	isTrustedData := false
 	for current := context; current != nil; {
 		if blueAnticoneSize, ok := current.BluesAnticoneSizes()[*block]; ok {
+			gm.caches.addBlueAnticoneSize(block, visitedChainBlocks, blueAnticoneSize)
 			return blueAnticoneSize, nil
 		}
 		if current.SelectedParent().Equal(gm.genesisHash) {
 			break
 		}
+		if !isTrustedData {
+			if blueAnticoneSize, ok := gm.caches.blueAnticoneSize(block, current.SelectedParent()); ok {
+				gm.caches.addBlueAnticoneSize(block, visitedChainBlocks, blueAnticoneSize)
+				return blueAnticoneSize, nil
+			}
+			if gm.caches != nil {
+				visitedChainBlocks = append(visitedChainBlocks, current.SelectedParent())
+			}
+		}
 
 		var err error
-		current, err = gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, current.SelectedParent(), isTrustedData)
+		current, err = gm.ghostdagData(stagingArea, current.SelectedParent(), isTrustedData)
 		if err != nil {
 			return 0, err
 		}
 		if current.SelectedParent().Equal(model.VirtualGenesisBlockHash) {
 			isTrustedData = true
-			current, err = gm.ghostdagDataStore.Get(gm.databaseContext, stagingArea, current.SelectedParent(), isTrustedData)
+			current, err = gm.ghostdagData(stagingArea, current.SelectedParent(), isTrustedData)
 			if err != nil {
 				return 0, err
 			}
@@ -14,6 +14,9 @@ type ghostdagManager struct {
 
 	k           externalapi.KType
 	genesisHash *externalapi.DomainHash
+
+	// caches is only set on the managers that compute batches, see GHOSTDAGBatch
+	caches *batchCaches
 }
 
 // New instantiates a new GHOSTDAGManager
@@ -30,7 +30,7 @@ func (gm *ghostdagManager) mergeSetWithoutSelectedParent(stagingArea *model.Stag
 		current, queue = queue[0], queue[1:]
 		// For each parent of the current block we check whether it is in the past of the selected parent. If not,
 		// we add the it to the resulting anticone-set and queue it for further processing.
-		currentParents, err := gm.dagTopologyManager.Parents(stagingArea, current)
+		currentParents, err := gm.parents(stagingArea, current)
 		if err != nil {
 			return nil, err
 		}
@@ -43,7 +43,7 @@ func (gm *ghostdagManager) mergeSetWithoutSelectedParent(stagingArea *model.Stag
 				continue
 			}
 
-			isAncestorOfSelectedParent, err := gm.dagTopologyManager.IsAncestorOf(stagingArea, parent, selectedParent)
+			isAncestorOfSelectedParent, err := gm.isAncestorOf(stagingArea, parent, selectedParent)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,357 @@
+package integritychecker
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/multiset"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+)
+
+// checkBlockRelations verifies that the parents in the block relations of blockHash appear in its header,
+// and that every parent and child lists blockHash in the opposite direction.
+func (c *integrityCheck) checkBlockRelations(blockHash *externalapi.DomainHash) error {
+	ic := c.checker
+
+	relations, err := ic.blockRelationStore.BlockRelation(ic.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, false, "block relations are missing")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	header, err := ic.blockHeaderStore.BlockHeader(ic.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, false, "block header is missing")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	directParents := header.DirectParents()
+	for _, parent := range relations.Parents {
+		if parent.Equal(model.VirtualGenesisBlockHash) {
+			continue
+		}
+		if !directParents.Contains(parent) {
+			c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, false,
+				"parent %s does not appear in the block header", parent)
+		}
+
+		parentRelations, err := ic.blockRelationStore.BlockRelation(ic.databaseContext, c.stagingArea, parent)
+		if isNotFoundError(err) {
+			c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, false,
+				"parent %s has no block relations", parent)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if !containsHash(parentRelations.Children, blockHash) {
+			if c.shouldRepair {
+				repairedParentRelations := parentRelations.Clone()
+				repairedParentRelations.Children = append(repairedParentRelations.Children, blockHash)
+				ic.blockRelationStore.StageBlockRelation(c.stagingArea, parent, repairedParentRelations)
+			}
+			c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, c.shouldRepair,
+				"the block is missing from the children of its parent %s", parent)
+		}
+	}
+
+	validChildren := make([]*externalapi.DomainHash, 0, len(relations.Children))
+	for _, child := range relations.Children {
+		childRelations, err := ic.blockRelationStore.BlockRelation(ic.databaseContext, c.stagingArea, child)
+		if isNotFoundError(err) {
+			c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, c.shouldRepair,
+				"child %s has no block relations", child)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !containsHash(childRelations.Parents, blockHash) {
+			c.addIssue(externalapi.IntegrityIssueBlockRelations, blockHash, c.shouldRepair,
+				"the block is missing from the parents of its child %s", child)
+			continue
+		}
+		validChildren = append(validChildren, child)
+	}
+	if c.shouldRepair && len(validChildren) != len(relations.Children) {
+		repairedRelations := relations.Clone()
+		repairedRelations.Children = validChildren
+		ic.blockRelationStore.StageBlockRelation(c.stagingArea, blockHash, repairedRelations)
+	}
+
+	return nil
+}
+
+// checkGHOSTDAGData recomputes the GHOSTDAG data of blockHash and compares it to the stored data
+func (c *integrityCheck) checkGHOSTDAGData(blockHash *externalapi.DomainHash) error {
+	ic := c.checker
+
+	// The mergeset of the pruning point reaches into pruned data, so it cannot be recomputed
+	if blockHash.Equal(c.pruningPoint) || blockHash.Equal(ic.genesisHash) {
+		return nil
+	}
+
+	storedGHOSTDAGData, err := ic.ghostdagDataStore.Get(ic.databaseContext, c.stagingArea, blockHash, false)
+	if err != nil && !isNotFoundError(err) {
+		return err
+	}
+	// Blocks with trusted data have no parents to recompute their GHOSTDAG data from
+	if storedGHOSTDAGData != nil && storedGHOSTDAGData.SelectedParent().Equal(model.VirtualGenesisBlockHash) {
+		return nil
+	}
+
+	// The recomputation is staged in a separate staging area, so that it never ends up in the
+	// database unless it's explicitly staged as a repair.
+	recomputeStagingArea := model.NewStagingArea()
+	err = ic.ghostdagManager.GHOSTDAG(recomputeStagingArea, blockHash)
+	if isNotFoundError(err) {
+		log.Debugf("Skipping the GHOSTDAG check of %s since its past is pruned: %s", blockHash, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recomputedGHOSTDAGData, err := ic.ghostdagDataStore.Get(ic.databaseContext, recomputeStagingArea, blockHash, false)
+	if err != nil {
+		return err
+	}
+
+	if storedGHOSTDAGData == nil {
+		if c.shouldRepair {
+			ic.ghostdagDataStore.Stage(c.stagingArea, blockHash, recomputedGHOSTDAGData, false)
+		}
+		c.addIssue(externalapi.IntegrityIssueGHOSTDAGData, blockHash, c.shouldRepair, "GHOSTDAG data is missing")
+		return nil
+	}
+
+	if !storedGHOSTDAGData.Equal(recomputedGHOSTDAGData) {
+		if c.shouldRepair {
+			ic.ghostdagDataStore.Stage(c.stagingArea, blockHash, recomputedGHOSTDAGData, false)
+		}
+		c.addIssue(externalapi.IntegrityIssueGHOSTDAGData, blockHash, c.shouldRepair,
+			"stored GHOSTDAG data (blue score %d, blue work %s, selected parent %s, %d blues, %d reds) "+
+				"differs from the recomputed data (blue score %d, blue work %s, selected parent %s, %d blues, %d reds)",
+			storedGHOSTDAGData.BlueScore(), storedGHOSTDAGData.BlueWork(), storedGHOSTDAGData.SelectedParent(),
+			len(storedGHOSTDAGData.MergeSetBlues()), len(storedGHOSTDAGData.MergeSetReds()),
+			recomputedGHOSTDAGData.BlueScore(), recomputedGHOSTDAGData.BlueWork(), recomputedGHOSTDAGData.SelectedParent(),
+			len(recomputedGHOSTDAGData.MergeSetBlues()), len(recomputedGHOSTDAGData.MergeSetReds()))
+	}
+
+	return nil
+}
+
+// checkReachability verifies that the reachability interval of blockHash is strictly contained in the
+// interval of its reachability tree parent, and that it's correctly ordered among its siblings.
+func (c *integrityCheck) checkReachability(blockHash *externalapi.DomainHash) error {
+	ic := c.checker
+
+	reachabilityData, err := ic.reachabilityDataStore.ReachabilityData(ic.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false, "reachability data is missing")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	interval := reachabilityData.Interval()
+	if interval.Start > interval.End {
+		c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false, "interval %s is empty", interval)
+	}
+
+	parent := reachabilityData.Parent()
+	if parent == nil {
+		return nil
+	}
+
+	parentReachabilityData, err := ic.reachabilityDataStore.ReachabilityData(ic.databaseContext, c.stagingArea, parent)
+	if isNotFoundError(err) {
+		c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false,
+			"reachability tree parent %s has no reachability data", parent)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A parent never allocates the last index of its interval to its children, so the containment is strict
+	parentInterval := parentReachabilityData.Interval()
+	if interval.Start < parentInterval.Start || interval.End >= parentInterval.End {
+		c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false,
+			"interval %s is not strictly contained in the interval %s of its tree parent %s",
+			interval, parentInterval, parent)
+	}
+
+	siblings := parentReachabilityData.Children()
+	index := -1
+	for i, sibling := range siblings {
+		if sibling.Equal(blockHash) {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		isRepaired := false
+		if c.shouldRepair {
+			// Children are kept ordered by their intervals, so the block can only be appended if its
+			// interval comes after the intervals of all its siblings.
+			isAfterSiblings := true
+			if len(siblings) > 0 {
+				lastSiblingInterval, err := c.interval(siblings[len(siblings)-1])
+				if err != nil {
+					return err
+				}
+				isAfterSiblings = lastSiblingInterval != nil && lastSiblingInterval.End < interval.Start
+			}
+			if isAfterSiblings {
+				repairedParentReachabilityData := parentReachabilityData.CloneMutable()
+				repairedParentReachabilityData.AddChild(blockHash)
+				ic.reachabilityDataStore.StageReachabilityData(c.stagingArea, parent, repairedParentReachabilityData)
+				isRepaired = true
+			}
+		}
+		c.addIssue(externalapi.IntegrityIssueReachability, blockHash, isRepaired,
+			"the block is missing from the children of its tree parent %s", parent)
+		return nil
+	}
+
+	if index > 0 {
+		previousSiblingInterval, err := c.interval(siblings[index-1])
+		if err != nil {
+			return err
+		}
+		if previousSiblingInterval != nil && previousSiblingInterval.End >= interval.Start {
+			c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false,
+				"interval %s overlaps or precedes the interval %s of its previous sibling %s",
+				interval, previousSiblingInterval, siblings[index-1])
+		}
+	}
+	if index < len(siblings)-1 {
+		nextSiblingInterval, err := c.interval(siblings[index+1])
+		if err != nil {
+			return err
+		}
+		if nextSiblingInterval != nil && interval.End >= nextSiblingInterval.Start {
+			c.addIssue(externalapi.IntegrityIssueReachability, blockHash, false,
+				"interval %s overlaps or follows the interval %s of its next sibling %s",
+				interval, nextSiblingInterval, siblings[index+1])
+		}
+	}
+
+	return nil
+}
+
+// interval returns the reachability interval of blockHash, or nil if it has no reachability data
+func (c *integrityCheck) interval(blockHash *externalapi.DomainHash) (*model.ReachabilityInterval, error) {
+	reachabilityData, err := c.checker.reachabilityDataStore.ReachabilityData(c.checker.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reachabilityData.Interval(), nil
+}
+
+// checkMultiset verifies that the stored multiset of blockHash, if any, matches its header's UTXO commitment.
+// The pruning point is checked separately against the pruning point UTXO set.
+func (c *integrityCheck) checkMultiset(blockHash *externalapi.DomainHash) error {
+	ic := c.checker
+
+	if blockHash.Equal(c.pruningPoint) {
+		return nil
+	}
+
+	blockMultiset, err := ic.multisetStore.Get(ic.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		// Only blocks whose UTXO state was verified have a multiset
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	header, err := ic.blockHeaderStore.BlockHeader(ic.databaseContext, c.stagingArea, blockHash)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !header.UTXOCommitment().Equal(blockMultiset.Hash()) {
+		c.addIssue(externalapi.IntegrityIssueUTXOCommitment, blockHash, false,
+			"stored multiset hash %s doesn't match the header UTXO commitment %s",
+			blockMultiset.Hash(), header.UTXOCommitment())
+	}
+
+	return nil
+}
+
+// checkPruningPointUTXOSet verifies that the pruning point UTXO set matches the pruning point's UTXO commitment.
+// If it does but the stored multiset of the pruning point doesn't, the multiset is repaired from the UTXO set.
+func (c *integrityCheck) checkPruningPointUTXOSet() error {
+	ic := c.checker
+
+	utxoSetIterator, err := ic.pruningStore.PruningPointUTXOIterator(ic.databaseContext)
+	if err != nil {
+		return err
+	}
+	defer utxoSetIterator.Close()
+
+	utxoSetMultiset := multiset.New()
+	for ok := utxoSetIterator.First(); ok; ok = utxoSetIterator.Next() {
+		outpoint, entry, err := utxoSetIterator.Get()
+		if err != nil {
+			return err
+		}
+		serializedUTXO, err := utxo.SerializeUTXO(entry, outpoint)
+		if err != nil {
+			return err
+		}
+		utxoSetMultiset.Add(serializedUTXO)
+	}
+
+	header, err := ic.blockHeaderStore.BlockHeader(ic.databaseContext, c.stagingArea, c.pruningPoint)
+	if err != nil {
+		return err
+	}
+
+	utxoSetHash := utxoSetMultiset.Hash()
+	if !header.UTXOCommitment().Equal(utxoSetHash) {
+		c.addIssue(externalapi.IntegrityIssueUTXOCommitment, c.pruningPoint, false,
+			"the pruning point UTXO set hash %s doesn't match the header UTXO commitment %s",
+			utxoSetHash, header.UTXOCommitment())
+		return nil
+	}
+
+	storedMultiset, err := ic.multisetStore.Get(ic.databaseContext, c.stagingArea, c.pruningPoint)
+	if err != nil && !isNotFoundError(err) {
+		return err
+	}
+	if storedMultiset == nil || !storedMultiset.Hash().Equal(utxoSetHash) {
+		if c.shouldRepair {
+			ic.multisetStore.Stage(c.stagingArea, c.pruningPoint, utxoSetMultiset)
+		}
+		c.addIssue(externalapi.IntegrityIssueUTXOCommitment, c.pruningPoint, c.shouldRepair,
+			"the stored pruning point multiset doesn't match the pruning point UTXO set")
+	}
+
+	return nil
+}
+
+func containsHash(hashes []*externalapi.DomainHash, hash *externalapi.DomainHash) bool {
+	for _, h := range hashes {
+		if h.Equal(hash) {
+			return true
+		}
+	}
+	return false
+}
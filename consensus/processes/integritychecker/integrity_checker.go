@@ -0,0 +1,162 @@
+package integritychecker
+
+import (
+	"fmt"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+type integrityChecker struct {
+	databaseContext model.DBReader
+	genesisHash     *externalapi.DomainHash
+
+	ghostdagManager model.GHOSTDAGManager
+
+	blockHeaderStore      model.BlockHeaderStore
+	blockRelationStore    model.BlockRelationStore
+	ghostdagDataStore     model.GHOSTDAGDataStore
+	reachabilityDataStore model.ReachabilityDataStore
+	multisetStore         model.MultisetStore
+	pruningStore          model.PruningStore
+}
+
+// New instantiates a new IntegrityChecker
+func New(
+	databaseContext model.DBReader,
+	genesisHash *externalapi.DomainHash,
+
+	ghostdagManager model.GHOSTDAGManager,
+
+	blockHeaderStore model.BlockHeaderStore,
+	blockRelationStore model.BlockRelationStore,
+	ghostdagDataStore model.GHOSTDAGDataStore,
+	reachabilityDataStore model.ReachabilityDataStore,
+	multisetStore model.MultisetStore,
+	pruningStore model.PruningStore,
+) model.IntegrityChecker {
+
+	return &integrityChecker{
+		databaseContext: databaseContext,
+		genesisHash:     genesisHash,
+
+		ghostdagManager: ghostdagManager,
+
+		blockHeaderStore:      blockHeaderStore,
+		blockRelationStore:    blockRelationStore,
+		ghostdagDataStore:     ghostdagDataStore,
+		reachabilityDataStore: reachabilityDataStore,
+		multisetStore:         multisetStore,
+		pruningStore:          pruningStore,
+	}
+}
+
+// CheckIntegrity walks the virtual selected parent chain down to the pruning point and verifies
+// that the block relations, GHOSTDAG data, reachability data and multisets of every chain block
+// are consistent. If shouldRepair is set, repairs for the issues that can be fixed are staged
+// into stagingArea; committing them is the responsibility of the caller.
+//
+// Only the level 0 stores are checked. The block relations, GHOSTDAG data and reachability data
+// of the higher block levels, which are only used to build pruning point proofs, are not.
+func (ic *integrityChecker) CheckIntegrity(stagingArea *model.StagingArea, shouldRepair bool) (
+	*externalapi.IntegrityReport, error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "CheckIntegrity")
+	defer onEnd()
+
+	pruningPoint, err := ic.pruningStore.PruningPoint(ic.databaseContext, stagingArea)
+	if err != nil {
+		return nil, err
+	}
+
+	virtualGHOSTDAGData, err := ic.ghostdagDataStore.Get(ic.databaseContext, stagingArea, model.VirtualBlockHash, false)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &integrityCheck{
+		checker:      ic,
+		stagingArea:  stagingArea,
+		shouldRepair: shouldRepair,
+		pruningPoint: pruningPoint,
+		report:       &externalapi.IntegrityReport{},
+	}
+
+	current := virtualGHOSTDAGData.SelectedParent()
+	for {
+		err := check.checkChainBlock(current)
+		if err != nil {
+			return nil, err
+		}
+		check.report.CheckedChainBlocks++
+		if check.report.CheckedChainBlocks%10_000 == 0 {
+			log.Infof("Checked %d chain blocks", check.report.CheckedChainBlocks)
+		}
+
+		if current.Equal(pruningPoint) || current.Equal(ic.genesisHash) {
+			break
+		}
+
+		currentGHOSTDAGData, err := ic.ghostdagDataStore.Get(ic.databaseContext, stagingArea, current, false)
+		if err != nil {
+			return nil, err
+		}
+		if currentGHOSTDAGData.SelectedParent().Equal(model.VirtualGenesisBlockHash) {
+			break
+		}
+		current = currentGHOSTDAGData.SelectedParent()
+	}
+
+	err = check.checkPruningPointUTXOSet()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Integrity check done. Checked %d chain blocks and found %d issues",
+		check.report.CheckedChainBlocks, len(check.report.Issues))
+	return check.report, nil
+}
+
+// integrityCheck holds the state of a single CheckIntegrity run
+type integrityCheck struct {
+	checker      *integrityChecker
+	stagingArea  *model.StagingArea
+	shouldRepair bool
+	pruningPoint *externalapi.DomainHash
+	report       *externalapi.IntegrityReport
+}
+
+func (c *integrityCheck) addIssue(kind externalapi.IntegrityIssueKind, blockHash *externalapi.DomainHash,
+	isRepaired bool, format string, args ...interface{}) {
+
+	issue := &externalapi.IntegrityIssue{
+		Kind:        kind,
+		BlockHash:   blockHash,
+		Description: fmt.Sprintf(format, args...),
+		IsRepaired:  isRepaired,
+	}
+	log.Warnf("Integrity issue: %s", issue)
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+func (c *integrityCheck) checkChainBlock(blockHash *externalapi.DomainHash) error {
+	err := c.checkBlockRelations(blockHash)
+	if err != nil {
+		return err
+	}
+	err = c.checkGHOSTDAGData(blockHash)
+	if err != nil {
+		return err
+	}
+	err = c.checkReachability(blockHash)
+	if err != nil {
+		return err
+	}
+	return c.checkMultiset(blockHash)
+}
+
+func isNotFoundError(err error) bool {
+	return database.IsNotFoundError(err)
+}
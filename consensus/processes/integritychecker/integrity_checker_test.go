@@ -0,0 +1,195 @@
+package integritychecker_test
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/multiset"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/reachabilitydata"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestCheckIntegrity")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		tipHash := consensusConfig.GenesisHash
+		for i := 0; i < 5; i++ {
+			tipHash, _, err = tc.AddBlock([]*externalapi.DomainHash{tipHash}, nil, nil)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+		}
+		tipGHOSTDAGData, err := tc.GHOSTDAGDataStore().Get(tc.DatabaseContext(), model.NewStagingArea(), tipHash, false)
+		if err != nil {
+			t.Fatalf("GHOSTDAGDataStore.Get: %+v", err)
+		}
+		tipSelectedParent := tipGHOSTDAGData.SelectedParent()
+
+		checkConsistent(t, tc, "before any corruption")
+
+		var tipMultiset model.Multiset
+
+		tests := []struct {
+			name         string
+			kind         externalapi.IntegrityIssueKind
+			isRepairable bool
+			corrupt      func(stagingArea *model.StagingArea)
+			restore      func(stagingArea *model.StagingArea)
+		}{
+			{
+				name:         "child missing from its parent's block relations",
+				kind:         externalapi.IntegrityIssueBlockRelations,
+				isRepairable: true,
+				corrupt: func(stagingArea *model.StagingArea) {
+					relations, err := tc.BlockRelationStore().BlockRelation(tc.DatabaseContext(), stagingArea, tipSelectedParent)
+					if err != nil {
+						t.Fatalf("BlockRelation: %+v", err)
+					}
+					corruptedRelations := relations.Clone()
+					corruptedRelations.Children = nil
+					tc.BlockRelationStore().StageBlockRelation(stagingArea, tipSelectedParent, corruptedRelations)
+				},
+			},
+			{
+				name:         "wrong GHOSTDAG data",
+				kind:         externalapi.IntegrityIssueGHOSTDAGData,
+				isRepairable: true,
+				corrupt: func(stagingArea *model.StagingArea) {
+					corruptedGHOSTDAGData := externalapi.NewBlockGHOSTDAGData(tipGHOSTDAGData.BlueScore()+1,
+						tipGHOSTDAGData.BlueWork(), tipGHOSTDAGData.SelectedParent(), tipGHOSTDAGData.MergeSetBlues(),
+						tipGHOSTDAGData.MergeSetReds(), tipGHOSTDAGData.BluesAnticoneSizes())
+					tc.GHOSTDAGDataStore().Stage(stagingArea, tipHash, corruptedGHOSTDAGData, false)
+				},
+			},
+			{
+				name:         "child missing from its reachability tree parent",
+				kind:         externalapi.IntegrityIssueReachability,
+				isRepairable: true,
+				corrupt: func(stagingArea *model.StagingArea) {
+					tipReachabilityData, err := tc.ReachabilityDataStore().ReachabilityData(tc.DatabaseContext(), stagingArea, tipHash)
+					if err != nil {
+						t.Fatalf("ReachabilityData: %+v", err)
+					}
+					treeParent := tipReachabilityData.Parent()
+					treeParentReachabilityData, err := tc.ReachabilityDataStore().ReachabilityData(tc.DatabaseContext(), stagingArea, treeParent)
+					if err != nil {
+						t.Fatalf("ReachabilityData: %+v", err)
+					}
+					corruptedReachabilityData := reachabilitydata.EmptyReachabilityData()
+					corruptedReachabilityData.SetParent(treeParentReachabilityData.Parent())
+					corruptedReachabilityData.SetInterval(treeParentReachabilityData.Interval())
+					corruptedReachabilityData.SetFutureCoveringSet(treeParentReachabilityData.FutureCoveringSet())
+					for _, child := range treeParentReachabilityData.Children() {
+						if !child.Equal(tipHash) {
+							corruptedReachabilityData.AddChild(child)
+						}
+					}
+					tc.ReachabilityDataStore().StageReachabilityData(stagingArea, treeParent, corruptedReachabilityData)
+				},
+			},
+			{
+				name:         "pruning point multiset that doesn't match the pruning point UTXO set",
+				kind:         externalapi.IntegrityIssueUTXOCommitment,
+				isRepairable: true,
+				corrupt: func(stagingArea *model.StagingArea) {
+					pruningPoint, err := tc.PruningStore().PruningPoint(tc.DatabaseContext(), stagingArea)
+					if err != nil {
+						t.Fatalf("PruningPoint: %+v", err)
+					}
+					corruptedMultiset := multiset.New()
+					corruptedMultiset.Add([]byte{1})
+					tc.MultisetStore().Stage(stagingArea, pruningPoint, corruptedMultiset)
+				},
+			},
+			{
+				// A chain block multiset can't be derived from the remaining data, so it's only reported
+				name:         "chain block multiset that doesn't match the UTXO commitment",
+				kind:         externalapi.IntegrityIssueUTXOCommitment,
+				isRepairable: false,
+				corrupt: func(stagingArea *model.StagingArea) {
+					tipMultiset, err = tc.MultisetStore().Get(tc.DatabaseContext(), stagingArea, tipHash)
+					if err != nil {
+						t.Fatalf("MultisetStore.Get: %+v", err)
+					}
+					corruptedMultiset := tipMultiset.Clone()
+					corruptedMultiset.Add([]byte{1})
+					tc.MultisetStore().Stage(stagingArea, tipHash, corruptedMultiset)
+				},
+				restore: func(stagingArea *model.StagingArea) {
+					tc.MultisetStore().Stage(stagingArea, tipHash, tipMultiset)
+				},
+			},
+		}
+
+		for _, test := range tests {
+			commit(t, tc, test.corrupt)
+
+			report, err := tc.CheckIntegrity(false)
+			if err != nil {
+				t.Fatalf("%s: CheckIntegrity: %+v", test.name, err)
+			}
+			if len(report.Issues) != 1 || report.Issues[0].Kind != test.kind || report.Issues[0].IsRepaired {
+				t.Fatalf("%s: expected a single unrepaired %s issue but got %s", test.name, test.kind, report.Issues)
+			}
+			if report.IsConsistent() {
+				t.Fatalf("%s: expected the report to be inconsistent", test.name)
+			}
+
+			report, err = tc.CheckIntegrity(true)
+			if err != nil {
+				t.Fatalf("%s: CheckIntegrity: %+v", test.name, err)
+			}
+			if len(report.Issues) != 1 || report.Issues[0].IsRepaired != test.isRepairable {
+				t.Fatalf("%s: expected a single issue with IsRepaired == %t but got %s",
+					test.name, test.isRepairable, report.Issues)
+			}
+
+			if test.restore != nil {
+				commit(t, tc, test.restore)
+			}
+			checkConsistent(t, tc, "after "+test.name)
+		}
+	})
+}
+
+func checkConsistent(t *testing.T, tc testapi.TestConsensus, stage string) {
+	report, err := tc.CheckIntegrity(false)
+	if err != nil {
+		t.Fatalf("%s: CheckIntegrity: %+v", stage, err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("%s: expected no integrity issues but got %s", stage, report.Issues)
+	}
+	if report.CheckedChainBlocks == 0 {
+		t.Fatalf("%s: expected chain blocks to be checked", stage)
+	}
+}
+
+// commit commits the changes staged by stage directly to the database, bypassing consensus
+func commit(t *testing.T, tc testapi.TestConsensus, stage func(stagingArea *model.StagingArea)) {
+	stagingArea := model.NewStagingArea()
+	stage(stagingArea)
+
+	dbTx, err := tc.DatabaseContext().Begin()
+	if err != nil {
+		t.Fatalf("Begin: %+v", err)
+	}
+	defer dbTx.RollbackUnlessClosed()
+
+	err = stagingArea.Commit(dbTx)
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+	err = dbTx.Commit()
+	if err != nil {
+		t.Fatalf("Commit: %+v", err)
+	}
+}
@@ -2,6 +2,7 @@ package pruningmanager
 
 import (
 	"sort"
+	"sync"
 
 	"github.com/karlsen-network/karlsend/v2/infrastructure/db/database"
 	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
@@ -30,6 +31,7 @@ type pruningManager struct {
 	blockStatusStore                    model.BlockStatusStore
 	headerSelectedTipStore              model.HeaderSelectedTipStore
 	blocksWithTrustedDataDAAWindowStore model.BlocksWithTrustedDataDAAWindowStore
+	retainedBodyStore                   model.RetainedBodyStore
 	multiSetStore                       model.MultisetStore
 	acceptanceDataStore                 model.AcceptanceDataStore
 	blocksStore                         model.BlockStore
@@ -39,6 +41,7 @@ type pruningManager struct {
 	reachabilityDataStore               model.ReachabilityDataStore
 
 	isArchivalNode                  bool
+	retentionPolicy                 model.RetentionPolicy
 	genesisHash                     *externalapi.DomainHash
 	finalityInterval                uint64
 	pruningDepth                    uint64
@@ -48,6 +51,11 @@ type pruningManager struct {
 
 	cachedPruningPoint         *externalapi.DomainHash
 	cachedPruningPointAnticone []*externalapi.DomainHash
+
+	// statisticsShardID is the shard that collects what the pruning in progress deletes, until it's committed
+	statisticsShardID model.StagingShardID
+	statisticsLock    sync.Mutex
+	statistics        externalapi.PruningStatistics
 }
 
 // New instantiates a new PruningManager
@@ -72,8 +80,10 @@ func New(
 	daaBlocksStore model.DAABlocksStore,
 	reachabilityDataStore model.ReachabilityDataStore,
 	blocksWithTrustedDataDAAWindowStore model.BlocksWithTrustedDataDAAWindowStore,
+	retainedBodyStore model.RetainedBodyStore,
 
 	isArchivalNode bool,
+	retentionPolicy model.RetentionPolicy,
 	genesisHash *externalapi.DomainHash,
 	finalityInterval uint64,
 	pruningDepth uint64,
@@ -102,14 +112,18 @@ func New(
 		daaBlocksStore:                      daaBlocksStore,
 		reachabilityDataStore:               reachabilityDataStore,
 		blocksWithTrustedDataDAAWindowStore: blocksWithTrustedDataDAAWindowStore,
+		retainedBodyStore:                   retainedBodyStore,
 
 		isArchivalNode:                  isArchivalNode,
+		retentionPolicy:                 retentionPolicy,
 		genesisHash:                     genesisHash,
 		pruningDepth:                    pruningDepth,
 		finalityInterval:                finalityInterval,
 		shouldSanityCheckPruningUTXOSet: shouldSanityCheckPruningUTXOSet,
 		k:                               k,
 		difficultyAdjustmentWindowSize:  difficultyAdjustmentWindowSize,
+
+		statisticsShardID: model.StagingShardID(staging.GenerateShardingID()),
 	}
 }
 
@@ -519,12 +533,24 @@ func (pm *pruningManager) deleteBlock(stagingArea *model.StagingArea, blockHash
 		return false, nil
 	}
 
+	acceptanceDataSize, err := pm.acceptanceDataStore.AcceptanceDataSize(pm.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return false, err
+	}
+	pendingStatistics := pm.pendingStatistics(stagingArea)
+	pendingStatistics.PrunedBlocks++
+	pendingStatistics.ReclaimedBytes += uint64(acceptanceDataSize)
+
 	pm.multiSetStore.Delete(stagingArea, blockHash)
 	pm.acceptanceDataStore.Delete(stagingArea, blockHash)
-	pm.blocksStore.Delete(stagingArea, blockHash)
 	pm.utxoDiffStore.Delete(stagingArea, blockHash)
 	pm.daaBlocksStore.Delete(stagingArea, blockHash)
 
+	err = pm.retainOrDeleteBody(stagingArea, blockHash)
+	if err != nil {
+		return false, err
+	}
+
 	return false, nil
 }
 
@@ -1025,16 +1051,23 @@ func (pm *pruningManager) updatePruningPoint() error {
 	if err != nil {
 		return err
 	}
+	err = pm.pruneRetainedBodies(stagingArea, pruningPoint)
+	if err != nil {
+		return err
+	}
 
 	err = staging.CommitAllChanges(pm.databaseContext, stagingArea)
 	if err != nil {
 		return err
 	}
+	pm.RecordPruning(stagingArea)
 
 	log.Debugf("Finishing updating the pruning point UTXO set")
 	return pm.pruningStore.FinishUpdatingPruningPointUTXOSet(pm.databaseContext)
 }
 
+// PruneAllBlocksBelow stages the pruning of all the blocks in the past of the given pruning point.
+// RecordPruning must be called once the given staging area is committed.
 func (pm *pruningManager) PruneAllBlocksBelow(stagingArea *model.StagingArea, pruningPointHash *externalapi.DomainHash) error {
 	onEnd := logger.LogAndMeasureExecutionTime(log, "PruneAllBlocksBelow")
 	defer onEnd()
@@ -1063,7 +1096,7 @@ func (pm *pruningManager) PruneAllBlocksBelow(stagingArea *model.StagingArea, pr
 		}
 	}
 
-	return nil
+	return pm.pruneRetainedBodies(stagingArea, pruningPointHash)
 }
 
 func (pm *pruningManager) PruningPointAndItsAnticone() ([]*externalapi.DomainHash, error) {
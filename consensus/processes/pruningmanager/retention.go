@@ -0,0 +1,222 @@
+package pruningmanager
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// pruningStatisticsShard collects what the pruning staged in a staging area deletes. It's added
+// to the pruning statistics by RecordPruning once the staging area is committed.
+type pruningStatisticsShard struct {
+	statistics externalapi.PruningStatistics
+}
+
+// Commit does nothing, since the statistics are only recorded after the commit
+func (pss *pruningStatisticsShard) Commit(model.DBTransaction) error {
+	return nil
+}
+
+func (pm *pruningManager) pendingStatistics(stagingArea *model.StagingArea) *externalapi.PruningStatistics {
+	return &stagingArea.GetOrCreateShard(pm.statisticsShardID, func() model.StagingShard {
+		return &pruningStatisticsShard{}
+	}).(*pruningStatisticsShard).statistics
+}
+
+// PruningStatistics returns the pruning statistics collected since this pruningManager
+// was instantiated
+func (pm *pruningManager) PruningStatistics() *externalapi.PruningStatistics {
+	pm.statisticsLock.Lock()
+	defer pm.statisticsLock.Unlock()
+
+	statistics := pm.statistics
+	return &statistics
+}
+
+// RecordPruning adds what the pruning staged in the given staging area deleted to the pruning
+// statistics. It must be called once the staging area is committed.
+func (pm *pruningManager) RecordPruning(stagingArea *model.StagingArea) {
+	pm.statisticsLock.Lock()
+	defer pm.statisticsLock.Unlock()
+
+	pending := pm.pendingStatistics(stagingArea)
+	statistics := &pm.statistics
+	statistics.Prunings++
+	statistics.PrunedBlocks += pending.PrunedBlocks
+	statistics.ReclaimedBytes += pending.ReclaimedBytes
+	statistics.LastReclaimedBytes = pending.ReclaimedBytes
+	statistics.RetainedBodies = pending.RetainedBodies
+	statistics.RetainedBytes = pending.RetainedBytes
+	statistics.BudgetEvictions += pending.BudgetEvictions
+	*pending = externalapi.PruningStatistics{}
+}
+
+func (pm *pruningManager) deleteBody(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	blockSize, err := pm.blocksStore.BlockSize(pm.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	pm.pendingStatistics(stagingArea).ReclaimedBytes += uint64(blockSize)
+	pm.blocksStore.Delete(stagingArea, blockHash)
+	return nil
+}
+
+// retainOrDeleteBody takes the retention decision for the body of a block that's being pruned. Bodies
+// the policy may keep are recorded in the retained body store, so that pruneRetainedBodies doesn't
+// have to read them again, and the rest are deleted.
+func (pm *pruningManager) retainOrDeleteBody(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) error {
+	if !pm.retentionPolicy.RetainsBodies() {
+		return pm.deleteBody(stagingArea, blockHash)
+	}
+
+	paysRetainedScript := false
+	if len(pm.retentionPolicy.ScriptPublicKeys) > 0 {
+		var err error
+		paysRetainedScript, err = pm.paysRetainedScript(stagingArea, blockHash)
+		if err != nil {
+			return err
+		}
+	}
+	if !paysRetainedScript && pm.retentionPolicy.PruningPeriods == 0 {
+		return pm.deleteBody(stagingArea, blockHash)
+	}
+
+	header, err := pm.blockHeaderStore.BlockHeader(pm.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	blockSize, err := pm.blocksStore.BlockSize(pm.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return err
+	}
+	pm.retainedBodyStore.Stage(stagingArea, &model.RetainedBody{
+		BlockHash:          blockHash,
+		BlueScore:          header.BlueScore(),
+		Size:               uint64(blockSize),
+		PaysRetainedScript: paysRetainedScript,
+	})
+	return nil
+}
+
+// pruneRetainedBodies deletes the retained bodies that fell out of the retention span, and then deletes
+// retained bodies until the rest fit the disk budget. The retained body store keeps the bodies ordered by
+// blue score, so only the bodies that are deleted are gone over.
+func (pm *pruningManager) pruneRetainedBodies(stagingArea *model.StagingArea, pruningPoint *externalapi.DomainHash) error {
+	if pm.isArchivalNode || !pm.retentionPolicy.RetainsBodies() {
+		return nil
+	}
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "pruningManager.pruneRetainedBodies")
+	defer onEnd()
+
+	pruningPointHeader, err := pm.blockHeaderStore.BlockHeader(pm.databaseContext, stagingArea, pruningPoint)
+	if err != nil {
+		return err
+	}
+	retentionSpan := pm.retentionPolicy.PruningPeriods * pm.finalityInterval
+
+	// The bodies kept by their scripts never fall out of the retention span
+	_, err = pm.deleteLowestRetainedBodies(stagingArea, false, func(retainedBody *model.RetainedBody) bool {
+		return retainedBody.BlueScore+retentionSpan <= pruningPointHeader.BlueScore()
+	})
+	if err != nil {
+		return err
+	}
+
+	retainedCount, retainedBytes, err := pm.retainedBodyStore.Totals(pm.databaseContext, stagingArea)
+	if err != nil {
+		return err
+	}
+
+	pendingStatistics := pm.pendingStatistics(stagingArea)
+	diskBudget := pm.retentionPolicy.DiskBudgetBytes
+	if diskBudget > 0 && retainedBytes > diskBudget {
+		exceedsDiskBudget := func(retainedBody *model.RetainedBody) bool {
+			if retainedBytes <= diskBudget {
+				return false
+			}
+			retainedCount--
+			retainedBytes -= retainedBody.Size
+			return true
+		}
+
+		// The bodies kept by their blue score are evicted first
+		evictedByBlueScore, err := pm.deleteLowestRetainedBodies(stagingArea, false, exceedsDiskBudget)
+		if err != nil {
+			return err
+		}
+		evictedByScript, err := pm.deleteLowestRetainedBodies(stagingArea, true, exceedsDiskBudget)
+		if err != nil {
+			return err
+		}
+		if evictedByScript > 0 {
+			log.Warnf("Deleted %d bodies that pay the retained scripts to fit the disk budget of %d bytes",
+				evictedByScript, diskBudget)
+		}
+		log.Debugf("Deleted %d retained bodies to fit the disk budget of %d bytes",
+			evictedByBlueScore+evictedByScript, diskBudget)
+		pendingStatistics.BudgetEvictions += uint64(evictedByBlueScore + evictedByScript)
+	}
+
+	pendingStatistics.RetainedBodies = retainedCount
+	pendingStatistics.RetainedBytes = retainedBytes
+	return nil
+}
+
+// deleteLowestRetainedBodies deletes the retained bodies kept by their scripts if paysRetainedScript is true,
+// or by their blue score otherwise, from the lowest blue score up, for as long as shouldDelete returns true.
+// It returns the number of deleted bodies.
+func (pm *pruningManager) deleteLowestRetainedBodies(stagingArea *model.StagingArea, paysRetainedScript bool,
+	shouldDelete func(retainedBody *model.RetainedBody) bool) (int, error) {
+
+	iterator, err := pm.retainedBodyStore.RetainedBodies(pm.databaseContext, stagingArea, paysRetainedScript)
+	if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+
+	deleted := 0
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		retainedBody, err := iterator.Get()
+		if err != nil {
+			return 0, err
+		}
+		if !shouldDelete(retainedBody) {
+			break
+		}
+		err = pm.deleteRetainedBody(stagingArea, retainedBody)
+		if err != nil {
+			return 0, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (pm *pruningManager) deleteRetainedBody(stagingArea *model.StagingArea, retainedBody *model.RetainedBody) error {
+	err := pm.deleteBody(stagingArea, retainedBody.BlockHash)
+	if err != nil {
+		return err
+	}
+	pm.retainedBodyStore.Delete(stagingArea, retainedBody)
+	return nil
+}
+
+// paysRetainedScript returns whether the given block has a transaction output that pays one
+// of the scripts of the retention policy
+func (pm *pruningManager) paysRetainedScript(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (bool, error) {
+	block, err := pm.blocksStore.Block(pm.databaseContext, stagingArea, blockHash)
+	if err != nil {
+		return false, err
+	}
+	for _, transaction := range block.Transactions {
+		for _, output := range transaction.Outputs {
+			for _, scriptPublicKey := range pm.retentionPolicy.ScriptPublicKeys {
+				if output.ScriptPublicKey.Equal(scriptPublicKey) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
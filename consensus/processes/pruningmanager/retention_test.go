@@ -0,0 +1,229 @@
+package pruningmanager_test
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+// retentionTestChainLength is long enough for the pruning point to move well past the
+// bodies the tests look at
+const retentionTestChainLength = 80
+
+func retentionTestConfig(retentionPolicy model.RetentionPolicy) *consensus.Config {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+	consensusConfig.DisableDifficultyAdjustment = true
+	// This is done to reduce the finality interval to 5 blocks and the pruning depth to 12 blocks
+	consensusConfig.FinalityDuration = 5 * consensusConfig.TargetTimePerBlock
+	consensusConfig.K = 0
+	consensusConfig.DifficultyAdjustmentWindowSize = 10
+	consensusConfig.RetentionPolicy = retentionPolicy
+	return consensusConfig
+}
+
+// mineRetentionTestChain mines a chain, paying the coinbase of the block at minerAIndex to minerA
+// and all the others to minerB, and returns the chain along with the blue score of the pruning point
+func mineRetentionTestChain(t *testing.T, tc testapi.TestConsensus, consensusConfig *consensus.Config,
+	minerAIndex int, minerA, minerB *externalapi.DomainCoinbaseData) ([]*externalapi.DomainHash, uint64) {
+
+	chain := make([]*externalapi.DomainHash, retentionTestChainLength)
+	tip := consensusConfig.GenesisHash
+	for i := range chain {
+		coinbaseData := minerB
+		if i == minerAIndex {
+			coinbaseData = minerA
+		}
+		blockHash, _, err := tc.AddBlock([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		chain[i] = blockHash
+		tip = blockHash
+	}
+
+	pruningPoint, err := tc.PruningPoint()
+	if err != nil {
+		t.Fatalf("PruningPoint: %+v", err)
+	}
+	pruningPointHeader, err := tc.GetBlockHeader(pruningPoint)
+	if err != nil {
+		t.Fatalf("GetBlockHeader: %+v", err)
+	}
+	return chain, pruningPointHeader.BlueScore()
+}
+
+func hasBody(t *testing.T, tc testapi.TestConsensus, blockHash *externalapi.DomainHash) bool {
+	hasBlock, err := tc.BlockStore().HasBlock(tc.DatabaseContext(), model.NewStagingArea(), blockHash)
+	if err != nil {
+		t.Fatalf("HasBlock: %+v", err)
+	}
+	return hasBlock
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	minerA := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	minerB := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{2}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	const minerAIndex = 10
+	const pruningPeriods = 4
+
+	tests := []struct {
+		name            string
+		retentionPolicy model.RetentionPolicy
+		// expectsBody returns whether the body of a block far enough below the pruning point to be
+		// out of its difficulty window is expected to be kept
+		expectsBody func(index int, blueScore, pruningPointBlueScore uint64) bool
+	}{
+		{
+			name:            "headers only",
+			retentionPolicy: model.RetentionPolicy{},
+			expectsBody: func(int, uint64, uint64) bool {
+				return false
+			},
+		},
+		{
+			name:            "pruning periods",
+			retentionPolicy: model.RetentionPolicy{PruningPeriods: pruningPeriods},
+			expectsBody: func(_ int, blueScore, pruningPointBlueScore uint64) bool {
+				return blueScore+pruningPeriods*5 > pruningPointBlueScore
+			},
+		},
+		{
+			// The block after the one minerA mined pays minerA in its coinbase
+			name:            "script public keys",
+			retentionPolicy: model.RetentionPolicy{ScriptPublicKeys: []*externalapi.ScriptPublicKey{minerA.ScriptPublicKey}},
+			expectsBody: func(index int, _, _ uint64) bool {
+				return index == minerAIndex+1
+			},
+		},
+		{
+			name:            "disk budget",
+			retentionPolicy: model.RetentionPolicy{PruningPeriods: pruningPeriods, DiskBudgetBytes: 1},
+			expectsBody: func(int, uint64, uint64) bool {
+				return false
+			},
+		},
+	}
+
+	for _, test := range tests {
+		consensusConfig := retentionTestConfig(test.retentionPolicy)
+		tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestRetentionPolicy")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+
+		chain, pruningPointBlueScore := mineRetentionTestChain(t, tc, consensusConfig, minerAIndex, minerA, minerB)
+		for i, blockHash := range chain {
+			header, err := tc.GetBlockHeader(blockHash)
+			if err != nil {
+				t.Fatalf("GetBlockHeader: %+v", err)
+			}
+			blueScore := header.BlueScore()
+			if blueScore+uint64(consensusConfig.DifficultyAdjustmentWindowSize)+2 > pruningPointBlueScore {
+				continue
+			}
+			expectsBody := test.expectsBody(i, blueScore, pruningPointBlueScore)
+			if hasBody(t, tc, blockHash) != expectsBody {
+				t.Fatalf("TestRetentionPolicy: %s: expected the body of block %d with blue score %d to be kept: %t, "+
+					"pruning point blue score: %d", test.name, i, blueScore, expectsBody, pruningPointBlueScore)
+			}
+		}
+
+		statistics, err := tc.GetPruningStatistics()
+		if err != nil {
+			t.Fatalf("GetPruningStatistics: %+v", err)
+		}
+		if statistics.Prunings == 0 || statistics.PrunedBlocks == 0 || statistics.ReclaimedBytes == 0 {
+			t.Fatalf("TestRetentionPolicy: %s: expected pruning to be reported, got: %+v", test.name, statistics)
+		}
+		if test.retentionPolicy.DiskBudgetBytes > 0 {
+			if statistics.RetainedBodies != 0 || statistics.RetainedBytes != 0 || statistics.BudgetEvictions == 0 {
+				t.Fatalf("TestRetentionPolicy: %s: expected every retained body to be evicted, got: %+v",
+					test.name, statistics)
+			}
+		} else if test.retentionPolicy.RetainsBodies() && statistics.RetainedBodies == 0 {
+			t.Fatalf("TestRetentionPolicy: %s: expected retained bodies to be reported, got: %+v", test.name, statistics)
+		}
+
+		teardown(false)
+	}
+}
+
+// TestRetentionDiskBudgetPrecedence checks that the disk budget evicts the bodies kept by their
+// blue score before the bodies kept by their scripts
+func TestRetentionDiskBudgetPrecedence(t *testing.T) {
+	minerA := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	minerB := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{2}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	const minerAIndex = 10
+	scriptPublicKeys := []*externalapi.ScriptPublicKey{minerA.ScriptPublicKey}
+
+	// The budget fits the body that pays minerA, but not along with another one
+	consensusConfig := retentionTestConfig(model.RetentionPolicy{ScriptPublicKeys: scriptPublicKeys})
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestRetentionDiskBudgetPrecedence")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	mineRetentionTestChain(t, tc, consensusConfig, minerAIndex, minerA, minerB)
+	statistics, err := tc.GetPruningStatistics()
+	if err != nil {
+		t.Fatalf("GetPruningStatistics: %+v", err)
+	}
+	if statistics.RetainedBodies != 1 {
+		t.Fatalf("TestRetentionDiskBudgetPrecedence: expected a single body to pay minerA, got: %+v", statistics)
+	}
+	scriptRetainedBytes := statistics.RetainedBytes
+	teardown(false)
+
+	consensusConfig = retentionTestConfig(model.RetentionPolicy{
+		PruningPeriods:   4,
+		ScriptPublicKeys: scriptPublicKeys,
+		DiskBudgetBytes:  scriptRetainedBytes + scriptRetainedBytes/2,
+	})
+	tc, teardown, err = consensus.NewFactory().NewTestConsensus(consensusConfig, "TestRetentionDiskBudgetPrecedence")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	chain, pruningPointBlueScore := mineRetentionTestChain(t, tc, consensusConfig, minerAIndex, minerA, minerB)
+	for i, blockHash := range chain {
+		header, err := tc.GetBlockHeader(blockHash)
+		if err != nil {
+			t.Fatalf("GetBlockHeader: %+v", err)
+		}
+		blueScore := header.BlueScore()
+		if blueScore+uint64(consensusConfig.DifficultyAdjustmentWindowSize)+2 > pruningPointBlueScore {
+			continue
+		}
+		expectsBody := i == minerAIndex+1
+		if hasBody(t, tc, blockHash) != expectsBody {
+			t.Fatalf("TestRetentionDiskBudgetPrecedence: expected the body of block %d with blue score %d "+
+				"to be kept: %t, pruning point blue score: %d", i, blueScore, expectsBody, pruningPointBlueScore)
+		}
+	}
+
+	statistics, err = tc.GetPruningStatistics()
+	if err != nil {
+		t.Fatalf("GetPruningStatistics: %+v", err)
+	}
+	if statistics.RetainedBodies != 1 || statistics.RetainedBytes != scriptRetainedBytes || statistics.BudgetEvictions == 0 {
+		t.Fatalf("TestRetentionDiskBudgetPrecedence: expected only the body that pays minerA to be kept, got: %+v",
+			statistics)
+	}
+}
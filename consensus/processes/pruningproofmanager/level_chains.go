@@ -0,0 +1,74 @@
+package pruningproofmanager
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+)
+
+// PruningPointProofLevelChains returns the selected chain of every level of the given proof. The
+// levels are built the same way ValidatePruningPointProof builds them, in a staging area that is
+// discarded, but the proof isn't validated against the current DAG.
+func (ppm *pruningProofManager) PruningPointProofLevelChains(pruningPointProof *externalapi.PruningPointProof) (
+	[]*externalapi.PruningPointProofLevelChain, error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "PruningPointProofLevelChains")
+	defer onEnd()
+
+	if len(pruningPointProof.Headers) == 0 {
+		return nil, errors.Wrap(ruleerrors.ErrPruningProofEmpty, "pruning proof is empty")
+	}
+	maxLevel := len(pruningPointProof.Headers) - 1
+	if maxLevel > ppm.maxBlockLevel {
+		return nil, errors.Errorf("proof has %d levels while the maximal block level is %d",
+			len(pruningPointProof.Headers), ppm.maxBlockLevel)
+	}
+
+	stagingArea := model.NewStagingArea()
+	blockHeaderStore, blockRelationStores, reachabilityDataStores, ghostdagDataStores, err := ppm.dagStores(maxLevel)
+	if err != nil {
+		return nil, err
+	}
+	reachabilityManagers, dagTopologyManagers, ghostdagManagers := ppm.dagProcesses(maxLevel, blockHeaderStore,
+		blockRelationStores, reachabilityDataStores, ghostdagDataStores)
+
+	err = ppm.initProofLevels(stagingArea, maxLevel, reachabilityManagers, dagTopologyManagers, ghostdagDataStores)
+	if err != nil {
+		return nil, err
+	}
+
+	levelChains := make([]*externalapi.PruningPointProofLevelChain, maxLevel+1)
+	for blockLevel := maxLevel; blockLevel >= 0; blockLevel-- {
+		levelHeaders := pruningPointProof.Headers[blockLevel]
+		selectedTip, err := ppm.populateProofLevel(stagingArea, blockLevel, levelHeaders,
+			blockHeaderStore, ghostdagDataStores[blockLevel], reachabilityManagers[blockLevel],
+			dagTopologyManagers[blockLevel], ghostdagManagers[blockLevel])
+		if err != nil {
+			return nil, err
+		}
+
+		levelChain := &externalapi.PruningPointProofLevelChain{
+			Level:       blockLevel,
+			HeaderCount: len(levelHeaders),
+		}
+		for current := selectedTip; current != nil && !current.Equal(model.VirtualGenesisBlockHash); {
+			ghostdagData, err := ghostdagDataStores[blockLevel].Get(ppm.databaseContext, stagingArea, current, false)
+			if err != nil {
+				return nil, err
+			}
+			levelChain.Chain = append(levelChain.Chain, &externalapi.PruningPointProofChainBlock{
+				Hash:      current,
+				BlueScore: ghostdagData.BlueScore(),
+				BlueWork:  ghostdagData.BlueWork(),
+			})
+			current = ghostdagData.SelectedParent()
+		}
+		for i, j := 0, len(levelChain.Chain)-1; i < j; i, j = i+1, j-1 {
+			levelChain.Chain[i], levelChain.Chain[j] = levelChain.Chain[j], levelChain.Chain[i]
+		}
+		levelChains[blockLevel] = levelChain
+	}
+	return levelChains, nil
+}
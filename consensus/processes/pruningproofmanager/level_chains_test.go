@@ -0,0 +1,99 @@
+package pruningproofmanager_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/pruningpointproof"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
+)
+
+func TestPruningPointProofLevelChains(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		// This is done to reduce the pruning depth to 6 blocks
+		finalityDepth := 5
+		consensusConfig.FinalityDuration = time.Duration(finalityDepth) * consensusConfig.TargetTimePerBlock
+		consensusConfig.K = 0
+		consensusConfig.PruningProofM = 1
+
+		factory := consensus.NewFactory()
+		tcSyncer, teardownSyncer, err := factory.NewTestConsensus(consensusConfig, "TestPruningPointProofLevelChainsSyncer")
+		if err != nil {
+			t.Fatalf("Error setting up tcSyncer: %+v", err)
+		}
+		defer teardownSyncer(false)
+
+		tip := consensusConfig.GenesisHash
+		for i := 0; i < 20; i++ {
+			block, _, err := tcSyncer.BuildBlockWithParents([]*externalapi.DomainHash{tip}, nil, nil)
+			if err != nil {
+				t.Fatalf("BuildBlockWithParents: %+v", err)
+			}
+			err = tcSyncer.ValidateAndInsertBlock(block, true)
+			if err != nil {
+				t.Fatalf("ValidateAndInsertBlock: %+v", err)
+			}
+			tip = consensushashing.BlockHash(block)
+		}
+
+		pruningPoint, err := tcSyncer.PruningPoint()
+		if err != nil {
+			t.Fatalf("PruningPoint: %+v", err)
+		}
+		if pruningPoint.Equal(consensusConfig.GenesisHash) {
+			t.Fatalf("TestPruningPointProofLevelChains: the pruning point didn't move")
+		}
+		proof, err := tcSyncer.BuildPruningPointProof()
+		if err != nil {
+			t.Fatalf("BuildPruningPointProof: %+v", err)
+		}
+
+		// The proof has to survive a round trip through its binary encoding
+		serialized, err := pruningpointproof.Serialize(proof)
+		if err != nil {
+			t.Fatalf("Serialize: %+v", err)
+		}
+		proof, err = pruningpointproof.Deserialize(serialized)
+		if err != nil {
+			t.Fatalf("Deserialize: %+v", err)
+		}
+
+		tcVerifier, teardownVerifier, err := factory.NewTestConsensus(consensusConfig, "TestPruningPointProofLevelChainsVerifier")
+		if err != nil {
+			t.Fatalf("Error setting up tcVerifier: %+v", err)
+		}
+		defer teardownVerifier(false)
+
+		err = tcVerifier.ValidatePruningPointProof(proof)
+		if err != nil {
+			t.Fatalf("ValidatePruningPointProof: %+v", err)
+		}
+		levelChains, err := tcVerifier.GetPruningPointProofLevelChains(proof)
+		if err != nil {
+			t.Fatalf("GetPruningPointProofLevelChains: %+v", err)
+		}
+		if len(levelChains) != len(proof.Headers) {
+			t.Fatalf("TestPruningPointProofLevelChains: unexpected level count: want: %d, got: %d",
+				len(proof.Headers), len(levelChains))
+		}
+
+		// With K=0 and a single chain, the level 0 chain is made of all of its headers
+		level0Chain := levelChains[0]
+		if !level0Chain.SelectedTip().Hash.Equal(pruningPoint) {
+			t.Fatalf("TestPruningPointProofLevelChains: unexpected level 0 selected tip: want: %s, got: %s",
+				pruningPoint, level0Chain.SelectedTip().Hash)
+		}
+		if len(level0Chain.Chain) != level0Chain.HeaderCount {
+			t.Fatalf("TestPruningPointProofLevelChains: unexpected level 0 chain length: want: %d, got: %d",
+				level0Chain.HeaderCount, len(level0Chain.Chain))
+		}
+		for i := 1; i < len(level0Chain.Chain); i++ {
+			if level0Chain.Chain[i].BlueWork.Cmp(level0Chain.Chain[i-1].BlueWork) <= 0 {
+				t.Fatalf("TestPruningPointProofLevelChains: the blue work of the level 0 chain doesn't increase")
+			}
+		}
+	})
+}
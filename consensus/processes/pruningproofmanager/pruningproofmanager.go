@@ -343,94 +343,19 @@ func (ppm *pruningProofManager) ValidatePruningPointProof(pruningPointProof *ext
 
 	reachabilityManagers, dagTopologyManagers, ghostdagManagers := ppm.dagProcesses(maxLevel, blockHeaderStore, blockRelationStores, reachabilityDataStores, ghostdagDataStores)
 
-	for blockLevel := 0; blockLevel <= maxLevel; blockLevel++ {
-		err := reachabilityManagers[blockLevel].Init(stagingArea)
-		if err != nil {
-			return err
-		}
-
-		err = dagTopologyManagers[blockLevel].SetParents(stagingArea, model.VirtualGenesisBlockHash, nil)
-		if err != nil {
-			return err
-		}
-
-		ghostdagDataStores[blockLevel].Stage(stagingArea, model.VirtualGenesisBlockHash, externalapi.NewBlockGHOSTDAGData(
-			0,
-			big.NewInt(0),
-			nil,
-			nil,
-			nil,
-			nil,
-		), false)
+	err = ppm.initProofLevels(stagingArea, maxLevel, reachabilityManagers, dagTopologyManagers, ghostdagDataStores)
+	if err != nil {
+		return err
 	}
 
 	selectedTipByLevel := make([]*externalapi.DomainHash, maxLevel+1)
 	for blockLevel := maxLevel; blockLevel >= 0; blockLevel-- {
 		log.Infof("Validating level %d from the pruning point proof", blockLevel)
-		headers := make([]externalapi.BlockHeader, len(pruningPointProof.Headers[blockLevel]))
-		copy(headers, pruningPointProof.Headers[blockLevel])
-
-		var selectedTip *externalapi.DomainHash
-		for i, header := range headers {
-			blockHash := consensushashing.HeaderHash(header)
-			if header.BlockLevel(ppm.maxBlockLevel) < blockLevel {
-				return errors.Wrapf(ruleerrors.ErrPruningProofWrongBlockLevel, "block %s level is %d when it's "+
-					"expected to be at least %d", blockHash, header.BlockLevel(ppm.maxBlockLevel), blockLevel)
-			}
-
-			blockHeaderStore.Stage(stagingArea, blockHash, header)
-
-			var parents []*externalapi.DomainHash
-			for _, parent := range ppm.parentsManager.ParentsAtLevel(header, blockLevel) {
-				_, err := ghostdagDataStores[blockLevel].Get(ppm.databaseContext, stagingArea, parent, false)
-				if database.IsNotFoundError(err) {
-					continue
-				}
-				if err != nil {
-					return err
-				}
-
-				parents = append(parents, parent)
-			}
-
-			if len(parents) == 0 {
-				if i != 0 {
-					return errors.Wrapf(ruleerrors.ErrPruningProofHeaderWithNoKnownParents, "the proof header "+
-						"%s is missing known parents", blockHash)
-				}
-				parents = append(parents, model.VirtualGenesisBlockHash)
-			}
-
-			err := dagTopologyManagers[blockLevel].SetParents(stagingArea, blockHash, parents)
-			if err != nil {
-				return err
-			}
-
-			err = ghostdagManagers[blockLevel].GHOSTDAG(stagingArea, blockHash)
-			if err != nil {
-				return err
-			}
-
-			if selectedTip == nil {
-				selectedTip = blockHash
-			} else {
-				selectedTip, err = ghostdagManagers[blockLevel].ChooseSelectedParent(stagingArea, selectedTip, blockHash)
-				if err != nil {
-					return err
-				}
-			}
-
-			err = reachabilityManagers[blockLevel].AddBlock(stagingArea, blockHash)
-			if err != nil {
-				return err
-			}
-
-			if selectedTip.Equal(blockHash) {
-				err := reachabilityManagers[blockLevel].UpdateReindexRoot(stagingArea, selectedTip)
-				if err != nil {
-					return err
-				}
-			}
+		selectedTip, err := ppm.populateProofLevel(stagingArea, blockLevel, pruningPointProof.Headers[blockLevel],
+			blockHeaderStore, ghostdagDataStores[blockLevel], reachabilityManagers[blockLevel],
+			dagTopologyManagers[blockLevel], ghostdagManagers[blockLevel])
+		if err != nil {
+			return err
 		}
 
 		if blockLevel < maxLevel {
@@ -569,21 +494,132 @@ func (ppm *pruningProofManager) ValidatePruningPointProof(pruningPointProof *ext
 		"shared blocks with the known DAGs, but doesn't have enough headers from levels higher than the existing block levels.")
 }
 
+// initProofLevels stages the virtual genesis as the root of every level of the proof DAG
+func (ppm *pruningProofManager) initProofLevels(stagingArea *model.StagingArea, maxLevel int,
+	reachabilityManagers []model.ReachabilityManager, dagTopologyManagers []model.DAGTopologyManager,
+	ghostdagDataStores []model.GHOSTDAGDataStore) error {
+
+	for blockLevel := 0; blockLevel <= maxLevel; blockLevel++ {
+		err := reachabilityManagers[blockLevel].Init(stagingArea)
+		if err != nil {
+			return err
+		}
+
+		err = dagTopologyManagers[blockLevel].SetParents(stagingArea, model.VirtualGenesisBlockHash, nil)
+		if err != nil {
+			return err
+		}
+
+		ghostdagDataStores[blockLevel].Stage(stagingArea, model.VirtualGenesisBlockHash, externalapi.NewBlockGHOSTDAGData(
+			0,
+			big.NewInt(0),
+			nil,
+			nil,
+			nil,
+			nil,
+		), false)
+	}
+	return nil
+}
+
+// populateProofLevel adds the headers of a single proof level to the DAG of that level,
+// and returns the selected tip of the level. The proof headers of a level are sorted
+// topologically, so their GHOSTDAG data is computed as a single batch.
+func (ppm *pruningProofManager) populateProofLevel(stagingArea *model.StagingArea, blockLevel int,
+	levelHeaders []externalapi.BlockHeader, blockHeaderStore model.BlockHeaderStore,
+	ghostdagDataStore model.GHOSTDAGDataStore, reachabilityManager model.ReachabilityManager,
+	dagTopologyManager model.DAGTopologyManager, ghostdagManager model.GHOSTDAGManager) (*externalapi.DomainHash, error) {
+
+	blockHashes := make([]*externalapi.DomainHash, len(levelHeaders))
+	inLevel := make(map[externalapi.DomainHash]struct{}, len(levelHeaders))
+	for i, header := range levelHeaders {
+		blockHash := consensushashing.HeaderHash(header)
+		if header.BlockLevel(ppm.maxBlockLevel) < blockLevel {
+			return nil, errors.Wrapf(ruleerrors.ErrPruningProofWrongBlockLevel, "block %s level is %d when it's "+
+				"expected to be at least %d", blockHash, header.BlockLevel(ppm.maxBlockLevel), blockLevel)
+		}
+
+		blockHeaderStore.Stage(stagingArea, blockHash, header)
+
+		var parents []*externalapi.DomainHash
+		for _, parent := range ppm.parentsManager.ParentsAtLevel(header, blockLevel) {
+			if _, ok := inLevel[*parent]; ok {
+				parents = append(parents, parent)
+				continue
+			}
+			_, err := ghostdagDataStore.Get(ppm.databaseContext, stagingArea, parent, false)
+			if database.IsNotFoundError(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			parents = append(parents, parent)
+		}
+
+		if len(parents) == 0 {
+			if i != 0 {
+				return nil, errors.Wrapf(ruleerrors.ErrPruningProofHeaderWithNoKnownParents, "the proof header "+
+					"%s is missing known parents", blockHash)
+			}
+			parents = append(parents, model.VirtualGenesisBlockHash)
+		}
+
+		err := dagTopologyManager.SetParents(stagingArea, blockHash, parents)
+		if err != nil {
+			return nil, err
+		}
+
+		blockHashes[i] = blockHash
+		inLevel[*blockHash] = struct{}{}
+	}
+
+	ghostdagBatchManager := ghostdagmanager.NewBatch(ppm.databaseContext, dagTopologyManager, reachabilityManager,
+		ghostdagDataStore, blockHeaderStore, ppm.k, ppm.genesisHash, 0)
+	err := ghostdagBatchManager.GHOSTDAGBatch(stagingArea, blockHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	var selectedTip *externalapi.DomainHash
+	for _, blockHash := range blockHashes {
+		if selectedTip == nil {
+			selectedTip = blockHash
+			continue
+		}
+		selectedTip, err = ghostdagManager.ChooseSelectedParent(stagingArea, selectedTip, blockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The batch adds the blocks to the reachability tree without moving its reindex root,
+	// so it's moved to the selected tip of the level once all of them are added
+	if selectedTip != nil {
+		err = reachabilityManager.UpdateReindexRoot(stagingArea, selectedTip)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return selectedTip, nil
+}
+
 func (ppm *pruningProofManager) dagStores(maxLevel int) (model.BlockHeaderStore, []model.BlockRelationStore, []model.ReachabilityDataStore, []model.GHOSTDAGDataStore, error) {
 	blockRelationStores := make([]model.BlockRelationStore, maxLevel+1)
 	reachabilityDataStores := make([]model.ReachabilityDataStore, maxLevel+1)
 	ghostdagDataStores := make([]model.GHOSTDAGDataStore, maxLevel+1)
 
 	prefix := consensusDB.MakeBucket([]byte("pruningProofManager"))
-	blockHeaderStore, err := blockheaderstore.New(ppm.databaseContext, prefix, 0, false)
+	blockHeaderStore, err := blockheaderstore.New(ppm.databaseContext, prefix, 0, false, model.StoreEncodingProtobuf)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 
 	for i := 0; i <= maxLevel; i++ {
 		blockRelationStores[i] = blockrelationstore.New(prefix, 0, false)
-		reachabilityDataStores[i] = reachabilitydatastore.New(prefix, 0, false)
-		ghostdagDataStores[i] = ghostdagdatastore.New(prefix, 0, false)
+		reachabilityDataStores[i] = reachabilitydatastore.New(prefix, 0, false, model.StoreEncodingProtobuf)
+		ghostdagDataStores[i] = ghostdagdatastore.New(prefix, 0, false, model.StoreEncodingProtobuf)
 	}
 
 	return blockHeaderStore, blockRelationStores, reachabilityDataStores, ghostdagDataStores, nil
@@ -699,7 +735,7 @@ func (ppm *pruningProofManager) populateProofReachabilityAndHeaders(pruningPoint
 	tmpStagingArea := model.NewStagingArea()
 
 	bucket := consensusDB.MakeBucket([]byte("TMP"))
-	ghostdagDataStoreForTargetReachabilityManager := ghostdagdatastore.New(bucket, 0, false)
+	ghostdagDataStoreForTargetReachabilityManager := ghostdagdatastore.New(bucket, 0, false, model.StoreEncodingProtobuf)
 	ghostdagDataStoreForTargetReachabilityManager.Stage(stagingArea, model.VirtualGenesisBlockHash, externalapi.NewBlockGHOSTDAGData(
 		0,
 		big.NewInt(0),
@@ -718,7 +754,7 @@ func (ppm *pruningProofManager) populateProofReachabilityAndHeaders(pruningPoint
 	}
 
 	dagTopologyManager := dagtopologymanager.New(ppm.databaseContext, targetReachabilityManager, nil, nil)
-	ghostdagDataStore := ghostdagdatastore.New(bucket, 0, false)
+	ghostdagDataStore := ghostdagdatastore.New(bucket, 0, false, model.StoreEncodingProtobuf)
 	tmpGHOSTDAGManager := ghostdagmanager.New(ppm.databaseContext, nil, ghostdagDataStore, nil, 0, nil)
 	dagTraversalManager := dagtraversalmanager.New(ppm.databaseContext, nil, ghostdagDataStore, nil, tmpGHOSTDAGManager, nil, nil, nil, 0)
 	allProofBlocksUpHeap := dagTraversalManager.NewUpHeap(tmpStagingArea)
@@ -0,0 +1,152 @@
+package reachabilitymanager
+
+import (
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/reachabilitydata"
+)
+
+// CompactIntervals rebuilds the intervals of the reachability subtree of root from scratch,
+// allocating the interval of root to its subtree according to the current subtree sizes.
+// Over time reindexes leave most of the interval space with the blocks around the reindex
+// root, and pruning leaves holes where the removed subtrees used to be, so this is meant
+// to be run offline on a pruned DAG.
+//
+// References to blocks that have no reachability data are removed from the tree children and
+// future covering sets along the way. Since intervals are always allocated in the order of the
+// tree children, the future covering sets remain ordered.
+//
+// If the reindex root is in the subtree of root, the interval space is concentrated towards it
+// the same way it is when the reindex root moves, so that the chain blocks below it get their
+// reindex slack back. If the reindex root has no reachability data, root becomes the new reindex
+// root, and it is advisable to update the reindex root towards the selected tip afterwards.
+func (rt *reachabilityManager) CompactIntervals(stagingArea *model.StagingArea, root *externalapi.DomainHash) (
+	*externalapi.ReachabilityCompactionResult, error) {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "CompactIntervals")
+	defer onEnd()
+
+	reindexRoot, err := rt.reindexRoot(stagingArea)
+	if err != nil {
+		return nil, err
+	}
+	hasReindexRoot, err := rt.reachabilityDataStore.HasReachabilityData(rt.databaseContext, stagingArea, reindexRoot)
+	if err != nil {
+		return nil, err
+	}
+	isReindexRootInSubtree := false
+	if hasReindexRoot {
+		isReindexRootInSubtree, err = rt.IsReachabilityTreeAncestorOf(stagingArea, root, reindexRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &externalapi.ReachabilityCompactionResult{}
+	queue := []*externalapi.DomainHash{root}
+	for len(queue) > 0 {
+		var current *externalapi.DomainHash
+		current, queue = queue[0], queue[1:]
+
+		children, removedChildren, err := rt.existingBlocks(stagingArea, current, rt.children)
+		if err != nil {
+			return nil, err
+		}
+		futureCoveringSet, removedFutureCoveringSetEntries, err := rt.existingBlocks(stagingArea, current,
+			func(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) ([]*externalapi.DomainHash, error) {
+				return rt.futureCoveringSet(stagingArea, blockHash)
+			})
+		if err != nil {
+			return nil, err
+		}
+
+		if removedChildren > 0 || removedFutureCoveringSetEntries > 0 {
+			data, err := rt.reachabilityDataStore.ReachabilityData(rt.databaseContext, stagingArea, current)
+			if err != nil {
+				return nil, err
+			}
+			rt.stageData(stagingArea, current,
+				reachabilitydata.New(children, data.Parent(), data.Interval(), futureCoveringSet))
+
+			result.RemovedTreeChildren += removedChildren
+			result.RemovedFutureCoveringSetEntries += removedFutureCoveringSetEntries
+		}
+
+		queue = append(queue, children...)
+	}
+
+	rc := newReindexContext(rt)
+	if isReindexRootInSubtree && !root.Equal(reindexRoot) {
+		err = rc.concentrateIntervalsTowards(stagingArea, root, reindexRoot)
+	} else {
+		err = rc.propagateInterval(stagingArea, root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Blocks = rc.subTreeSizesCache[*root]
+
+	if !hasReindexRoot {
+		rt.stageReindexRoot(stagingArea, root)
+	}
+
+	log.Debugf("Compacted the intervals of %d blocks, removing %d tree children and %d future "+
+		"covering set entries without reachability data", result.Blocks, result.RemovedTreeChildren,
+		result.RemovedFutureCoveringSetEntries)
+	return result, nil
+}
+
+// concentrateIntervalsTowards rebuilds the intervals of the subtree of root, concentrating
+// them along the reachability tree chain from root to reindexRoot. The intervals of the
+// siblings of the chain are tightened and every chain block is propagated as if it were the
+// final reindex root, since its previous interval can't be relied on to contain the intervals
+// of its subtree.
+func (rc *reindexContext) concentrateIntervalsTowards(stagingArea *model.StagingArea,
+	root, reindexRoot *externalapi.DomainHash) error {
+
+	err := rc.countSubtrees(stagingArea, root)
+	if err != nil {
+		return err
+	}
+
+	current := root
+	for !current.Equal(reindexRoot) {
+		chosenChild, err := rc.manager.FindNextAncestor(stagingArea, reindexRoot, current)
+		if err != nil {
+			return err
+		}
+
+		err = rc.concentrateInterval(stagingArea, current, chosenChild, true)
+		if err != nil {
+			return err
+		}
+		current = chosenChild
+	}
+
+	return rc.propagateInterval(stagingArea, reindexRoot)
+}
+
+// existingBlocks returns the blocks listed by the given getter for blockHash that have
+// reachability data, along with the number of blocks that were left out
+func (rt *reachabilityManager) existingBlocks(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash,
+	getter func(*model.StagingArea, *externalapi.DomainHash) ([]*externalapi.DomainHash, error)) (
+	[]*externalapi.DomainHash, uint64, error) {
+
+	blocks, err := getter(stagingArea, blockHash)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	existing := make([]*externalapi.DomainHash, 0, len(blocks))
+	for _, block := range blocks {
+		hasData, err := rt.reachabilityDataStore.HasReachabilityData(rt.databaseContext, stagingArea, block)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hasData {
+			existing = append(existing, block)
+		}
+	}
+	return existing, uint64(len(blocks) - len(existing)), nil
+}
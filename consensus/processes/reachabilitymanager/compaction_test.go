@@ -0,0 +1,143 @@
+package reachabilitymanager
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+func TestReindexStatistics(t *testing.T) {
+	reachabilityDataStore := newReachabilityDataStoreMock()
+	manager := NewWithReindexParameters(nil, nil, reachabilityDataStore, 10, 20).(*reachabilityManager)
+	helper := newTestHelper(manager, t, reachabilityDataStore)
+
+	stagingArea := model.NewStagingArea()
+
+	root := helper.newNodeWithInterval(stagingArea, newReachabilityInterval(1, 100))
+
+	// Add a chain of child nodes just before a reindex occurs (2^6=64 < 100)
+	currentTip := root
+	for i := 0; i < 6; i++ {
+		node := helper.newNode(stagingArea)
+		helper.addChild(stagingArea, currentTip, node, root)
+		currentTip = node
+	}
+
+	statistics := manager.ReindexStatistics()
+	if statistics.Reindexes != 0 {
+		t.Fatalf("TestReindexStatistics: unexpected reindexes: want: 0, got: %d", statistics.Reindexes)
+	}
+
+	// Add another node to the tip of the chain to trigger a reindex (100 < 2^7=128)
+	helper.addChild(stagingArea, currentTip, helper.newNode(stagingArea), root)
+
+	statistics = manager.ReindexStatistics()
+	if statistics.ReindexSlack != 10 || statistics.ReindexWindow != 20 {
+		t.Fatalf("TestReindexStatistics: unexpected reindex parameters: want: 10 and 20, got: %d and %d",
+			statistics.ReindexSlack, statistics.ReindexWindow)
+	}
+	if statistics.Reindexes != 1 {
+		t.Fatalf("TestReindexStatistics: unexpected reindexes: want: 1, got: %d", statistics.Reindexes)
+	}
+	if statistics.SlackReclaims != 0 {
+		t.Fatalf("TestReindexStatistics: unexpected slack reclaims: want: 0, got: %d", statistics.SlackReclaims)
+	}
+	if statistics.MaxReindexDepth == 0 || statistics.TotalReindexDepth != statistics.MaxReindexDepth {
+		t.Fatalf("TestReindexStatistics: unexpected reindex depths: total: %d, max: %d",
+			statistics.TotalReindexDepth, statistics.MaxReindexDepth)
+	}
+	if statistics.TotalReindexedBlocks == 0 || statistics.TotalReindexedBlocks > 7 {
+		t.Fatalf("TestReindexStatistics: unexpected reindexed blocks: %d", statistics.TotalReindexedBlocks)
+	}
+	if statistics.AverageReindexDepth() != float64(statistics.MaxReindexDepth) {
+		t.Fatalf("TestReindexStatistics: unexpected average reindex depth: %f", statistics.AverageReindexDepth())
+	}
+}
+
+func TestCompactIntervals(t *testing.T) {
+	reachabilityDataStore := newReachabilityDataStoreMock()
+	manager := New(nil, nil, reachabilityDataStore).(*reachabilityManager)
+	helper := newTestHelper(manager, t, reachabilityDataStore)
+
+	stagingArea := model.NewStagingArea()
+
+	// Build the following tree, where the interval space of root is mostly left unused:
+	//   root -> a -> b -> c
+	//        -> d -> e
+	//        -> f
+	root := helper.newNodeWithInterval(stagingArea, newReachabilityInterval(1, 1_000_000))
+	helper.dataStore.reachabilityReindexRootStaging = root
+	nodes := make(map[string]*externalapi.DomainHash)
+	for _, edge := range []struct{ parent, child string }{
+		{"root", "a"}, {"a", "b"}, {"b", "c"}, {"root", "d"}, {"d", "e"}, {"root", "f"},
+	} {
+		parent := root
+		if edge.parent != "root" {
+			parent = nodes[edge.parent]
+		}
+		nodes[edge.child] = helper.newNode(stagingArea)
+		helper.addChild(stagingArea, parent, nodes[edge.child], root)
+	}
+
+	// c is in the future of d, and so is f, which is pruned along with its subtree
+	err := manager.stageFutureCoveringSet(stagingArea, nodes["d"], model.FutureCoveringTreeNodeSet{nodes["c"], nodes["f"]})
+	if err != nil {
+		t.Fatalf("stageFutureCoveringSet: %s", err)
+	}
+	delete(helper.dataStore.reachabilityDataStaging, *nodes["f"])
+
+	result, err := manager.CompactIntervals(stagingArea, root)
+	if err != nil {
+		t.Fatalf("CompactIntervals: %s", err)
+	}
+	expectedResult := externalapi.ReachabilityCompactionResult{
+		Blocks:                          6,
+		RemovedTreeChildren:             1,
+		RemovedFutureCoveringSetEntries: 1,
+	}
+	if *result != expectedResult {
+		t.Fatalf("TestCompactIntervals: unexpected result: want: %+v, got: %+v", expectedResult, *result)
+	}
+
+	err = manager.validateIntervals(stagingArea, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The whole interval of root is allocated to its subtree, according to the subtree sizes
+	allocatedSize := uint64(0)
+	children, err := manager.children(stagingArea, root)
+	if err != nil {
+		t.Fatalf("children: %s", err)
+	}
+	for _, child := range children {
+		allocatedSize += helper.getIntervalSize(stagingArea, child)
+	}
+	allocationInterval, err := manager.intervalRangeForChildAllocation(stagingArea, root)
+	if err != nil {
+		t.Fatalf("intervalRangeForChildAllocation: %s", err)
+	}
+	if allocatedSize != intervalSize(allocationInterval) {
+		t.Fatalf("TestCompactIntervals: unexpected allocated size: want: %d, got: %d",
+			intervalSize(allocationInterval), allocatedSize)
+	}
+	if helper.getIntervalSize(stagingArea, nodes["a"]) <= helper.getIntervalSize(stagingArea, nodes["d"]) {
+		t.Fatalf("TestCompactIntervals: expected the larger subtree of a to get a larger interval than d")
+	}
+
+	futureCoveringSet, err := manager.futureCoveringSet(stagingArea, nodes["d"])
+	if err != nil {
+		t.Fatalf("futureCoveringSet: %s", err)
+	}
+	if len(futureCoveringSet) != 1 || !futureCoveringSet[0].Equal(nodes["c"]) {
+		t.Fatalf("TestCompactIntervals: unexpected future covering set: %v", futureCoveringSet)
+	}
+	if !helper.isReachabilityTreeAncestorOf(stagingArea, nodes["a"], nodes["c"]) ||
+		helper.isReachabilityTreeAncestorOf(stagingArea, nodes["d"], nodes["c"]) {
+		t.Fatalf("TestCompactIntervals: unexpected reachability tree ancestry after compaction")
+	}
+	if !helper.dataStore.reachabilityReindexRootStaging.Equal(root) {
+		t.Fatalf("TestCompactIntervals: expected the reindex root to remain root")
+	}
+}
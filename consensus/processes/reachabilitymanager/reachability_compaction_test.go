@@ -0,0 +1,149 @@
+package reachabilitymanager_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+type ancestryQuery struct {
+	blockA, blockB *externalapi.DomainHash
+	isAncestorOf   bool
+}
+
+func randomAncestryQueries(t *testing.T, tc testapi.TestConsensus, blocks []*externalapi.DomainHash,
+	randSource *rand.Rand, count int) []ancestryQuery {
+
+	stagingArea := model.NewStagingArea()
+	queries := make([]ancestryQuery, count)
+	for i := range queries {
+		blockA := blocks[randSource.Intn(len(blocks))]
+		blockB := blocks[randSource.Intn(len(blocks))]
+		isAncestorOf, err := tc.ReachabilityManager().IsDAGAncestorOf(stagingArea, blockA, blockB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queries[i] = ancestryQuery{blockA: blockA, blockB: blockB, isAncestorOf: isAncestorOf}
+	}
+	return queries
+}
+
+func compactJsonDAG(t *testing.T, tc testapi.TestConsensus, attackJson bool) {
+	buildJsonDAG(t, tc, attackJson)
+
+	statistics, err := tc.GetReachabilityReindexStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statistics.Reindexes == 0 || statistics.TotalReindexedBlocks == 0 || statistics.ReindexRootUpdates == 0 {
+		t.Fatalf("Expected the DAG to trigger reindexes, got statistics %+v", statistics)
+	}
+
+	blocks, err := tc.ReachabilityManager().GetAllNodes(tc.DAGParams().GenesisHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	randSource := rand.New(rand.NewSource(33233))
+	queries := randomAncestryQueries(t, tc, blocks, randSource, 10_000)
+	// Queries between random blocks are mostly about blocks in anticone, so check every
+	// block against the genesis and its tree parent as well
+	stagingArea := model.NewStagingArea()
+	for _, block := range blocks {
+		queries = append(queries, ancestryQuery{blockA: tc.DAGParams().GenesisHash, blockB: block, isAncestorOf: true})
+		parents, err := tc.DAGTopologyManager().Parents(stagingArea, block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, parent := range parents {
+			queries = append(queries, ancestryQuery{blockA: block, blockB: parent, isAncestorOf: false})
+		}
+	}
+
+	result, err := tc.CompactReachabilityIntervals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tree also holds the virtual genesis
+	if result.Blocks != uint64(len(blocks))+1 {
+		t.Fatalf("Unexpected compacted blocks: want: %d, got: %d", len(blocks)+1, result.Blocks)
+	}
+	if result.RemovedTreeChildren != 0 || result.RemovedFutureCoveringSetEntries != 0 {
+		t.Fatalf("Unexpected removed references in an unpruned DAG: %+v", result)
+	}
+
+	err = tc.ReachabilityManager().ValidateIntervals(tc.DAGParams().GenesisHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stagingArea = model.NewStagingArea()
+	for _, query := range queries {
+		isAncestorOf, err := tc.ReachabilityManager().IsDAGAncestorOf(stagingArea, query.blockA, query.blockB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isAncestorOf != query.isAncestorOf {
+			t.Fatalf("IsDAGAncestorOf(%s, %s) changed by the compaction: was %t, now %t",
+				query.blockA, query.blockB, query.isAncestorOf, isAncestorOf)
+		}
+	}
+
+	// Make sure the compacted tree keeps growing correctly
+	for i := 0; i < 200; i++ {
+		parent := blocks[randSource.Intn(len(blocks))]
+		newBlock, _, err := tc.AddUTXOInvalidHeader([]*externalapi.DomainHash{parent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, newBlock)
+	}
+
+	err = tc.ReachabilityManager().ValidateIntervals(tc.DAGParams().GenesisHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoAttackCompactIntervals(t *testing.T) {
+	tc, teardown := initializeTest(t, "TestNoAttackCompactIntervals")
+	defer teardown(false)
+	compactJsonDAG(t, tc, false)
+}
+
+func TestAttackCompactIntervals(t *testing.T) {
+	tc, teardown := initializeTest(t, "TestAttackCompactIntervals")
+	defer teardown(false)
+	compactJsonDAG(t, tc, true)
+}
+
+func TestReachabilityReindexParametersConfig(t *testing.T) {
+	t.Parallel()
+	consensusConfig := consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+	consensusConfig.ReachabilityReindexSlack = 10
+	consensusConfig.ReachabilityReindexWindow = 30
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(&consensusConfig, "TestReachabilityReindexParametersConfig")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	if tc.ReachabilityManager().ReachabilityReindexSlack() != 10 {
+		t.Fatalf("Unexpected reindex slack: want: 10, got: %d", tc.ReachabilityManager().ReachabilityReindexSlack())
+	}
+
+	statistics, err := tc.GetReachabilityReindexStatistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statistics.ReindexSlack != 10 || statistics.ReindexWindow != 30 {
+		t.Fatalf("Unexpected reindex parameters: want: 10 and 30, got: %d and %d",
+			statistics.ReindexSlack, statistics.ReindexWindow)
+	}
+}
@@ -1,6 +1,8 @@
 package reachabilitymanager
 
 import (
+	"sync"
+
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 )
@@ -13,20 +15,42 @@ type reachabilityManager struct {
 	ghostdagDataStore     model.GHOSTDAGDataStore
 	reindexSlack          uint64
 	reindexWindow         uint64
+
+	statisticsLock sync.Mutex
+	statistics     externalapi.ReachabilityReindexStatistics
 }
 
-// New instantiates a new reachabilityManager
+// New instantiates a new reachabilityManager with the default reindex parameters
 func New(
 	databaseContext model.DBReader,
 	ghostdagDataStore model.GHOSTDAGDataStore,
 	reachabilityDataStore model.ReachabilityDataStore,
 ) model.ReachabilityManager {
+	return NewWithReindexParameters(databaseContext, ghostdagDataStore, reachabilityDataStore, 0, 0)
+}
+
+// NewWithReindexParameters instantiates a new reachabilityManager with the given reindex slack
+// and reindex window. A zero reindexSlack or reindexWindow selects the default one.
+func NewWithReindexParameters(
+	databaseContext model.DBReader,
+	ghostdagDataStore model.GHOSTDAGDataStore,
+	reachabilityDataStore model.ReachabilityDataStore,
+	reindexSlack uint64,
+	reindexWindow uint64,
+) model.ReachabilityManager {
+	if reindexSlack == 0 {
+		reindexSlack = defaultReindexSlack
+	}
+	if reindexWindow == 0 {
+		reindexWindow = defaultReindexWindow
+	}
+
 	return &reachabilityManager{
 		databaseContext:       databaseContext,
 		ghostdagDataStore:     ghostdagDataStore,
 		reachabilityDataStore: reachabilityDataStore,
-		reindexSlack:          defaultReindexSlack,
-		reindexWindow:         defaultReindexWindow,
+		reindexSlack:          reindexSlack,
+		reindexWindow:         reindexWindow,
 	}
 }
 
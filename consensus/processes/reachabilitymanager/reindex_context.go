@@ -22,6 +22,11 @@ var (
 type reindexContext struct {
 	manager           *reachabilityManager
 	subTreeSizesCache map[externalapi.DomainHash]uint64
+
+	// The following are collected for the reindex statistics
+	depth                uint64
+	reclaimedSlack       bool
+	stagedIntervalsCount uint64
 }
 
 // newReindexContext creates a new empty reindex context
@@ -32,6 +37,14 @@ func newReindexContext(rt *reachabilityManager) reindexContext {
 	}
 }
 
+// stageInterval stages the new interval of the given node, and counts it for the reindex statistics
+func (rc *reindexContext) stageInterval(stagingArea *model.StagingArea, node *externalapi.DomainHash,
+	interval *model.ReachabilityInterval) error {
+
+	rc.stagedIntervalsCount++
+	return rc.manager.stageInterval(stagingArea, node, interval)
+}
+
 /*
 
 Core (BFS) algorithms used during reindexing
@@ -164,7 +177,7 @@ func (rc *reindexContext) propagateInterval(stagingArea *model.StagingArea, node
 
 			for i, child := range children {
 				childInterval := intervals[i]
-				err = rc.manager.stageInterval(stagingArea, child, childInterval)
+				err = rc.stageInterval(stagingArea, child, childInterval)
 				if err != nil {
 					return err
 				}
@@ -249,10 +262,12 @@ func (rc *reindexContext) reindexIntervals(stagingArea *model.StagingArea, newCh
 			// 1. we set requiredAllocation=currentSubtreeSize in order to double the
 			// current interval capacity
 			// 2. it might be the case that current is the `newChild` itself
+			rc.reclaimedSlack = true
 			return rc.reindexIntervalsEarlierThanRoot(stagingArea, current, reindexRoot, parent, currentSubtreeSize)
 		}
 
 		current = parent
+		rc.depth++
 	}
 
 	// Propagate the interval to the subtree
@@ -315,7 +330,7 @@ func (rc *reindexContext) reclaimIntervalBefore(stagingArea *model.StagingArea,
 			}
 
 			offset := requiredAllocation + rc.manager.reindexSlack*pathLen - slackSum
-			err = rc.manager.stageInterval(stagingArea, current, intervalIncreaseStart(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, current, intervalIncreaseStart(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -350,7 +365,7 @@ func (rc *reindexContext) reclaimIntervalBefore(stagingArea *model.StagingArea,
 			// Set offset to be just enough to satisfy required allocation
 			offset := slackBeforeCurrent - (slackSum - requiredAllocation)
 
-			err = rc.manager.stageInterval(stagingArea, current, intervalIncreaseStart(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, current, intervalIncreaseStart(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -396,7 +411,7 @@ func (rc *reindexContext) reclaimIntervalBefore(stagingArea *model.StagingArea,
 		}
 
 		offset := slackBeforeCurrent - pathSlackAlloc
-		err = rc.manager.stageInterval(stagingArea, current, intervalIncreaseStart(originalInterval, offset))
+		err = rc.stageInterval(stagingArea, current, intervalIncreaseStart(originalInterval, offset))
 		if err != nil {
 			return err
 		}
@@ -433,7 +448,7 @@ func (rc *reindexContext) offsetSiblingsBefore(stagingArea *model.StagingArea,
 				return err
 			}
 
-			err = rc.manager.stageInterval(stagingArea, allocationNode, intervalIncreaseEnd(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, allocationNode, intervalIncreaseEnd(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -451,7 +466,7 @@ func (rc *reindexContext) offsetSiblingsBefore(stagingArea *model.StagingArea,
 			return err
 		}
 
-		err = rc.manager.stageInterval(stagingArea, sibling, intervalIncrease(previousInterval, offset))
+		err = rc.stageInterval(stagingArea, sibling, intervalIncrease(previousInterval, offset))
 		if err != nil {
 			return err
 		}
@@ -487,7 +502,7 @@ func (rc *reindexContext) reclaimIntervalAfter(stagingArea *model.StagingArea,
 			}
 
 			offset := requiredAllocation + rc.manager.reindexSlack*pathLen - slackSum
-			err = rc.manager.stageInterval(stagingArea, current, intervalDecreaseEnd(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, current, intervalDecreaseEnd(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -522,7 +537,7 @@ func (rc *reindexContext) reclaimIntervalAfter(stagingArea *model.StagingArea,
 			// Set offset to be just enough to satisfy required allocation
 			offset := slackAfterCurrent - (slackSum - requiredAllocation)
 
-			err = rc.manager.stageInterval(stagingArea, current, intervalDecreaseEnd(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, current, intervalDecreaseEnd(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -568,7 +583,7 @@ func (rc *reindexContext) reclaimIntervalAfter(stagingArea *model.StagingArea,
 		}
 
 		offset := slackAfterCurrent - pathSlackAlloc
-		err = rc.manager.stageInterval(stagingArea, current, intervalDecreaseEnd(originalInterval, offset))
+		err = rc.stageInterval(stagingArea, current, intervalDecreaseEnd(originalInterval, offset))
 		if err != nil {
 			return err
 		}
@@ -603,7 +618,7 @@ func (rc *reindexContext) offsetSiblingsAfter(stagingArea *model.StagingArea,
 				return err
 			}
 
-			err = rc.manager.stageInterval(stagingArea, allocationNode, intervalDecreaseStart(previousInterval, offset))
+			err = rc.stageInterval(stagingArea, allocationNode, intervalDecreaseStart(previousInterval, offset))
 			if err != nil {
 				return err
 			}
@@ -621,7 +636,7 @@ func (rc *reindexContext) offsetSiblingsAfter(stagingArea *model.StagingArea,
 			return err
 		}
 
-		err = rc.manager.stageInterval(stagingArea, sibling, intervalDecrease(previousInterval, offset))
+		err = rc.stageInterval(stagingArea, sibling, intervalDecrease(previousInterval, offset))
 		if err != nil {
 			return err
 		}
@@ -743,7 +758,7 @@ func (rc *reindexContext) expandIntervalToChosen(stagingArea *model.StagingArea,
 		// expandIntervalToChosen is called (next time the
 		// reindex root moves), newChosenInterval is likely to
 		// contain currentChosenInterval.
-		err := rc.manager.stageInterval(stagingArea, chosenChild, newReachabilityInterval(
+		err := rc.stageInterval(stagingArea, chosenChild, newReachabilityInterval(
 			newChosenInterval.Start+rc.manager.reindexSlack,
 			newChosenInterval.End-rc.manager.reindexSlack,
 		))
@@ -757,7 +772,7 @@ func (rc *reindexContext) expandIntervalToChosen(stagingArea *model.StagingArea,
 		}
 	}
 
-	err = rc.manager.stageInterval(stagingArea, chosenChild, newChosenInterval)
+	err = rc.stageInterval(stagingArea, chosenChild, newChosenInterval)
 	if err != nil {
 		return err
 	}
@@ -793,7 +808,7 @@ func (rc *reindexContext) propagateChildrenIntervals(stagingArea *model.StagingA
 
 	for i, child := range children {
 		childInterval := childIntervals[i]
-		err := rc.manager.stageInterval(stagingArea, child, childInterval)
+		err := rc.stageInterval(stagingArea, child, childInterval)
 		if err != nil {
 			return err
 		}
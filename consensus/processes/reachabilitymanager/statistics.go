@@ -0,0 +1,51 @@
+package reachabilitymanager
+
+import (
+	"time"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// ReindexStatistics returns the reindex statistics collected since this reachabilityManager
+// was instantiated
+func (rt *reachabilityManager) ReindexStatistics() *externalapi.ReachabilityReindexStatistics {
+	rt.statisticsLock.Lock()
+	defer rt.statisticsLock.Unlock()
+
+	statistics := rt.statistics
+	statistics.ReindexSlack = rt.reindexSlack
+	statistics.ReindexWindow = rt.reindexWindow
+	return &statistics
+}
+
+func (rt *reachabilityManager) recordReindex(rc *reindexContext, duration time.Duration) {
+	rt.statisticsLock.Lock()
+	defer rt.statisticsLock.Unlock()
+
+	statistics := &rt.statistics
+	statistics.Reindexes++
+	if rc.reclaimedSlack {
+		statistics.SlackReclaims++
+	}
+
+	statistics.TotalReindexDepth += rc.depth
+	if rc.depth > statistics.MaxReindexDepth {
+		statistics.MaxReindexDepth = rc.depth
+	}
+	statistics.TotalReindexedBlocks += rc.stagedIntervalsCount
+	if rc.stagedIntervalsCount > statistics.MaxReindexedBlocks {
+		statistics.MaxReindexedBlocks = rc.stagedIntervalsCount
+	}
+	statistics.TotalReindexDuration += duration
+	if duration > statistics.MaxReindexDuration {
+		statistics.MaxReindexDuration = duration
+	}
+}
+
+func (rt *reachabilityManager) recordReindexRootUpdate(rc *reindexContext) {
+	rt.statisticsLock.Lock()
+	defer rt.statisticsLock.Unlock()
+
+	rt.statistics.ReindexRootUpdates++
+	rt.statistics.ConcentratedBlocks += rc.stagedIntervalsCount
+}
@@ -318,6 +318,8 @@ func (rt *reachabilityManager) addChild(stagingArea *model.StagingArea, node, ch
 			"block %s. Took %dms.",
 			node, reindexTimeElapsed.Milliseconds())
 
+		rt.recordReindex(&rc, reindexTimeElapsed)
+
 		return nil
 	}
 
@@ -381,6 +383,8 @@ func (rt *reachabilityManager) updateReindexRoot(stagingArea *model.StagingArea,
 	// Update reindex root data store
 	rt.stageReindexRoot(stagingArea, newReindexRoot)
 	log.Tracef("Updated the reindex root to %s", newReindexRoot)
+
+	rt.recordReindexRootUpdate(&rc)
 	return nil
 }
 
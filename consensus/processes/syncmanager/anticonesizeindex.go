@@ -0,0 +1,255 @@
+package syncmanager
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// anticoneSizeIndexDepth is the blue score distance below the context block within which the merged blocks
+// are indexed by the anticoneSizeIndex
+const anticoneSizeIndexDepth = 100
+
+// anticoneSizeIndexMaxTraversal is the maximum traversal allowed when collecting the anticone of a newly
+// indexed block. Blocks that pass it are left out of the index.
+const anticoneSizeIndexMaxTraversal = 10000
+
+// anticoneSizeIndex keeps the anticone sizes of the blocks near the tips, from the point of view of the highest
+// context block GetAnticone was called with. The anticone of a block never shrinks as its context moves up the
+// selected chain, so when the context moves the sizes are only increased by the blocks the new context adds to
+// the past, and the blocks it merges are indexed from scratch.
+//
+// The traversal of an anticone passes at least as many blocks as the anticone has, so requests for anticones
+// that are indexed as larger than the traversal limit fail without traversing the DAG.
+type anticoneSizeIndex struct {
+	lock sync.Mutex
+
+	contextHash      *externalapi.DomainHash
+	contextBlueScore uint64
+
+	// entries maps every indexed block to its anticone size, and to the blue score of the chain block that merged it
+	entries map[externalapi.DomainHash]*anticoneSizeIndexEntry
+}
+
+type anticoneSizeIndexEntry struct {
+	anticoneSize     uint64
+	mergingBlueScore uint64
+}
+
+// mergedBlock is a block that entered the past of the context, along with the blue score of the chain block
+// that merged it
+type mergedBlock struct {
+	blockHash        *externalapi.DomainHash
+	mergingBlueScore uint64
+}
+
+func newAnticoneSizeIndex() *anticoneSizeIndex {
+	return &anticoneSizeIndex{
+		entries: make(map[externalapi.DomainHash]*anticoneSizeIndexEntry),
+	}
+}
+
+// indexedAnticoneSize returns the anticone size of blockHash from the point of view of contextHash. If contextHash
+// is higher than the context of the index, the index is moved up to it first. ok is false if blockHash isn't
+// indexed, or if contextHash is not the context of the index.
+func (sm *syncManager) indexedAnticoneSize(stagingArea *model.StagingArea, blockHash, contextHash *externalapi.DomainHash) (
+	anticoneSize uint64, ok bool, err error) {
+
+	index := sm.anticoneSizeIndex
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	if index.contextHash == nil || !index.contextHash.Equal(contextHash) {
+		contextGHOSTDAGData, err := sm.ghostdagDataStore.Get(sm.databaseContext, stagingArea, contextHash, false)
+		if err != nil {
+			return 0, false, err
+		}
+		// Only moving up keeps the index near the tips. Lower contexts are served by traversing the DAG.
+		if index.contextHash != nil && contextGHOSTDAGData.BlueScore() <= index.contextBlueScore {
+			return 0, false, nil
+		}
+		err = sm.moveAnticoneSizeIndex(stagingArea, contextHash, contextGHOSTDAGData.BlueScore())
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	entry, ok := index.entries[*blockHash]
+	if !ok {
+		return 0, false, nil
+	}
+	return entry.anticoneSize, true, nil
+}
+
+// moveAnticoneSizeIndex moves the index to contextHash. If the current context is in the selected chain of
+// contextHash and close enough to it, the index is updated with the blocks contextHash adds to the past.
+// Otherwise it's rebuilt. The caller is expected to hold the index lock.
+func (sm *syncManager) moveAnticoneSizeIndex(stagingArea *model.StagingArea, contextHash *externalapi.DomainHash,
+	contextBlueScore uint64) error {
+
+	index := sm.anticoneSizeIndex
+
+	canUpdate := false
+	if index.contextHash != nil && contextBlueScore-index.contextBlueScore <= anticoneSizeIndexDepth {
+		var err error
+		canUpdate, err = sm.dagTopologyManager.IsInSelectedParentChainOf(stagingArea, index.contextHash, contextHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	var mergedBlocks []*mergedBlock
+	var err error
+	if canUpdate {
+		mergedBlocks, err = sm.blocksMergedSince(stagingArea, index.contextHash, contextHash)
+	} else {
+		index.entries = make(map[externalapi.DomainHash]*anticoneSizeIndexEntry)
+		mergedBlocks, err = sm.blocksMergedWithinAnticoneSizeIndexDepth(stagingArea, contextHash, contextBlueScore)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The merged blocks can't be in the past of the indexed blocks, so they're in the anticone
+	// of every indexed block that isn't in their past
+	for indexedBlockHash, entry := range index.entries {
+		indexedBlockHash := indexedBlockHash
+		for _, merged := range mergedBlocks {
+			isIndexedBlockAncestorOfMergedBlock, err :=
+				sm.dagTopologyManager.IsAncestorOf(stagingArea, &indexedBlockHash, merged.blockHash)
+			if err != nil {
+				return err
+			}
+			if !isIndexedBlockAncestorOfMergedBlock {
+				entry.anticoneSize++
+			}
+		}
+	}
+
+	for _, merged := range mergedBlocks {
+		anticone, err := sm.dagTraversalManager.AnticoneFromBlocks(stagingArea,
+			[]*externalapi.DomainHash{contextHash}, merged.blockHash, anticoneSizeIndexMaxTraversal)
+		if errors.Is(err, model.ErrReachedMaxTraversalAllowed) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		index.entries[*merged.blockHash] = &anticoneSizeIndexEntry{
+			anticoneSize:     uint64(len(anticone)),
+			mergingBlueScore: merged.mergingBlueScore,
+		}
+	}
+
+	for indexedBlockHash, entry := range index.entries {
+		if entry.mergingBlueScore+anticoneSizeIndexDepth < contextBlueScore {
+			delete(index.entries, indexedBlockHash)
+		}
+	}
+
+	index.contextHash = contextHash
+	index.contextBlueScore = contextBlueScore
+	return nil
+}
+
+// blocksMergedSince returns the blocks in the past of highHash, including highHash, that aren't in the past
+// of lowHash or lowHash itself. lowHash is expected to be in the selected chain of highHash.
+func (sm *syncManager) blocksMergedSince(stagingArea *model.StagingArea, lowHash, highHash *externalapi.DomainHash) (
+	[]*mergedBlock, error) {
+
+	var mergedBlocks []*mergedBlock
+	iterator, err := sm.dagTraversalManager.SelectedChildIterator(stagingArea, highHash, lowHash, false)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		chainBlock, err := iterator.Get()
+		if err != nil {
+			return nil, err
+		}
+		chainBlockMergedBlocks, err := sm.blocksMergedByChainBlock(stagingArea, chainBlock)
+		if err != nil {
+			return nil, err
+		}
+		mergedBlocks = append(mergedBlocks, chainBlockMergedBlocks...)
+	}
+	return mergedBlocks, nil
+}
+
+// blocksMergedWithinAnticoneSizeIndexDepth returns the blocks merged by the selected chain of contextHash,
+// including contextHash, down to anticoneSizeIndexDepth below it or to the pruning point
+func (sm *syncManager) blocksMergedWithinAnticoneSizeIndexDepth(stagingArea *model.StagingArea,
+	contextHash *externalapi.DomainHash, contextBlueScore uint64) ([]*mergedBlock, error) {
+
+	pruningPoint, err := sm.pruningStore.PruningPoint(sm.databaseContext, stagingArea)
+	if err != nil {
+		return nil, err
+	}
+
+	var mergedBlocks []*mergedBlock
+	chainBlock := contextHash
+	for {
+		// The merge sets of the pruning point and of the blocks below it might refer to pruned blocks
+		isInPastOfPruningPoint, err := sm.dagTopologyManager.IsAncestorOf(stagingArea, chainBlock, pruningPoint)
+		if err != nil {
+			return nil, err
+		}
+		if isInPastOfPruningPoint && !chainBlock.Equal(pruningPoint) {
+			break
+		}
+		chainBlockGHOSTDAGData, err := sm.ghostdagDataStore.Get(sm.databaseContext, stagingArea, chainBlock, false)
+		if err != nil {
+			return nil, err
+		}
+		if chainBlockGHOSTDAGData.BlueScore()+anticoneSizeIndexDepth < contextBlueScore {
+			break
+		}
+		if isInPastOfPruningPoint || chainBlock.Equal(sm.genesisBlockHash) {
+			mergedBlocks = append(mergedBlocks, &mergedBlock{
+				blockHash:        chainBlock,
+				mergingBlueScore: chainBlockGHOSTDAGData.BlueScore(),
+			})
+			break
+		}
+		chainBlockMergedBlocks, err := sm.blocksMergedByChainBlock(stagingArea, chainBlock)
+		if err != nil {
+			return nil, err
+		}
+		mergedBlocks = append(mergedBlocks, chainBlockMergedBlocks...)
+		chainBlock = chainBlockGHOSTDAGData.SelectedParent()
+	}
+	return mergedBlocks, nil
+}
+
+// blocksMergedByChainBlock returns the given chain block and its merge set, except for its selected parent
+func (sm *syncManager) blocksMergedByChainBlock(stagingArea *model.StagingArea, chainBlock *externalapi.DomainHash) (
+	[]*mergedBlock, error) {
+
+	chainBlockGHOSTDAGData, err := sm.ghostdagDataStore.Get(sm.databaseContext, stagingArea, chainBlock, false)
+	if err != nil {
+		return nil, err
+	}
+	sortedMergeSet, err := sm.sortedMergeSet(stagingArea, chainBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedBlocks := make([]*mergedBlock, 0, len(sortedMergeSet))
+	mergedBlocks = append(mergedBlocks, &mergedBlock{
+		blockHash:        chainBlock,
+		mergingBlueScore: chainBlockGHOSTDAGData.BlueScore(),
+	})
+	for _, blockHash := range sortedMergeSet {
+		if blockHash.Equal(chainBlockGHOSTDAGData.SelectedParent()) {
+			continue
+		}
+		mergedBlocks = append(mergedBlocks, &mergedBlock{
+			blockHash:        blockHash,
+			mergingBlueScore: chainBlockGHOSTDAGData.BlueScore(),
+		})
+	}
+	return mergedBlocks, nil
+}
@@ -62,7 +62,7 @@ func (sm *syncManager) antiPastHashesBetween(stagingArea *model.StagingArea, low
 		// Since the rest of the merge set is in the anticone of selectedParent, it's position in the list does not
 		// matter, even though it's blue score is the highest, we can arbitrarily decide it comes first.
 		// Therefore we first append the selectedParent, then the rest of blocks in ghostdag order.
-		sortedMergeSet, err := sm.ghostdagManager.GetSortedMergeSet(stagingArea, current)
+		sortedMergeSet, err := sm.sortedMergeSet(stagingArea, current)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -94,6 +94,22 @@ func (sm *syncManager) antiPastHashesBetween(stagingArea *model.StagingArea, low
 	return blockHashes, highHash, nil
 }
 
+// sortedMergeSet returns the sorted merge set of the given block. Merge sets never change, so they're
+// cached for the requests of other syncing peers. The caller may modify the returned slice.
+func (sm *syncManager) sortedMergeSet(stagingArea *model.StagingArea, blockHash *externalapi.DomainHash) (
+	[]*externalapi.DomainHash, error) {
+
+	if sortedMergeSet, ok := sm.sortedMergeSetCache.Get(blockHash); ok {
+		return externalapi.CloneHashes(sortedMergeSet.([]*externalapi.DomainHash)), nil
+	}
+	sortedMergeSet, err := sm.ghostdagManager.GetSortedMergeSet(stagingArea, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	sm.sortedMergeSetCache.Add(blockHash, externalapi.CloneHashes(sortedMergeSet))
+	return sortedMergeSet, nil
+}
+
 func (sm *syncManager) findLowHashInHighHashSelectedParentChain(stagingArea *model.StagingArea,
 	lowHash *externalapi.DomainHash, highHash *externalapi.DomainHash) (*externalapi.DomainHash, error) {
 	for {
@@ -145,16 +161,12 @@ func (sm *syncManager) missingBlockBodyHashes(stagingArea *model.StagingArea, hi
 		lowHash = selectedChild
 	}
 	if !foundHeaderOnlyBlock {
-		if lowHash.Equal(highHash) {
-			// Blocks can be inserted inside the DAG during IBD if those were requested before IBD started.
-			// In rare cases, all the IBD blocks might be already inserted by the time we reach this point.
-			// In these cases - return an empty list of blocks to sync
-			return []*externalapi.DomainHash{}, nil
-		}
-		// TODO: Once block children are fixed (https://github.com/karlsen-network/karlsend/issues/1499),
-		// this error should be returned rather the logged
-		log.Errorf("No header-only blocks between %s and %s",
-			lowHash, highHash)
+		// Blocks can be inserted inside the DAG during IBD if those were requested before IBD started.
+		// In rare cases, all the IBD blocks might be already inserted by the time we reach this point.
+		// Every block in the selected chain of highHash then has a body, and since a block body is only
+		// accepted once the bodies of its parents above the pruning point exist, no block between the
+		// pruning point and highHash misses a body either. In these cases - return an empty list of blocks to sync
+		return []*externalapi.DomainHash{}, nil
 	}
 
 	hashesBetween, _, err := sm.antiPastHashesBetween(stagingArea, lowHash, highHash, 0)
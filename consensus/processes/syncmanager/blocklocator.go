@@ -18,6 +18,11 @@ func (sm *syncManager) createBlockLocator(stagingArea *model.StagingArea, lowHas
 	}
 	lowBlockBlueScore := lowBlockGHOSTDAGData.BlueScore()
 
+	err = sm.selectedChainCache.update(sm, stagingArea, highHash)
+	if err != nil {
+		return nil, err
+	}
+
 	currentHash := highHash
 	step := uint64(1)
 	locator := make(externalapi.BlockLocator, 0)
@@ -58,7 +63,7 @@ func (sm *syncManager) createBlockLocator(stagingArea *model.StagingArea, lowHas
 		}
 
 		// Walk down currentHash's selected parent chain to the appropriate ancestor
-		currentHash, err = sm.dagTraversalManager.LowestChainBlockAboveOrEqualToBlueScore(stagingArea, currentHash, nextBlueScore)
+		currentHash, err = sm.lowestChainBlockAboveOrEqualToBlueScore(stagingArea, currentHash, nextBlueScore)
 		if err != nil {
 			return nil, err
 		}
@@ -70,6 +75,18 @@ func (sm *syncManager) createBlockLocator(stagingArea *model.StagingArea, lowHas
 	return locator, nil
 }
 
+// lowestChainBlockAboveOrEqualToBlueScore looks the chain block up in the selected chain cache, and falls
+// back to walking down the selected parent chain of highHash if it isn't cached
+func (sm *syncManager) lowestChainBlockAboveOrEqualToBlueScore(stagingArea *model.StagingArea,
+	highHash *externalapi.DomainHash, blueScore uint64) (*externalapi.DomainHash, error) {
+
+	blockHash, ok := sm.selectedChainCache.lowestChainBlockAboveOrEqualToBlueScore(highHash, blueScore)
+	if ok {
+		return blockHash, nil
+	}
+	return sm.dagTraversalManager.LowestChainBlockAboveOrEqualToBlueScore(stagingArea, highHash, blueScore)
+}
+
 func (sm *syncManager) createHeadersSelectedChainBlockLocator(stagingArea *model.StagingArea,
 	lowHash, highHash *externalapi.DomainHash) (externalapi.BlockLocator, error) {
 
@@ -0,0 +1,152 @@
+package syncmanager
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// selectedChainCacheCapacity is the maximum number of selected chain blocks kept by the selectedChainCache
+const selectedChainCacheCapacity = 10000
+
+// selectedChainCache keeps a segment of the selected parent chain of the most recent high hash a block
+// locator was created for, so that the chain blocks of a locator can be found without walking down the
+// chain on every request. Syncing peers mostly ask for locators from the tips, so the segment is usually
+// extended by a few blocks at a time.
+//
+// Since the selected parent chain of a block never changes, the cached segment never goes stale. It
+// might only hold blocks that were pruned since, which are never looked up because the low hash of a
+// locator must exist.
+type selectedChainCache struct {
+	lock     sync.Mutex
+	capacity int
+
+	// blocks is ordered by ascending blue score, and every block is the selected parent of the next one
+	blocks     []*externalapi.DomainHash
+	blueScores []uint64
+
+	// positions maps every cached block to its position in blocks, offset by base
+	positions map[externalapi.DomainHash]int
+	base      int
+}
+
+func newSelectedChainCache(capacity int) *selectedChainCache {
+	return &selectedChainCache{
+		capacity:  capacity,
+		positions: make(map[externalapi.DomainHash]int),
+	}
+}
+
+// index returns the index of the given block in blocks
+func (scc *selectedChainCache) index(blockHash *externalapi.DomainHash) (int, bool) {
+	position, ok := scc.positions[*blockHash]
+	if !ok {
+		return 0, false
+	}
+	return position - scc.base, true
+}
+
+// lowestChainBlockAboveOrEqualToBlueScore is the cached equivalent of
+// DAGTraversalManager.LowestChainBlockAboveOrEqualToBlueScore. ok is false if highHash
+// isn't cached, or if the result might be below the cached segment.
+func (scc *selectedChainCache) lowestChainBlockAboveOrEqualToBlueScore(highHash *externalapi.DomainHash,
+	blueScore uint64) (blockHash *externalapi.DomainHash, ok bool) {
+
+	scc.lock.Lock()
+	defer scc.lock.Unlock()
+
+	highIndex, ok := scc.index(highHash)
+	if !ok || scc.blueScores[highIndex] < blueScore {
+		return nil, false
+	}
+	index := sort.Search(highIndex+1, func(i int) bool {
+		return scc.blueScores[i] >= blueScore
+	})
+	// The selected parent of the lowest cached block isn't cached, so it's unknown whether it's
+	// above blueScore as well
+	if index == 0 {
+		return nil, false
+	}
+	return scc.blocks[index], true
+}
+
+// update makes the cached segment end at highHash. If highHash is above a cached block, only the
+// blocks between them are walked over.
+func (scc *selectedChainCache) update(sm *syncManager, stagingArea *model.StagingArea,
+	highHash *externalapi.DomainHash) error {
+
+	scc.lock.Lock()
+	_, isCached := scc.index(highHash)
+	scc.lock.Unlock()
+	if isCached {
+		return nil
+	}
+
+	// Walk down from highHash until reaching a cached block, the genesis or the capacity
+	var walkedBlocks []*externalapi.DomainHash
+	var walkedBlueScores []uint64
+	var cachedAncestor *externalapi.DomainHash
+	currentHash := highHash
+	for len(walkedBlocks) < scc.capacity {
+		scc.lock.Lock()
+		_, isCached := scc.index(currentHash)
+		scc.lock.Unlock()
+		if isCached {
+			cachedAncestor = currentHash
+			break
+		}
+
+		ghostdagData, err := sm.ghostdagDataStore.Get(sm.databaseContext, stagingArea, currentHash, false)
+		if err != nil {
+			return err
+		}
+		walkedBlocks = append(walkedBlocks, currentHash)
+		walkedBlueScores = append(walkedBlueScores, ghostdagData.BlueScore())
+
+		if currentHash.Equal(sm.genesisBlockHash) {
+			break
+		}
+		currentHash = ghostdagData.SelectedParent()
+	}
+
+	scc.lock.Lock()
+	defer scc.lock.Unlock()
+
+	cachedAncestorIndex, isCachedAncestorStillCached := -1, false
+	if cachedAncestor != nil {
+		cachedAncestorIndex, isCachedAncestorStillCached = scc.index(cachedAncestor)
+	}
+	if isCachedAncestorStillCached {
+		// Drop the cached blocks that aren't in the selected parent chain of highHash
+		for _, blockHash := range scc.blocks[cachedAncestorIndex+1:] {
+			delete(scc.positions, *blockHash)
+		}
+		scc.blocks = scc.blocks[:cachedAncestorIndex+1]
+		scc.blueScores = scc.blueScores[:cachedAncestorIndex+1]
+	} else {
+		scc.blocks = nil
+		scc.blueScores = nil
+		scc.positions = make(map[externalapi.DomainHash]int)
+		scc.base = 0
+	}
+
+	for i := len(walkedBlocks) - 1; i >= 0; i-- {
+		scc.positions[*walkedBlocks[i]] = scc.base + len(scc.blocks)
+		scc.blocks = append(scc.blocks, walkedBlocks[i])
+		scc.blueScores = append(scc.blueScores, walkedBlueScores[i])
+	}
+
+	if len(scc.blocks) > scc.capacity {
+		overflow := len(scc.blocks) - scc.capacity
+		for _, blockHash := range scc.blocks[:overflow] {
+			delete(scc.positions, *blockHash)
+		}
+		// Copy the remaining blocks so that the evicted ones can be garbage collected
+		scc.blocks = append([]*externalapi.DomainHash(nil), scc.blocks[overflow:]...)
+		scc.blueScores = append([]uint64(nil), scc.blueScores[overflow:]...)
+		scc.base += overflow
+	}
+	return nil
+}
@@ -5,8 +5,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/lrucache"
 )
 
+// sortedMergeSetCacheSize is the number of sorted merge sets kept for GetHashesBetween
+const sortedMergeSetCacheSize = 10000
+
 type syncManager struct {
 	databaseContext  model.DBReader
 	genesisBlockHash *externalapi.DomainHash
@@ -24,6 +28,10 @@ type syncManager struct {
 	headersSelectedChainStore model.HeadersSelectedChainStore
 
 	mergeSetSizeLimit uint64
+
+	selectedChainCache  *selectedChainCache
+	sortedMergeSetCache *lrucache.LRUCache
+	anticoneSizeIndex   *anticoneSizeIndex
 }
 
 // New instantiates a new SyncManager
@@ -58,6 +66,10 @@ func New(
 		blockHeaderStore:  blockHeaderStore,
 		blockStore:        blockStore,
 		pruningStore:      pruningStore,
+
+		selectedChainCache:  newSelectedChainCache(selectedChainCacheCapacity),
+		sortedMergeSetCache: lrucache.New(sortedMergeSetCacheSize, false),
+		anticoneSizeIndex:   newAnticoneSizeIndex(),
 	}
 }
 
@@ -82,6 +94,19 @@ func (sm *syncManager) GetAnticone(stagingArea *model.StagingArea, blockHash, co
 			blockHash,
 			contextHash)
 	}
+
+	if maxBlocks != 0 {
+		anticoneSize, ok, err := sm.indexedAnticoneSize(stagingArea, blockHash, contextHash)
+		if err != nil {
+			return nil, err
+		}
+		if ok && anticoneSize > maxBlocks {
+			return nil, errors.Wrapf(model.ErrReachedMaxTraversalAllowed,
+				"The anticone of %s from the point of view of %s has %d blocks, which passes the max allowed traversal (%d)",
+				blockHash, contextHash, anticoneSize, maxBlocks)
+		}
+	}
+
 	return sm.dagTraversalManager.AnticoneFromBlocks(stagingArea, []*externalapi.DomainHash{contextHash}, blockHash, maxBlocks)
 }
 
@@ -0,0 +1,118 @@
+package syncmanager_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+// benchmarkDAGWidths are the numbers of parallel blocks per layer of the benchmark DAGs, from a
+// chain up to the widths seen at higher block rates
+var benchmarkDAGWidths = []int{1, 4, 8}
+
+const benchmarkDAGLayers = 200
+
+// buildLayeredDAG builds a DAG of layers of `width` parallel blocks, where every block points at
+// all the blocks of the layer below it, and merges the last layer into a single tip. It returns
+// the layers and the tip.
+func buildLayeredDAG(b *testing.B, tc testapi.TestConsensus, genesisHash *externalapi.DomainHash, width int) (
+	layers [][]*externalapi.DomainHash, tipHash *externalapi.DomainHash) {
+
+	parents := []*externalapi.DomainHash{genesisHash}
+	for i := 0; i < benchmarkDAGLayers; i++ {
+		layer := make([]*externalapi.DomainHash, width)
+		for j := range layer {
+			blockHash, _, err := tc.AddBlock(parents, nil, nil)
+			if err != nil {
+				b.Fatalf("AddBlock: %+v", err)
+			}
+			layer[j] = blockHash
+		}
+		layers = append(layers, layer)
+		parents = layer
+	}
+
+	tipHash, _, err := tc.AddBlock(parents, nil, nil)
+	if err != nil {
+		b.Fatalf("AddBlock: %+v", err)
+	}
+	return layers, tipHash
+}
+
+func setUpBenchmarkConsensus(b *testing.B, name string) (tc testapi.TestConsensus, teardown func(), genesisHash *externalapi.DomainHash) {
+	consensusConfig := &consensus.Config{Params: dagconfig.DevnetParams}
+	consensusConfig.SkipProofOfWork = true
+
+	tc, teardownFunc, err := consensus.NewFactory().NewTestConsensus(consensusConfig, name)
+	if err != nil {
+		b.Fatalf("Error setting up consensus: %+v", err)
+	}
+	return tc, func() { teardownFunc(false) }, consensusConfig.GenesisHash
+}
+
+func BenchmarkGetHashesBetween(b *testing.B) {
+	for _, width := range benchmarkDAGWidths {
+		b.Run(fmt.Sprintf("width-%d", width), func(b *testing.B) {
+			tc, teardown, genesisHash := setUpBenchmarkConsensus(b, "BenchmarkGetHashesBetween")
+			defer teardown()
+
+			layers, tipHash := buildLayeredDAG(b, tc, genesisHash, width)
+			lowHash := layers[len(layers)/2][0]
+			stagingArea := model.NewStagingArea()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _, err := tc.SyncManager().GetHashesBetween(stagingArea, lowHash, tipHash, 0)
+				if err != nil {
+					b.Fatalf("GetHashesBetween: %+v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetAnticone(b *testing.B) {
+	for _, width := range benchmarkDAGWidths {
+		b.Run(fmt.Sprintf("width-%d", width), func(b *testing.B) {
+			tc, teardown, genesisHash := setUpBenchmarkConsensus(b, "BenchmarkGetAnticone")
+			defer teardown()
+
+			// The anticone of a block near the tips, as requested when sending the anticone of the pruning point
+			layers, tipHash := buildLayeredDAG(b, tc, genesisHash, width)
+			sideBlockHash, _, err := tc.AddBlock(layers[len(layers)-10], nil, nil)
+			if err != nil {
+				b.Fatalf("AddBlock: %+v", err)
+			}
+			stagingArea := model.NewStagingArea()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := tc.SyncManager().GetAnticone(stagingArea, sideBlockHash, tipHash, 0)
+				if err != nil {
+					b.Fatalf("GetAnticone: %+v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCreateBlockLocator(b *testing.B) {
+	tc, teardown, genesisHash := setUpBenchmarkConsensus(b, "BenchmarkCreateBlockLocator")
+	defer teardown()
+
+	_, tipHash := buildLayeredDAG(b, tc, genesisHash, 1)
+	stagingArea := model.NewStagingArea()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := tc.SyncManager().CreateBlockLocator(stagingArea, genesisHash, tipHash, 0)
+		if err != nil {
+			b.Fatalf("CreateBlockLocator: %+v", err)
+		}
+	}
+}
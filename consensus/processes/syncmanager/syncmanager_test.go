@@ -6,10 +6,12 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/zilong-dai/karlsen-miner/consensus/model"
 
 	"github.com/zilong-dai/karlsen-miner/consensus"
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/hashset"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
 )
 
@@ -79,3 +81,109 @@ func TestSyncManager_GetHashesBetween(t *testing.T) {
 		}
 	})
 }
+
+func TestSyncManager_GetAnticone(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		stagingArea := model.NewStagingArea()
+
+		factory := consensus.NewFactory()
+		tc, teardown, err := factory.NewTestConsensus(consensusConfig, "TestSyncManager_GetAnticone")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		blocks := []*externalapi.DomainHash{consensusConfig.GenesisHash}
+		addBlock := func(parentHashes []*externalapi.DomainHash, coinbaseData *externalapi.DomainCoinbaseData) *externalapi.DomainHash {
+			blockHash, _, err := tc.AddBlock(parentHashes, coinbaseData, nil)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+			blocks = append(blocks, blockHash)
+			return blockHash
+		}
+
+		// Create a chain of 5 blocks and a side block, both on top of the genesis, and a block that merges them.
+		// From the point of view of the merging block, the anticone of the side block is the whole chain.
+		chain := make([]*externalapi.DomainHash, 0, 5)
+		tipHash := consensusConfig.GenesisHash
+		for i := 0; i < 5; i++ {
+			tipHash = addBlock([]*externalapi.DomainHash{tipHash}, nil)
+			chain = append(chain, tipHash)
+		}
+		sideChainCoinbaseData := &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+			ExtraData:       []byte{},
+		}
+		sideBlockHash := addBlock([]*externalapi.DomainHash{consensusConfig.GenesisHash}, sideChainCoinbaseData)
+		mergingBlockHash := addBlock([]*externalapi.DomainHash{tipHash, sideBlockHash}, nil)
+
+		anticone, err := tc.SyncManager().GetAnticone(stagingArea, sideBlockHash, mergingBlockHash, 0)
+		if err != nil {
+			t.Fatalf("GetAnticone: %+v", err)
+		}
+		if len(anticone) != len(chain) || !hashset.NewFromSlice(chain...).ContainsAllInSlice(anticone) {
+			t.Fatalf("Unexpected anticone: want: %s, got: %s", chain, anticone)
+		}
+
+		// The traversal of an anticone passes the anticone and the future of the block, so both the
+		// anticones that are indexed and the ones that aren't must fit exactly in that traversal
+		checkAnticones := func(contextHash *externalapi.DomainHash) {
+			for _, blockHash := range blocks {
+				isBlockInPastOfContext, err := tc.DAGTopologyManager().IsAncestorOf(stagingArea, blockHash, contextHash)
+				if err != nil {
+					t.Fatalf("IsAncestorOf: %+v", err)
+				}
+				if !isBlockInPastOfContext {
+					continue
+				}
+				traversalSize := uint64(0)
+				for _, otherBlockHash := range blocks {
+					isInPastOfContext, err := tc.DAGTopologyManager().IsAncestorOf(stagingArea, otherBlockHash, contextHash)
+					if err != nil {
+						t.Fatalf("IsAncestorOf: %+v", err)
+					}
+					isInPastOfBlock, err := tc.DAGTopologyManager().IsAncestorOf(stagingArea, otherBlockHash, blockHash)
+					if err != nil {
+						t.Fatalf("IsAncestorOf: %+v", err)
+					}
+					if isInPastOfContext && !isInPastOfBlock {
+						traversalSize++
+					}
+				}
+				if traversalSize == 0 {
+					continue
+				}
+
+				_, err = tc.SyncManager().GetAnticone(stagingArea, blockHash, contextHash, traversalSize)
+				if err != nil {
+					t.Fatalf("GetAnticone of %s from %s within %d blocks: %+v", blockHash, contextHash, traversalSize, err)
+				}
+				_, err = tc.SyncManager().GetAnticone(stagingArea, blockHash, contextHash, traversalSize-1)
+				if !errors.Is(err, model.ErrReachedMaxTraversalAllowed) {
+					t.Fatalf("Expected ErrReachedMaxTraversalAllowed for the anticone of %s from %s within %d blocks "+
+						"but got: %+v", blockHash, contextHash, traversalSize-1, err)
+				}
+			}
+		}
+		checkAnticones(mergingBlockHash)
+
+		// Move the context up with a block that adds another side block to the past. Its anticone
+		// sizes are updated from the ones of the merging block.
+		secondSideBlockHash := addBlock([]*externalapi.DomainHash{chain[0]}, sideChainCoinbaseData)
+		newContextHash := addBlock([]*externalapi.DomainHash{mergingBlockHash, secondSideBlockHash}, nil)
+		checkAnticones(newContextHash)
+
+		anticone, err = tc.SyncManager().GetAnticone(stagingArea, sideBlockHash, newContextHash, 0)
+		if err != nil {
+			t.Fatalf("GetAnticone: %+v", err)
+		}
+		expectedAnticone := append(externalapi.CloneHashes(chain), secondSideBlockHash)
+		if len(anticone) != len(expectedAnticone) || !hashset.NewFromSlice(expectedAnticone...).ContainsAllInSlice(anticone) {
+			t.Fatalf("Unexpected anticone: want: %s, got: %s", expectedAnticone, anticone)
+		}
+
+		// Lower contexts are served by traversing the DAG
+		checkAnticones(mergingBlockHash)
+	})
+}
@@ -0,0 +1,276 @@
+package transactionselector
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/subnetworks"
+)
+
+type transactionSelector struct {
+	transactionValidator model.TransactionValidator
+}
+
+// New instantiates a new TransactionSelector
+func New(transactionValidator model.TransactionValidator) model.TransactionSelector {
+	return &transactionSelector{
+		transactionValidator: transactionValidator,
+	}
+}
+
+type candidateTransaction struct {
+	*externalapi.DomainTransaction
+	id      *externalapi.DomainTransactionID
+	feeRate float64
+}
+
+// SelectTransactions chooses the transactions of a new block out of the given candidates, maximizing
+// their total fees while keeping their total mass under options.MaxMass. The fees of the candidates
+// are expected to be populated, e.g. by ValidateTransactionAndPopulateWithConsensusData.
+// Candidates that spend an output of another candidate are never selected, since a block may not
+// contain chained transactions, and of several candidates spending the same outpoint only one is selected.
+func (ts *transactionSelector) SelectTransactions(candidates []*externalapi.DomainTransaction,
+	options *externalapi.TransactionSelectionOptions) (*externalapi.TransactionSelection, error) {
+
+	if options.MaxMass == 0 {
+		return nil, errors.New("the max mass of the selected transactions must be positive")
+	}
+
+	candidateTransactions := ts.eligibleCandidates(candidates)
+
+	var selected []*candidateTransaction
+	switch options.Mode {
+	case externalapi.TransactionSelectionByFeeRate:
+		selected = selectByFeeRate(candidateTransactions, options.MaxMass)
+	case externalapi.TransactionSelectionRandomizedWeighted:
+		selected = selectRandomizedWeighted(candidateTransactions, options.MaxMass)
+	default:
+		return nil, errors.Errorf("unknown transaction selection mode %d", options.Mode)
+	}
+
+	// Blocks must have their transactions sorted by subnetwork
+	sort.SliceStable(selected, func(i, j int) bool {
+		return subnetworks.Less(selected[i].SubnetworkID, selected[j].SubnetworkID)
+	})
+
+	selection := &externalapi.TransactionSelection{
+		Transactions: make([]*externalapi.DomainTransaction, len(selected)),
+	}
+	for i, candidate := range selected {
+		selection.Transactions[i] = candidate.DomainTransaction
+		selection.TotalMass += candidate.Mass
+		selection.TotalFees += candidate.Fee
+	}
+
+	log.Debugf("Selected %d out of %d candidate transactions (%s, %d mass, %d in fees)",
+		len(selection.Transactions), len(candidates), options.Mode, selection.TotalMass, selection.TotalFees)
+
+	return selection, nil
+}
+
+// eligibleCandidates filters out the candidates that spend an output of another candidate, and the candidates
+// with no mass, which have no meaningful fee rate and can't be part of a valid block
+func (ts *transactionSelector) eligibleCandidates(candidates []*externalapi.DomainTransaction) []*candidateTransaction {
+	candidateIDs := make(map[externalapi.DomainTransactionID]struct{}, len(candidates))
+	for _, candidate := range candidates {
+		candidateIDs[*consensushashing.TransactionID(candidate)] = struct{}{}
+	}
+
+	eligible := make([]*candidateTransaction, 0, len(candidates))
+	for _, candidate := range candidates {
+		if spendsCandidateOutput(candidate, candidateIDs) {
+			log.Tracef("Skipping transaction %s since it spends the output of another candidate",
+				consensushashing.TransactionID(candidate))
+			continue
+		}
+
+		ts.transactionValidator.PopulateMass(candidate)
+		if candidate.Mass == 0 {
+			log.Tracef("Skipping transaction %s since it has no mass", consensushashing.TransactionID(candidate))
+			continue
+		}
+		eligible = append(eligible, &candidateTransaction{
+			DomainTransaction: candidate,
+			id:                consensushashing.TransactionID(candidate),
+			feeRate:           float64(candidate.Fee) / float64(candidate.Mass),
+		})
+	}
+	return eligible
+}
+
+func spendsCandidateOutput(transaction *externalapi.DomainTransaction,
+	candidateIDs map[externalapi.DomainTransactionID]struct{}) bool {
+
+	for _, input := range transaction.Inputs {
+		if _, ok := candidateIDs[input.PreviousOutpoint.TransactionID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blockSpace tracks the mass and the outpoints already used by the selected transactions
+type blockSpace struct {
+	maxMass        uint64
+	totalMass      uint64
+	spentOutpoints map[externalapi.DomainOutpoint]struct{}
+}
+
+func newBlockSpace(maxMass uint64) *blockSpace {
+	return &blockSpace{
+		maxMass:        maxMass,
+		spentOutpoints: make(map[externalapi.DomainOutpoint]struct{}),
+	}
+}
+
+// tryAdd adds the given candidate if it fits in the remaining mass
+// and doesn't double spend an already selected transaction
+func (bs *blockSpace) tryAdd(candidate *candidateTransaction) bool {
+	if bs.totalMass+candidate.Mass < bs.totalMass || bs.totalMass+candidate.Mass > bs.maxMass {
+		return false
+	}
+	for _, input := range candidate.Inputs {
+		if _, ok := bs.spentOutpoints[input.PreviousOutpoint]; ok {
+			return false
+		}
+	}
+
+	for _, input := range candidate.Inputs {
+		bs.spentOutpoints[input.PreviousOutpoint] = struct{}{}
+	}
+	bs.totalMass += candidate.Mass
+	return true
+}
+
+// isFull returns whether no candidate could fit in the remaining mass anymore
+func (bs *blockSpace) isFull(minCandidateMass uint64) bool {
+	return bs.maxMass-bs.totalMass < minCandidateMass
+}
+
+// selectByFeeRate fills the block with the candidates with the highest fee rate first. Candidates
+// that don't fit are skipped rather than ending the selection, so that smaller candidates may still
+// use the remaining mass.
+func selectByFeeRate(candidates []*candidateTransaction, maxMass uint64) []*candidateTransaction {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].feeRate != candidates[j].feeRate {
+			return candidates[i].feeRate > candidates[j].feeRate
+		}
+		return candidates[i].id.Less(candidates[j].id)
+	})
+
+	minCandidateMass := minMass(candidates)
+	space := newBlockSpace(maxMass)
+	selected := make([]*candidateTransaction, 0, len(candidates))
+	for _, candidate := range candidates {
+		if space.tryAdd(candidate) {
+			selected = append(selected, candidate)
+		}
+		if space.isFull(minCandidateMass) {
+			break
+		}
+	}
+	return selected
+}
+
+const (
+	// alpha is a coefficient that defines how uniform the distribution of
+	// candidate transactions should be. A smaller alpha makes the distribution
+	// more uniform.
+	alpha = 3
+
+	// rebalanceThreshold is the part of the total probability taken by the drawn candidates
+	// above which the remaining candidates are rebalanced. Rebalancing is a heavy operation,
+	// but until it's done, drawing an already drawn candidate is a wasted draw.
+	rebalanceThreshold = 0.95
+)
+
+type weightedCandidate struct {
+	*candidateTransaction
+	p, start, end float64
+	isDrawn       bool
+}
+
+// selectRandomizedWeighted draws the candidates at random, each with a probability of
+// feeRate^alpha / Σ(feeRate^alpha) over the candidates that weren't drawn yet, until
+// the candidates or the block mass run out
+func selectRandomizedWeighted(candidates []*candidateTransaction, maxMass uint64) []*candidateTransaction {
+	weightedCandidates := make([]*weightedCandidate, len(candidates))
+	for i, candidate := range candidates {
+		weightedCandidates[i] = &weightedCandidate{
+			candidateTransaction: candidate,
+			p:                    math.Pow(candidate.feeRate, alpha),
+		}
+	}
+	weightedCandidates, totalP := rebalance(weightedCandidates)
+
+	minCandidateMass := minMass(candidates)
+	space := newBlockSpace(maxMass)
+	selected := make([]*candidateTransaction, 0, len(candidates))
+	drawnCount, drawnP := 0, 0.0
+	for len(weightedCandidates)-drawnCount > 0 && !space.isFull(minCandidateMass) {
+		if drawnP >= rebalanceThreshold*totalP {
+			weightedCandidates, totalP = rebalance(weightedCandidates)
+			drawnCount, drawnP = 0, 0.0
+			if len(weightedCandidates) == 0 {
+				break
+			}
+		}
+
+		candidate := findCandidate(weightedCandidates, rand.Float64()*totalP)
+		if candidate.isDrawn {
+			continue
+		}
+		candidate.isDrawn = true
+		drawnCount++
+		drawnP += candidate.p
+
+		if space.tryAdd(candidate.candidateTransaction) {
+			selected = append(selected, candidate.candidateTransaction)
+		}
+	}
+	return selected
+}
+
+// rebalance removes the drawn candidates and recalculates the probability ranges of the rest
+func rebalance(candidates []*weightedCandidate) (remaining []*weightedCandidate, totalP float64) {
+	remaining = make([]*weightedCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.isDrawn {
+			continue
+		}
+		candidate.start = totalP
+		candidate.end = totalP + candidate.p
+		totalP += candidate.p
+		remaining = append(remaining, candidate)
+	}
+	return remaining, totalP
+}
+
+// findCandidate finds the candidate in whose probability range r falls
+func findCandidate(candidates []*weightedCandidate, r float64) *weightedCandidate {
+	low, high := 0, len(candidates)-1
+	for low < high {
+		i := (low + high) / 2
+		if candidates[i].end <= r {
+			low = i + 1
+		} else {
+			high = i
+		}
+	}
+	return candidates[low]
+}
+
+func minMass(candidates []*candidateTransaction) uint64 {
+	var min uint64 = math.MaxUint64
+	for _, candidate := range candidates {
+		if candidate.Mass < min {
+			min = candidate.Mass
+		}
+	}
+	return min
+}
@@ -0,0 +1,125 @@
+package transactionselector
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/subnetworks"
+)
+
+// fakeTransactionValidator expects the masses of the candidates to be populated already
+type fakeTransactionValidator struct {
+	model.TransactionValidator
+}
+
+func (fakeTransactionValidator) PopulateMass(*externalapi.DomainTransaction) {}
+
+func candidate(outpointIndex uint32, fee, mass uint64) *externalapi.DomainTransaction {
+	return &externalapi.DomainTransaction{
+		Inputs: []*externalapi.DomainTransactionInput{{
+			PreviousOutpoint: externalapi.DomainOutpoint{Index: outpointIndex},
+		}},
+		SubnetworkID: subnetworks.SubnetworkIDNative,
+		Fee:          fee,
+		Mass:         mass,
+	}
+}
+
+func TestSelectByFeeRate(t *testing.T) {
+	selector := New(fakeTransactionValidator{})
+
+	highFeeRate := candidate(1, 1000, 100)
+	large := candidate(2, 1500, 900)
+	small := candidate(3, 100, 50)
+	doubleSpend := candidate(1, 900, 100)
+	chained := candidate(0, 10_000, 10)
+	chained.Inputs[0].PreviousOutpoint.TransactionID = *consensushashing.TransactionID(small)
+
+	selection, err := selector.SelectTransactions(
+		[]*externalapi.DomainTransaction{large, small, doubleSpend, chained, highFeeRate},
+		&externalapi.TransactionSelectionOptions{Mode: externalapi.TransactionSelectionByFeeRate, MaxMass: 500})
+	if err != nil {
+		t.Fatalf("SelectTransactions: %+v", err)
+	}
+
+	// large doesn't fit after highFeeRate is selected, but small still does
+	if len(selection.Transactions) != 2 || selection.Transactions[0] != highFeeRate || selection.Transactions[1] != small {
+		t.Fatalf("expected highFeeRate and small to be selected, got %d transactions", len(selection.Transactions))
+	}
+	if selection.TotalMass != 150 || selection.TotalFees != 1100 {
+		t.Fatalf("expected a total mass of 150 and fees of 1100, got %d and %d",
+			selection.TotalMass, selection.TotalFees)
+	}
+}
+
+func TestSelectTransactionsSkipsMasslessCandidates(t *testing.T) {
+	selector := New(fakeTransactionValidator{})
+
+	massless := candidate(1, 1000, 0)
+	regular := candidate(2, 100, 50)
+	for _, mode := range []externalapi.TransactionSelectionMode{
+		externalapi.TransactionSelectionByFeeRate, externalapi.TransactionSelectionRandomizedWeighted} {
+
+		selection, err := selector.SelectTransactions([]*externalapi.DomainTransaction{massless, regular},
+			&externalapi.TransactionSelectionOptions{Mode: mode, MaxMass: 500})
+		if err != nil {
+			t.Fatalf("SelectTransactions: %+v", err)
+		}
+		if len(selection.Transactions) != 1 || selection.Transactions[0] != regular {
+			t.Fatalf("expected only the candidate with mass to be selected in mode %s, got %d transactions",
+				mode, len(selection.Transactions))
+		}
+	}
+}
+
+func TestSelectRandomizedWeighted(t *testing.T) {
+	selector := New(fakeTransactionValidator{})
+
+	candidates := make([]*externalapi.DomainTransaction, 100)
+	for i := range candidates {
+		candidates[i] = candidate(uint32(i), uint64(i), 10)
+	}
+	candidates[7].SubnetworkID = externalapi.DomainSubnetworkID{0xff}
+	candidates[42] = candidate(41, 1000, 10)
+
+	selection, err := selector.SelectTransactions(candidates,
+		&externalapi.TransactionSelectionOptions{Mode: externalapi.TransactionSelectionRandomizedWeighted, MaxMass: 1000})
+	if err != nil {
+		t.Fatalf("SelectTransactions: %+v", err)
+	}
+	if len(selection.Transactions) != 99 || selection.TotalMass != 990 {
+		t.Fatalf("expected all candidates but one double spend to be selected, got %d with mass %d",
+			len(selection.Transactions), selection.TotalMass)
+	}
+	for i := 1; i < len(selection.Transactions); i++ {
+		if subnetworks.Less(selection.Transactions[i].SubnetworkID, selection.Transactions[i-1].SubnetworkID) {
+			t.Fatalf("expected the selected transactions to be sorted by subnetwork")
+		}
+	}
+
+	selection, err = selector.SelectTransactions(candidates,
+		&externalapi.TransactionSelectionOptions{Mode: externalapi.TransactionSelectionRandomizedWeighted, MaxMass: 105})
+	if err != nil {
+		t.Fatalf("SelectTransactions: %+v", err)
+	}
+	if len(selection.Transactions) != 10 || selection.TotalMass > 105 {
+		t.Fatalf("expected 10 transactions to fill the block, got %d with mass %d",
+			len(selection.Transactions), selection.TotalMass)
+	}
+}
+
+func TestSelectTransactionsInvalidOptions(t *testing.T) {
+	selector := New(fakeTransactionValidator{})
+
+	_, err := selector.SelectTransactions(nil, &externalapi.TransactionSelectionOptions{})
+	if err == nil {
+		t.Fatalf("expected a zero max mass to be rejected")
+	}
+	_, err = selector.SelectTransactions(nil, &externalapi.TransactionSelectionOptions{
+		Mode: externalapi.TransactionSelectionRandomizedWeighted + 1, MaxMass: 1})
+	if err == nil {
+		t.Fatalf("expected an unknown mode to be rejected")
+	}
+}
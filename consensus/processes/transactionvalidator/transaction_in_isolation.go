@@ -41,8 +41,7 @@ func (v *transactionValidator) ValidateTransactionInIsolation(tx *externalapi.Do
 		return err
 	}
 
-	// TODO: fill it with the node's subnetwork id.
-	err = v.checkTransactionSubnetwork(tx, nil)
+	err = v.checkTransactionSubnetwork(tx, v.localSubnetworkID)
 	if err != nil {
 		return err
 	}
@@ -18,6 +18,7 @@ type transactionValidator struct {
 	ghostdagDataStore                       model.GHOSTDAGDataStore
 	daaBlocksStore                          model.DAABlocksStore
 	enableNonNativeSubnetworks              bool
+	localSubnetworkID                       *externalapi.DomainSubnetworkID
 	maxCoinbasePayloadLength                uint64
 	ghostdagK                               externalapi.KType
 	coinbasePayloadScriptPublicKeyMaxLength uint8
@@ -29,6 +30,7 @@ type transactionValidator struct {
 // New instantiates a new TransactionValidator
 func New(blockCoinbaseMaturity uint64,
 	enableNonNativeSubnetworks bool,
+	localSubnetworkID *externalapi.DomainSubnetworkID,
 	maxCoinbasePayloadLength uint64,
 	ghostdagK externalapi.KType,
 	coinbasePayloadScriptPublicKeyMaxLength uint8,
@@ -41,6 +43,7 @@ func New(blockCoinbaseMaturity uint64,
 	return &transactionValidator{
 		blockCoinbaseMaturity:                   blockCoinbaseMaturity,
 		enableNonNativeSubnetworks:              enableNonNativeSubnetworks,
+		localSubnetworkID:                       localSubnetworkID,
 		maxCoinbasePayloadLength:                maxCoinbasePayloadLength,
 		ghostdagK:                               ghostdagK,
 		coinbasePayloadScriptPublicKeyMaxLength: coinbasePayloadScriptPublicKeyMaxLength,
@@ -156,6 +156,10 @@ var (
 	// by subnetwork
 	ErrInvalidGas = newRuleError("ErrInvalidGas")
 
+	// ErrSubnetworkGasLimitExceeded indicates that the transactions of a block use more gas
+	// in a single subnetwork than the gas limit of that subnetwork
+	ErrSubnetworkGasLimitExceeded = newRuleError("ErrSubnetworkGasLimitExceeded")
+
 	// ErrInvalidPayload transaction includes a payload in a subnetwork that doesn't allow
 	// a Payload
 	ErrInvalidPayload = newRuleError("ErrInvalidPayload")
@@ -331,6 +335,23 @@ func (e ErrInvalidTransactionsInNewBlock) Error() string {
 	return fmt.Sprint(e.InvalidTransactions)
 }
 
+// WithoutInvalidTransactions returns the given transactions except for the invalid ones, keeping their order
+func (e ErrInvalidTransactionsInNewBlock) WithoutInvalidTransactions(
+	transactions []*externalapi.DomainTransaction) []*externalapi.DomainTransaction {
+
+	invalidTransactionIDs := make(map[externalapi.DomainTransactionID]struct{}, len(e.InvalidTransactions))
+	for _, invalidTransaction := range e.InvalidTransactions {
+		invalidTransactionIDs[*consensushashing.TransactionID(invalidTransaction.Transaction)] = struct{}{}
+	}
+	validTransactions := make([]*externalapi.DomainTransaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if _, ok := invalidTransactionIDs[*consensushashing.TransactionID(transaction)]; !ok {
+			validTransactions = append(validTransactions, transaction)
+		}
+	}
+	return validTransactions
+}
+
 // NewErrInvalidTransactionsInNewBlock Creates a new ErrInvalidTransactionsInNewBlock error wrapped in a RuleError
 func NewErrInvalidTransactionsInNewBlock(invalidTransactions []InvalidTransaction) error {
 	return errors.WithStack(RuleError{
@@ -0,0 +1,407 @@
+package consensus
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/migrations"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/acceptancedatastore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockheaderstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockrelationstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockstatusstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/blockstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/consensusstatestore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/daablocksstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/daawindowstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/finalityconflictstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/finalitystore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/ghostdagdatastore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/headersselectedchainstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/headersselectedtipstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/mergedepthrootstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/multisetstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/pruningstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/reachabilitydatastore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/retainedbodystore"
+	"github.com/zilong-dai/karlsen-miner/consensus/datastructures/utxodiffstore"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/multiset"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/snapshot"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+)
+
+// snapshotImportBatchSize is the amount of records written to the database in a single transaction
+// while importing a snapshot
+const snapshotImportBatchSize = 10_000
+
+// snapshotEntry describes a single database key or bucket that is a part of a consensus snapshot
+type snapshotEntry struct {
+	section  snapshot.Section
+	name     []byte
+	isBucket bool
+	perLevel bool
+}
+
+// snapshotEntries lists everything that is copied into a snapshot. Transient data, such as
+// an in-progress pruning point UTXO set import, is deliberately left out.
+var snapshotEntries = []snapshotEntry{
+	{section: snapshot.SectionPruningPoint, name: pruningstore.CurrentPruningPointIndexKeyName},
+	{section: snapshot.SectionPruningPoint, name: pruningstore.CandidatePruningPointHashKeyName},
+	{section: snapshot.SectionPruningPoint, name: pruningstore.PruningPointByIndexBucketName, isBucket: true},
+
+	{section: snapshot.SectionPruningPointUTXOSet, name: pruningstore.PruningPointUTXOSetBucketName, isBucket: true},
+
+	{section: snapshot.SectionHeaders, name: blockheaderstore.CountKeyName},
+	{section: snapshot.SectionHeaders, name: blockheaderstore.BucketName, isBucket: true},
+
+	{section: snapshot.SectionBlockRelations, name: blockrelationstore.BucketName, isBucket: true, perLevel: true},
+
+	{section: snapshot.SectionGHOSTDAGData, name: ghostdagdatastore.GHOSTDAGDataBucketName, isBucket: true, perLevel: true},
+	{section: snapshot.SectionGHOSTDAGData, name: ghostdagdatastore.TrustedDataBucketName, isBucket: true, perLevel: true},
+
+	{section: snapshot.SectionReachability, name: reachabilitydatastore.ReachabilityReindexRootKeyName},
+	{section: snapshot.SectionReachability, name: reachabilitydatastore.ReachabilityDataBucketName, isBucket: true},
+	{section: snapshot.SectionReachability, name: reachabilitydatastore.ReachabilityReindexRootKeyName, perLevel: true},
+	{section: snapshot.SectionReachability, name: reachabilitydatastore.ReachabilityDataBucketName, isBucket: true, perLevel: true},
+
+	{section: snapshot.SectionTips, name: consensusstatestore.TipsKeyName},
+	{section: snapshot.SectionTips, name: headersselectedtipstore.KeyName},
+
+	{section: snapshot.SectionConsensusState, name: migrations.SchemaVersionKeyName},
+	{section: snapshot.SectionConsensusState, name: blockstore.CountKeyName},
+	{section: snapshot.SectionConsensusState, name: blockstore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: retainedbodystore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: blockstatusstore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: consensusstatestore.UTXOSetBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: utxodiffstore.UTXODiffBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: utxodiffstore.UTXODiffChildBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: acceptancedatastore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: multisetstore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: daablocksstore.DAAScoreBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: daablocksstore.DAAAddedBlocksBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: daawindowstore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: finalitystore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: finalityconflictstore.ConflictsBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: finalityconflictstore.ViolatingBlocksBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: finalityconflictstore.ResolutionsBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: mergedepthrootstore.BucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: headersselectedchainstore.HighestChainBlockIndexKeyName},
+	{section: snapshot.SectionConsensusState, name: headersselectedchainstore.ChainBlockHashByIndexBucketName, isBucket: true},
+	{section: snapshot.SectionConsensusState, name: headersselectedchainstore.ChainBlockIndexByHashBucketName, isBucket: true},
+}
+
+// ExportSnapshot writes a consistent point-in-time snapshot of this consensus to the given writer.
+// The consensus lock is held for the whole export, so no block can be inserted in the meantime.
+func (s *consensus) ExportSnapshot(writer io.Writer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "ExportSnapshot")
+	defer onEnd()
+
+	isImportingPruningPointUTXOSet, err := s.consensusStateStore.HadStartedImportingPruningPointUTXOSet(s.databaseContext)
+	if err != nil {
+		return err
+	}
+	if isImportingPruningPointUTXOSet {
+		return errors.New("cannot export a snapshot while a pruning point UTXO set is being imported")
+	}
+	isUpdatingPruningPointUTXOSet, err := s.pruningStore.HadStartedUpdatingPruningPointUTXOSet(s.databaseContext)
+	if err != nil {
+		return err
+	}
+	if isUpdatingPruningPointUTXOSet {
+		return errors.New("cannot export a snapshot while the pruning point UTXO set is being updated")
+	}
+
+	stagingArea := model.NewStagingArea()
+	pruningPoint, err := s.pruningStore.PruningPoint(s.databaseContext, stagingArea)
+	if err != nil {
+		return err
+	}
+	tips, err := s.consensusStateStore.Tips(stagingArea, s.databaseContext)
+	if err != nil {
+		return err
+	}
+
+	snapshotWriter, err := snapshot.NewWriter(writer, &snapshot.Header{
+		Version:      snapshot.Version,
+		GenesisHash:  s.genesisHash,
+		PruningPoint: pruningPoint,
+		Tips:         tips,
+	})
+	if err != nil {
+		return err
+	}
+
+	maxBlockLevel := len(s.ghostdagDataStores) - 1
+	for _, entry := range snapshotEntries {
+		buckets := []model.DBBucket{s.prefixBucket}
+		if entry.perLevel {
+			buckets = make([]model.DBBucket, 0, maxBlockLevel+1)
+			for level := 0; level <= maxBlockLevel; level++ {
+				buckets = append(buckets, s.prefixBucket.Bucket([]byte{byte(level)}))
+			}
+		}
+
+		for _, bucket := range buckets {
+			if entry.isBucket {
+				err = s.exportSnapshotBucket(snapshotWriter, entry.section, bucket.Bucket(entry.name))
+			} else {
+				err = s.exportSnapshotKey(snapshotWriter, entry.section, bucket.Key(entry.name))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Infof("Exported %d records of a snapshot at pruning point %s", snapshotWriter.RecordCount(), pruningPoint)
+	return snapshotWriter.Close()
+}
+
+func (s *consensus) exportSnapshotKey(snapshotWriter *snapshot.Writer, section snapshot.Section, key model.DBKey) error {
+	value, err := s.databaseContext.Get(key)
+	if database.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return snapshotWriter.WriteRecord(&snapshot.Record{
+		Section: section,
+		Key:     s.keyRelativeToPrefix(key),
+		Value:   value,
+	})
+}
+
+func (s *consensus) exportSnapshotBucket(snapshotWriter *snapshot.Writer, section snapshot.Section, bucket model.DBBucket) error {
+	cursor, err := s.databaseContext.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		value, err := cursor.Value()
+		if err != nil {
+			return err
+		}
+
+		err = snapshotWriter.WriteRecord(&snapshot.Record{
+			Section: section,
+			Key:     s.keyRelativeToPrefix(key),
+			Value:   value,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *consensus) keyRelativeToPrefix(key model.DBKey) []byte {
+	return key.Bytes()[len(s.prefixBucket.Path()):]
+}
+
+// importSnapshot writes the snapshot read from snapshotReader into the given, empty, database prefix,
+// and verifies that the imported pruning point UTXO set matches its header's UTXO commitment.
+// The whole snapshot is read and its digest verified before anything is written, so a corrupted
+// snapshot never reaches the database. If the import fails later, everything written under the
+// prefix is deleted.
+func importSnapshot(config *Config, dbManager model.DBManager, prefixBucket model.DBBucket,
+	snapshotReader io.ReadSeeker) error {
+
+	onEnd := logger.LogAndMeasureExecutionTime(log, "importSnapshot")
+	defer onEnd()
+
+	isEmpty, err := isBucketEmpty(dbManager, prefixBucket)
+	if err != nil {
+		return err
+	}
+	if !isEmpty {
+		return errors.Errorf("cannot import a snapshot into the non-empty database prefix %x", prefixBucket.Path())
+	}
+
+	header, err := snapshot.Verify(bufio.NewReader(snapshotReader))
+	if err != nil {
+		return err
+	}
+	if !header.GenesisHash.Equal(config.GenesisHash) {
+		return errors.Errorf("snapshot genesis %s doesn't match the network genesis %s",
+			header.GenesisHash, config.GenesisHash)
+	}
+	log.Infof("Verified the digest of a snapshot at pruning point %s", header.PruningPoint)
+
+	_, err = snapshotReader.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	err = writeSnapshot(dbManager, prefixBucket, bufio.NewReader(snapshotReader))
+	if err != nil {
+		clearErr := clearBucket(dbManager, prefixBucket)
+		if clearErr != nil {
+			log.Errorf("Failed clearing the database prefix after a failed snapshot import: %s", clearErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func writeSnapshot(dbManager model.DBManager, prefixBucket model.DBBucket, snapshotReader io.Reader) error {
+	reader, err := snapshot.NewReader(snapshotReader)
+	if err != nil {
+		return err
+	}
+	header := reader.Header()
+
+	dbTx, err := dbManager.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// dbTx is replaced after every batch, so it must be evaluated when the function returns
+		_ = dbTx.RollbackUnlessClosed()
+	}()
+
+	recordCount := 0
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = dbTx.Put(prefixBucket.Key(record.Key), record.Value)
+		if err != nil {
+			return err
+		}
+
+		recordCount++
+		if recordCount%snapshotImportBatchSize == 0 {
+			err = dbTx.Commit()
+			if err != nil {
+				return err
+			}
+			dbTx, err = dbManager.Begin()
+			if err != nil {
+				return err
+			}
+			log.Debugf("Imported %d snapshot records", recordCount)
+		}
+	}
+
+	err = dbTx.Commit()
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %d records of a snapshot at pruning point %s", recordCount, header.PruningPoint)
+
+	return verifyImportedSnapshot(dbManager, prefixBucket, header)
+}
+
+// verifyImportedSnapshot makes sure that the imported data agrees with the snapshot header, and
+// that the pruning point UTXO set matches the UTXO commitment of the pruning point header.
+func verifyImportedSnapshot(dbManager model.DBManager, prefixBucket model.DBBucket, header *snapshot.Header) error {
+	stagingArea := model.NewStagingArea()
+
+	pruningStore := pruningstore.New(prefixBucket, 2, false)
+	pruningPoint, err := pruningStore.PruningPoint(dbManager, stagingArea)
+	if err != nil {
+		return err
+	}
+	if !pruningPoint.Equal(header.PruningPoint) {
+		return errors.Errorf("snapshot header indicates pruning point %s but imported data has %s",
+			header.PruningPoint, pruningPoint)
+	}
+
+	consensusStateStore := consensusstatestore.New(prefixBucket, 1, false)
+	tips, err := consensusStateStore.Tips(stagingArea, dbManager)
+	if err != nil {
+		return err
+	}
+	if !externalapi.HashesEqual(tips, header.Tips) {
+		return errors.Errorf("snapshot header indicates tips %s but imported data has %s", header.Tips, tips)
+	}
+
+	blockHeaderStore, err := blockheaderstore.New(dbManager, prefixBucket, 1, false, model.StoreEncodingProtobuf)
+	if err != nil {
+		return err
+	}
+	pruningPointHeader, err := blockHeaderStore.BlockHeader(dbManager, stagingArea, pruningPoint)
+	if err != nil {
+		return err
+	}
+
+	utxoSetIterator, err := pruningStore.PruningPointUTXOIterator(dbManager)
+	if err != nil {
+		return err
+	}
+	defer utxoSetIterator.Close()
+
+	utxoSetMultiset := multiset.New()
+	for ok := utxoSetIterator.First(); ok; ok = utxoSetIterator.Next() {
+		outpoint, entry, err := utxoSetIterator.Get()
+		if err != nil {
+			return err
+		}
+		serializedUTXO, err := utxo.SerializeUTXO(entry, outpoint)
+		if err != nil {
+			return err
+		}
+		utxoSetMultiset.Add(serializedUTXO)
+	}
+
+	utxoSetHash := utxoSetMultiset.Hash()
+	if !pruningPointHeader.UTXOCommitment().Equal(utxoSetHash) {
+		return errors.Errorf("the snapshot pruning point UTXO set doesn't match the UTXO commitment of "+
+			"pruning point %s. Calculated: %s, commitment: %s", pruningPoint, utxoSetHash, pruningPointHeader.UTXOCommitment())
+	}
+
+	log.Infof("Verified the snapshot pruning point %s UTXO commitment: %s", pruningPoint, utxoSetHash)
+	return nil
+}
+
+func isBucketEmpty(dbContext model.DBReader, bucket model.DBBucket) (bool, error) {
+	cursor, err := dbContext.Cursor(bucket)
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close()
+
+	return !cursor.First(), nil
+}
+
+func clearBucket(dbManager model.DBManager, bucket model.DBBucket) error {
+	cursor, err := dbManager.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		key, err := cursor.Key()
+		if err != nil {
+			return err
+		}
+		err = dbManager.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package consensus_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	consensusdatabase "github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/memorydb"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/snapshot"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/testutils"
+	"github.com/zilong-dai/karlsen-miner/prefixmanager/prefix"
+)
+
+func TestSnapshotExportImport(t *testing.T) {
+	testutils.ForAllNets(t, true, func(t *testing.T, consensusConfig *consensus.Config) {
+		factory := consensus.NewFactory()
+		tc, teardown, err := factory.NewTestConsensus(consensusConfig, "TestSnapshotExportImport")
+		if err != nil {
+			t.Fatalf("Error setting up consensus: %+v", err)
+		}
+		defer teardown(false)
+
+		// Build a chain with a side block next to it, so that the snapshot contains a merge
+		blockHashes := []*externalapi.DomainHash{consensusConfig.GenesisHash}
+		tipHash := consensusConfig.GenesisHash
+		for i := 0; i < 5; i++ {
+			tipHash, _, err = tc.AddBlock([]*externalapi.DomainHash{tipHash}, nil, nil)
+			if err != nil {
+				t.Fatalf("AddBlock: %+v", err)
+			}
+			blockHashes = append(blockHashes, tipHash)
+		}
+		sideBlockHash, _, err := tc.AddBlock([]*externalapi.DomainHash{blockHashes[3]}, &externalapi.DomainCoinbaseData{
+			ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+			ExtraData:       []byte{},
+		}, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		blockHashes = append(blockHashes, sideBlockHash)
+		tipHash, _, err = tc.AddBlock([]*externalapi.DomainHash{tipHash, sideBlockHash}, nil, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		blockHashes = append(blockHashes, tipHash)
+
+		var snapshotBuffer bytes.Buffer
+		err = tc.ExportSnapshot(&snapshotBuffer)
+		if err != nil {
+			t.Fatalf("ExportSnapshot: %+v", err)
+		}
+		snapshotBytes := snapshotBuffer.Bytes()
+
+		imported, err := factory.NewConsensusFromSnapshot(consensusConfig, memorydb.New(), &prefix.Prefix{},
+			bytes.NewReader(snapshotBytes), nil)
+		if err != nil {
+			t.Fatalf("NewConsensusFromSnapshot: %+v", err)
+		}
+
+		expectedPruningPoint, err := tc.PruningPoint()
+		if err != nil {
+			t.Fatalf("PruningPoint: %+v", err)
+		}
+		pruningPoint, err := imported.PruningPoint()
+		if err != nil {
+			t.Fatalf("PruningPoint: %+v", err)
+		}
+		if !pruningPoint.Equal(expectedPruningPoint) {
+			t.Fatalf("Expected the imported pruning point to be %s but got %s", expectedPruningPoint, pruningPoint)
+		}
+
+		expectedTips, err := tc.Tips()
+		if err != nil {
+			t.Fatalf("Tips: %+v", err)
+		}
+		tips, err := imported.Tips()
+		if err != nil {
+			t.Fatalf("Tips: %+v", err)
+		}
+		if !externalapi.HashesEqual(tips, expectedTips) {
+			t.Fatalf("Expected the imported tips to be %s but got %s", expectedTips, tips)
+		}
+
+		virtualSelectedParent, err := imported.GetVirtualSelectedParent()
+		if err != nil {
+			t.Fatalf("GetVirtualSelectedParent: %+v", err)
+		}
+		if !virtualSelectedParent.Equal(tipHash) {
+			t.Fatalf("Expected the imported virtual selected parent to be %s but got %s", tipHash, virtualSelectedParent)
+		}
+
+		for _, blockHash := range blockHashes {
+			expectedBlockInfo, err := tc.GetBlockInfo(blockHash)
+			if err != nil {
+				t.Fatalf("GetBlockInfo: %+v", err)
+			}
+			blockInfo, err := imported.GetBlockInfo(blockHash)
+			if err != nil {
+				t.Fatalf("GetBlockInfo: %+v", err)
+			}
+			if !blockInfo.Exists || blockInfo.BlockStatus != expectedBlockInfo.BlockStatus ||
+				blockInfo.BlueScore != expectedBlockInfo.BlueScore {
+				t.Fatalf("Expected the imported info of block %s to be %+v but got %+v",
+					blockHash, expectedBlockInfo, blockInfo)
+			}
+		}
+
+		report, err := imported.CheckIntegrity(false)
+		if err != nil {
+			t.Fatalf("CheckIntegrity: %+v", err)
+		}
+		if len(report.Issues) != 0 {
+			t.Fatalf("Expected no integrity issues in the imported consensus but got %s", report.Issues)
+		}
+
+		// A snapshot with a corrupted digest must be rejected before anything is written
+		corruptedSnapshotBytes := append([]byte{}, snapshotBytes...)
+		corruptedSnapshotBytes[len(corruptedSnapshotBytes)-1] ^= 0xff
+		db := memorydb.New()
+		_, err = factory.NewConsensusFromSnapshot(consensusConfig, db, &prefix.Prefix{},
+			bytes.NewReader(corruptedSnapshotBytes), nil)
+		if !errors.Is(err, snapshot.ErrChecksumMismatch) {
+			t.Fatalf("Expected the import of a corrupted snapshot to fail with %s but got %+v",
+				snapshot.ErrChecksumMismatch, err)
+		}
+		cursor, err := consensusdatabase.New(db).Cursor(consensusdatabase.MakeBucket((&prefix.Prefix{}).Serialize()))
+		if err != nil {
+			t.Fatalf("Cursor: %+v", err)
+		}
+		defer cursor.Close()
+		if cursor.First() {
+			t.Fatalf("Expected nothing to be written by the import of a corrupted snapshot")
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package consensus
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+// subnetworkGasLimitsByGenesis maps the genesis hash of every network to the total gas the transactions
+// of a single block may use in each of its custom subnetworks. The gas limits are consensus rules, so
+// they're defined per network rather than per node.
+//
+// Custom subnetworks that have no gas limit here stay unlimited, as they were before gas limits were
+// enforced, so adding a limit to a network that is already running is a hard fork and has to be
+// coordinated with all of its nodes. No network limits the gas of a custom subnetwork yet.
+var subnetworkGasLimitsByGenesis = map[externalapi.DomainHash]map[externalapi.DomainSubnetworkID]uint64{
+	*dagconfig.MainnetParams.GenesisHash: {},
+	*dagconfig.TestnetParams.GenesisHash: {},
+	*dagconfig.SimnetParams.GenesisHash:  {},
+	*dagconfig.DevnetParams.GenesisHash:  {},
+}
+
+// subnetworkGasLimits returns the gas limits of the custom subnetworks of the network of the given config
+func subnetworkGasLimits(config *Config) map[externalapi.DomainSubnetworkID]uint64 {
+	return subnetworkGasLimitsByGenesis[*config.GenesisHash]
+}
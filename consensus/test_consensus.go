@@ -55,7 +55,7 @@ func (tc *testConsensus) AddBlock(parentHashes []*externalapi.DomainHash, coinba
 
 	// Require write lock because BuildBlockWithParents stages temporary data
 	tc.lock.Lock()
-	defer tc.lock.Unlock()
+	defer tc.unlock()
 
 	block, _, err := tc.testBlockBuilder.BuildBlockWithParents(parentHashes, coinbaseData, transactions)
 	if err != nil {
@@ -86,7 +86,7 @@ func (tc *testConsensus) AddUTXOInvalidHeader(parentHashes []*externalapi.Domain
 
 	// Require write lock because BuildBlockWithParents stages temporary data
 	tc.lock.Lock()
-	defer tc.lock.Unlock()
+	defer tc.unlock()
 
 	header, err := tc.testBlockBuilder.BuildUTXOInvalidHeader(parentHashes)
 	if err != nil {
@@ -109,7 +109,7 @@ func (tc *testConsensus) AddUTXOInvalidBlock(parentHashes []*externalapi.DomainH
 
 	// Require write lock because BuildBlockWithParents stages temporary data
 	tc.lock.Lock()
-	defer tc.lock.Unlock()
+	defer tc.unlock()
 
 	block, err := tc.testBlockBuilder.BuildUTXOInvalidBlock(parentHashes)
 	if err != nil {
@@ -126,7 +126,7 @@ func (tc *testConsensus) AddUTXOInvalidBlock(parentHashes []*externalapi.DomainH
 
 func (tc *testConsensus) ResolveVirtualWithMaxParam(maxBlocksToResolve uint64) (*externalapi.VirtualChangeSet, bool, error) {
 	tc.lock.Lock()
-	defer tc.lock.Unlock()
+	defer tc.unlock()
 
 	return tc.resolveVirtualChunkNoLock(maxBlocksToResolve)
 }
@@ -0,0 +1,144 @@
+package difficultysimulator
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/karlsen-network/karlsend/v2/util/difficulty"
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+const (
+	defaultTicksPerBlock                = 10
+	defaultBlocksPerSample              = 60
+	defaultHashrateEstimationWindowSize = 1000
+)
+
+// TimestampStrategy selects the timestamps an attacker sets in its blocks
+type TimestampStrategy int
+
+const (
+	// TimestampStrategyFuture sets the timestamps as far in the future as the timestamp
+	// deviation tolerance allows, in order to stretch the DAA window and lower the difficulty
+	TimestampStrategyFuture TimestampStrategy = iota
+
+	// TimestampStrategyPast sets the timestamps to the lowest ones the past median time
+	// allows, as in a time warp attack
+	TimestampStrategyPast
+)
+
+// Attacker is a miner that manipulates the timestamps of its blocks
+type Attacker struct {
+	Hashrate HashrateTimeline
+	Strategy TimestampStrategy
+}
+
+// Config is the configuration of a simulation
+type Config struct {
+	// ConsensusConfig is the configuration of the simulated consensus. Proof of work
+	// is always skipped.
+	ConsensusConfig *consensus.Config
+
+	// Duration is the simulated time to run for
+	Duration time.Duration
+	// Tick is the resolution of the simulated time. Blocks that are found in the
+	// same tick share their parents. Zero selects a tenth of the target time per block.
+	Tick time.Duration
+	// PropagationDelay is the time it takes a block to reach all the miners. It's
+	// rounded up to whole ticks.
+	PropagationDelay time.Duration
+
+	// Honest is the hashrate of the miners that set their clock time as the block timestamp
+	Honest HashrateTimeline
+	// Attacker is an optional timestamp-manipulating miner
+	Attacker *Attacker
+
+	// SampleInterval is the simulated time between samples. Zero selects 60 target
+	// times per block.
+	SampleInterval time.Duration
+	// HashrateEstimationWindowSize is the window size passed to EstimateNetworkHashesPerSecond.
+	// Zero selects 1000, the minimal window size.
+	HashrateEstimationWindowSize int
+
+	// Seed seeds the randomness of the simulation, so that a configuration always
+	// results in the same simulation
+	Seed int64
+}
+
+func (c *Config) params() *dagconfig.Params {
+	return &c.ConsensusConfig.Params
+}
+
+func (c *Config) tick() time.Duration {
+	if c.Tick != 0 {
+		return c.Tick
+	}
+	return c.params().TargetTimePerBlock / defaultTicksPerBlock
+}
+
+func (c *Config) sampleInterval() time.Duration {
+	if c.SampleInterval != 0 {
+		return c.SampleInterval
+	}
+	return c.params().TargetTimePerBlock * defaultBlocksPerSample
+}
+
+func (c *Config) hashrateEstimationWindowSize() int {
+	if c.HashrateEstimationWindowSize != 0 {
+		return c.HashrateEstimationWindowSize
+	}
+	return defaultHashrateEstimationWindowSize
+}
+
+func (c *Config) validate() error {
+	if c.ConsensusConfig == nil {
+		return errors.New("a consensus config is required")
+	}
+	if c.Honest == nil {
+		return errors.New("an honest hashrate is required")
+	}
+	if c.Duration <= 0 {
+		return errors.New("the duration must be positive")
+	}
+	if c.tick() <= 0 {
+		return errors.New("the tick must be positive")
+	}
+	if c.sampleInterval() < c.tick() {
+		return errors.Errorf("the sample interval %s must be at least the tick %s", c.sampleInterval(), c.tick())
+	}
+	if c.PropagationDelay < 0 {
+		return errors.New("the propagation delay must not be negative")
+	}
+	if c.Attacker != nil && c.Attacker.Hashrate == nil {
+		return errors.New("an attacker hashrate is required")
+	}
+
+	// Blocks are validated against the real time, so the whole simulation has to fit
+	// between the genesis and now
+	genesisTime := time.UnixMilli(c.params().GenesisBlock.Header.TimeInMilliseconds())
+	if genesisTime.Add(c.Duration).Add(c.maxTimestampOffset()).After(time.Now()) {
+		return errors.Errorf("a simulation of %s doesn't fit between the genesis time %s and now",
+			c.Duration, genesisTime)
+	}
+	return nil
+}
+
+// maxTimestampOffset is the furthest in the future a block timestamp may be
+func (c *Config) maxTimestampOffset() time.Duration {
+	return time.Duration(c.params().TimestampDeviationTolerance) * c.params().TargetTimePerBlock
+}
+
+// GenesisHashesPerSecond returns the hashrate at which the genesis difficulty yields a block
+// every target time per block. Since the difficulty isn't adjusted before the DAA window is
+// full, it's the natural hashrate to start a simulation with.
+func GenesisHashesPerSecond(params *dagconfig.Params) float64 {
+	return hashesPerBlock(params.GenesisBlock.Header.Bits()) / params.TargetTimePerBlock.Seconds()
+}
+
+// hashesPerBlock returns the expected number of hashes it takes to find a block of the given bits
+func hashesPerBlock(bits uint32) float64 {
+	work, _ := new(big.Float).SetInt(difficulty.CalcWork(bits)).Float64()
+	return work
+}
@@ -0,0 +1,129 @@
+package difficultysimulator
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// HashrateTimeline describes a hashrate over the simulated time. Hashrates are
+// measured in the same hashes the difficulty is measured in, so with skipped
+// PoW they are nominal.
+type HashrateTimeline interface {
+	// HashesPerSecond returns the hashrate at the given time since the start of the simulation
+	HashesPerSecond(elapsed time.Duration) float64
+}
+
+// ConstantHashrate is a hashrate that never changes
+type ConstantHashrate float64
+
+// HashesPerSecond implements HashrateTimeline
+func (h ConstantHashrate) HashesPerSecond(time.Duration) float64 {
+	return float64(h)
+}
+
+// HashrateStep sets the hashrate from a point in the simulated time on
+type HashrateStep struct {
+	At              time.Duration
+	HashesPerSecond float64
+}
+
+// StepHashrate is a hashrate that changes in steps. The hashrate before the
+// first step is zero.
+type StepHashrate []HashrateStep
+
+// NewStepHashrate returns a StepHashrate of the given steps, sorted by their time
+func NewStepHashrate(steps ...HashrateStep) StepHashrate {
+	sorted := make(StepHashrate, len(steps))
+	copy(sorted, steps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At < sorted[j].At
+	})
+	return sorted
+}
+
+// HashesPerSecond implements HashrateTimeline
+func (h StepHashrate) HashesPerSecond(elapsed time.Duration) float64 {
+	index := sort.Search(len(h), func(i int) bool {
+		return h[i].At > elapsed
+	})
+	if index == 0 {
+		return 0
+	}
+	return h[index-1].HashesPerSecond
+}
+
+// Miner is a single miner that mines from Joins until Leaves. A zero Leaves
+// means that the miner never leaves.
+type Miner struct {
+	HashesPerSecond float64
+	Joins           time.Duration
+	Leaves          time.Duration
+}
+
+func (m *Miner) isMining(elapsed time.Duration) bool {
+	return elapsed >= m.Joins && (m.Leaves == 0 || elapsed < m.Leaves)
+}
+
+// MinerSet is the combined hashrate of a set of miners
+type MinerSet []Miner
+
+// HashesPerSecond implements HashrateTimeline
+func (m MinerSet) HashesPerSecond(elapsed time.Duration) float64 {
+	hashesPerSecond := float64(0)
+	for i := range m {
+		if m[i].isMining(elapsed) {
+			hashesPerSecond += m[i].HashesPerSecond
+		}
+	}
+	return hashesPerSecond
+}
+
+// NewChurningMiners returns minerCount miners of the given hashrate that keep going
+// online and offline during the given duration. The online and offline periods of
+// every miner are exponentially distributed around the given means, and every miner
+// starts online or offline according to the share of time it's expected to be online.
+func NewChurningMiners(randSource *rand.Rand, minerCount int, minerHashesPerSecond float64,
+	duration, meanOnline, meanOffline time.Duration) MinerSet {
+
+	onlineShare := float64(meanOnline) / float64(meanOnline+meanOffline)
+	exponential := func(mean time.Duration) time.Duration {
+		return time.Duration(randSource.ExpFloat64() * float64(mean))
+	}
+
+	miners := make(MinerSet, 0, minerCount)
+	for i := 0; i < minerCount; i++ {
+		elapsed := time.Duration(0)
+		if randSource.Float64() >= onlineShare {
+			elapsed = exponential(meanOffline)
+		}
+		for elapsed < duration {
+			leaves := elapsed + exponential(meanOnline)
+			if leaves >= duration {
+				leaves = 0
+			}
+			miners = append(miners, Miner{
+				HashesPerSecond: minerHashesPerSecond,
+				Joins:           elapsed,
+				Leaves:          leaves,
+			})
+			if leaves == 0 {
+				break
+			}
+			elapsed = leaves + exponential(meanOffline)
+		}
+	}
+	return miners
+}
+
+// SumHashrate is the sum of several hashrates, such as a baseline and a set of churning miners
+type SumHashrate []HashrateTimeline
+
+// HashesPerSecond implements HashrateTimeline
+func (h SumHashrate) HashesPerSecond(elapsed time.Duration) float64 {
+	hashesPerSecond := float64(0)
+	for _, timeline := range h {
+		hashesPerSecond += timeline.HashesPerSecond(elapsed)
+	}
+	return hashesPerSecond
+}
@@ -0,0 +1,66 @@
+package difficultysimulator
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var samplesCSVHeader = []string{"elapsed_seconds", "honest_hashes_per_second", "attacker_hashes_per_second",
+	"blocks", "block_rate", "required_difficulty_bits", "hashes_per_block", "estimated_hashes_per_second"}
+
+var blocksCSVHeader = []string{"hash", "mined_at_seconds", "time_in_milliseconds", "bits", "daa_score", "is_attacker"}
+
+// WriteSamplesCSV writes the samples of the result as CSV, with a header line
+func (r *Result) WriteSamplesCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(samplesCSVHeader)
+	if err != nil {
+		return err
+	}
+	for _, sample := range r.Samples {
+		err := csvWriter.Write([]string{
+			formatFloat(sample.Elapsed.Seconds()),
+			formatFloat(sample.HonestHashesPerSecond),
+			formatFloat(sample.AttackerHashesPerSecond),
+			strconv.Itoa(sample.Blocks),
+			formatFloat(sample.BlockRate),
+			strconv.FormatUint(uint64(sample.RequiredDifficulty), 16),
+			formatFloat(sample.HashesPerBlock),
+			strconv.FormatUint(sample.EstimatedHashesPerSecond, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteBlocksCSV writes the blocks of the result as CSV, with a header line
+func (r *Result) WriteBlocksCSV(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(blocksCSVHeader)
+	if err != nil {
+		return err
+	}
+	for _, block := range r.Blocks {
+		err := csvWriter.Write([]string{
+			block.Hash.String(),
+			formatFloat(block.MinedAt.Seconds()),
+			strconv.FormatInt(block.TimeInMilliseconds, 10),
+			strconv.FormatUint(uint64(block.Bits), 16),
+			strconv.FormatUint(block.DAAScore, 10),
+			strconv.FormatBool(block.IsAttacker),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
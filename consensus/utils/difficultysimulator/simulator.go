@@ -0,0 +1,345 @@
+package difficultysimulator
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+// maxPoissonLambda is the largest mean drawn at once, since exp(-lambda) underflows for larger means
+const maxPoissonLambda = 500
+
+// Block describes a block mined by the simulation
+type Block struct {
+	Hash               *externalapi.DomainHash
+	MinedAt            time.Duration
+	TimeInMilliseconds int64
+	Bits               uint32
+	DAAScore           uint64
+	IsAttacker         bool
+}
+
+// Sample describes the state of the simulation at some point in the simulated time
+type Sample struct {
+	Elapsed                 time.Duration
+	HonestHashesPerSecond   float64
+	AttackerHashesPerSecond float64
+	// Blocks is the number of blocks mined up to the sample, and BlockRate is the
+	// number of blocks per second mined since the previous sample
+	Blocks    int
+	BlockRate float64
+	// RequiredDifficulty is the difficulty of the headers selected tip, and
+	// HashesPerBlock is the expected number of hashes it takes to find a block
+	// of that difficulty
+	RequiredDifficulty uint32
+	HashesPerBlock     float64
+	// EstimatedHashesPerSecond is the result of EstimateNetworkHashesPerSecond on the
+	// headers selected tip, or zero while there are fewer blocks than its window
+	EstimatedHashesPerSecond uint64
+}
+
+// Result is the outcome of a simulation
+type Result struct {
+	Blocks  []*Block
+	Samples []*Sample
+}
+
+type minedBlock struct {
+	header  externalapi.BlockHeader
+	block   *Block
+	arrives time.Duration
+}
+
+type simulator struct {
+	config     *Config
+	tc         testapi.TestConsensus
+	randSource *rand.Rand
+
+	startTimeInMilliseconds int64
+	tips                    []*externalapi.DomainHash
+	inFlight                []*minedBlock
+	template                externalapi.BlockHeader
+	deliveredBlocks         int
+
+	result *Result
+}
+
+// Run runs a simulation on a fresh in-memory test consensus. Blocks are mined on top of
+// the tips every miner sees, with the difficulty the real difficulty manager requires,
+// and are inserted into the consensus once they propagate.
+func Run(config *Config) (*Result, error) {
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	consensusConfig := *config.ConsensusConfig
+	consensusConfig.SkipProofOfWork = true
+	factory := consensus.NewFactory()
+	factory.SetTestInMemoryDatabase(true)
+	tc, teardown, err := factory.NewTestConsensus(&consensusConfig, "DifficultySimulation")
+	if err != nil {
+		return nil, err
+	}
+	defer teardown(false)
+
+	s := &simulator{
+		config:                  config,
+		tc:                      tc,
+		randSource:              rand.New(rand.NewSource(config.Seed)),
+		startTimeInMilliseconds: consensusConfig.GenesisBlock.Header.TimeInMilliseconds(),
+		tips:                    []*externalapi.DomainHash{consensusConfig.GenesisHash},
+		result:                  &Result{},
+	}
+	err = s.run()
+	if err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+func (s *simulator) run() error {
+	tick := s.config.tick()
+	sampleInterval := s.config.sampleInterval()
+	nextSample := sampleInterval
+	blocksAtPreviousSample := 0
+
+	for elapsed := tick; elapsed <= s.config.Duration; elapsed += tick {
+		err := s.deliverBlocks(elapsed)
+		if err != nil {
+			return err
+		}
+
+		err = s.mineTick(elapsed, tick)
+		if err != nil {
+			return err
+		}
+
+		if elapsed >= nextSample {
+			sample, err := s.sample(elapsed)
+			if err != nil {
+				return err
+			}
+			sample.BlockRate = float64(sample.Blocks-blocksAtPreviousSample) / sampleInterval.Seconds()
+			blocksAtPreviousSample = sample.Blocks
+			s.result.Samples = append(s.result.Samples, sample)
+			nextSample += sampleInterval
+		}
+	}
+	return nil
+}
+
+// deliverBlocks inserts the blocks that propagated by the given time into the consensus
+func (s *simulator) deliverBlocks(elapsed time.Duration) error {
+	delivered := 0
+	for _, mined := range s.inFlight {
+		if mined.arrives > elapsed {
+			break
+		}
+
+		err := s.tc.ValidateAndInsertBlock(&externalapi.DomainBlock{Header: mined.header}, true)
+		if err != nil {
+			return err
+		}
+		s.updateTips(mined.block.Hash, mined.header.DirectParents())
+		s.deliveredBlocks++
+		delivered++
+	}
+
+	if delivered > 0 {
+		s.inFlight = s.inFlight[delivered:]
+		s.template = nil
+	}
+	return nil
+}
+
+func (s *simulator) updateTips(blockHash *externalapi.DomainHash, parents []*externalapi.DomainHash) {
+	newTips := make([]*externalapi.DomainHash, 0, len(s.tips)+1)
+	for _, tip := range s.tips {
+		isParent := false
+		for _, parent := range parents {
+			if tip.Equal(parent) {
+				isParent = true
+				break
+			}
+		}
+		if !isParent {
+			newTips = append(newTips, tip)
+		}
+	}
+	s.tips = append(newTips, blockHash)
+}
+
+// mineTick mines the blocks the honest miners and the attacker find during the tick that ends at elapsed
+func (s *simulator) mineTick(elapsed, tick time.Duration) error {
+	honestHashes := s.config.Honest.HashesPerSecond(elapsed) * tick.Seconds()
+	attackerHashes := float64(0)
+	if s.config.Attacker != nil {
+		attackerHashes = s.config.Attacker.Hashrate.HashesPerSecond(elapsed) * tick.Seconds()
+	}
+	if honestHashes <= 0 && attackerHashes <= 0 {
+		return nil
+	}
+
+	template, err := s.blockTemplate()
+	if err != nil {
+		return err
+	}
+	expectedHashesPerBlock := hashesPerBlock(template.Bits())
+
+	for _, isAttacker := range []bool{false, true} {
+		hashes := honestHashes
+		if isAttacker {
+			hashes = attackerHashes
+		}
+		if hashes <= 0 {
+			continue
+		}
+
+		count := s.poisson(hashes / expectedHashesPerBlock)
+		for i := 0; i < count; i++ {
+			s.mineBlock(template, elapsed, isAttacker)
+		}
+	}
+	return nil
+}
+
+// blockTemplate returns the header every miner mines on at the moment. It's built on
+// the tips with the highest blue work, and its timestamp is the minimal one allowed.
+func (s *simulator) blockTemplate() (externalapi.BlockHeader, error) {
+	if s.template != nil {
+		return s.template, nil
+	}
+
+	parents, err := s.templateParents()
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.tc.BuildHeaderWithParents(parents)
+	if err != nil {
+		return nil, err
+	}
+	s.template = template
+	return template, nil
+}
+
+func (s *simulator) templateParents() ([]*externalapi.DomainHash, error) {
+	stagingArea := model.NewStagingArea()
+	ghostdagDatas := make(map[externalapi.DomainHash]*externalapi.BlockGHOSTDAGData, len(s.tips))
+	for _, tip := range s.tips {
+		ghostdagData, err := s.tc.GHOSTDAGDataStore().Get(s.tc.DatabaseContext(), stagingArea, tip, false)
+		if err != nil {
+			return nil, err
+		}
+		ghostdagDatas[*tip] = ghostdagData
+	}
+
+	parents := make([]*externalapi.DomainHash, len(s.tips))
+	copy(parents, s.tips)
+	sort.Slice(parents, func(i, j int) bool {
+		blueWorkComparison := ghostdagDatas[*parents[i]].BlueWork().Cmp(ghostdagDatas[*parents[j]].BlueWork())
+		if blueWorkComparison != 0 {
+			return blueWorkComparison > 0
+		}
+		return parents[i].Less(parents[j])
+	})
+
+	maxBlockParents := int(s.config.params().MaxBlockParents)
+	if len(parents) > maxBlockParents {
+		parents = parents[:maxBlockParents]
+	}
+	return parents, nil
+}
+
+func (s *simulator) mineBlock(template externalapi.BlockHeader, elapsed time.Duration, isAttacker bool) {
+	minTimeInMilliseconds := template.TimeInMilliseconds()
+	clockTimeInMilliseconds := s.startTimeInMilliseconds + elapsed.Milliseconds()
+
+	timeInMilliseconds := clockTimeInMilliseconds
+	if isAttacker {
+		switch s.config.Attacker.Strategy {
+		case TimestampStrategyFuture:
+			timeInMilliseconds = clockTimeInMilliseconds + s.config.maxTimestampOffset().Milliseconds()
+		case TimestampStrategyPast:
+			timeInMilliseconds = minTimeInMilliseconds
+		}
+	}
+	if timeInMilliseconds < minTimeInMilliseconds {
+		timeInMilliseconds = minTimeInMilliseconds
+	}
+
+	mutableHeader := template.ToMutable()
+	mutableHeader.SetTimeInMilliseconds(timeInMilliseconds)
+	mutableHeader.SetNonce(s.randSource.Uint64())
+	header := mutableHeader.ToImmutable()
+
+	block := &Block{
+		Hash:               consensushashing.HeaderHash(header),
+		MinedAt:            elapsed,
+		TimeInMilliseconds: timeInMilliseconds,
+		Bits:               header.Bits(),
+		DAAScore:           header.DAAScore(),
+		IsAttacker:         isAttacker,
+	}
+	s.result.Blocks = append(s.result.Blocks, block)
+	s.inFlight = append(s.inFlight, &minedBlock{
+		header:  header,
+		block:   block,
+		arrives: elapsed + s.config.PropagationDelay,
+	})
+}
+
+func (s *simulator) sample(elapsed time.Duration) (*Sample, error) {
+	sample := &Sample{
+		Elapsed:               elapsed,
+		HonestHashesPerSecond: s.config.Honest.HashesPerSecond(elapsed),
+		Blocks:                len(s.result.Blocks),
+	}
+	if s.config.Attacker != nil {
+		sample.AttackerHashesPerSecond = s.config.Attacker.Hashrate.HashesPerSecond(elapsed)
+	}
+
+	stagingArea := model.NewStagingArea()
+	selectedTip, err := s.tc.GHOSTDAGManager().ChooseSelectedParent(stagingArea, s.tips...)
+	if err != nil {
+		return nil, err
+	}
+	sample.RequiredDifficulty, err = s.tc.DifficultyManager().RequiredDifficulty(stagingArea, selectedTip)
+	if err != nil {
+		return nil, err
+	}
+	sample.HashesPerBlock = hashesPerBlock(sample.RequiredDifficulty)
+
+	windowSize := s.config.hashrateEstimationWindowSize()
+	if s.deliveredBlocks >= windowSize {
+		sample.EstimatedHashesPerSecond, err = s.tc.EstimateNetworkHashesPerSecond(selectedTip, windowSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sample, nil
+}
+
+// poisson draws the number of blocks found when lambda blocks are expected
+func (s *simulator) poisson(lambda float64) int {
+	count := 0
+	for lambda > 0 {
+		chunk := math.Min(lambda, maxPoissonLambda)
+		lambda -= chunk
+
+		limit := math.Exp(-chunk)
+		product := s.randSource.Float64()
+		for product > limit {
+			count++
+			product *= s.randSource.Float64()
+		}
+	}
+	return count
+}
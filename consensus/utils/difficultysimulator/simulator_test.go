@@ -0,0 +1,135 @@
+package difficultysimulator
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+func TestStepHashrate(t *testing.T) {
+	timeline := NewStepHashrate(
+		HashrateStep{At: 10 * time.Second, HashesPerSecond: 20},
+		HashrateStep{At: 0, HashesPerSecond: 10},
+	)
+	tests := []struct {
+		elapsed  time.Duration
+		expected float64
+	}{
+		{elapsed: 0, expected: 10},
+		{elapsed: 9 * time.Second, expected: 10},
+		{elapsed: 10 * time.Second, expected: 20},
+		{elapsed: time.Hour, expected: 20},
+	}
+	for _, test := range tests {
+		hashesPerSecond := timeline.HashesPerSecond(test.elapsed)
+		if hashesPerSecond != test.expected {
+			t.Fatalf("TestStepHashrate: unexpected hashrate at %s: want: %f, got: %f",
+				test.elapsed, test.expected, hashesPerSecond)
+		}
+	}
+}
+
+func TestChurningMiners(t *testing.T) {
+	const duration = 10 * time.Hour
+	miners := NewChurningMiners(rand.New(rand.NewSource(0)), 100, 1, duration, time.Hour, time.Hour)
+
+	// With equal online and offline means, about half of the miners mine at any time
+	for elapsed := time.Duration(0); elapsed < duration; elapsed += time.Hour {
+		hashesPerSecond := miners.HashesPerSecond(elapsed)
+		if hashesPerSecond < 25 || hashesPerSecond > 75 {
+			t.Fatalf("TestChurningMiners: unexpected hashrate at %s: %f", elapsed, hashesPerSecond)
+		}
+	}
+}
+
+func TestSimulateHashrateStep(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.DifficultyAdjustmentWindowSize = 140
+
+	genesisHashesPerSecond := GenesisHashesPerSecond(&consensusConfig.Params)
+	result, err := Run(&Config{
+		ConsensusConfig:  consensusConfig,
+		Duration:         30 * time.Minute,
+		PropagationDelay: 200 * time.Millisecond,
+		Honest: NewStepHashrate(
+			HashrateStep{At: 0, HashesPerSecond: genesisHashesPerSecond},
+			HashrateStep{At: 10 * time.Minute, HashesPerSecond: 4 * genesisHashesPerSecond},
+		),
+		Seed: 1,
+	})
+	if err != nil {
+		t.Fatalf("Run: %+v", err)
+	}
+
+	beforeStep := result.Samples[len(result.Samples)/3-1]
+	last := result.Samples[len(result.Samples)-1]
+	if last.HashesPerBlock < 2*beforeStep.HashesPerBlock {
+		t.Fatalf("TestSimulateHashrateStep: expected the difficulty to follow the hashrate: "+
+			"hashes per block before the step: %f, at the end: %f", beforeStep.HashesPerBlock, last.HashesPerBlock)
+	}
+	if last.BlockRate < 0.5 || last.BlockRate > 2 {
+		t.Fatalf("TestSimulateHashrateStep: expected the block rate to return to the target, got: %f", last.BlockRate)
+	}
+	if last.EstimatedHashesPerSecond == 0 {
+		t.Fatalf("TestSimulateHashrateStep: expected a hashrate estimation at the end of the simulation")
+	}
+
+	var samplesCSV bytes.Buffer
+	err = result.WriteSamplesCSV(&samplesCSV)
+	if err != nil {
+		t.Fatalf("WriteSamplesCSV: %+v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(samplesCSV.String()), "\n")
+	if len(lines) != len(result.Samples)+1 {
+		t.Fatalf("TestSimulateHashrateStep: unexpected CSV line count: want: %d, got: %d",
+			len(result.Samples)+1, len(lines))
+	}
+}
+
+func TestSimulateFutureTimestampAttacker(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.DifficultyAdjustmentWindowSize = 140
+
+	genesisHashesPerSecond := GenesisHashesPerSecond(&consensusConfig.Params)
+	simulate := func(honest HashrateTimeline, attacker *Attacker) *Result {
+		result, err := Run(&Config{
+			ConsensusConfig: consensusConfig,
+			Duration:        10 * time.Minute,
+			Honest:          honest,
+			Attacker:        attacker,
+			Seed:            2,
+		})
+		if err != nil {
+			t.Fatalf("Run: %+v", err)
+		}
+		return result
+	}
+
+	withoutAttacker := simulate(ConstantHashrate(genesisHashesPerSecond), nil)
+	withAttacker := simulate(ConstantHashrate(genesisHashesPerSecond*3/4),
+		&Attacker{Hashrate: ConstantHashrate(genesisHashesPerSecond / 4), Strategy: TimestampStrategyFuture})
+
+	attackerBlocks := 0
+	for _, block := range withAttacker.Blocks {
+		if block.IsAttacker {
+			attackerBlocks++
+		}
+	}
+	if attackerBlocks == 0 {
+		t.Fatalf("TestSimulateFutureTimestampAttacker: expected the attacker to mine blocks")
+	}
+
+	// Future timestamps stretch the DAA window, so the same total hashrate ends up with a lower difficulty
+	lastWithoutAttacker := withoutAttacker.Samples[len(withoutAttacker.Samples)-1]
+	lastWithAttacker := withAttacker.Samples[len(withAttacker.Samples)-1]
+	if lastWithAttacker.HashesPerBlock >= lastWithoutAttacker.HashesPerBlock {
+		t.Fatalf("TestSimulateFutureTimestampAttacker: expected future timestamps to lower the difficulty: "+
+			"hashes per block without an attacker: %f, with an attacker: %f",
+			lastWithoutAttacker.HashesPerBlock, lastWithAttacker.HashesPerBlock)
+	}
+}
@@ -0,0 +1,41 @@
+package emission
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+var csvHeader = []string{"month", "start_daa_score", "end_daa_score", "block_subsidy", "period_subsidy", "supply_at_end"}
+
+// WriteCSV writes the given periods as CSV, with a header line
+func WriteCSV(writer io.Writer, periods []*Period) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, period := range periods {
+		err := csvWriter.Write([]string{
+			strconv.FormatInt(period.Month, 10),
+			strconv.FormatUint(period.StartDAAScore, 10),
+			strconv.FormatUint(period.EndDAAScore, 10),
+			strconv.FormatUint(period.BlockSubsidy, 10),
+			strconv.FormatUint(period.PeriodSubsidy, 10),
+			strconv.FormatUint(period.SupplyAtEnd, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteJSON writes the given periods as an indented JSON array
+func WriteJSON(writer io.Writer, periods []*Period) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(periods)
+}
@@ -0,0 +1,147 @@
+package emission
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+// Schedule calculates the emission of a network according to its subsidy parameters.
+//
+// The genesis block, at DAA score 0, is rewarded with the genesis subsidy, and every other
+// DAA score is assumed to be reached by exactly one rewarded block. Blocks that are merged
+// without being added to the DAA window of the merging block aren't rewarded, and don't
+// increase the DAA score either.
+type Schedule struct {
+	subsidyGenesisReward            uint64
+	preDeflationaryPhaseBaseSubsidy uint64
+	deflationaryPhaseDAAScore       uint64
+	deflationaryPhaseBaseSubsidy    uint64
+}
+
+// NewSchedule returns the emission schedule of the network with the given params
+func NewSchedule(params *dagconfig.Params) *Schedule {
+	return &Schedule{
+		subsidyGenesisReward:            params.SubsidyGenesisReward,
+		preDeflationaryPhaseBaseSubsidy: params.PreDeflationaryPhaseBaseSubsidy,
+		deflationaryPhaseDAAScore:       params.DeflationaryPhaseDaaScore,
+		deflationaryPhaseBaseSubsidy:    params.DeflationaryPhaseBaseSubsidy,
+	}
+}
+
+// Period is a range of DAA scores in which every block has the same subsidy
+type Period struct {
+	// Month is the month of the deflationary phase, or -1 for the pre-deflationary phase
+	Month int64 `json:"month"`
+
+	// StartDAAScore is inclusive and EndDAAScore is exclusive
+	StartDAAScore uint64 `json:"startDaaScore"`
+	EndDAAScore   uint64 `json:"endDaaScore"`
+
+	BlockSubsidy  uint64 `json:"blockSubsidy"`
+	PeriodSubsidy uint64 `json:"periodSubsidy"`
+
+	// SupplyAtEnd is the supply issued by all the blocks up to and including EndDAAScore - 1
+	SupplyAtEnd uint64 `json:"supplyAtEnd"`
+}
+
+// BlockSubsidy returns the subsidy of a block with the given DAA score
+func (s *Schedule) BlockSubsidy(daaScore uint64) uint64 {
+	if daaScore == 0 {
+		return s.subsidyGenesisReward
+	}
+	if daaScore < s.deflationaryPhaseDAAScore {
+		return s.preDeflationaryPhaseBaseSubsidy
+	}
+	return DeflationaryMonthBlockSubsidy(DeflationaryMonth(s.deflationaryPhaseDAAScore, daaScore))
+}
+
+// Periods returns the pre-deflationary phase followed by every month of the deflationary
+// phase that has a non-zero subsidy
+func (s *Schedule) Periods() []*Period {
+	periods := make([]*Period, 0, DeflationaryMonthsCount()+1)
+	supply := s.subsidyGenesisReward
+
+	if s.deflationaryPhaseDAAScore > 1 {
+		periodSubsidy := (s.deflationaryPhaseDAAScore - 1) * s.preDeflationaryPhaseBaseSubsidy
+		supply += periodSubsidy
+		periods = append(periods, &Period{
+			Month:         -1,
+			StartDAAScore: 1,
+			EndDAAScore:   s.deflationaryPhaseDAAScore,
+			BlockSubsidy:  s.preDeflationaryPhaseBaseSubsidy,
+			PeriodSubsidy: periodSubsidy,
+			SupplyAtEnd:   supply,
+		})
+	}
+
+	for month := uint64(0); month < DeflationaryMonthsCount(); month++ {
+		startDAAScore := s.deflationaryPhaseDAAScore + month*DAAScorePerDeflationaryMonth
+		endDAAScore := startDAAScore + DAAScorePerDeflationaryMonth
+		if startDAAScore == 0 {
+			// The genesis block is rewarded with the genesis subsidy instead
+			startDAAScore = 1
+		}
+
+		blockSubsidy := DeflationaryMonthBlockSubsidy(month)
+		periodSubsidy := (endDAAScore - startDAAScore) * blockSubsidy
+		supply += periodSubsidy
+		periods = append(periods, &Period{
+			Month:         int64(month),
+			StartDAAScore: startDAAScore,
+			EndDAAScore:   endDAAScore,
+			BlockSubsidy:  blockSubsidy,
+			PeriodSubsidy: periodSubsidy,
+			SupplyAtEnd:   supply,
+		})
+	}
+
+	return periods
+}
+
+// Supply returns the supply issued by all the blocks up to and including the given DAA score
+func (s *Schedule) Supply(daaScore uint64) uint64 {
+	supply := s.subsidyGenesisReward
+	for _, period := range s.Periods() {
+		if daaScore < period.StartDAAScore {
+			break
+		}
+		if daaScore >= period.EndDAAScore {
+			supply = period.SupplyAtEnd
+			continue
+		}
+		supply += (daaScore - period.StartDAAScore + 1) * period.BlockSubsidy
+		break
+	}
+	return supply
+}
+
+// TotalSupply returns the supply issued once the subsidy reaches zero
+func (s *Schedule) TotalSupply() uint64 {
+	periods := s.Periods()
+	if len(periods) == 0 {
+		return s.subsidyGenesisReward
+	}
+	return periods[len(periods)-1].SupplyAtEnd
+}
+
+// CrossCheck verifies that the subsidy table, which is what consensus uses, matches
+// DeflationaryMonthBlockSubsidyFloatCalc with the deflationary phase base subsidy of the schedule
+func (s *Schedule) CrossCheck() error {
+	mismatchCount := 0
+	var firstMismatch error
+	for month := uint64(0); month <= DeflationaryMonthsCount(); month++ {
+		tableSubsidy := DeflationaryMonthBlockSubsidy(month)
+		calculatedSubsidy := DeflationaryMonthBlockSubsidyFloatCalc(s.deflationaryPhaseBaseSubsidy, month)
+		if tableSubsidy != calculatedSubsidy {
+			if firstMismatch == nil {
+				firstMismatch = errors.Errorf("the subsidy table has %d for month %d but %d was calculated",
+					tableSubsidy, month, calculatedSubsidy)
+			}
+			mismatchCount++
+		}
+	}
+	if firstMismatch != nil {
+		return errors.Wrapf(firstMismatch, "%d months of the subsidy table mismatch the calculation", mismatchCount)
+	}
+	return nil
+}
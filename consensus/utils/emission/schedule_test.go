@@ -0,0 +1,132 @@
+package emission
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+)
+
+var allParams = []*dagconfig.Params{
+	&dagconfig.MainnetParams,
+	&dagconfig.TestnetParams,
+	&dagconfig.SimnetParams,
+	&dagconfig.DevnetParams,
+}
+
+func TestCrossCheck(t *testing.T) {
+	for _, params := range allParams {
+		err := NewSchedule(params).CrossCheck()
+		if err != nil {
+			t.Fatalf("%s: CrossCheck: %+v", params.Name, err)
+		}
+	}
+
+	params := dagconfig.MainnetParams
+	params.DeflationaryPhaseBaseSubsidy = 40 * constants.SompiPerKarlsen
+	err := NewSchedule(&params).CrossCheck()
+	if err == nil {
+		t.Fatalf("expected a different base subsidy to mismatch the subsidy table")
+	}
+}
+
+func TestSupplyNeverExceedsMaxSompi(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	for _, params := range allParams {
+		schedule := NewSchedule(params)
+		totalSupply := schedule.TotalSupply()
+		if totalSupply > constants.MaxSompi {
+			t.Fatalf("%s: the total supply %d exceeds the max sompi %d", params.Name, totalSupply, constants.MaxSompi)
+		}
+		if schedule.Supply(math.MaxUint64) != totalSupply {
+			t.Fatalf("%s: expected the supply to reach the total supply", params.Name)
+		}
+
+		daaScores := []uint64{0, 1, params.DeflationaryPhaseDaaScore - 1, params.DeflationaryPhaseDaaScore}
+		for _, period := range schedule.Periods() {
+			daaScores = append(daaScores, period.StartDAAScore, period.EndDAAScore-1, period.EndDAAScore)
+		}
+		maxDAAScore := params.DeflationaryPhaseDaaScore + (DeflationaryMonthsCount()+1)*DAAScorePerDeflationaryMonth
+		for i := 0; i < 1000; i++ {
+			daaScores = append(daaScores, uint64(random.Int63n(int64(maxDAAScore))))
+		}
+
+		for _, daaScore := range daaScores {
+			supply := schedule.Supply(daaScore)
+			if supply > totalSupply {
+				t.Fatalf("%s: the supply %d at DAA score %d exceeds the total supply %d",
+					params.Name, supply, daaScore, totalSupply)
+			}
+			if daaScore == 0 {
+				continue
+			}
+			previousSupply := schedule.Supply(daaScore - 1)
+			if supply-previousSupply != schedule.BlockSubsidy(daaScore) {
+				t.Fatalf("%s: expected the supply to grow by the block subsidy %d at DAA score %d, but it grew by %d",
+					params.Name, schedule.BlockSubsidy(daaScore), daaScore, supply-previousSupply)
+			}
+		}
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	schedule := NewSchedule(&dagconfig.MainnetParams)
+	periods := schedule.Periods()
+	if uint64(len(periods)) != DeflationaryMonthsCount()+1 {
+		t.Fatalf("expected the pre-deflationary phase and %d months, got %d periods",
+			DeflationaryMonthsCount(), len(periods))
+	}
+
+	supply := dagconfig.MainnetParams.SubsidyGenesisReward
+	previousEndDAAScore := uint64(1)
+	for _, period := range periods {
+		if period.StartDAAScore != previousEndDAAScore {
+			t.Fatalf("expected month %d to start at DAA score %d, but it starts at %d",
+				period.Month, previousEndDAAScore, period.StartDAAScore)
+		}
+		if period.BlockSubsidy == 0 {
+			t.Fatalf("expected month %d to have a non-zero subsidy", period.Month)
+		}
+		supply += period.PeriodSubsidy
+		if period.SupplyAtEnd != supply || schedule.Supply(period.EndDAAScore-1) != supply {
+			t.Fatalf("expected the supply at the end of month %d to be %d", period.Month, supply)
+		}
+		previousEndDAAScore = period.EndDAAScore
+	}
+	if schedule.BlockSubsidy(previousEndDAAScore) != 0 {
+		t.Fatalf("expected the subsidy to be zero after the last period")
+	}
+}
+
+func TestOutput(t *testing.T) {
+	periods := NewSchedule(&dagconfig.MainnetParams).Periods()
+
+	var csvBuffer bytes.Buffer
+	err := WriteCSV(&csvBuffer, periods)
+	if err != nil {
+		t.Fatalf("WriteCSV: %+v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvBuffer.String()), "\n")
+	if len(lines) != len(periods)+1 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("expected a header line and a line per period, got %d lines", len(lines))
+	}
+
+	var jsonBuffer bytes.Buffer
+	err = WriteJSON(&jsonBuffer, periods)
+	if err != nil {
+		t.Fatalf("WriteJSON: %+v", err)
+	}
+	var decodedPeriods []*Period
+	err = json.Unmarshal(jsonBuffer.Bytes(), &decodedPeriods)
+	if err != nil {
+		t.Fatalf("Unmarshal: %+v", err)
+	}
+	if len(decodedPeriods) != len(periods) || *decodedPeriods[1] != *periods[1] {
+		t.Fatalf("expected the periods to survive a JSON round trip")
+	}
+}
@@ -2,6 +2,7 @@ package merkle
 
 import (
 	"math"
+	"sort"
 
 	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
 	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
@@ -59,8 +60,39 @@ func CalculateIDMerkleRoot(transactions []*externalapi.DomainTransaction) *exter
 	return merkleRoot(txIDs)
 }
 
+// AcceptedTransactions returns the transactions accepted by the given acceptance data, sorted by ID.
+// This is the order the accepted ID merkle root of a block is calculated over.
+func AcceptedTransactions(acceptanceData externalapi.AcceptanceData) []*externalapi.DomainTransaction {
+	var acceptedTransactions []*externalapi.DomainTransaction
+	for _, blockAcceptanceData := range acceptanceData {
+		for _, transactionAcceptance := range blockAcceptanceData.TransactionAcceptanceData {
+			if !transactionAcceptance.IsAccepted {
+				continue
+			}
+			acceptedTransactions = append(acceptedTransactions, transactionAcceptance.Transaction)
+		}
+	}
+	sort.Slice(acceptedTransactions, func(i, j int) bool {
+		return consensushashing.TransactionID(acceptedTransactions[i]).Less(
+			consensushashing.TransactionID(acceptedTransactions[j]))
+	})
+	return acceptedTransactions
+}
+
+// CalculateAcceptedIDMerkleRoot calculates the accepted ID merkle root of a block with the given acceptance data
+func CalculateAcceptedIDMerkleRoot(acceptanceData externalapi.AcceptanceData) *externalapi.DomainHash {
+	return CalculateIDMerkleRoot(AcceptedTransactions(acceptanceData))
+}
+
 // merkleRoot creates a merkle tree from a slice of hashes, and returns its root.
 func merkleRoot(hashes []*externalapi.DomainHash) *externalapi.DomainHash {
+	merkles := merkleTree(hashes)
+	return merkles[len(merkles)-1]
+}
+
+// merkleTree creates a merkle tree from a slice of hashes, and returns it as a linear
+// array. The leaves come first, followed by every level of the tree up to the root.
+func merkleTree(hashes []*externalapi.DomainHash) []*externalapi.DomainHash {
 	// Calculate how many entries are required to hold the binary merkle
 	// tree as a linear array and create an array of that size.
 	nextPoT := nextPowerOfTwo(len(hashes))
@@ -96,5 +128,5 @@ func merkleRoot(hashes []*externalapi.DomainHash) *externalapi.DomainHash {
 		offset++
 	}
 
-	return merkles[len(merkles)-1]
+	return merkles
 }
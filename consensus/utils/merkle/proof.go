@@ -0,0 +1,109 @@
+package merkle
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+// CalculateHashMerkleProof returns a proof that the hash of the transaction at the given index
+// is included in the merkle root calculated by CalculateHashMerkleRoot
+func CalculateHashMerkleProof(transactions []*externalapi.DomainTransaction, index int) (
+	*externalapi.MerkleProof, error) {
+
+	txHashes := make([]*externalapi.DomainHash, len(transactions))
+	for i, tx := range transactions {
+		txHashes[i] = consensushashing.TransactionHash(tx)
+	}
+	return merkleProof(txHashes, index)
+}
+
+// CalculateIDMerkleProof returns a proof that the ID of the transaction at the given index
+// is included in the merkle root calculated by CalculateIDMerkleRoot
+func CalculateIDMerkleProof(transactions []*externalapi.DomainTransaction, index int) (
+	*externalapi.MerkleProof, error) {
+
+	txIDs := make([]*externalapi.DomainHash, len(transactions))
+	for i, tx := range transactions {
+		txIDs[i] = (*externalapi.DomainHash)(consensushashing.TransactionID(tx))
+	}
+	return merkleProof(txIDs, index)
+}
+
+// merkleProof creates a merkle tree from a slice of hashes, and returns the proof of the
+// hash at the given index
+func merkleProof(hashes []*externalapi.DomainHash, index int) (*externalapi.MerkleProof, error) {
+	if index < 0 || index >= len(hashes) {
+		return nil, errors.Errorf("index %d is out of range of %d hashes", index, len(hashes))
+	}
+
+	merkles := merkleTree(hashes)
+	proof := &externalapi.MerkleProof{LeafIndex: uint32(index)}
+	levelOffset := 0
+	for levelWidth := nextPowerOfTwo(len(hashes)); levelWidth > 1; levelWidth /= 2 {
+		sibling := merkles[levelOffset+(index^1)]
+
+		// Nodes are filled from the left, so only a right sibling may be missing,
+		// in which case the parent was hashed with zeros
+		if sibling == nil {
+			sibling = &externalapi.DomainHash{}
+		}
+		proof.Siblings = append(proof.Siblings, sibling)
+
+		levelOffset += levelWidth
+		index /= 2
+	}
+	return proof, nil
+}
+
+// MerkleProofRoot returns the merkle root the given proof leads to from the given leaf
+func MerkleProofRoot(leaf *externalapi.DomainHash, proof *externalapi.MerkleProof) (*externalapi.DomainHash, error) {
+	if len(proof.Siblings) > externalapi.MaxMerkleProofDepth {
+		return nil, errors.Errorf("merkle proof has %d siblings, more than the maximum of %d",
+			len(proof.Siblings), externalapi.MaxMerkleProofDepth)
+	}
+	// Any index bits above the depth of the tree would be ignored, so a proof that
+	// has them is rejected rather than accepted for a leaf it doesn't describe
+	if uint64(proof.LeafIndex) >= uint64(1)<<len(proof.Siblings) {
+		return nil, errors.Errorf("leaf index %d is out of range of a merkle tree of depth %d",
+			proof.LeafIndex, len(proof.Siblings))
+	}
+
+	current := leaf
+	index := proof.LeafIndex
+	for _, sibling := range proof.Siblings {
+		if index%2 == 0 {
+			current = hashMerkleBranches(current, sibling)
+		} else {
+			current = hashMerkleBranches(sibling, current)
+		}
+		index /= 2
+	}
+	return current, nil
+}
+
+// VerifyMerkleProof returns whether the given proof proves that leaf is included in the
+// merkle tree of the given root
+func VerifyMerkleProof(leaf, root *externalapi.DomainHash, proof *externalapi.MerkleProof) bool {
+	proofRoot, err := MerkleProofRoot(leaf, proof)
+	if err != nil {
+		return false
+	}
+	return proofRoot.Equal(root)
+}
+
+// VerifyHashMerkleProof returns whether the given proof proves that transaction is included
+// in a block whose hash merkle root is hashMerkleRoot
+func VerifyHashMerkleProof(transaction *externalapi.DomainTransaction, hashMerkleRoot *externalapi.DomainHash,
+	proof *externalapi.MerkleProof) bool {
+
+	return VerifyMerkleProof(consensushashing.TransactionHash(transaction), hashMerkleRoot, proof)
+}
+
+// VerifyIDMerkleProof returns whether the given proof proves that the ID of transaction is
+// included in the merkle tree of the given root, such as an accepted ID merkle root
+func VerifyIDMerkleProof(transaction *externalapi.DomainTransaction, idMerkleRoot *externalapi.DomainHash,
+	proof *externalapi.MerkleProof) bool {
+
+	return VerifyMerkleProof((*externalapi.DomainHash)(consensushashing.TransactionID(transaction)), idMerkleRoot, proof)
+}
@@ -0,0 +1,115 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/subnetworks"
+)
+
+func testTransactions(count int) []*externalapi.DomainTransaction {
+	transactions := make([]*externalapi.DomainTransaction, count)
+	for i := range transactions {
+		transactions[i] = &externalapi.DomainTransaction{
+			Version:      0,
+			Inputs:       []*externalapi.DomainTransactionInput{},
+			Outputs:      []*externalapi.DomainTransactionOutput{},
+			SubnetworkID: subnetworks.SubnetworkIDNative,
+			Payload:      []byte{byte(i), byte(i >> 8)},
+		}
+	}
+	return transactions
+}
+
+func TestMerkleProofs(t *testing.T) {
+	// Cover full trees as well as every padding pattern of the trees in between
+	for count := 1; count <= 17; count++ {
+		transactions := testTransactions(count)
+		hashMerkleRoot := CalculateHashMerkleRoot(transactions)
+		idMerkleRoot := CalculateIDMerkleRoot(transactions)
+
+		for i, transaction := range transactions {
+			hashProof, err := CalculateHashMerkleProof(transactions, i)
+			if err != nil {
+				t.Fatalf("CalculateHashMerkleProof: %+v", err)
+			}
+			if !VerifyHashMerkleProof(transaction, hashMerkleRoot, hashProof) {
+				t.Fatalf("TestMerkleProofs: hash proof of transaction %d out of %d doesn't verify", i, count)
+			}
+			idProof, err := CalculateIDMerkleProof(transactions, i)
+			if err != nil {
+				t.Fatalf("CalculateIDMerkleProof: %+v", err)
+			}
+			if !VerifyIDMerkleProof(transaction, idMerkleRoot, idProof) {
+				t.Fatalf("TestMerkleProofs: ID proof of transaction %d out of %d doesn't verify", i, count)
+			}
+
+			// A proof is bound to its leaf and its position
+			other := transactions[(i+1)%count]
+			if count > 1 && VerifyHashMerkleProof(other, hashMerkleRoot, hashProof) {
+				t.Fatalf("TestMerkleProofs: hash proof of transaction %d out of %d verifies another transaction", i, count)
+			}
+			if count > 1 {
+				movedProof := hashProof.Clone()
+				movedProof.LeafIndex ^= 1
+				if VerifyHashMerkleProof(transaction, hashMerkleRoot, movedProof) {
+					t.Fatalf("TestMerkleProofs: hash proof of transaction %d out of %d verifies at another index", i, count)
+				}
+			}
+			highIndexProof := hashProof.Clone()
+			highIndexProof.LeafIndex |= 1 << len(highIndexProof.Siblings)
+			if VerifyHashMerkleProof(transaction, hashMerkleRoot, highIndexProof) {
+				t.Fatalf("TestMerkleProofs: hash proof of transaction %d out of %d verifies with an index "+
+					"beyond the tree depth", i, count)
+			}
+		}
+	}
+}
+
+func TestMerkleProofOutOfRange(t *testing.T) {
+	transactions := testTransactions(3)
+	_, err := CalculateHashMerkleProof(transactions, 3)
+	if err == nil {
+		t.Fatalf("TestMerkleProofOutOfRange: expected an error for an index past the transactions")
+	}
+	_, err = CalculateIDMerkleProof(nil, 0)
+	if err == nil {
+		t.Fatalf("TestMerkleProofOutOfRange: expected an error for an empty tree")
+	}
+}
+
+func TestMerkleProofSerialization(t *testing.T) {
+	transactions := testTransactions(11)
+	idMerkleRoot := CalculateIDMerkleRoot(transactions)
+	proof, err := CalculateIDMerkleProof(transactions, 10)
+	if err != nil {
+		t.Fatalf("CalculateIDMerkleProof: %+v", err)
+	}
+
+	serialized := proof.Serialize()
+	expectedLength := 1 + 4 + 1 + len(proof.Siblings)*externalapi.DomainHashSize
+	if len(serialized) != expectedLength {
+		t.Fatalf("TestMerkleProofSerialization: unexpected length: want: %d, got: %d", expectedLength, len(serialized))
+	}
+	deserialized, err := externalapi.DeserializeMerkleProof(serialized)
+	if err != nil {
+		t.Fatalf("DeserializeMerkleProof: %+v", err)
+	}
+	if !deserialized.Equal(proof) {
+		t.Fatalf("TestMerkleProofSerialization: the deserialized proof differs from the original")
+	}
+	if !VerifyIDMerkleProof(transactions[10], idMerkleRoot, deserialized) {
+		t.Fatalf("TestMerkleProofSerialization: the deserialized proof doesn't verify")
+	}
+
+	_, err = externalapi.DeserializeMerkleProof(serialized[:len(serialized)-1])
+	if err == nil {
+		t.Fatalf("TestMerkleProofSerialization: expected an error for a truncated proof")
+	}
+	unknownVersion := append([]byte{}, serialized...)
+	unknownVersion[0]++
+	_, err = externalapi.DeserializeMerkleProof(unknownVersion)
+	if err == nil {
+		t.Fatalf("TestMerkleProofSerialization: expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,99 @@
+package pruningpointproof
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// Serialize encodes a pruning point proof with its canonical binary layout: the magic and the
+// version, followed by the number of levels, and for every level the number of headers followed
+// by every header as a length-prefixed compact header record. All integers are little-endian.
+func Serialize(proof *externalapi.PruningPointProof) ([]byte, error) {
+	serialized := append([]byte{}, magic[:]...)
+	serialized = append(serialized, Version)
+	serialized = binary.LittleEndian.AppendUint32(serialized, uint32(len(proof.Headers)))
+	for _, levelHeaders := range proof.Headers {
+		serialized = binary.LittleEndian.AppendUint32(serialized, uint32(len(levelHeaders)))
+		for _, header := range levelHeaders {
+			headerBytes, err := binaryserialization.SerializeBlockHeader(header)
+			if err != nil {
+				return nil, err
+			}
+			serialized = binary.LittleEndian.AppendUint32(serialized, uint32(len(headerBytes)))
+			serialized = append(serialized, headerBytes...)
+		}
+	}
+	return serialized, nil
+}
+
+// Deserialize decodes a pruning point proof that was encoded by Serialize
+func Deserialize(serialized []byte) (*externalapi.PruningPointProof, error) {
+	if len(serialized) < len(magic)+1 || [len(magic)]byte(serialized[:len(magic)]) != magic {
+		return nil, errors.Wrap(ErrMalformedProof, "not a binary pruning point proof")
+	}
+	if serialized[len(magic)] != Version {
+		return nil, errors.Errorf("unsupported pruning point proof version %d, expected %d",
+			serialized[len(magic)], Version)
+	}
+	r := &reader{serialized: serialized, offset: len(magic) + 1}
+
+	// Every level takes at least the 4 bytes of its header count, and every header
+	// at least the 4 bytes of its length
+	levelCount, err := r.readCount(4)
+	if err != nil {
+		return nil, err
+	}
+	proof := &externalapi.PruningPointProof{Headers: make([][]externalapi.BlockHeader, levelCount)}
+	for level := range proof.Headers {
+		headerCount, err := r.readCount(4)
+		if err != nil {
+			return nil, err
+		}
+		proof.Headers[level] = make([]externalapi.BlockHeader, headerCount)
+		for i := range proof.Headers[level] {
+			headerLength, err := r.readCount(1)
+			if err != nil {
+				return nil, err
+			}
+			header, err := binaryserialization.DeserializeBlockHeader(r.next(headerLength))
+			if err != nil {
+				return nil, errors.Wrapf(ErrMalformedProof, "header %d of level %d: %s", i, level, err)
+			}
+			proof.Headers[level][i] = header
+		}
+	}
+	if r.offset != len(serialized) {
+		return nil, errors.Wrapf(ErrMalformedProof, "the proof has %d trailing bytes", len(serialized)-r.offset)
+	}
+	return proof, nil
+}
+
+type reader struct {
+	serialized []byte
+	offset     int
+}
+
+// readCount reads a uint32 count and rejects it if the remaining bytes can't hold
+// count elements of at least elementSize bytes
+func (r *reader) readCount(elementSize int) (int, error) {
+	if len(r.serialized)-r.offset < 4 {
+		return 0, errors.Wrapf(ErrMalformedProof, "the proof is truncated at offset %d", r.offset)
+	}
+	count := binary.LittleEndian.Uint32(r.serialized[r.offset:])
+	r.offset += 4
+	if uint64(count)*uint64(elementSize) > uint64(len(r.serialized)-r.offset) {
+		return 0, errors.Wrapf(ErrMalformedProof, "count %d at offset %d exceeds the proof length",
+			count, r.offset-4)
+	}
+	return int(count), nil
+}
+
+// next returns the following length bytes. The length must already have been checked by readCount.
+func (r *reader) next(length int) []byte {
+	bytes := r.serialized[r.offset : r.offset+length]
+	r.offset += length
+	return bytes
+}
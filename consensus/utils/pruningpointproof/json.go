@@ -0,0 +1,164 @@
+package pruningpointproof
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+// jsonProof is the JSON representation of a pruning point proof
+type jsonProof struct {
+	Version uint8           `json:"version"`
+	Levels  [][]*jsonHeader `json:"levels"`
+}
+
+// jsonHeader is the JSON representation of a block header. Hashes are hex encoded, and so is
+// the blue work. Hash is redundant, and is checked against the other fields when decoding.
+type jsonHeader struct {
+	Hash                 string     `json:"hash"`
+	Version              uint16     `json:"version"`
+	Parents              [][]string `json:"parents"`
+	HashMerkleRoot       string     `json:"hashMerkleRoot"`
+	AcceptedIDMerkleRoot string     `json:"acceptedIdMerkleRoot"`
+	UTXOCommitment       string     `json:"utxoCommitment"`
+	TimeInMilliseconds   int64      `json:"timeInMilliseconds"`
+	Bits                 uint32     `json:"bits"`
+	Nonce                uint64     `json:"nonce"`
+	DAAScore             uint64     `json:"daaScore"`
+	BlueScore            uint64     `json:"blueScore"`
+	BlueWork             string     `json:"blueWork"`
+	PruningPoint         string     `json:"pruningPoint"`
+}
+
+// WriteJSON writes the given proof as indented JSON. The levels and their headers keep
+// the order of the proof.
+func WriteJSON(writer io.Writer, proof *externalapi.PruningPointProof) error {
+	encoded := &jsonProof{Version: Version, Levels: make([][]*jsonHeader, len(proof.Headers))}
+	for level, levelHeaders := range proof.Headers {
+		encoded.Levels[level] = make([]*jsonHeader, len(levelHeaders))
+		for i, header := range levelHeaders {
+			encoded.Levels[level][i] = headerToJSON(header)
+		}
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(encoded)
+}
+
+// ReadJSON reads a proof that was written by WriteJSON
+func ReadJSON(reader io.Reader) (*externalapi.PruningPointProof, error) {
+	encoded := &jsonProof{}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(encoded)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedProof, "failed decoding JSON: %s", err)
+	}
+	if encoded.Version != Version {
+		return nil, errors.Errorf("unsupported pruning point proof version %d, expected %d", encoded.Version, Version)
+	}
+
+	proof := &externalapi.PruningPointProof{Headers: make([][]externalapi.BlockHeader, len(encoded.Levels))}
+	for level, levelHeaders := range encoded.Levels {
+		proof.Headers[level] = make([]externalapi.BlockHeader, len(levelHeaders))
+		for i, encodedHeader := range levelHeaders {
+			header, err := headerFromJSON(encodedHeader)
+			if err != nil {
+				return nil, errors.Wrapf(ErrMalformedProof, "header %d of level %d: %s", i, level, err)
+			}
+			proof.Headers[level][i] = header
+		}
+	}
+	return proof, nil
+}
+
+func headerToJSON(header externalapi.BlockHeader) *jsonHeader {
+	parents := make([][]string, len(header.Parents()))
+	for level, levelParents := range header.Parents() {
+		parents[level] = make([]string, len(levelParents))
+		for i, parent := range levelParents {
+			parents[level][i] = parent.String()
+		}
+	}
+
+	return &jsonHeader{
+		Hash:                 consensushashing.HeaderHash(header).String(),
+		Version:              header.Version(),
+		Parents:              parents,
+		HashMerkleRoot:       header.HashMerkleRoot().String(),
+		AcceptedIDMerkleRoot: header.AcceptedIDMerkleRoot().String(),
+		UTXOCommitment:       header.UTXOCommitment().String(),
+		TimeInMilliseconds:   header.TimeInMilliseconds(),
+		Bits:                 header.Bits(),
+		Nonce:                header.Nonce(),
+		DAAScore:             header.DAAScore(),
+		BlueScore:            header.BlueScore(),
+		BlueWork:             header.BlueWork().Text(16),
+		PruningPoint:         header.PruningPoint().String(),
+	}
+}
+
+func headerFromJSON(encoded *jsonHeader) (externalapi.BlockHeader, error) {
+	if encoded == nil {
+		return nil, errors.New("the header is null")
+	}
+
+	parents := make([]externalapi.BlockLevelParents, len(encoded.Parents))
+	for level, levelParents := range encoded.Parents {
+		parents[level] = make(externalapi.BlockLevelParents, len(levelParents))
+		for i, parent := range levelParents {
+			parentHash, err := externalapi.NewDomainHashFromString(parent)
+			if err != nil {
+				return nil, err
+			}
+			parents[level][i] = parentHash
+		}
+	}
+
+	hashMerkleRoot, err := externalapi.NewDomainHashFromString(encoded.HashMerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	acceptedIDMerkleRoot, err := externalapi.NewDomainHashFromString(encoded.AcceptedIDMerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	utxoCommitment, err := externalapi.NewDomainHashFromString(encoded.UTXOCommitment)
+	if err != nil {
+		return nil, err
+	}
+	pruningPoint, err := externalapi.NewDomainHashFromString(encoded.PruningPoint)
+	if err != nil {
+		return nil, err
+	}
+	blueWork, ok := new(big.Int).SetString(encoded.BlueWork, 16)
+	if !ok || blueWork.Sign() < 0 {
+		return nil, errors.Errorf("invalid blue work %q", encoded.BlueWork)
+	}
+
+	header := blockheader.NewImmutableBlockHeader(
+		encoded.Version,
+		parents,
+		hashMerkleRoot,
+		acceptedIDMerkleRoot,
+		utxoCommitment,
+		encoded.TimeInMilliseconds,
+		encoded.Bits,
+		encoded.Nonce,
+		encoded.DAAScore,
+		encoded.BlueScore,
+		blueWork,
+		pruningPoint,
+	)
+	hash := consensushashing.HeaderHash(header)
+	if hash.String() != encoded.Hash {
+		return nil, errors.Errorf("the header hashes to %s rather than %s", hash, encoded.Hash)
+	}
+	return header, nil
+}
@@ -0,0 +1,12 @@
+package pruningpointproof
+
+import "github.com/pkg/errors"
+
+// Version is the current version of the binary pruning point proof encoding
+const Version byte = 1
+
+// magic identifies a binary encoded pruning point proof. It is written at the very beginning of every proof.
+var magic = [8]byte{'K', 'L', 'S', 'P', 'R', 'O', 'O', 'F'}
+
+// ErrMalformedProof indicates that an encoded proof couldn't be parsed
+var ErrMalformedProof = errors.New("malformed pruning point proof")
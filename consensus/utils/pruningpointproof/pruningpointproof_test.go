@@ -0,0 +1,124 @@
+package pruningpointproof
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/blockheader"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+func testHeader(seed byte, parents []externalapi.BlockLevelParents) externalapi.BlockHeader {
+	return blockheader.NewImmutableBlockHeader(
+		1,
+		parents,
+		externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{seed, 1}),
+		externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{seed, 2}),
+		externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{seed, 3}),
+		1_700_000_000_000+int64(seed),
+		0x1e7fffff,
+		uint64(seed)<<56|0x1234,
+		uint64(seed)*10,
+		uint64(seed)*9,
+		new(big.Int).Lsh(big.NewInt(int64(seed)+1), 70),
+		externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{seed, 4}),
+	)
+}
+
+func testProof() *externalapi.PruningPointProof {
+	root := testHeader(1, nil)
+	rootHash := consensushashing.HeaderHash(root)
+	child := testHeader(2, []externalapi.BlockLevelParents{{rootHash}, {rootHash}})
+	childHash := consensushashing.HeaderHash(child)
+	tip := testHeader(3, []externalapi.BlockLevelParents{{childHash, rootHash}, {childHash}})
+	return &externalapi.PruningPointProof{
+		Headers: [][]externalapi.BlockHeader{
+			{root, child, tip},
+			{root, child},
+			{},
+		},
+	}
+}
+
+func proofsEqual(a, b *externalapi.PruningPointProof) bool {
+	if len(a.Headers) != len(b.Headers) {
+		return false
+	}
+	for level := range a.Headers {
+		if len(a.Headers[level]) != len(b.Headers[level]) {
+			return false
+		}
+		for i := range a.Headers[level] {
+			if !a.Headers[level][i].Equal(b.Headers[level][i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	proof := testProof()
+	serialized, err := Serialize(proof)
+	if err != nil {
+		t.Fatalf("Serialize: %+v", err)
+	}
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Deserialize: %+v", err)
+	}
+	if !proofsEqual(proof, deserialized) {
+		t.Fatalf("TestBinaryRoundTrip: the deserialized proof differs from the original")
+	}
+
+	reserialized, err := Serialize(deserialized)
+	if err != nil {
+		t.Fatalf("Serialize: %+v", err)
+	}
+	if !bytes.Equal(serialized, reserialized) {
+		t.Fatalf("TestBinaryRoundTrip: the encoding is not canonical")
+	}
+
+	for _, length := range []int{0, len(magic), len(magic) + 3, len(serialized) / 2, len(serialized) - 1} {
+		_, err := Deserialize(serialized[:length])
+		if !errors.Is(err, ErrMalformedProof) {
+			t.Fatalf("TestBinaryRoundTrip: unexpected error for a proof truncated to %d bytes: %v", length, err)
+		}
+	}
+	_, err = Deserialize(append(serialized, 0))
+	if !errors.Is(err, ErrMalformedProof) {
+		t.Fatalf("TestBinaryRoundTrip: unexpected error for a proof with trailing bytes: %v", err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	proof := testProof()
+	buffer := &bytes.Buffer{}
+	err := WriteJSON(buffer, proof)
+	if err != nil {
+		t.Fatalf("WriteJSON: %+v", err)
+	}
+	encoded := buffer.String()
+
+	decoded, err := ReadJSON(strings.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("ReadJSON: %+v", err)
+	}
+	if !proofsEqual(proof, decoded) {
+		t.Fatalf("TestJSONRoundTrip: the decoded proof differs from the original")
+	}
+
+	// Changing any header field breaks the redundant hash
+	tamperedDAAScore := strings.Replace(encoded, `"daaScore": 10,`, `"daaScore": 11,`, 1)
+	if tamperedDAAScore == encoded {
+		t.Fatalf("TestJSONRoundTrip: the test proof doesn't contain the expected DAA score")
+	}
+	_, err = ReadJSON(strings.NewReader(tamperedDAAScore))
+	if !errors.Is(err, ErrMalformedProof) {
+		t.Fatalf("TestJSONRoundTrip: unexpected error for a tampered header: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/serialization"
+)
+
+// maxFieldLength is the maximum length of a single key or value inside a snapshot.
+// It protects the reader from allocating absurd amounts of memory on corrupted input.
+const maxFieldLength = 1 << 28
+
+// Reader reads a consensus snapshot archive written by Writer
+type Reader struct {
+	r           io.Reader
+	digested    io.Reader
+	digest      hash.Hash
+	header      *Header
+	recordCount uint64
+	isDone      bool
+}
+
+// NewReader reads and validates the archive preamble and header from r, and
+// returns a Reader positioned at the first record.
+func NewReader(r io.Reader) (*Reader, error) {
+	digest := sha256.New()
+	reader := &Reader{
+		r:        r,
+		digested: io.TeeReader(r, digest),
+		digest:   digest,
+	}
+
+	var archiveMagic [len(magic)]byte
+	_, err := io.ReadFull(reader.digested, archiveMagic[:])
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading snapshot magic: %s", err)
+	}
+	if archiveMagic != magic {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "not a consensus snapshot")
+	}
+
+	header := &Header{}
+	err = serialization.ReadElement(reader.digested, &header.Version)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading snapshot version: %s", err)
+	}
+	if header.Version != Version {
+		return nil, errors.Errorf("unsupported snapshot version %d, expected %d", header.Version, Version)
+	}
+	header.GenesisHash, err = reader.readHash()
+	if err != nil {
+		return nil, err
+	}
+	header.PruningPoint, err = reader.readHash()
+	if err != nil {
+		return nil, err
+	}
+	var tipsCount uint64
+	err = serialization.ReadElement(reader.digested, &tipsCount)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading tips count: %s", err)
+	}
+	if tipsCount > maxFieldLength/externalapi.DomainHashSize {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "too many tips: %d", tipsCount)
+	}
+	header.Tips = make([]*externalapi.DomainHash, tipsCount)
+	for i := range header.Tips {
+		header.Tips[i], err = reader.readHash()
+		if err != nil {
+			return nil, err
+		}
+	}
+	reader.header = header
+
+	return reader, nil
+}
+
+// Header returns the header of the archive
+func (sr *Reader) Header() *Header {
+	return sr.header
+}
+
+// Next returns the next record in the archive. Once all records were read, and the
+// archive's trailer was validated, Next returns io.EOF.
+func (sr *Reader) Next() (*Record, error) {
+	if sr.isDone {
+		return nil, io.EOF
+	}
+
+	var marker uint8
+	err := serialization.ReadElement(sr.digested, &marker)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading record marker: %s", err)
+	}
+
+	switch marker {
+	case recordMarker:
+		return sr.readRecord()
+	case trailerMarker:
+		err = sr.readTrailer()
+		if err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	default:
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "unexpected marker %d", marker)
+	}
+}
+
+func (sr *Reader) readRecord() (*Record, error) {
+	var section uint8
+	err := serialization.ReadElement(sr.digested, &section)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading record section: %s", err)
+	}
+	if Section(section) >= numberOfSections {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "unknown section %d", section)
+	}
+	key, err := sr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	value, err := sr.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	var checksum uint32
+	err = serialization.ReadElement(sr.digested, &checksum)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading record checksum: %s", err)
+	}
+
+	record := &Record{
+		Section: Section(section),
+		Key:     key,
+		Value:   value,
+	}
+	if recordChecksum(record) != checksum {
+		return nil, errors.Wrapf(ErrChecksumMismatch, "record #%d in section %s", sr.recordCount, record.Section)
+	}
+	sr.recordCount++
+
+	return record, nil
+}
+
+func (sr *Reader) readTrailer() error {
+	var recordCount uint64
+	err := serialization.ReadElement(sr.digested, &recordCount)
+	if err != nil {
+		return errors.Wrapf(ErrMalformedSnapshot, "failed reading record count: %s", err)
+	}
+	if recordCount != sr.recordCount {
+		return errors.Wrapf(ErrMalformedSnapshot, "trailer indicates %d records but %d were read",
+			recordCount, sr.recordCount)
+	}
+
+	expectedDigest := sr.digest.Sum(nil)
+	archiveDigest := make([]byte, len(expectedDigest))
+	_, err = io.ReadFull(sr.r, archiveDigest)
+	if err != nil {
+		return errors.Wrapf(ErrMalformedSnapshot, "failed reading snapshot digest: %s", err)
+	}
+	if !bytes.Equal(expectedDigest, archiveDigest) {
+		return errors.Wrapf(ErrChecksumMismatch, "snapshot digest is %x but calculated %x",
+			archiveDigest, expectedDigest)
+	}
+
+	sr.isDone = true
+	return nil
+}
+
+func (sr *Reader) readHash() (*externalapi.DomainHash, error) {
+	var hashBytes [externalapi.DomainHashSize]byte
+	_, err := io.ReadFull(sr.digested, hashBytes[:])
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading hash: %s", err)
+	}
+	return externalapi.NewDomainHashFromByteArray(&hashBytes), nil
+}
+
+func (sr *Reader) readBytes() ([]byte, error) {
+	var length uint64
+	err := serialization.ReadElement(sr.digested, &length)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading field length: %s", err)
+	}
+	if length > maxFieldLength {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "field length %d exceeds the maximum of %d",
+			length, maxFieldLength)
+	}
+	field := make([]byte, length)
+	_, err = io.ReadFull(sr.digested, field)
+	if err != nil {
+		return nil, errors.Wrapf(ErrMalformedSnapshot, "failed reading field: %s", err)
+	}
+	return field, nil
+}
+
+// Verify reads the whole archive from r, validating the checksum of every record and the
+// archive's digest, and returns its header. The records themselves are discarded.
+func Verify(r io.Reader) (*Header, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return reader.Header(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
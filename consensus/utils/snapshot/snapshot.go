@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// Version is the current version of the snapshot archive format
+const Version uint16 = 1
+
+// magic identifies a consensus snapshot archive. It is written at the very beginning of every archive.
+var magic = [8]byte{'K', 'L', 'S', 'S', 'N', 'A', 'P', 0}
+
+const (
+	recordMarker  byte = 0x01
+	trailerMarker byte = 0x00
+)
+
+// ErrChecksumMismatch indicates that the archive content doesn't match one of its checksums
+var ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+// ErrMalformedSnapshot indicates that the archive couldn't be parsed
+var ErrMalformedSnapshot = errors.New("malformed snapshot")
+
+// Section identifies the kind of consensus data a Record belongs to
+type Section uint8
+
+// These are the sections a snapshot is built of
+const (
+	SectionPruningPoint Section = iota
+	SectionPruningPointUTXOSet
+	SectionHeaders
+	SectionBlockRelations
+	SectionGHOSTDAGData
+	SectionReachability
+	SectionTips
+	SectionConsensusState
+
+	numberOfSections
+)
+
+var sectionNames = map[Section]string{
+	SectionPruningPoint:        "PruningPoint",
+	SectionPruningPointUTXOSet: "PruningPointUTXOSet",
+	SectionHeaders:             "Headers",
+	SectionBlockRelations:      "BlockRelations",
+	SectionGHOSTDAGData:        "GHOSTDAGData",
+	SectionReachability:        "Reachability",
+	SectionTips:                "Tips",
+	SectionConsensusState:      "ConsensusState",
+}
+
+func (s Section) String() string {
+	name, ok := sectionNames[s]
+	if !ok {
+		return "Unknown"
+	}
+	return name
+}
+
+// Header holds the metadata written at the beginning of every snapshot
+type Header struct {
+	Version      uint16
+	GenesisHash  *externalapi.DomainHash
+	PruningPoint *externalapi.DomainHash
+	Tips         []*externalapi.DomainHash
+}
+
+// Record is a single database entry inside a snapshot. Key is relative to
+// the database prefix the snapshot was taken from.
+type Record struct {
+	Section Section
+	Key     []byte
+	Value   []byte
+}
@@ -0,0 +1,146 @@
+package snapshot
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+func testHeader() *Header {
+	return &Header{
+		Version:      Version,
+		GenesisHash:  externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{1}),
+		PruningPoint: externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{2}),
+		Tips: []*externalapi.DomainHash{
+			externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{3}),
+			externalapi.NewDomainHashFromByteArray(&[externalapi.DomainHashSize]byte{4}),
+		},
+	}
+}
+
+func testRecords() []*Record {
+	return []*Record{
+		{Section: SectionPruningPoint, Key: []byte("pruning-block-index"), Value: []byte{0, 0, 0, 0, 0, 0, 0, 0}},
+		{Section: SectionPruningPointUTXOSet, Key: []byte("pruning-point-utxo-set/a"), Value: []byte("utxo")},
+		{Section: SectionHeaders, Key: []byte("block-headers/b"), Value: []byte("header")},
+		{Section: SectionTips, Key: []byte("tips"), Value: []byte{}},
+	}
+}
+
+func writeTestSnapshot(t *testing.T) []byte {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWriter(buffer, testHeader())
+	if err != nil {
+		t.Fatalf("NewWriter: %+v", err)
+	}
+	for _, record := range testRecords() {
+		err = writer.WriteRecord(record)
+		if err != nil {
+			t.Fatalf("WriteRecord: %+v", err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Close: %+v", err)
+	}
+	return buffer.Bytes()
+}
+
+func readAllRecords(snapshotBytes []byte) (*Header, []*Record, error) {
+	reader, err := NewReader(bytes.NewReader(snapshotBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+	var records []*Record
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+	}
+	return reader.Header(), records, nil
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	header, records, err := readAllRecords(writeTestSnapshot(t))
+	if err != nil {
+		t.Fatalf("readAllRecords: %+v", err)
+	}
+	if !reflect.DeepEqual(header, testHeader()) {
+		t.Fatalf("unexpected header. Want: %+v, got: %+v", testHeader(), header)
+	}
+	if !reflect.DeepEqual(records, testRecords()) {
+		t.Fatalf("unexpected records. Want: %+v, got: %+v", testRecords(), records)
+	}
+
+	header, err = Verify(bytes.NewReader(writeTestSnapshot(t)))
+	if err != nil {
+		t.Fatalf("Verify: %+v", err)
+	}
+	if !reflect.DeepEqual(header, testHeader()) {
+		t.Fatalf("unexpected verified header. Want: %+v, got: %+v", testHeader(), header)
+	}
+}
+
+func TestSnapshotCorruption(t *testing.T) {
+	snapshotBytes := writeTestSnapshot(t)
+
+	tests := []struct {
+		name          string
+		corrupt       func([]byte) []byte
+		expectedError error
+	}{
+		{
+			name: "bad magic",
+			corrupt: func(b []byte) []byte {
+				b[0] ^= 0xff
+				return b
+			},
+			expectedError: ErrMalformedSnapshot,
+		},
+		{
+			name: "flipped record byte",
+			corrupt: func(b []byte) []byte {
+				index := bytes.Index(b, []byte("header"))
+				b[index] ^= 0xff
+				return b
+			},
+			expectedError: ErrChecksumMismatch,
+		},
+		{
+			name: "flipped digest byte",
+			corrupt: func(b []byte) []byte {
+				b[len(b)-1] ^= 0xff
+				return b
+			},
+			expectedError: ErrChecksumMismatch,
+		},
+		{
+			name: "truncated",
+			corrupt: func(b []byte) []byte {
+				return b[:len(b)-40]
+			},
+			expectedError: ErrMalformedSnapshot,
+		},
+	}
+
+	for _, test := range tests {
+		corrupted := test.corrupt(append([]byte{}, snapshotBytes...))
+		_, _, err := readAllRecords(corrupted)
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("%s: expected error %s but got %+v", test.name, test.expectedError, err)
+		}
+		_, err = Verify(bytes.NewReader(corrupted))
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("%s: expected Verify to fail with %s but got %+v", test.name, test.expectedError, err)
+		}
+	}
+}
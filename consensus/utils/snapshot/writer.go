@@ -0,0 +1,99 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/serialization"
+)
+
+// Writer writes a consensus snapshot archive to an underlying io.Writer.
+// Every record is protected by its own CRC32 checksum, and the whole archive
+// is protected by a SHA256 digest written in its trailer.
+type Writer struct {
+	out         io.Writer
+	w           io.Writer
+	digest      hash.Hash
+	recordCount uint64
+	isClosed    bool
+}
+
+// NewWriter writes the archive preamble and the given header to w, and returns
+// a Writer that appends records after them.
+func NewWriter(w io.Writer, header *Header) (*Writer, error) {
+	digest := sha256.New()
+	writer := &Writer{
+		out:    w,
+		w:      io.MultiWriter(w, digest),
+		digest: digest,
+	}
+
+	_, err := writer.w.Write(magic[:])
+	if err != nil {
+		return nil, err
+	}
+	err = serialization.WriteElements(writer.w, Version, header.GenesisHash, header.PruningPoint, uint64(len(header.Tips)))
+	if err != nil {
+		return nil, err
+	}
+	for _, tip := range header.Tips {
+		err = serialization.WriteElement(writer.w, tip)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
+
+// WriteRecord appends the given record to the archive
+func (sw *Writer) WriteRecord(record *Record) error {
+	if sw.isClosed {
+		return errors.New("cannot write a record to a closed snapshot writer")
+	}
+	if record.Section >= numberOfSections {
+		return errors.Errorf("unknown snapshot section %d", record.Section)
+	}
+
+	err := serialization.WriteElements(sw.w, recordMarker, uint8(record.Section), record.Key, record.Value,
+		recordChecksum(record))
+	if err != nil {
+		return err
+	}
+	sw.recordCount++
+	return nil
+}
+
+// RecordCount returns the number of records written so far
+func (sw *Writer) RecordCount() uint64 {
+	return sw.recordCount
+}
+
+// Close writes the archive trailer. It does not close the underlying io.Writer.
+func (sw *Writer) Close() error {
+	if sw.isClosed {
+		return errors.New("snapshot writer is already closed")
+	}
+	sw.isClosed = true
+
+	err := serialization.WriteElements(sw.w, trailerMarker, sw.recordCount)
+	if err != nil {
+		return err
+	}
+
+	// The digest itself is not part of the digested data, so it's written
+	// directly to the underlying writer.
+	_, err = sw.out.Write(sw.digest.Sum(nil))
+	return err
+}
+
+func recordChecksum(record *Record) uint32 {
+	checksum := crc32.NewIEEE()
+	checksum.Write([]byte{uint8(record.Section)})
+	checksum.Write(record.Key)
+	checksum.Write(record.Value)
+	return checksum.Sum32()
+}
@@ -4,14 +4,28 @@ go 1.22.3
 
 require (
 	github.com/edsrzf/mmap-go v1.1.0
+	github.com/golang/protobuf v1.5.3
 	github.com/karlsen-network/karlsend/v2 v2.1.1
+	github.com/kaspanet/go-muhash v0.0.4
+	github.com/kaspanet/go-secp256k1 v0.0.7
 	github.com/pkg/errors v0.9.1
+	github.com/syndtr/goleveldb v1.0.1-0.20190923125748-758128399b1d
 	golang.org/x/crypto v0.26.0
+	google.golang.org/protobuf v1.34.2
+	lukechampine.com/blake3 v1.2.1
 )
 
 require (
+	github.com/btcsuite/btcutil v1.0.2 // indirect
+	github.com/gofrs/flock v0.8.1 // indirect
+	github.com/golang/snappy v0.0.4 // indirect
 	github.com/jrick/logrotate v1.0.0 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.6 // indirect
+	github.com/tyler-smith/go-bip39 v1.1.0 // indirect
+	golang.org/x/net v0.28.0 // indirect
 	golang.org/x/sys v0.23.0 // indirect
-	lukechampine.com/blake3 v1.2.1 // indirect
+	golang.org/x/term v0.23.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240221002015-b0ce06bbee7c // indirect
+	google.golang.org/grpc v1.61.1 // indirect
 )
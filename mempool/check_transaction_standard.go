@@ -0,0 +1,124 @@
+package mempool
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/txscript"
+)
+
+const (
+	// maxStandardP2SHSigOps is the maximum number of signature operations
+	// that are considered standard in a pay-to-script-hash script.
+	maxStandardP2SHSigOps = 15
+
+	// maximumStandardSignatureScriptSize is the maximum size allowed for a
+	// transaction input signature script to be considered standard. This
+	// value allows for a 15-of-15 CHECKMULTISIG pay-to-script-hash with
+	// compressed keys.
+	maximumStandardSignatureScriptSize = 1650
+
+	// MaximumStandardTransactionMass is the maximum mass allowed for transactions that
+	// are considered standard and will therefore be relayed and considered for mining.
+	MaximumStandardTransactionMass = 100_000
+
+	// minimumSpendingInputSize is the size of a typical pay-to-pubkey input, which is
+	// the least it costs to spend an output: 36 bytes of previous outpoint, 1 byte of
+	// script length, 64 bytes of signature script and 4 bytes of sequence
+	minimumSpendingInputSize = 105
+)
+
+// checkTransactionStandardInIsolation checks the parts of the standardness policy
+// that don't depend on the UTXO entries the transaction spends
+func (mp *mempool) checkTransactionStandardInIsolation(transaction *externalapi.DomainTransaction) error {
+	if transaction.Version > mp.config.MaximumStandardTransactionVersion ||
+		transaction.Version < mp.config.MinimumStandardTransactionVersion {
+		return transactionRuleError(RejectNonstandard, "transaction version %d is not in the valid range of %d-%d",
+			transaction.Version, mp.config.MinimumStandardTransactionVersion, mp.config.MaximumStandardTransactionVersion)
+	}
+
+	// Since extremely large transactions with a lot of inputs can cost
+	// almost as much to process as the sender fees, limit the maximum
+	// size of a transaction
+	if transaction.Mass > MaximumStandardTransactionMass {
+		return transactionRuleError(RejectNonstandard, "transaction mass of %d is larger than max allowed size of %d",
+			transaction.Mass, MaximumStandardTransactionMass)
+	}
+
+	for i, input := range transaction.Inputs {
+		if len(input.SignatureScript) > maximumStandardSignatureScriptSize {
+			return transactionRuleError(RejectNonstandard, "transaction input %d: signature script size of %d bytes "+
+				"is larger than the maximum allowed size of %d bytes",
+				i, len(input.SignatureScript), maximumStandardSignatureScriptSize)
+		}
+	}
+
+	for i, output := range transaction.Outputs {
+		if output.ScriptPublicKey.Version > constants.MaxScriptPublicKeyVersion {
+			return transactionRuleError(RejectNonstandard, "transaction output %d: unknown script public key version %d",
+				i, output.ScriptPublicKey.Version)
+		}
+		if txscript.GetScriptClass(output.ScriptPublicKey.Script) == txscript.NonStandardTy {
+			return transactionRuleError(RejectNonstandard, "transaction output %d: non-standard script form", i)
+		}
+		if mp.isTransactionOutputDust(output) {
+			return transactionRuleError(RejectDust, "transaction output %d: payment of %d is dust", i, output.Value)
+		}
+	}
+
+	return nil
+}
+
+// isTransactionOutputDust returns whether the cost to the network of spending the given output,
+// estimated with a typical pay-to-pubkey input, is more than a third of the value of the output
+// according to the minimum relay transaction fee
+func (mp *mempool) isTransactionOutputDust(output *externalapi.DomainTransactionOutput) bool {
+	if txscript.IsUnspendable(output.ScriptPublicKey.Script) {
+		return true
+	}
+
+	// value, script public key version, script length and script
+	outputSize := uint64(8 + 2 + 8 + len(output.ScriptPublicKey.Script))
+	totalSerializedSize := outputSize + minimumSpendingInputSize
+
+	return output.Value*1000/(3*totalSerializedSize) < mp.config.MinimumRelayTransactionFee
+}
+
+// checkTransactionStandardInContext checks the parts of the standardness policy that depend on the
+// UTXO entries the transaction spends, including the minimum fee for acceptance into the mempool
+func (mp *mempool) checkTransactionStandardInContext(transaction *externalapi.DomainTransaction) error {
+	for i, input := range transaction.Inputs {
+		originScriptPublicKey := input.UTXOEntry.ScriptPublicKey()
+		switch txscript.GetScriptClass(originScriptPublicKey.Script) {
+		case txscript.ScriptHashTy:
+			numSigOps := txscript.GetPreciseSigOpCount(input.SignatureScript, originScriptPublicKey, true)
+			if numSigOps > maxStandardP2SHSigOps {
+				return transactionRuleError(RejectNonstandard, "transaction input #%d has %d signature operations "+
+					"which is more than the allowed max amount of %d", i, numSigOps, maxStandardP2SHSigOps)
+			}
+
+		case txscript.NonStandardTy:
+			return transactionRuleError(RejectNonstandard, "transaction input #%d has a non-standard script form", i)
+		}
+	}
+
+	minimumFee := mp.minimumRequiredTransactionRelayFee(transaction.Mass)
+	if transaction.Fee < minimumFee {
+		return transactionRuleError(RejectInsufficientFee, "transaction has %d fees which is under "+
+			"the required amount of %d", transaction.Fee, minimumFee)
+	}
+
+	return nil
+}
+
+// minimumRequiredTransactionRelayFee returns the minimum fee for a transaction
+// with the given mass to be accepted into the mempool
+func (mp *mempool) minimumRequiredTransactionRelayFee(mass uint64) uint64 {
+	minimumFee := mass * mp.config.MinimumRelayTransactionFee / 1000
+	if minimumFee == 0 && mp.config.MinimumRelayTransactionFee > 0 {
+		minimumFee = mp.config.MinimumRelayTransactionFee
+	}
+	if minimumFee > constants.MaxSompi {
+		minimumFee = constants.MaxSompi
+	}
+	return minimumFee
+}
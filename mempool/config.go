@@ -0,0 +1,48 @@
+package mempool
+
+import (
+	"time"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
+)
+
+const (
+	defaultMaximumTransactionCount = 1_000_000
+
+	defaultOrphanExpireInterval = time.Minute
+
+	defaultMaximumOrphanTransactionMass  = 100_000
+	defaultMaximumOrphanTransactionCount = 50
+
+	// defaultMinimumRelayTransactionFee is specified in sompi per 1000 grams of transaction mass
+	defaultMinimumRelayTransactionFee = 1000
+)
+
+// Config represents a mempool configuration
+type Config struct {
+	MaximumTransactionCount       uint64
+	MaximumOrphanTransactionCount uint64
+	MaximumOrphanTransactionMass  uint64
+	OrphanExpireIntervalDAAScore  uint64
+
+	// AcceptNonStandard disables the standardness policy, leaving only the consensus rules
+	AcceptNonStandard bool
+
+	// MinimumRelayTransactionFee is specified in sompi per 1000 grams of transaction mass
+	MinimumRelayTransactionFee        uint64
+	MinimumStandardTransactionVersion uint16
+	MaximumStandardTransactionVersion uint16
+}
+
+// DefaultConfig returns the default mempool configuration for a network with the given target time per block
+func DefaultConfig(targetTimePerBlock time.Duration) *Config {
+	return &Config{
+		MaximumTransactionCount:           defaultMaximumTransactionCount,
+		MaximumOrphanTransactionCount:     defaultMaximumOrphanTransactionCount,
+		MaximumOrphanTransactionMass:      defaultMaximumOrphanTransactionMass,
+		OrphanExpireIntervalDAAScore:      uint64(defaultOrphanExpireInterval / targetTimePerBlock),
+		MinimumRelayTransactionFee:        defaultMinimumRelayTransactionFee,
+		MinimumStandardTransactionVersion: constants.MaxTransactionVersion,
+		MaximumStandardTransactionVersion: constants.MaxTransactionVersion,
+	}
+}
@@ -0,0 +1,58 @@
+package mempool
+
+import (
+	"fmt"
+)
+
+// RejectCode identifies the reason a transaction was rejected from the mempool
+type RejectCode uint8
+
+// These are the reasons a transaction may be rejected from the mempool
+const (
+	RejectInvalid         RejectCode = 0x10
+	RejectDuplicate       RejectCode = 0x12
+	RejectNonstandard     RejectCode = 0x40
+	RejectDust            RejectCode = 0x41
+	RejectInsufficientFee RejectCode = 0x42
+	RejectBadOrphan       RejectCode = 0x64
+)
+
+var rejectCodeStrings = map[RejectCode]string{
+	RejectInvalid:         "REJECT_INVALID",
+	RejectDuplicate:       "REJECT_DUPLICATE",
+	RejectNonstandard:     "REJECT_NON_STANDARD",
+	RejectDust:            "REJECT_DUST",
+	RejectInsufficientFee: "REJECT_INSUFFICIENT_FEE",
+	RejectBadOrphan:       "REJECT_BAD_ORPHAN",
+}
+
+func (code RejectCode) String() string {
+	if s, ok := rejectCodeStrings[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown RejectCode (%d)", uint8(code))
+}
+
+// TxRuleError is returned when a transaction is rejected from the mempool.
+// If the transaction was rejected by consensus, Err holds the consensus rule error.
+type TxRuleError struct {
+	RejectCode  RejectCode
+	Description string
+	Err         error
+}
+
+func (e TxRuleError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Description, e.Err)
+	}
+	return e.Description
+}
+
+// Unwrap returns the consensus rule error the transaction was rejected by, if any
+func (e TxRuleError) Unwrap() error {
+	return e.Err
+}
+
+func transactionRuleError(rejectCode RejectCode, format string, args ...interface{}) TxRuleError {
+	return TxRuleError{RejectCode: rejectCode, Description: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,102 @@
+package mempool
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// HandleVirtualChangeSet updates the mempool according to the UTXO diff of the given virtual change set:
+//   - Transactions spending outpoints that were removed from the UTXO set, because they were either accepted
+//     or double spent, are removed. Their redeemers are validated again against the new virtual.
+//   - Orphans spending outpoints that were added to the UTXO set are validated again.
+//   - Orphans that were kept for longer than OrphanExpireIntervalDAAScore are removed.
+//
+// Transactions of blocks that were removed from the virtual selected chain aren't returned to the mempool.
+// The returned transactions are the orphans and redeemers that were accepted.
+func (mp *mempool) HandleVirtualChangeSet(virtualChangeSet *externalapi.VirtualChangeSet) (
+	acceptedTransactions []*externalapi.DomainTransaction, err error) {
+
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	mp.virtualDAAScore = virtualChangeSet.VirtualDAAScore
+	if virtualChangeSet.VirtualUTXODiff == nil {
+		return nil, nil
+	}
+
+	removedOutpoints, err := outpoints(virtualChangeSet.VirtualUTXODiff.ToRemove())
+	if err != nil {
+		return nil, err
+	}
+	var redeemersToRevalidate []*mempoolTransaction
+	for _, outpoint := range removedOutpoints {
+		transaction, ok := mp.transactionsPool.spentOutpoints[outpoint]
+		if !ok {
+			continue
+		}
+		redeemers := mp.transactionsPool.redeemers(transaction)
+		mp.removeTransaction(transaction, true)
+		redeemersToRevalidate = append(redeemersToRevalidate, redeemers...)
+	}
+
+	for _, redeemer := range redeemersToRevalidate {
+		if _, ok := mp.transactionsPool.allTransactions[*redeemer.id]; ok {
+			continue
+		}
+		isAccepted, err := mp.validateAndInsertTransaction(redeemer.transaction, true)
+		if err != nil {
+			log.Debugf("Removed transaction %s: %s", redeemer.id, err)
+			continue
+		}
+		if isAccepted {
+			acceptedTransactions = append(acceptedTransactions, redeemer.transaction)
+		}
+	}
+
+	addedOutpoints, err := outpoints(virtualChangeSet.VirtualUTXODiff.ToAdd())
+	if err != nil {
+		return nil, err
+	}
+	acceptedTransactions = append(acceptedTransactions, mp.processOrphansSpending(addedOutpoints)...)
+
+	expiredCount := mp.orphanPool.expire(mp.virtualDAAScore, mp.config.OrphanExpireIntervalDAAScore)
+	if expiredCount > 0 {
+		log.Debugf("Expired %d orphan transactions", expiredCount)
+	}
+
+	log.Debugf("Handled a virtual change set: %d transactions and %d orphans are in the mempool",
+		len(mp.transactionsPool.allTransactions), len(mp.orphanPool.allOrphans))
+
+	return acceptedTransactions, nil
+}
+
+func outpoints(collection externalapi.UTXOCollection) ([]externalapi.DomainOutpoint, error) {
+	outpoints := make([]externalapi.DomainOutpoint, 0, collection.Len())
+	iterator := collection.Iterator()
+	defer iterator.Close()
+
+	for ok := iterator.First(); ok; ok = iterator.Next() {
+		outpoint, _, err := iterator.Get()
+		if err != nil {
+			return nil, err
+		}
+		outpoints = append(outpoints, *outpoint)
+	}
+	return outpoints, nil
+}
+
+// HandleConsensusEvents handles the VirtualChangeSet events of the given subscription
+// until it's canceled. Events dropped by the subscription are reported as an error,
+// since the mempool may then hold transactions that are no longer valid.
+func (mp *mempool) HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error {
+	return subscription.HandleEvents(func(event externalapi.ConsensusEvent) error {
+		virtualChangeSet, ok := event.(*externalapi.VirtualChangeSet)
+		if !ok {
+			return nil
+		}
+		_, err := mp.HandleVirtualChangeSet(virtualChangeSet)
+		return err
+	}, func(droppedCount uint64) error {
+		return errors.Errorf("%d consensus events were dropped", droppedCount)
+	})
+}
@@ -0,0 +1,309 @@
+package mempool
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/constants"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+)
+
+// Mempool holds the unconfirmed transactions that were validated against the virtual,
+// and feeds them to the block templates
+type Mempool interface {
+	ValidateAndInsertTransaction(transaction *externalapi.DomainTransaction, allowOrphan bool) (
+		acceptedTransactions []*externalapi.DomainTransaction, err error)
+	RemoveTransaction(transactionID *externalapi.DomainTransactionID, removeRedeemers bool)
+	HandleVirtualChangeSet(virtualChangeSet *externalapi.VirtualChangeSet) (
+		acceptedTransactions []*externalapi.DomainTransaction, err error)
+	HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error
+	BlockCandidateTransactions() []*externalapi.DomainTransaction
+	BuildBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData,
+		selectionMode externalapi.TransactionSelectionMode) (*externalapi.DomainBlockTemplate, error)
+	GetTransaction(transactionID *externalapi.DomainTransactionID) (
+		transaction *externalapi.DomainTransaction, isOrphan bool, found bool)
+	TransactionCount() int
+	OrphanCount() int
+}
+
+type mempool struct {
+	lock sync.RWMutex
+
+	config    *Config
+	consensus externalapi.Consensus
+
+	transactionsPool *transactionsPool
+	orphanPool       *orphanPool
+
+	// virtualDAAScore is the DAA score of the virtual as of the last handled VirtualChangeSet
+	virtualDAAScore uint64
+}
+
+// New instantiates a new Mempool that validates its transactions with the given consensus
+func New(config *Config, consensus externalapi.Consensus) Mempool {
+	return &mempool{
+		config:           config,
+		consensus:        consensus,
+		transactionsPool: newTransactionsPool(),
+		orphanPool:       newOrphanPool(),
+	}
+}
+
+// ValidateAndInsertTransaction validates the given transaction against the consensus rules and the
+// standardness policy, and inserts it into the mempool. If the transaction spends outpoints that are
+// unknown yet it's kept as an orphan, if allowOrphan is set. The returned transactions are the given
+// transaction followed by the orphans that were accepted thanks to it.
+func (mp *mempool) ValidateAndInsertTransaction(transaction *externalapi.DomainTransaction, allowOrphan bool) (
+	acceptedTransactions []*externalapi.DomainTransaction, err error) {
+
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	isAccepted, err := mp.validateAndInsertTransaction(transaction, allowOrphan)
+	if err != nil || !isAccepted {
+		return nil, err
+	}
+
+	acceptedTransactions = []*externalapi.DomainTransaction{transaction}
+	acceptedOrphans := mp.processOrphansAfterAcceptedTransaction(transaction)
+	return append(acceptedTransactions, acceptedOrphans...), nil
+}
+
+// validateAndInsertTransaction returns whether the given transaction was inserted into the transactions
+// pool. It returns false with no error if the transaction was inserted into the orphan pool instead.
+func (mp *mempool) validateAndInsertTransaction(transaction *externalapi.DomainTransaction, allowOrphan bool) (
+	isAccepted bool, err error) {
+
+	transactionID := consensushashing.TransactionID(transaction)
+	if _, ok := mp.transactionsPool.allTransactions[*transactionID]; ok {
+		return false, transactionRuleError(RejectDuplicate, "transaction %s is already in the mempool", transactionID)
+	}
+	if _, ok := mp.orphanPool.allOrphans[*transactionID]; ok {
+		return false, transactionRuleError(RejectDuplicate, "transaction %s is already in the orphan pool", transactionID)
+	}
+
+	mp.consensus.PopulateMass(transaction)
+	if !mp.config.AcceptNonStandard {
+		err := mp.checkTransactionStandardInIsolation(transaction)
+		if err != nil {
+			return false, errors.Wrapf(err, "transaction %s is not standard", transactionID)
+		}
+	}
+
+	for _, input := range transaction.Inputs {
+		if spendingTransaction, ok := mp.transactionsPool.spentOutpoints[input.PreviousOutpoint]; ok {
+			return false, transactionRuleError(RejectDuplicate, "transaction %s spends outpoint %s "+
+				"that is already spent by transaction %s in the mempool",
+				transactionID, input.PreviousOutpoint, spendingTransaction.id)
+		}
+	}
+
+	mp.populateUTXOEntriesFromTransactionsPool(transaction)
+	err = mp.consensus.ValidateTransactionAndPopulateWithConsensusData(transaction)
+	if err != nil {
+		missingTxOutErr := ruleerrors.ErrMissingTxOut{}
+		if errors.As(err, &missingTxOutErr) {
+			return false, mp.insertOrphan(transaction, transactionID, allowOrphan)
+		}
+		return false, TxRuleError{RejectCode: RejectInvalid,
+			Description: "transaction " + transactionID.String() + " is invalid", Err: err}
+	}
+
+	if !mp.config.AcceptNonStandard {
+		err := mp.checkTransactionStandardInContext(transaction)
+		if err != nil {
+			return false, errors.Wrapf(err, "transaction %s inputs are not standard", transactionID)
+		}
+	}
+
+	newTransaction := newMempoolTransaction(transaction)
+	err = mp.makeRoomFor(newTransaction)
+	if err != nil {
+		return false, err
+	}
+	mp.transactionsPool.add(newTransaction)
+	log.Debugf("Accepted transaction %s (fee rate %.2f sompi/gram) into the mempool, %d transactions in the pool",
+		transactionID, newTransaction.feeRate, len(mp.transactionsPool.allTransactions))
+
+	return true, nil
+}
+
+// populateUTXOEntriesFromTransactionsPool populates the inputs that spend outputs of transactions in the
+// pool, and clears the rest of the inputs so that consensus populates them from the virtual UTXO set
+func (mp *mempool) populateUTXOEntriesFromTransactionsPool(transaction *externalapi.DomainTransaction) {
+	for _, input := range transaction.Inputs {
+		input.UTXOEntry = nil
+		parent, ok := mp.transactionsPool.allTransactions[input.PreviousOutpoint.TransactionID]
+		if !ok || input.PreviousOutpoint.Index >= uint32(len(parent.transaction.Outputs)) {
+			continue
+		}
+		output := parent.transaction.Outputs[input.PreviousOutpoint.Index]
+		input.UTXOEntry = utxo.NewUTXOEntry(output.Value, output.ScriptPublicKey, false, constants.UnacceptedDAAScore)
+	}
+}
+
+func (mp *mempool) insertOrphan(transaction *externalapi.DomainTransaction,
+	transactionID *externalapi.DomainTransactionID, allowOrphan bool) error {
+
+	if !allowOrphan {
+		return transactionRuleError(RejectBadOrphan, "transaction %s spends unknown outpoints "+
+			"and orphans are not allowed", transactionID)
+	}
+	if transaction.Mass > mp.config.MaximumOrphanTransactionMass {
+		return transactionRuleError(RejectBadOrphan, "orphan transaction %s has a mass of %d which is "+
+			"larger than the maximum of %d", transactionID, transaction.Mass, mp.config.MaximumOrphanTransactionMass)
+	}
+	if mp.config.MaximumOrphanTransactionCount == 0 {
+		return transactionRuleError(RejectBadOrphan, "transaction %s spends unknown outpoints "+
+			"and the orphan pool is disabled", transactionID)
+	}
+
+	for uint64(len(mp.orphanPool.allOrphans)) >= mp.config.MaximumOrphanTransactionCount {
+		oldest := mp.orphanPool.oldest()
+		log.Debugf("Evicting orphan transaction %s since the orphan pool is full", oldest.id)
+		mp.orphanPool.remove(oldest)
+	}
+
+	for _, input := range transaction.Inputs {
+		input.UTXOEntry = nil
+	}
+	mp.orphanPool.add(transaction, mp.virtualDAAScore)
+	log.Debugf("Added orphan transaction %s to the orphan pool", transactionID)
+	return nil
+}
+
+// makeRoomFor evicts the transactions with the lowest fee rate, along with their redeemers,
+// if the mempool is full. The given transaction is rejected if its own fee rate is lower.
+func (mp *mempool) makeRoomFor(transaction *mempoolTransaction) error {
+	for uint64(len(mp.transactionsPool.allTransactions)) >= mp.config.MaximumTransactionCount {
+		lowest := mp.transactionsPool.lowestFeeRate()
+		if lowest == nil || !transaction.hasHigherFeeRate(lowest) {
+			return transactionRuleError(RejectInsufficientFee, "the mempool is full and transaction %s "+
+				"doesn't pay a higher fee rate than the transactions in it", transaction.id)
+		}
+		log.Debugf("Evicting transaction %s since the mempool is full", lowest.id)
+		mp.removeTransaction(lowest, true)
+	}
+	return nil
+}
+
+// processOrphansAfterAcceptedTransaction retries the orphans that spend the outputs of the given transaction
+func (mp *mempool) processOrphansAfterAcceptedTransaction(
+	transaction *externalapi.DomainTransaction) []*externalapi.DomainTransaction {
+
+	transactionID := consensushashing.TransactionID(transaction)
+	outpoints := make([]externalapi.DomainOutpoint, len(transaction.Outputs))
+	for i := range transaction.Outputs {
+		outpoints[i] = externalapi.DomainOutpoint{TransactionID: *transactionID, Index: uint32(i)}
+	}
+	return mp.processOrphansSpending(outpoints)
+}
+
+// processOrphansSpending removes the orphans that spend any of the given outpoints from the orphan pool,
+// and validates them again. Orphans that are still missing outpoints are returned to the orphan pool.
+func (mp *mempool) processOrphansSpending(outpoints []externalapi.DomainOutpoint) []*externalapi.DomainTransaction {
+	var acceptedOrphans []*externalapi.DomainTransaction
+	queue := mp.orphanPool.removeOrphansSpending(outpoints)
+	for len(queue) > 0 {
+		orphan := queue[0]
+		queue = queue[1:]
+
+		isAccepted, err := mp.validateAndInsertTransaction(orphan.transaction, true)
+		if err != nil {
+			log.Debugf("Removed orphan transaction %s: %s", orphan.id, err)
+			continue
+		}
+		if !isAccepted {
+			continue
+		}
+		acceptedOrphans = append(acceptedOrphans, orphan.transaction)
+
+		childOutpoints := make([]externalapi.DomainOutpoint, len(orphan.transaction.Outputs))
+		for i := range orphan.transaction.Outputs {
+			childOutpoints[i] = externalapi.DomainOutpoint{TransactionID: *orphan.id, Index: uint32(i)}
+		}
+		queue = append(queue, mp.orphanPool.removeOrphansSpending(childOutpoints)...)
+	}
+	return acceptedOrphans
+}
+
+// RemoveTransaction removes the given transaction from the mempool or the orphan pool.
+// If removeRedeemers is set, the transactions spending its outputs are removed as well.
+func (mp *mempool) RemoveTransaction(transactionID *externalapi.DomainTransactionID, removeRedeemers bool) {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+
+	if orphan, ok := mp.orphanPool.allOrphans[*transactionID]; ok {
+		mp.orphanPool.remove(orphan)
+		return
+	}
+	if transaction, ok := mp.transactionsPool.allTransactions[*transactionID]; ok {
+		mp.removeTransaction(transaction, removeRedeemers)
+	}
+}
+
+func (mp *mempool) removeTransaction(transaction *mempoolTransaction, removeRedeemers bool) {
+	if removeRedeemers {
+		for _, redeemer := range mp.transactionsPool.redeemers(transaction) {
+			mp.transactionsPool.remove(redeemer)
+		}
+	}
+	mp.transactionsPool.remove(transaction)
+}
+
+// BlockCandidateTransactions returns clones of the transactions in the mempool, by descending fee rate
+func (mp *mempool) BlockCandidateTransactions() []*externalapi.DomainTransaction {
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	candidates := make([]*externalapi.DomainTransaction, len(mp.transactionsPool.byFeeRate))
+	for i, transaction := range mp.transactionsPool.byFeeRate {
+		candidates[i] = transaction.transaction.Clone()
+	}
+	return candidates
+}
+
+// BuildBlockTemplate builds a block template with the given coinbase data
+// out of the transactions in the mempool
+func (mp *mempool) BuildBlockTemplate(coinbaseData *externalapi.DomainCoinbaseData,
+	selectionMode externalapi.TransactionSelectionMode) (*externalapi.DomainBlockTemplate, error) {
+
+	candidates := mp.BlockCandidateTransactions()
+	return mp.consensus.BuildBlockTemplateFromCandidates(coinbaseData, candidates,
+		&externalapi.TransactionSelectionOptions{Mode: selectionMode})
+}
+
+// GetTransaction returns the transaction with the given ID from the mempool or the orphan pool
+func (mp *mempool) GetTransaction(transactionID *externalapi.DomainTransactionID) (
+	transaction *externalapi.DomainTransaction, isOrphan bool, found bool) {
+
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	if mempoolTransaction, ok := mp.transactionsPool.allTransactions[*transactionID]; ok {
+		return mempoolTransaction.transaction.Clone(), false, true
+	}
+	if orphan, ok := mp.orphanPool.allOrphans[*transactionID]; ok {
+		return orphan.transaction.Clone(), true, true
+	}
+	return nil, false, false
+}
+
+// TransactionCount returns the number of transactions in the mempool, not including orphans
+func (mp *mempool) TransactionCount() int {
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	return len(mp.transactionsPool.allTransactions)
+}
+
+// OrphanCount returns the number of transactions in the orphan pool
+func (mp *mempool) OrphanCount() int {
+	mp.lock.RLock()
+	defer mp.lock.RUnlock()
+
+	return len(mp.orphanPool.allOrphans)
+}
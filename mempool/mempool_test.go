@@ -0,0 +1,218 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+)
+
+// fakeConsensus validates transactions against a plain UTXO set, and
+// charges every transaction a fee of the difference between its inputs and outputs
+type fakeConsensus struct {
+	externalapi.Consensus
+	utxoSet map[externalapi.DomainOutpoint]externalapi.UTXOEntry
+}
+
+func (fc *fakeConsensus) PopulateMass(transaction *externalapi.DomainTransaction) {
+	transaction.Mass = 1000
+}
+
+func (fc *fakeConsensus) ValidateTransactionAndPopulateWithConsensusData(transaction *externalapi.DomainTransaction) error {
+	var missingOutpoints []*externalapi.DomainOutpoint
+	inputsValue := uint64(0)
+	for _, input := range transaction.Inputs {
+		if input.UTXOEntry == nil {
+			entry, ok := fc.utxoSet[input.PreviousOutpoint]
+			if !ok {
+				missingOutpoints = append(missingOutpoints, &input.PreviousOutpoint)
+				continue
+			}
+			input.UTXOEntry = entry
+		}
+		inputsValue += input.UTXOEntry.Amount()
+	}
+	if len(missingOutpoints) > 0 {
+		return ruleerrors.NewErrMissingTxOut(missingOutpoints)
+	}
+
+	outputsValue := uint64(0)
+	for _, output := range transaction.Outputs {
+		outputsValue += output.Value
+	}
+	if outputsValue > inputsValue {
+		return errors.Wrapf(ruleerrors.ErrSpendTooHigh, "outputs exceed inputs")
+	}
+	transaction.Fee = inputsValue - outputsValue
+	return nil
+}
+
+func (fc *fakeConsensus) accept(transaction *externalapi.DomainTransaction) *externalapi.VirtualChangeSet {
+	toAdd := make(map[externalapi.DomainOutpoint]externalapi.UTXOEntry)
+	toRemove := make(map[externalapi.DomainOutpoint]externalapi.UTXOEntry)
+	for _, input := range transaction.Inputs {
+		toRemove[input.PreviousOutpoint] = fc.utxoSet[input.PreviousOutpoint]
+		delete(fc.utxoSet, input.PreviousOutpoint)
+	}
+	transactionID := consensushashing.TransactionID(transaction)
+	for i, output := range transaction.Outputs {
+		outpoint := externalapi.DomainOutpoint{TransactionID: *transactionID, Index: uint32(i)}
+		fc.utxoSet[outpoint] = utxo.NewUTXOEntry(output.Value, output.ScriptPublicKey, false, 1)
+		toAdd[outpoint] = fc.utxoSet[outpoint]
+	}
+	diff, err := utxo.NewUTXODiffFromCollections(utxo.NewUTXOCollection(toAdd), utxo.NewUTXOCollection(toRemove))
+	if err != nil {
+		panic(err)
+	}
+	return &externalapi.VirtualChangeSet{VirtualUTXODiff: diff, VirtualDAAScore: 1}
+}
+
+var scriptPublicKey = &externalapi.ScriptPublicKey{Script: []byte{0x51}}
+
+func spend(outpoint externalapi.DomainOutpoint, outputValue uint64) *externalapi.DomainTransaction {
+	return &externalapi.DomainTransaction{
+		Inputs:  []*externalapi.DomainTransactionInput{{PreviousOutpoint: outpoint}},
+		Outputs: []*externalapi.DomainTransactionOutput{{Value: outputValue, ScriptPublicKey: scriptPublicKey}},
+	}
+}
+
+func outpointOf(transaction *externalapi.DomainTransaction) externalapi.DomainOutpoint {
+	return externalapi.DomainOutpoint{TransactionID: *consensushashing.TransactionID(transaction)}
+}
+
+func setup() (*fakeConsensus, Mempool, []externalapi.DomainOutpoint) {
+	consensus := &fakeConsensus{utxoSet: make(map[externalapi.DomainOutpoint]externalapi.UTXOEntry)}
+	outpoints := make([]externalapi.DomainOutpoint, 3)
+	for i := range outpoints {
+		outpoints[i] = externalapi.DomainOutpoint{Index: uint32(i)}
+		consensus.utxoSet[outpoints[i]] = utxo.NewUTXOEntry(100_000, scriptPublicKey, false, 0)
+	}
+
+	config := DefaultConfig(time.Second)
+	config.AcceptNonStandard = true
+	return consensus, New(config, consensus), outpoints
+}
+
+func TestValidateAndInsertTransaction(t *testing.T) {
+	_, mempool, outpoints := setup()
+
+	lowFee := spend(outpoints[0], 99_000)
+	highFee := spend(outpoints[1], 90_000)
+	for _, transaction := range []*externalapi.DomainTransaction{lowFee, highFee} {
+		_, err := mempool.ValidateAndInsertTransaction(transaction, false)
+		if err != nil {
+			t.Fatalf("ValidateAndInsertTransaction: %+v", err)
+		}
+	}
+
+	var txRuleErr TxRuleError
+	_, err := mempool.ValidateAndInsertTransaction(spend(outpoints[0], 99_000), false)
+	if !errors.As(err, &txRuleErr) || txRuleErr.RejectCode != RejectDuplicate {
+		t.Fatalf("expected a duplicate transaction to be rejected, got %v", err)
+	}
+	_, err = mempool.ValidateAndInsertTransaction(spend(outpoints[0], 50_000), false)
+	if !errors.As(err, &txRuleErr) || txRuleErr.RejectCode != RejectDuplicate {
+		t.Fatalf("expected a double spend to be rejected, got %v", err)
+	}
+	_, err = mempool.ValidateAndInsertTransaction(spend(outpoints[2], 200_000), false)
+	if !errors.As(err, &txRuleErr) || txRuleErr.RejectCode != RejectInvalid || !errors.Is(err, ruleerrors.ErrSpendTooHigh) {
+		t.Fatalf("expected an invalid transaction to be rejected with the consensus error, got %v", err)
+	}
+
+	candidates := mempool.BlockCandidateTransactions()
+	if len(candidates) != 2 || !candidates[0].Equal(highFee) || !candidates[1].Equal(lowFee) {
+		t.Fatalf("expected the candidates to be sorted by descending fee rate")
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	_, mempool, outpoints := setup()
+
+	parent := spend(outpoints[0], 90_000)
+	child := spend(outpointOf(parent), 80_000)
+	grandchild := spend(outpointOf(child), 70_000)
+
+	_, err := mempool.ValidateAndInsertTransaction(grandchild, false)
+	var txRuleErr TxRuleError
+	if !errors.As(err, &txRuleErr) || txRuleErr.RejectCode != RejectBadOrphan {
+		t.Fatalf("expected an orphan to be rejected when orphans are not allowed, got %v", err)
+	}
+
+	for _, orphan := range []*externalapi.DomainTransaction{grandchild, child} {
+		acceptedTransactions, err := mempool.ValidateAndInsertTransaction(orphan, true)
+		if err != nil {
+			t.Fatalf("ValidateAndInsertTransaction: %+v", err)
+		}
+		if len(acceptedTransactions) != 0 {
+			t.Fatalf("expected the orphan not to be accepted")
+		}
+	}
+	if mempool.OrphanCount() != 2 {
+		t.Fatalf("expected 2 orphans, got %d", mempool.OrphanCount())
+	}
+
+	acceptedTransactions, err := mempool.ValidateAndInsertTransaction(parent, true)
+	if err != nil {
+		t.Fatalf("ValidateAndInsertTransaction: %+v", err)
+	}
+	if len(acceptedTransactions) != 3 || mempool.TransactionCount() != 3 || mempool.OrphanCount() != 0 {
+		t.Fatalf("expected the parent to unorphan its descendants, got %d accepted transactions",
+			len(acceptedTransactions))
+	}
+}
+
+func TestHandleVirtualChangeSet(t *testing.T) {
+	consensus, mempool, outpoints := setup()
+
+	parent := spend(outpoints[0], 90_000)
+	child := spend(outpointOf(parent), 80_000)
+	doubleSpent := spend(outpoints[1], 90_000)
+	doubleSpentChild := spend(outpointOf(doubleSpent), 80_000)
+	orphan := spend(externalapi.DomainOutpoint{Index: 100}, 10_000)
+	for _, transaction := range []*externalapi.DomainTransaction{parent, child, doubleSpent, doubleSpentChild, orphan} {
+		_, err := mempool.ValidateAndInsertTransaction(transaction, true)
+		if err != nil {
+			t.Fatalf("ValidateAndInsertTransaction: %+v", err)
+		}
+	}
+
+	// parent is accepted by consensus, and doubleSpent is double spent by another transaction
+	_, err := mempool.HandleVirtualChangeSet(consensus.accept(parent))
+	if err != nil {
+		t.Fatalf("HandleVirtualChangeSet: %+v", err)
+	}
+	_, err = mempool.HandleVirtualChangeSet(consensus.accept(spend(outpoints[1], 95_000)))
+	if err != nil {
+		t.Fatalf("HandleVirtualChangeSet: %+v", err)
+	}
+
+	for _, transaction := range []*externalapi.DomainTransaction{parent, doubleSpent} {
+		_, _, found := mempool.GetTransaction(consensushashing.TransactionID(transaction))
+		if found {
+			t.Fatalf("expected transaction %s to be removed", consensushashing.TransactionID(transaction))
+		}
+	}
+	_, isOrphan, found := mempool.GetTransaction(consensushashing.TransactionID(child))
+	if !found || isOrphan {
+		t.Fatalf("expected the child of the accepted transaction to stay in the mempool")
+	}
+	_, isOrphan, found = mempool.GetTransaction(consensushashing.TransactionID(doubleSpentChild))
+	if !found || !isOrphan {
+		t.Fatalf("expected the child of the double spent transaction to become an orphan")
+	}
+
+	// The orphans expire once the DAA score advances enough
+	changeSet := consensus.accept(spend(outpoints[2], 90_000))
+	changeSet.VirtualDAAScore = 1000
+	_, err = mempool.HandleVirtualChangeSet(changeSet)
+	if err != nil {
+		t.Fatalf("HandleVirtualChangeSet: %+v", err)
+	}
+	if mempool.OrphanCount() != 0 {
+		t.Fatalf("expected the orphans to expire, %d are left", mempool.OrphanCount())
+	}
+}
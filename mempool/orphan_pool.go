@@ -0,0 +1,91 @@
+package mempool
+
+import (
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+type orphanTransaction struct {
+	transaction     *externalapi.DomainTransaction
+	id              *externalapi.DomainTransactionID
+	addedAtDAAScore uint64
+}
+
+// orphanPool holds the transactions that spend outpoints that are neither in the
+// virtual UTXO set nor created by a transaction in the pool
+type orphanPool struct {
+	allOrphans map[externalapi.DomainTransactionID]*orphanTransaction
+
+	// orphansByPreviousOutpoint maps every outpoint spent by an orphan to the orphans spending it
+	orphansByPreviousOutpoint map[externalapi.DomainOutpoint]map[externalapi.DomainTransactionID]*orphanTransaction
+}
+
+func newOrphanPool() *orphanPool {
+	return &orphanPool{
+		allOrphans:                make(map[externalapi.DomainTransactionID]*orphanTransaction),
+		orphansByPreviousOutpoint: make(map[externalapi.DomainOutpoint]map[externalapi.DomainTransactionID]*orphanTransaction),
+	}
+}
+
+func (op *orphanPool) add(transaction *externalapi.DomainTransaction, daaScore uint64) {
+	orphan := &orphanTransaction{
+		transaction:     transaction,
+		id:              consensushashing.TransactionID(transaction),
+		addedAtDAAScore: daaScore,
+	}
+	op.allOrphans[*orphan.id] = orphan
+	for _, input := range transaction.Inputs {
+		orphans, ok := op.orphansByPreviousOutpoint[input.PreviousOutpoint]
+		if !ok {
+			orphans = make(map[externalapi.DomainTransactionID]*orphanTransaction)
+			op.orphansByPreviousOutpoint[input.PreviousOutpoint] = orphans
+		}
+		orphans[*orphan.id] = orphan
+	}
+}
+
+func (op *orphanPool) remove(orphan *orphanTransaction) {
+	delete(op.allOrphans, *orphan.id)
+	for _, input := range orphan.transaction.Inputs {
+		orphans := op.orphansByPreviousOutpoint[input.PreviousOutpoint]
+		delete(orphans, *orphan.id)
+		if len(orphans) == 0 {
+			delete(op.orphansByPreviousOutpoint, input.PreviousOutpoint)
+		}
+	}
+}
+
+// removeOrphansSpending removes and returns the orphans that spend any of the given outpoints
+func (op *orphanPool) removeOrphansSpending(outpoints []externalapi.DomainOutpoint) []*orphanTransaction {
+	var removed []*orphanTransaction
+	for _, outpoint := range outpoints {
+		for _, orphan := range op.orphansByPreviousOutpoint[outpoint] {
+			op.remove(orphan)
+			removed = append(removed, orphan)
+		}
+	}
+	return removed
+}
+
+// oldest returns the orphan that was added at the lowest DAA score, or nil if the pool is empty
+func (op *orphanPool) oldest() *orphanTransaction {
+	var oldest *orphanTransaction
+	for _, orphan := range op.allOrphans {
+		if oldest == nil || orphan.addedAtDAAScore < oldest.addedAtDAAScore {
+			oldest = orphan
+		}
+	}
+	return oldest
+}
+
+// expire removes the orphans that were added at least expireIntervalDAAScore before the given DAA score
+func (op *orphanPool) expire(daaScore, expireIntervalDAAScore uint64) int {
+	expiredCount := 0
+	for _, orphan := range op.allOrphans {
+		if daaScore >= orphan.addedAtDAAScore+expireIntervalDAAScore {
+			op.remove(orphan)
+			expiredCount++
+		}
+	}
+	return expiredCount
+}
@@ -0,0 +1,110 @@
+package mempool
+
+import (
+	"sort"
+
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+type mempoolTransaction struct {
+	transaction *externalapi.DomainTransaction
+	id          *externalapi.DomainTransactionID
+	feeRate     float64
+}
+
+func newMempoolTransaction(transaction *externalapi.DomainTransaction) *mempoolTransaction {
+	return &mempoolTransaction{
+		transaction: transaction,
+		id:          consensushashing.TransactionID(transaction),
+		feeRate:     float64(transaction.Fee) / float64(transaction.Mass),
+	}
+}
+
+// hasHigherFeeRate orders transactions by descending fee rate, and by ID between equal fee rates
+func (mt *mempoolTransaction) hasHigherFeeRate(other *mempoolTransaction) bool {
+	if mt.feeRate != other.feeRate {
+		return mt.feeRate > other.feeRate
+	}
+	return mt.id.Less(other.id)
+}
+
+// transactionsPool holds the transactions that were fully validated against the virtual UTXO set
+// combined with the outputs of the other transactions in the pool
+type transactionsPool struct {
+	allTransactions map[externalapi.DomainTransactionID]*mempoolTransaction
+
+	// byFeeRate is the fee rate index, sorted by descending fee rate
+	byFeeRate []*mempoolTransaction
+
+	// spentOutpoints maps every outpoint spent by a transaction in the pool to that transaction
+	spentOutpoints map[externalapi.DomainOutpoint]*mempoolTransaction
+}
+
+func newTransactionsPool() *transactionsPool {
+	return &transactionsPool{
+		allTransactions: make(map[externalapi.DomainTransactionID]*mempoolTransaction),
+		spentOutpoints:  make(map[externalapi.DomainOutpoint]*mempoolTransaction),
+	}
+}
+
+func (tp *transactionsPool) add(transaction *mempoolTransaction) {
+	tp.allTransactions[*transaction.id] = transaction
+	for _, input := range transaction.transaction.Inputs {
+		tp.spentOutpoints[input.PreviousOutpoint] = transaction
+	}
+
+	i := sort.Search(len(tp.byFeeRate), func(i int) bool {
+		return transaction.hasHigherFeeRate(tp.byFeeRate[i])
+	})
+	tp.byFeeRate = append(tp.byFeeRate, nil)
+	copy(tp.byFeeRate[i+1:], tp.byFeeRate[i:])
+	tp.byFeeRate[i] = transaction
+}
+
+func (tp *transactionsPool) remove(transaction *mempoolTransaction) {
+	delete(tp.allTransactions, *transaction.id)
+	for _, input := range transaction.transaction.Inputs {
+		delete(tp.spentOutpoints, input.PreviousOutpoint)
+	}
+
+	i := sort.Search(len(tp.byFeeRate), func(i int) bool {
+		return !tp.byFeeRate[i].hasHigherFeeRate(transaction)
+	})
+	if i < len(tp.byFeeRate) && tp.byFeeRate[i] == transaction {
+		tp.byFeeRate = append(tp.byFeeRate[:i], tp.byFeeRate[i+1:]...)
+	}
+}
+
+// lowestFeeRate returns the transaction with the lowest fee rate in the pool, or nil if it's empty
+func (tp *transactionsPool) lowestFeeRate() *mempoolTransaction {
+	if len(tp.byFeeRate) == 0 {
+		return nil
+	}
+	return tp.byFeeRate[len(tp.byFeeRate)-1]
+}
+
+// redeemers returns the transactions in the pool that spend the outputs of the given
+// transaction, directly or through other transactions in the pool, in breadth-first order
+func (tp *transactionsPool) redeemers(transaction *mempoolTransaction) []*mempoolTransaction {
+	var redeemers []*mempoolTransaction
+	visited := map[*mempoolTransaction]struct{}{transaction: {}}
+	queue := []*mempoolTransaction{transaction}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for i := range current.transaction.Outputs {
+			outpoint := externalapi.DomainOutpoint{TransactionID: *current.id, Index: uint32(i)}
+			redeemer, ok := tp.spentOutpoints[outpoint]
+			if !ok {
+				continue
+			}
+			if _, ok := visited[redeemer]; !ok {
+				visited[redeemer] = struct{}{}
+				redeemers = append(redeemers, redeemer)
+				queue = append(queue, redeemer)
+			}
+		}
+	}
+	return redeemers
+}
@@ -0,0 +1,207 @@
+package txindex
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+)
+
+var (
+	txIndexBucketName              = []byte("transaction-index")
+	transactionsBucketName         = []byte("transactions")
+	acceptedTransactionsBucketName = []byte("accepted-transactions")
+	virtualSelectedParentKeyName   = []byte("virtual-selected-parent")
+)
+
+const (
+	transactionAcceptanceRecordSize = 2*externalapi.DomainHashSize + 8
+	virtualSelectedParentRecordSize = externalapi.DomainHashSize + 8
+	deleteBatchSize                 = 1000
+)
+
+// transactionAcceptanceRecord is what the index keeps for every accepted transaction
+type transactionAcceptanceRecord struct {
+	includingBlockHash      *externalapi.DomainHash
+	acceptingBlockHash      *externalapi.DomainHash
+	acceptingBlockBlueScore uint64
+}
+
+// txIndexStore keeps a record for every transaction accepted by the virtual selected parent
+// chain, and the IDs of the transactions accepted by every chain block so that they can be
+// removed when the block leaves the chain
+type txIndexStore struct {
+	databaseContext model.DBManager
+
+	bucket                   model.DBBucket
+	transactionsBucket       model.DBBucket
+	acceptedTransactions     model.DBBucket
+	virtualSelectedParentKey model.DBKey
+}
+
+func newTXIndexStore(databaseContext model.DBManager, prefixBucket model.DBBucket) *txIndexStore {
+	bucket := prefixBucket.Bucket(txIndexBucketName)
+	return &txIndexStore{
+		databaseContext:          databaseContext,
+		bucket:                   bucket,
+		transactionsBucket:       bucket.Bucket(transactionsBucketName),
+		acceptedTransactions:     bucket.Bucket(acceptedTransactionsBucketName),
+		virtualSelectedParentKey: bucket.Key(virtualSelectedParentKeyName),
+	}
+}
+
+func (s *txIndexStore) transactionKey(transactionID *externalapi.DomainTransactionID) model.DBKey {
+	return s.transactionsBucket.Key(transactionID.ByteSlice())
+}
+
+func (s *txIndexStore) acceptedTransactionsKey(blockHash *externalapi.DomainHash) model.DBKey {
+	return s.acceptedTransactions.Key(blockHash.ByteSlice())
+}
+
+func serializeTransactionAcceptanceRecord(record *transactionAcceptanceRecord) []byte {
+	serialized := make([]byte, transactionAcceptanceRecordSize)
+	copy(serialized, record.includingBlockHash.ByteSlice())
+	copy(serialized[externalapi.DomainHashSize:], record.acceptingBlockHash.ByteSlice())
+	binary.LittleEndian.PutUint64(serialized[2*externalapi.DomainHashSize:], record.acceptingBlockBlueScore)
+	return serialized
+}
+
+func deserializeTransactionAcceptanceRecord(serialized []byte) (*transactionAcceptanceRecord, error) {
+	if len(serialized) != transactionAcceptanceRecordSize {
+		return nil, errors.Errorf("a transaction acceptance record is %d bytes but got %d bytes",
+			transactionAcceptanceRecordSize, len(serialized))
+	}
+	includingBlockHash, err := externalapi.NewDomainHashFromByteSlice(serialized[:externalapi.DomainHashSize])
+	if err != nil {
+		return nil, err
+	}
+	acceptingBlockHash, err := externalapi.NewDomainHashFromByteSlice(
+		serialized[externalapi.DomainHashSize : 2*externalapi.DomainHashSize])
+	if err != nil {
+		return nil, err
+	}
+	return &transactionAcceptanceRecord{
+		includingBlockHash:      includingBlockHash,
+		acceptingBlockHash:      acceptingBlockHash,
+		acceptingBlockBlueScore: binary.LittleEndian.Uint64(serialized[2*externalapi.DomainHashSize:]),
+	}, nil
+}
+
+func (s *txIndexStore) transactionAcceptanceRecord(dbContext model.DBReader, transactionID *externalapi.DomainTransactionID) (
+	record *transactionAcceptanceRecord, found bool, err error) {
+
+	serialized, err := dbContext.Get(s.transactionKey(transactionID))
+	if database.IsNotFoundError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	record, err = deserializeTransactionAcceptanceRecord(serialized)
+	if err != nil {
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+func (s *txIndexStore) virtualSelectedParent() (hash *externalapi.DomainHash, blueScore uint64, err error) {
+	serialized, err := s.databaseContext.Get(s.virtualSelectedParentKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(serialized) != virtualSelectedParentRecordSize {
+		return nil, 0, errors.Errorf("a virtual selected parent record is %d bytes but got %d bytes",
+			virtualSelectedParentRecordSize, len(serialized))
+	}
+	hash, err = externalapi.NewDomainHashFromByteSlice(serialized[:externalapi.DomainHashSize])
+	if err != nil {
+		return nil, 0, err
+	}
+	return hash, binary.LittleEndian.Uint64(serialized[externalapi.DomainHashSize:]), nil
+}
+
+// addChainBlock indexes the transactions accepted by the given chain block
+func (s *txIndexStore) addChainBlock(dbTx model.DBTransaction, chainBlockHash *externalapi.DomainHash,
+	chainBlockBlueScore uint64, acceptanceData externalapi.AcceptanceData) error {
+
+	var acceptedTransactionIDs []*externalapi.DomainTransactionID
+	for _, blockAcceptanceData := range acceptanceData {
+		for _, transactionAcceptanceData := range blockAcceptanceData.TransactionAcceptanceData {
+			if !transactionAcceptanceData.IsAccepted {
+				continue
+			}
+			transactionID := consensushashing.TransactionID(transactionAcceptanceData.Transaction)
+			record := &transactionAcceptanceRecord{
+				includingBlockHash:      blockAcceptanceData.BlockHash,
+				acceptingBlockHash:      chainBlockHash,
+				acceptingBlockBlueScore: chainBlockBlueScore,
+			}
+			err := dbTx.Put(s.transactionKey(transactionID), serializeTransactionAcceptanceRecord(record))
+			if err != nil {
+				return err
+			}
+			acceptedTransactionIDs = append(acceptedTransactionIDs, transactionID)
+		}
+	}
+
+	serializedIDs := make([]byte, 0, len(acceptedTransactionIDs)*externalapi.DomainHashSize)
+	for _, transactionID := range acceptedTransactionIDs {
+		serializedIDs = append(serializedIDs, transactionID.ByteSlice()...)
+	}
+	return dbTx.Put(s.acceptedTransactionsKey(chainBlockHash), serializedIDs)
+}
+
+// removeChainBlock removes the transactions accepted by the given block, which left the
+// virtual selected parent chain. Transactions that were accepted by another chain block
+// since are left as they are.
+func (s *txIndexStore) removeChainBlock(dbTx model.DBTransaction, chainBlockHash *externalapi.DomainHash) error {
+	key := s.acceptedTransactionsKey(chainBlockHash)
+	serializedIDs, err := dbTx.Get(key)
+	if database.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	hashes, err := binaryserialization.DeserializeHashes(serializedIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		transactionID := externalapi.DomainTransactionID(*hash)
+		record, found, err := s.transactionAcceptanceRecord(dbTx, &transactionID)
+		if err != nil {
+			return err
+		}
+		if !found || !record.acceptingBlockHash.Equal(chainBlockHash) {
+			continue
+		}
+		err = dbTx.Delete(s.transactionKey(&transactionID))
+		if err != nil {
+			return err
+		}
+	}
+	return dbTx.Delete(key)
+}
+
+func (s *txIndexStore) setVirtualSelectedParent(dbTx model.DBTransaction, hash *externalapi.DomainHash, blueScore uint64) error {
+	serialized := make([]byte, virtualSelectedParentRecordSize)
+	copy(serialized, hash.ByteSlice())
+	binary.LittleEndian.PutUint64(serialized[externalapi.DomainHashSize:], blueScore)
+	return dbTx.Put(s.virtualSelectedParentKey, serialized)
+}
+
+// deleteAll deletes the whole index, starting with the virtual selected parent so that an
+// interrupted deletion is never mistaken for a synced index
+func (s *txIndexStore) deleteAll() error {
+	err := s.databaseContext.Delete(s.virtualSelectedParentKey)
+	if err != nil {
+		return err
+	}
+	return database.DeleteBucket(s.databaseContext, s.bucket, deleteBatchSize)
+}
@@ -0,0 +1,299 @@
+package txindex
+
+import (
+	"sync"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// rebuildStep is the number of chain blocks whose acceptance data is read and indexed at once
+const rebuildStep = 100
+
+// TXIndex indexes the transactions accepted by the virtual selected parent chain by their IDs.
+// It's kept up to date with the VirtualSelectedParentChainChanges of the VirtualChangeSet
+// events raised by consensus. Transactions that are only accepted by the virtual itself are
+// indexed once a chain block accepts them.
+type TXIndex interface {
+	Update(virtualChangeSet *externalapi.VirtualChangeSet) error
+	HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error
+	Rebuild() error
+	IsSynced() (bool, error)
+	TransactionAcceptance(transactionID *externalapi.DomainTransactionID) (acceptance *TransactionAcceptance, found bool, err error)
+}
+
+// TransactionAcceptance tells where an accepted transaction was included and accepted
+type TransactionAcceptance struct {
+	TransactionID      *externalapi.DomainTransactionID
+	IncludingBlockHash *externalapi.DomainHash
+	AcceptingBlockHash *externalapi.DomainHash
+
+	// AcceptingBlockBlueScore is the blue score of the accepting chain block
+	AcceptingBlockBlueScore uint64
+
+	// Confirmations is the number of blue blocks the accepting block is buried under, counting
+	// itself, as of the virtual selected parent the index is synced to. A transaction that's
+	// accepted by the virtual selected parent has one confirmation.
+	Confirmations uint64
+}
+
+type txIndex struct {
+	lock sync.Mutex
+
+	consensus externalapi.Consensus
+	store     *txIndexStore
+}
+
+// New instantiates a TXIndex over the virtual selected parent chain of the given consensus,
+// keeping it in the given database under prefixBucket. If the stored index doesn't match the
+// virtual selected parent of the consensus it's rebuilt.
+//
+// NOTE: No blocks may be added to the consensus while the index is instantiated.
+func New(consensus externalapi.Consensus, databaseContext model.DBManager, prefixBucket model.DBBucket) (TXIndex, error) {
+	ti := &txIndex{
+		consensus: consensus,
+		store:     newTXIndexStore(databaseContext, prefixBucket),
+	}
+
+	isSynced, err := ti.IsSynced()
+	if err != nil {
+		return nil, err
+	}
+	if !isSynced {
+		err := ti.Rebuild()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ti, nil
+}
+
+// IsSynced returns whether the index reflects the current virtual selected parent chain
+func (ti *txIndex) IsSynced() (bool, error) {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	indexVirtualSelectedParent, _, err := ti.store.virtualSelectedParent()
+	if database.IsNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	virtualSelectedParent, err := ti.consensus.GetVirtualSelectedParent()
+	if err != nil {
+		return false, err
+	}
+	return indexVirtualSelectedParent.Equal(virtualSelectedParent), nil
+}
+
+// Update removes the transactions accepted by the chain blocks that left the virtual selected parent
+// chain, and indexes the transactions accepted by the ones that joined it. Change sets that are
+// already reflected in the index, such as ones that were raised during a rebuild, may be applied
+// again as long as they're applied in order.
+func (ti *txIndex) Update(virtualChangeSet *externalapi.VirtualChangeSet) error {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "txIndex.Update")
+	defer onEnd()
+
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	chainChanges := virtualChangeSet.VirtualSelectedParentChainChanges
+	if chainChanges == nil || (len(chainChanges.Added) == 0 && len(chainChanges.Removed) == 0) {
+		return nil
+	}
+
+	acceptanceData, err := ti.consensus.GetBlocksAcceptanceData(chainChanges.Added)
+	if err != nil {
+		return err
+	}
+	blueScores, err := ti.blueScores(chainChanges.Added)
+	if err != nil {
+		return err
+	}
+
+	dbTx, err := ti.store.databaseContext.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.RollbackUnlessClosed()
+
+	for _, removedChainBlock := range chainChanges.Removed {
+		err := ti.store.removeChainBlock(dbTx, removedChainBlock)
+		if err != nil {
+			return err
+		}
+	}
+	for i, addedChainBlock := range chainChanges.Added {
+		err := ti.store.addChainBlock(dbTx, addedChainBlock, blueScores[i], acceptanceData[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(chainChanges.Added) > 0 {
+		virtualSelectedParent := chainChanges.Added[len(chainChanges.Added)-1]
+		err = ti.store.setVirtualSelectedParent(dbTx, virtualSelectedParent, blueScores[len(blueScores)-1])
+		if err != nil {
+			return err
+		}
+	}
+	err = dbTx.Commit()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Updated the transaction index with %d added and %d removed chain blocks",
+		len(chainChanges.Added), len(chainChanges.Removed))
+	return nil
+}
+
+func (ti *txIndex) blueScores(blockHashes []*externalapi.DomainHash) ([]uint64, error) {
+	blueScores := make([]uint64, len(blockHashes))
+	for i, blockHash := range blockHashes {
+		header, err := ti.consensus.GetBlockHeader(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		blueScores[i] = header.BlueScore()
+	}
+	return blueScores, nil
+}
+
+// HandleConsensusEvents handles the VirtualChangeSet events of the given subscription
+// until it's canceled. If events were dropped by the subscription the index is rebuilt,
+// since the chain changes of the dropped events are lost.
+func (ti *txIndex) HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error {
+	return subscription.HandleEvents(func(event externalapi.ConsensusEvent) error {
+		virtualChangeSet, ok := event.(*externalapi.VirtualChangeSet)
+		if !ok {
+			return nil
+		}
+		return ti.Update(virtualChangeSet)
+	}, func(droppedCount uint64) error {
+		log.Warnf("%d consensus events were dropped. Rebuilding the transaction index", droppedCount)
+		return ti.Rebuild()
+	})
+}
+
+// Rebuild deletes the whole index and rebuilds it from the acceptance data of the virtual
+// selected parent chain above the pruning point. Transactions that were accepted by the pruning
+// point or below it aren't indexed, since their acceptance data isn't guaranteed to be kept.
+func (ti *txIndex) Rebuild() error {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	log.Infof("Rebuilding the transaction index")
+
+	err := ti.store.deleteAll()
+	if err != nil {
+		return err
+	}
+
+	pruningPoint, err := ti.consensus.PruningPoint()
+	if err != nil {
+		return err
+	}
+	chainPath, err := ti.consensus.GetVirtualSelectedParentChainFromBlock(pruningPoint)
+	if err != nil {
+		return err
+	}
+	chainBlocks := chainPath.Added
+
+	if len(chainBlocks) == 0 {
+		pruningPointHeader, err := ti.consensus.GetBlockHeader(pruningPoint)
+		if err != nil {
+			return err
+		}
+		dbTx, err := ti.store.databaseContext.Begin()
+		if err != nil {
+			return err
+		}
+		defer dbTx.RollbackUnlessClosed()
+
+		err = ti.store.setVirtualSelectedParent(dbTx, pruningPoint, pruningPointHeader.BlueScore())
+		if err != nil {
+			return err
+		}
+		return dbTx.Commit()
+	}
+
+	for start := 0; start < len(chainBlocks); start += rebuildStep {
+		end := start + rebuildStep
+		if end > len(chainBlocks) {
+			end = len(chainBlocks)
+		}
+		err := ti.addChainBlocks(chainBlocks[start:end])
+		if err != nil {
+			return err
+		}
+		log.Debugf("Indexed %d of %d chain blocks", end, len(chainBlocks))
+	}
+
+	log.Infof("Rebuilt the transaction index from %d chain blocks", len(chainBlocks))
+	return nil
+}
+
+// addChainBlocks indexes the given chain blocks, and marks the last of them as the virtual selected parent
+func (ti *txIndex) addChainBlocks(chainBlocks []*externalapi.DomainHash) error {
+	acceptanceData, err := ti.consensus.GetBlocksAcceptanceData(chainBlocks)
+	if err != nil {
+		return err
+	}
+	blueScores, err := ti.blueScores(chainBlocks)
+	if err != nil {
+		return err
+	}
+
+	dbTx, err := ti.store.databaseContext.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.RollbackUnlessClosed()
+
+	for i, chainBlock := range chainBlocks {
+		err := ti.store.addChainBlock(dbTx, chainBlock, blueScores[i], acceptanceData[i])
+		if err != nil {
+			return err
+		}
+	}
+	err = ti.store.setVirtualSelectedParent(dbTx, chainBlocks[len(chainBlocks)-1], blueScores[len(blueScores)-1])
+	if err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// TransactionAcceptance returns where the given transaction was included and accepted, and how
+// many confirmations it has. found is false if the transaction isn't accepted by the virtual
+// selected parent chain.
+func (ti *txIndex) TransactionAcceptance(transactionID *externalapi.DomainTransactionID) (
+	acceptance *TransactionAcceptance, found bool, err error) {
+
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	record, found, err := ti.store.transactionAcceptanceRecord(ti.store.databaseContext, transactionID)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	_, virtualSelectedParentBlueScore, err := ti.store.virtualSelectedParent()
+	if err != nil {
+		return nil, false, err
+	}
+
+	confirmations := uint64(0)
+	if virtualSelectedParentBlueScore >= record.acceptingBlockBlueScore {
+		confirmations = virtualSelectedParentBlueScore - record.acceptingBlockBlueScore + 1
+	}
+	return &TransactionAcceptance{
+		TransactionID:           transactionID,
+		IncludingBlockHash:      record.includingBlockHash,
+		AcceptingBlockHash:      record.acceptingBlockHash,
+		AcceptingBlockBlueScore: record.acceptingBlockBlueScore,
+		Confirmations:           confirmations,
+	}, true, nil
+}
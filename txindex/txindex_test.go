@@ -0,0 +1,144 @@
+package txindex_test
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/consensushashing"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+	"github.com/zilong-dai/karlsen-miner/txindex"
+)
+
+func addChain(t *testing.T, tc testapi.TestConsensus, index txindex.TXIndex, tip *externalapi.DomainHash,
+	length int, coinbaseData *externalapi.DomainCoinbaseData) []*externalapi.DomainHash {
+
+	chain := make([]*externalapi.DomainHash, length)
+	for i := range chain {
+		blockHash, virtualChangeSet, err := tc.AddBlock([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		err = index.Update(virtualChangeSet)
+		if err != nil {
+			t.Fatalf("Update: %+v", err)
+		}
+		chain[i] = blockHash
+		tip = blockHash
+	}
+	return chain
+}
+
+func coinbaseTransactionID(t *testing.T, tc testapi.TestConsensus, blockHash *externalapi.DomainHash) *externalapi.DomainTransactionID {
+	block, err := tc.GetBlock(blockHash)
+	if err != nil {
+		t.Fatalf("GetBlock: %+v", err)
+	}
+	return consensushashing.TransactionID(block.Transactions[0])
+}
+
+func blueScore(t *testing.T, tc testapi.TestConsensus, blockHash *externalapi.DomainHash) uint64 {
+	header, err := tc.GetBlockHeader(blockHash)
+	if err != nil {
+		t.Fatalf("GetBlockHeader: %+v", err)
+	}
+	return header.BlueScore()
+}
+
+func TestTXIndex(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestTXIndex")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	index, err := txindex.New(tc, tc.DatabaseContext(), database.MakeBucket([]byte("updated")))
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+
+	mainChain := addChain(t, tc, index, consensusConfig.GenesisHash, 10, nil)
+
+	// The coinbase transaction of a chain block is accepted by its chain child
+	includingBlock := mainChain[3]
+	acceptingBlock := mainChain[4]
+	transactionID := coinbaseTransactionID(t, tc, includingBlock)
+	acceptance, found, err := index.TransactionAcceptance(transactionID)
+	if err != nil {
+		t.Fatalf("TransactionAcceptance: %+v", err)
+	}
+	if !found {
+		t.Fatalf("TestTXIndex: the coinbase transaction of block %s wasn't found", includingBlock)
+	}
+	if !acceptance.IncludingBlockHash.Equal(includingBlock) || !acceptance.AcceptingBlockHash.Equal(acceptingBlock) {
+		t.Fatalf("TestTXIndex: unexpected including and accepting blocks: want: %s and %s, got: %s and %s",
+			includingBlock, acceptingBlock, acceptance.IncludingBlockHash, acceptance.AcceptingBlockHash)
+	}
+	expectedConfirmations := blueScore(t, tc, mainChain[len(mainChain)-1]) - blueScore(t, tc, acceptingBlock) + 1
+	if acceptance.Confirmations != expectedConfirmations {
+		t.Fatalf("TestTXIndex: unexpected confirmations: want: %d, got: %d", expectedConfirmations, acceptance.Confirmations)
+	}
+
+	// A longer chain that forks below the accepting block removes the transaction from the index.
+	// Its blocks pay a different script, so that their coinbase transactions differ from the ones
+	// of the main chain blocks with the same blue scores.
+	sideChainCoinbaseData := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	sideChain := addChain(t, tc, index, mainChain[2], 12, sideChainCoinbaseData)
+	virtualSelectedParent, err := tc.GetVirtualSelectedParent()
+	if err != nil {
+		t.Fatalf("GetVirtualSelectedParent: %+v", err)
+	}
+	if !virtualSelectedParent.Equal(sideChain[len(sideChain)-1]) {
+		t.Fatalf("TestTXIndex: the side chain didn't become the selected chain")
+	}
+	_, found, err = index.TransactionAcceptance(transactionID)
+	if err != nil {
+		t.Fatalf("TransactionAcceptance: %+v", err)
+	}
+	if found {
+		t.Fatalf("TestTXIndex: a transaction accepted by a removed chain block is still indexed")
+	}
+
+	isSynced, err := index.IsSynced()
+	if err != nil {
+		t.Fatalf("IsSynced: %+v", err)
+	}
+	if !isSynced {
+		t.Fatalf("TestTXIndex: the updated index isn't synced")
+	}
+
+	// An index that's created now is rebuilt, and agrees with the updated one
+	rebuiltIndex, err := txindex.New(tc, tc.DatabaseContext(), database.MakeBucket([]byte("rebuilt")))
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	for i := 0; i < len(sideChain)-1; i++ {
+		transactionID := coinbaseTransactionID(t, tc, sideChain[i])
+		updated, updatedFound, err := index.TransactionAcceptance(transactionID)
+		if err != nil {
+			t.Fatalf("TransactionAcceptance: %+v", err)
+		}
+		rebuilt, rebuiltFound, err := rebuiltIndex.TransactionAcceptance(transactionID)
+		if err != nil {
+			t.Fatalf("TransactionAcceptance: %+v", err)
+		}
+		if !updatedFound || !rebuiltFound {
+			t.Fatalf("TestTXIndex: the coinbase transaction of side chain block %d is missing", i)
+		}
+		if !updated.AcceptingBlockHash.Equal(sideChain[i+1]) ||
+			!rebuilt.AcceptingBlockHash.Equal(updated.AcceptingBlockHash) ||
+			!rebuilt.IncludingBlockHash.Equal(updated.IncludingBlockHash) ||
+			rebuilt.Confirmations != updated.Confirmations {
+			t.Fatalf("TestTXIndex: unexpected acceptance of the coinbase transaction of side chain block %d: "+
+				"updated: %+v, rebuilt: %+v", i, updated, rebuilt)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package utxoindex
+
+import (
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/ruleerrors"
+)
+
+// rebuildStep is the number of pruning point UTXOs that are read and indexed at once
+const rebuildStep = 1000
+
+// Rebuild deletes the whole index and rebuilds it from the UTXO set of the pruning point,
+// after which the UTXO diff between the pruning point and the virtual is applied. If the
+// pruning point moves while the index is rebuilt, the rebuild starts over.
+func (ui *utxoIndex) Rebuild() error {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	for {
+		err := ui.rebuild()
+		if errors.Is(err, ruleerrors.ErrWrongPruningPointHash) {
+			log.Infof("The pruning point moved during the UTXO index rebuild. Starting over")
+			continue
+		}
+		return err
+	}
+}
+
+func (ui *utxoIndex) rebuild() error {
+	log.Infof("Rebuilding the UTXO index")
+
+	err := ui.store.deleteAll()
+	if err != nil {
+		return err
+	}
+
+	pruningPoint, err := ui.consensus.PruningPoint()
+	if err != nil {
+		return err
+	}
+
+	indexedCount := 0
+	var fromOutpoint *externalapi.DomainOutpoint
+	for {
+		pruningPointUTXOs, err := ui.consensus.GetPruningPointUTXOs(pruningPoint, fromOutpoint, rebuildStep)
+		if err != nil {
+			return err
+		}
+		err = ui.addPruningPointUTXOs(pruningPointUTXOs)
+		if err != nil {
+			return err
+		}
+		indexedCount += len(pruningPointUTXOs)
+		log.Debugf("Indexed %d UTXOs of pruning point %s", indexedCount, pruningPoint)
+
+		if len(pruningPointUTXOs) < rebuildStep {
+			break
+		}
+		fromOutpoint = pruningPointUTXOs[len(pruningPointUTXOs)-1].Outpoint
+	}
+
+	utxoDiff, virtualParents, err := ui.consensus.GetVirtualUTXODiffFromPruningPoint(pruningPoint)
+	if err != nil {
+		return err
+	}
+
+	// The virtual parents are written last, marking the rebuild as complete
+	tx, err := ui.store.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.rollbackUnlessClosed()
+
+	err = tx.applyUTXODiff(utxoDiff)
+	if err != nil {
+		return err
+	}
+	err = tx.setVirtualParents(virtualParents)
+	if err != nil {
+		return err
+	}
+	err = tx.commit()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Rebuilt the UTXO index from the %d UTXOs of pruning point %s, with %d added and %d removed "+
+		"UTXOs since", indexedCount, pruningPoint, utxoDiff.ToAdd().Len(), utxoDiff.ToRemove().Len())
+	return nil
+}
+
+func (ui *utxoIndex) addPruningPointUTXOs(pruningPointUTXOs []*externalapi.OutpointAndUTXOEntryPair) error {
+	tx, err := ui.store.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.rollbackUnlessClosed()
+
+	for _, pair := range pruningPointUTXOs {
+		err := tx.add(pair.Outpoint, pair.UTXOEntry)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.commit()
+}
@@ -0,0 +1,335 @@
+package utxoindex
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/database/binaryserialization"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/utils/utxo"
+)
+
+var (
+	utxoIndexBucketName   = []byte("utxo-index")
+	entriesBucketName     = []byte("entries")
+	balancesBucketName    = []byte("balances")
+	virtualParentsKeyName = []byte("virtual-parents")
+)
+
+const (
+	outpointLength  = externalapi.DomainHashSize + 4
+	balanceLength   = 16
+	deleteBatchSize = 1000
+)
+
+// utxoIndexStore keeps the indexed UTXOs under a bucket per script public key, where every
+// UTXO is keyed by its outpoint. The balance of every script public key is kept aside so
+// that it doesn't require going over its UTXOs.
+type utxoIndexStore struct {
+	databaseContext model.DBManager
+
+	bucket            model.DBBucket
+	entriesBucket     model.DBBucket
+	balancesBucket    model.DBBucket
+	virtualParentsKey model.DBKey
+}
+
+func newUTXOIndexStore(databaseContext model.DBManager, prefixBucket model.DBBucket) *utxoIndexStore {
+	bucket := prefixBucket.Bucket(utxoIndexBucketName)
+	return &utxoIndexStore{
+		databaseContext:   databaseContext,
+		bucket:            bucket,
+		entriesBucket:     bucket.Bucket(entriesBucketName),
+		balancesBucket:    bucket.Bucket(balancesBucketName),
+		virtualParentsKey: bucket.Key(virtualParentsKeyName),
+	}
+}
+
+// scriptPublicKeyKey serializes the given script public key with its length, so that no key
+// is a prefix of another
+func scriptPublicKeyKey(scriptPublicKey *externalapi.ScriptPublicKey) []byte {
+	key := make([]byte, 6+len(scriptPublicKey.Script))
+	binary.LittleEndian.PutUint16(key[:2], scriptPublicKey.Version)
+	binary.LittleEndian.PutUint32(key[2:6], uint32(len(scriptPublicKey.Script)))
+	copy(key[6:], scriptPublicKey.Script)
+	return key
+}
+
+func serializeOutpoint(outpoint *externalapi.DomainOutpoint) []byte {
+	serialized := make([]byte, outpointLength)
+	copy(serialized, outpoint.TransactionID.ByteSlice())
+	binary.LittleEndian.PutUint32(serialized[externalapi.DomainHashSize:], outpoint.Index)
+	return serialized
+}
+
+func (s *utxoIndexStore) entryBucket(scriptPublicKey *externalapi.ScriptPublicKey) model.DBBucket {
+	return s.entriesBucket.Bucket(scriptPublicKeyKey(scriptPublicKey))
+}
+
+func (s *utxoIndexStore) entryKey(outpoint *externalapi.DomainOutpoint, scriptPublicKey *externalapi.ScriptPublicKey) model.DBKey {
+	return s.entryBucket(scriptPublicKey).Key(serializeOutpoint(outpoint))
+}
+
+func (s *utxoIndexStore) balanceKey(scriptPublicKey *externalapi.ScriptPublicKey) model.DBKey {
+	return s.balancesBucket.Key(scriptPublicKeyKey(scriptPublicKey))
+}
+
+func serializeBalance(balance *Balance) []byte {
+	serialized := make([]byte, balanceLength)
+	binary.LittleEndian.PutUint64(serialized[:8], balance.Amount)
+	binary.LittleEndian.PutUint64(serialized[8:], balance.UTXOCount)
+	return serialized
+}
+
+func deserializeBalance(serialized []byte) (*Balance, error) {
+	if len(serialized) != balanceLength {
+		return nil, errors.Errorf("a serialized balance is %d bytes but got %d bytes", balanceLength, len(serialized))
+	}
+	return &Balance{
+		Amount:    binary.LittleEndian.Uint64(serialized[:8]),
+		UTXOCount: binary.LittleEndian.Uint64(serialized[8:]),
+	}, nil
+}
+
+func (s *utxoIndexStore) balance(dbContext model.DBReader, scriptPublicKey *externalapi.ScriptPublicKey) (*Balance, error) {
+	serialized, err := dbContext.Get(s.balanceKey(scriptPublicKey))
+	if database.IsNotFoundError(err) {
+		return &Balance{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deserializeBalance(serialized)
+}
+
+// utxos returns up to limit UTXOs of the given script public key, starting after fromOutpoint if it's set
+func (s *utxoIndexStore) utxos(scriptPublicKey *externalapi.ScriptPublicKey, fromOutpoint *externalapi.DomainOutpoint,
+	limit int) ([]*externalapi.OutpointAndUTXOEntryPair, error) {
+
+	bucket := s.entryBucket(scriptPublicKey)
+	cursor, err := s.databaseContext.Cursor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	// fromOutpoint may have been spent since the previous page, so it doesn't have to be indexed
+	var ok bool
+	if fromOutpoint != nil {
+		ok, err = database.SeekAfter(cursor, bucket.Key(serializeOutpoint(fromOutpoint)))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ok = cursor.First()
+	}
+
+	pairs := make([]*externalapi.OutpointAndUTXOEntryPair, 0, limit)
+	for ; ok && len(pairs) < limit; ok = cursor.Next() {
+		serialized, err := cursor.Value()
+		if err != nil {
+			return nil, err
+		}
+		entry, outpoint, err := utxo.DeserializeUTXO(serialized)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, &externalapi.OutpointAndUTXOEntryPair{Outpoint: outpoint, UTXOEntry: entry})
+	}
+	return pairs, nil
+}
+
+func (s *utxoIndexStore) virtualParents() ([]*externalapi.DomainHash, error) {
+	serialized, err := s.databaseContext.Get(s.virtualParentsKey)
+	if err != nil {
+		return nil, err
+	}
+	return binaryserialization.DeserializeHashes(serialized)
+}
+
+// deleteAll deletes the whole index. The virtual parents go first, since an index
+// without them is treated as unsynced and gets rebuilt.
+func (s *utxoIndexStore) deleteAll() error {
+	err := s.databaseContext.Delete(s.virtualParentsKey)
+	if err != nil {
+		return err
+	}
+	return database.DeleteBucket(s.databaseContext, s.bucket, deleteBatchSize)
+}
+
+// utxoIndexTransaction applies changes to the index within a single database transaction.
+// Adding an indexed UTXO or removing a missing one does nothing, which makes it safe to
+// apply a UTXO diff that's already reflected in the index.
+type utxoIndexTransaction struct {
+	store *utxoIndexStore
+	dbTx  model.DBTransaction
+
+	// entries holds the entries that were written in this transaction by their keys,
+	// where removed entries are nil, since the database transaction may not read its own writes
+	entries  map[string]externalapi.UTXOEntry
+	balances map[string]*stagedBalance
+}
+
+type stagedBalance struct {
+	scriptPublicKey *externalapi.ScriptPublicKey
+	balance         *Balance
+}
+
+func (s *utxoIndexStore) begin() (*utxoIndexTransaction, error) {
+	dbTx, err := s.databaseContext.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &utxoIndexTransaction{
+		store:    s,
+		dbTx:     dbTx,
+		entries:  make(map[string]externalapi.UTXOEntry),
+		balances: make(map[string]*stagedBalance),
+	}, nil
+}
+
+func (tx *utxoIndexTransaction) entry(key model.DBKey) (entry externalapi.UTXOEntry, found bool, err error) {
+	if entry, ok := tx.entries[string(key.Bytes())]; ok {
+		return entry, entry != nil, nil
+	}
+	serialized, err := tx.dbTx.Get(key)
+	if database.IsNotFoundError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	entry, _, err = utxo.DeserializeUTXO(serialized)
+	if err != nil {
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+func (tx *utxoIndexTransaction) balance(scriptPublicKey *externalapi.ScriptPublicKey) (*Balance, error) {
+	key := string(scriptPublicKeyKey(scriptPublicKey))
+	if staged, ok := tx.balances[key]; ok {
+		return staged.balance, nil
+	}
+	balance, err := tx.store.balance(tx.dbTx, scriptPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	tx.balances[key] = &stagedBalance{scriptPublicKey: scriptPublicKey, balance: balance}
+	return balance, nil
+}
+
+func (tx *utxoIndexTransaction) add(outpoint *externalapi.DomainOutpoint, entry externalapi.UTXOEntry) error {
+	key := tx.store.entryKey(outpoint, entry.ScriptPublicKey())
+	_, found, err := tx.entry(key)
+	if err != nil {
+		return err
+	}
+	if found {
+		return nil
+	}
+
+	serialized, err := utxo.SerializeUTXO(entry, outpoint)
+	if err != nil {
+		return err
+	}
+	err = tx.dbTx.Put(key, serialized)
+	if err != nil {
+		return err
+	}
+	tx.entries[string(key.Bytes())] = entry
+
+	balance, err := tx.balance(entry.ScriptPublicKey())
+	if err != nil {
+		return err
+	}
+	balance.Amount += entry.Amount()
+	balance.UTXOCount++
+	return nil
+}
+
+func (tx *utxoIndexTransaction) remove(outpoint *externalapi.DomainOutpoint, scriptPublicKey *externalapi.ScriptPublicKey) error {
+	key := tx.store.entryKey(outpoint, scriptPublicKey)
+	indexedEntry, found, err := tx.entry(key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+
+	err = tx.dbTx.Delete(key)
+	if err != nil {
+		return err
+	}
+	tx.entries[string(key.Bytes())] = nil
+
+	balance, err := tx.balance(scriptPublicKey)
+	if err != nil {
+		return err
+	}
+	if balance.Amount < indexedEntry.Amount() || balance.UTXOCount == 0 {
+		return errors.Errorf("the balance of %x is lower than its UTXO %s", scriptPublicKey.Script, outpoint)
+	}
+	balance.Amount -= indexedEntry.Amount()
+	balance.UTXOCount--
+	return nil
+}
+
+func (tx *utxoIndexTransaction) applyUTXODiff(utxoDiff externalapi.UTXODiff) error {
+	toRemoveIterator := utxoDiff.ToRemove().Iterator()
+	defer toRemoveIterator.Close()
+	for ok := toRemoveIterator.First(); ok; ok = toRemoveIterator.Next() {
+		outpoint, entry, err := toRemoveIterator.Get()
+		if err != nil {
+			return err
+		}
+		err = tx.remove(outpoint, entry.ScriptPublicKey())
+		if err != nil {
+			return err
+		}
+	}
+
+	toAddIterator := utxoDiff.ToAdd().Iterator()
+	defer toAddIterator.Close()
+	for ok := toAddIterator.First(); ok; ok = toAddIterator.Next() {
+		outpoint, entry, err := toAddIterator.Get()
+		if err != nil {
+			return err
+		}
+		err = tx.add(outpoint, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *utxoIndexTransaction) setVirtualParents(virtualParents []*externalapi.DomainHash) error {
+	return tx.dbTx.Put(tx.store.virtualParentsKey, binaryserialization.SerializeHashes(virtualParents))
+}
+
+func (tx *utxoIndexTransaction) commit() error {
+	for _, staged := range tx.balances {
+		key := tx.store.balanceKey(staged.scriptPublicKey)
+		var err error
+		if staged.balance.UTXOCount == 0 {
+			err = tx.dbTx.Delete(key)
+		} else {
+			err = tx.dbTx.Put(key, serializeBalance(staged.balance))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.dbTx.Commit()
+}
+
+func (tx *utxoIndexTransaction) rollbackUnlessClosed() {
+	err := tx.dbTx.RollbackUnlessClosed()
+	if err != nil {
+		log.Warnf("Failed to roll back a UTXO index transaction: %s", err)
+	}
+}
@@ -0,0 +1,179 @@
+package utxoindex
+
+import (
+	"sync"
+
+	"github.com/karlsen-network/karlsend/v2/infrastructure/logger"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+)
+
+// UTXOIndex indexes the virtual UTXO set by script public key. It's kept up to date
+// with the VirtualUTXODiff of the VirtualChangeSet events raised by consensus.
+type UTXOIndex interface {
+	Update(virtualChangeSet *externalapi.VirtualChangeSet) error
+	HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error
+	Rebuild() error
+	IsSynced() (bool, error)
+	UTXOs(scriptPublicKey *externalapi.ScriptPublicKey, fromOutpoint *externalapi.DomainOutpoint, limit int) (
+		[]*externalapi.OutpointAndUTXOEntryPair, error)
+	Balance(scriptPublicKey *externalapi.ScriptPublicKey) (*Balance, error)
+	TotalBalance(scriptPublicKeys []*externalapi.ScriptPublicKey) (*Balance, error)
+}
+
+// Balance is the sum of the UTXOs of one or more script public keys
+type Balance struct {
+	Amount    uint64
+	UTXOCount uint64
+}
+
+type utxoIndex struct {
+	lock sync.Mutex
+
+	consensus externalapi.Consensus
+	store     *utxoIndexStore
+}
+
+// New instantiates a UTXOIndex over the virtual UTXO set of the given consensus, keeping it
+// in the given database under prefixBucket. If the stored index doesn't match the virtual of
+// the consensus it's rebuilt.
+//
+// NOTE: No blocks may be added to the consensus while the index is instantiated.
+func New(consensus externalapi.Consensus, databaseContext model.DBManager, prefixBucket model.DBBucket) (UTXOIndex, error) {
+	ui := &utxoIndex{
+		consensus: consensus,
+		store:     newUTXOIndexStore(databaseContext, prefixBucket),
+	}
+
+	isSynced, err := ui.IsSynced()
+	if err != nil {
+		return nil, err
+	}
+	if !isSynced {
+		err := ui.Rebuild()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ui, nil
+}
+
+// IsSynced returns whether the index reflects the current virtual UTXO set
+func (ui *utxoIndex) IsSynced() (bool, error) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	indexVirtualParents, err := ui.store.virtualParents()
+	if database.IsNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	virtualInfo, err := ui.consensus.GetVirtualInfo()
+	if err != nil {
+		return false, err
+	}
+	return externalapi.HashesEqual(indexVirtualParents, virtualInfo.ParentHashes), nil
+}
+
+// Update applies the VirtualUTXODiff of the given virtual change set to the index. Change sets
+// that are already reflected in the index, such as ones that were raised during a rebuild, may
+// be applied again as long as they're applied in order.
+func (ui *utxoIndex) Update(virtualChangeSet *externalapi.VirtualChangeSet) error {
+	onEnd := logger.LogAndMeasureExecutionTime(log, "utxoIndex.Update")
+	defer onEnd()
+
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	if virtualChangeSet.VirtualUTXODiff == nil {
+		return nil
+	}
+
+	tx, err := ui.store.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.rollbackUnlessClosed()
+
+	err = tx.applyUTXODiff(virtualChangeSet.VirtualUTXODiff)
+	if err != nil {
+		return err
+	}
+	err = tx.setVirtualParents(virtualChangeSet.VirtualParents)
+	if err != nil {
+		return err
+	}
+	err = tx.commit()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Updated the UTXO index with %d added and %d removed UTXOs",
+		virtualChangeSet.VirtualUTXODiff.ToAdd().Len(), virtualChangeSet.VirtualUTXODiff.ToRemove().Len())
+	return nil
+}
+
+// HandleConsensusEvents handles the VirtualChangeSet events of the given subscription
+// until it's canceled. If events were dropped by the subscription the index is rebuilt,
+// since the UTXO diffs of the dropped events are lost.
+func (ui *utxoIndex) HandleConsensusEvents(subscription externalapi.ConsensusEventSubscription) error {
+	return subscription.HandleEvents(func(event externalapi.ConsensusEvent) error {
+		virtualChangeSet, ok := event.(*externalapi.VirtualChangeSet)
+		if !ok {
+			return nil
+		}
+		return ui.Update(virtualChangeSet)
+	}, func(droppedCount uint64) error {
+		log.Warnf("%d consensus events were dropped. Rebuilding the UTXO index", droppedCount)
+		return ui.Rebuild()
+	})
+}
+
+// UTXOs returns up to limit UTXOs of the given script public key, ordered by outpoint. If fromOutpoint
+// is set, the UTXOs start right after it, so that the last outpoint of a page can be passed to get
+// the next one, even if it was spent in the meantime.
+func (ui *utxoIndex) UTXOs(scriptPublicKey *externalapi.ScriptPublicKey, fromOutpoint *externalapi.DomainOutpoint,
+	limit int) ([]*externalapi.OutpointAndUTXOEntryPair, error) {
+
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	return ui.store.utxos(scriptPublicKey, fromOutpoint, limit)
+}
+
+// Balance returns the balance of the given script public key
+func (ui *utxoIndex) Balance(scriptPublicKey *externalapi.ScriptPublicKey) (*Balance, error) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	return ui.store.balance(ui.store.databaseContext, scriptPublicKey)
+}
+
+// TotalBalance returns the sum of the balances of the given script public keys.
+// Script public keys that appear more than once are only counted once.
+func (ui *utxoIndex) TotalBalance(scriptPublicKeys []*externalapi.ScriptPublicKey) (*Balance, error) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	total := &Balance{}
+	counted := make(map[string]struct{}, len(scriptPublicKeys))
+	for _, scriptPublicKey := range scriptPublicKeys {
+		key := string(scriptPublicKeyKey(scriptPublicKey))
+		if _, ok := counted[key]; ok {
+			continue
+		}
+		counted[key] = struct{}{}
+
+		balance, err := ui.store.balance(ui.store.databaseContext, scriptPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		total.Amount += balance.Amount
+		total.UTXOCount += balance.UTXOCount
+	}
+	return total, nil
+}
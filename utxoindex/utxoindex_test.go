@@ -0,0 +1,170 @@
+package utxoindex_test
+
+import (
+	"testing"
+
+	"github.com/zilong-dai/karlsen-miner/consensus"
+	"github.com/zilong-dai/karlsen-miner/consensus/database"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/externalapi"
+	"github.com/zilong-dai/karlsen-miner/consensus/model/testapi"
+	"github.com/zilong-dai/karlsen-miner/dagconfig"
+	"github.com/zilong-dai/karlsen-miner/utxoindex"
+)
+
+// virtualBalance sums the virtual UTXOs of the given script public key
+func virtualBalance(t *testing.T, tc testapi.TestConsensus, scriptPublicKey *externalapi.ScriptPublicKey) *utxoindex.Balance {
+	virtualInfo, err := tc.GetVirtualInfo()
+	if err != nil {
+		t.Fatalf("GetVirtualInfo: %+v", err)
+	}
+
+	balance := &utxoindex.Balance{}
+	var fromOutpoint *externalapi.DomainOutpoint
+	for {
+		const step = 100
+		virtualUTXOs, err := tc.GetVirtualUTXOs(virtualInfo.ParentHashes, fromOutpoint, step)
+		if err != nil {
+			t.Fatalf("GetVirtualUTXOs: %+v", err)
+		}
+		for _, pair := range virtualUTXOs {
+			if pair.UTXOEntry.ScriptPublicKey().Equal(scriptPublicKey) {
+				balance.Amount += pair.UTXOEntry.Amount()
+				balance.UTXOCount++
+			}
+		}
+		if len(virtualUTXOs) < step {
+			return balance
+		}
+		fromOutpoint = virtualUTXOs[len(virtualUTXOs)-1].Outpoint
+	}
+}
+
+func checkBalance(t *testing.T, index utxoindex.UTXOIndex, scriptPublicKey *externalapi.ScriptPublicKey,
+	expected *utxoindex.Balance, name string) {
+
+	balance, err := index.Balance(scriptPublicKey)
+	if err != nil {
+		t.Fatalf("Balance: %+v", err)
+	}
+	if *balance != *expected {
+		t.Fatalf("TestUTXOIndex: unexpected %s balance: want: %+v, got: %+v", name, expected, balance)
+	}
+
+	var utxoCount uint64
+	var fromOutpoint *externalapi.DomainOutpoint
+	for {
+		const step = 3
+		utxos, err := index.UTXOs(scriptPublicKey, fromOutpoint, step)
+		if err != nil {
+			t.Fatalf("UTXOs: %+v", err)
+		}
+		utxoCount += uint64(len(utxos))
+		if len(utxos) < step {
+			break
+		}
+		fromOutpoint = utxos[len(utxos)-1].Outpoint
+	}
+	if utxoCount != expected.UTXOCount {
+		t.Fatalf("TestUTXOIndex: unexpected %s UTXO count: want: %d, got: %d", name, expected.UTXOCount, utxoCount)
+	}
+}
+
+func TestUTXOIndex(t *testing.T) {
+	consensusConfig := &consensus.Config{Params: dagconfig.SimnetParams}
+	consensusConfig.SkipProofOfWork = true
+	// This is done to reduce the pruning depth to 12 blocks, so that the pruning point moves
+	consensusConfig.FinalityDuration = 5 * consensusConfig.TargetTimePerBlock
+	consensusConfig.K = 0
+
+	tc, teardown, err := consensus.NewFactory().NewTestConsensus(consensusConfig, "TestUTXOIndex")
+	if err != nil {
+		t.Fatalf("Error setting up consensus: %+v", err)
+	}
+	defer teardown(false)
+
+	minerA := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{1}, Version: 0},
+		ExtraData:       []byte{},
+	}
+	minerB := &externalapi.DomainCoinbaseData{
+		ScriptPublicKey: &externalapi.ScriptPublicKey{Script: []byte{2}, Version: 0},
+		ExtraData:       []byte{},
+	}
+
+	index, err := utxoindex.New(tc, tc.DatabaseContext(), database.MakeBucket([]byte("updated")))
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+
+	tip := consensusConfig.GenesisHash
+	for i := 0; i < 40; i++ {
+		coinbaseData := minerA
+		if i%3 == 0 {
+			coinbaseData = minerB
+		}
+		blockHash, virtualChangeSet, err := tc.AddBlock([]*externalapi.DomainHash{tip}, coinbaseData, nil)
+		if err != nil {
+			t.Fatalf("AddBlock: %+v", err)
+		}
+		err = index.Update(virtualChangeSet)
+		if err != nil {
+			t.Fatalf("Update: %+v", err)
+		}
+		tip = blockHash
+	}
+
+	pruningPoint, err := tc.PruningPoint()
+	if err != nil {
+		t.Fatalf("PruningPoint: %+v", err)
+	}
+	if pruningPoint.Equal(consensusConfig.GenesisHash) {
+		t.Fatalf("TestUTXOIndex: the pruning point didn't move")
+	}
+
+	isSynced, err := index.IsSynced()
+	if err != nil {
+		t.Fatalf("IsSynced: %+v", err)
+	}
+	if !isSynced {
+		t.Fatalf("TestUTXOIndex: the updated index isn't synced")
+	}
+
+	expectedA := virtualBalance(t, tc, minerA.ScriptPublicKey)
+	expectedB := virtualBalance(t, tc, minerB.ScriptPublicKey)
+	if expectedA.UTXOCount == 0 || expectedB.UTXOCount == 0 {
+		t.Fatalf("TestUTXOIndex: the miners weren't paid")
+	}
+	checkBalance(t, index, minerA.ScriptPublicKey, expectedA, "updated minerA")
+	checkBalance(t, index, minerB.ScriptPublicKey, expectedB, "updated minerB")
+
+	// An index that's created now is rebuilt from the pruning point UTXO set
+	rebuiltIndex, err := utxoindex.New(tc, tc.DatabaseContext(), database.MakeBucket([]byte("rebuilt")))
+	if err != nil {
+		t.Fatalf("New: %+v", err)
+	}
+	checkBalance(t, rebuiltIndex, minerA.ScriptPublicKey, expectedA, "rebuilt minerA")
+	checkBalance(t, rebuiltIndex, minerB.ScriptPublicKey, expectedB, "rebuilt minerB")
+
+	total, err := rebuiltIndex.TotalBalance([]*externalapi.ScriptPublicKey{
+		minerA.ScriptPublicKey, minerB.ScriptPublicKey, minerA.ScriptPublicKey})
+	if err != nil {
+		t.Fatalf("TotalBalance: %+v", err)
+	}
+	if total.Amount != expectedA.Amount+expectedB.Amount || total.UTXOCount != expectedA.UTXOCount+expectedB.UTXOCount {
+		t.Fatalf("TestUTXOIndex: unexpected total balance: want: %d, got: %d",
+			expectedA.Amount+expectedB.Amount, total.Amount)
+	}
+
+	// Applying a change set that's already reflected in the index changes nothing
+	_, virtualChangeSet, err := tc.AddBlock([]*externalapi.DomainHash{tip}, minerA, nil)
+	if err != nil {
+		t.Fatalf("AddBlock: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		err = index.Update(virtualChangeSet)
+		if err != nil {
+			t.Fatalf("Update: %+v", err)
+		}
+	}
+	checkBalance(t, index, minerA.ScriptPublicKey, virtualBalance(t, tc, minerA.ScriptPublicKey), "twice updated minerA")
+}